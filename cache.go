@@ -0,0 +1,483 @@
+package sdk
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// cacheKeyVersion changes when the key derivation does, so that old
+	// entries are missed rather than misread.
+	cacheKeyVersion = 1
+
+	defaultMemoryCacheEntries = 1000
+)
+
+// CacheStore stores the entries of a ResponseCache by key. Implementations
+// must be safe for concurrent use.
+type CacheStore interface {
+	// Get returns the value stored under key, and false if there is none.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte) error
+	Delete(ctx context.Context, key string) error
+}
+
+// CacheRule decides whether a request may be served from and stored in the
+// cache. request is the typed request of the call, as in Call.Request.
+type CacheRule func(provider Provider, request any) bool
+
+// CacheDeterministic is a CacheRule that caches only requests whose
+// responses are meant to be reproducible: with a temperature of 0 or, for
+// chat completions, a seed.
+func CacheDeterministic(_ Provider, request any) bool {
+	zero := func(temperature *float32) bool { return temperature != nil && *temperature == 0 }
+	switch request := request.(type) {
+	case *CreateChatCompletionRequest:
+		return zero(request.Temperature) || request.Seed != nil
+	case *CreateMessagesRequest:
+		return zero(request.Temperature)
+	case *CreateResponseRequest:
+		return zero(request.Temperature)
+	}
+	return false
+}
+
+// CacheOptions configures a ResponseCache.
+type CacheOptions struct {
+	// Store defaults to a MemoryCacheStore of 1000 entries.
+	Store CacheStore
+	// TTL is how long entries are served; zero keeps them until the store
+	// evicts them.
+	TTL time.Duration
+	// Rule, when set, must allow a request for it to be cached, e.g.
+	// CacheDeterministic. Requests it rejects bypass the cache.
+	Rule CacheRule
+}
+
+// CacheStats counts the calls a ResponseCache has seen.
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+	// Bypasses counts calls the cache didn't apply to: other operations,
+	// requests the Rule rejected and calls made with WithCacheBypass.
+	Bypasses int64
+	// Writes counts responses stored; failed calls and streams that
+	// didn't finish aren't.
+	Writes int64
+	// Errors counts store and encoding errors. Calls go on without the
+	// cache when they happen.
+	Errors int64
+}
+
+// HitRate returns the share of cacheable calls served from the cache.
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// ResponseCache serves repeated chat, Messages and Responses requests,
+// streaming or not, from a CacheStore. Entries are keyed by a hash of the
+// provider, the operation and the complete request, including the model,
+// messages, tools and sampling parameters. Streams are stored once they
+// finish and replayed as synthetic event streams.
+//
+// Set it as ClientOptions.Cache, or add Intercept to the interceptors of a
+// client to choose where it runs. It is safe for concurrent use.
+type ResponseCache struct {
+	options CacheOptions
+
+	hits, misses, bypasses, writes, errors atomic.Int64
+}
+
+// NewResponseCache creates a ResponseCache.
+//
+// Example, caching deterministic completions on disk for an hour:
+//
+//	store, err := sdk.NewFileCacheStore(".cache/gateway")
+//	if err != nil {
+//		return err
+//	}
+//	client := sdk.NewClient(&sdk.ClientOptions{
+//		BaseURL: "http://localhost:8080/v1",
+//		Cache: sdk.NewResponseCache(&sdk.CacheOptions{
+//			Store: store,
+//			TTL:   time.Hour,
+//			Rule:  sdk.CacheDeterministic,
+//		}),
+//	})
+func NewResponseCache(options *CacheOptions) *ResponseCache {
+	c := &ResponseCache{}
+	if options != nil {
+		c.options = *options
+	}
+	if c.options.Store == nil {
+		c.options.Store = NewMemoryCacheStore(defaultMemoryCacheEntries)
+	}
+	return c
+}
+
+// Stats returns the counts of the calls the cache has seen.
+func (c *ResponseCache) Stats() CacheStats {
+	return CacheStats{
+		Hits:     c.hits.Load(),
+		Misses:   c.misses.Load(),
+		Bypasses: c.bypasses.Load(),
+		Writes:   c.writes.Load(),
+		Errors:   c.errors.Load(),
+	}
+}
+
+type cacheBypassContextKey struct{}
+
+// WithCacheBypass makes the calls made with ctx bypass response caches,
+// e.g. to refresh a result.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassContextKey{}, true)
+}
+
+// cacheEntry is the stored form of a response.
+type cacheEntry struct {
+	Expires  time.Time       `json:"expires,omitzero"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Events   []SSEvent       `json:"events,omitempty"`
+}
+
+// Intercept is an Interceptor that serves calls from the cache and stores
+// their responses.
+func (c *ResponseCache) Intercept(ctx context.Context, call *Call, next Invoker) (any, error) {
+	if !cacheable(call.Operation) || ctx.Value(cacheBypassContextKey{}) != nil ||
+		(c.options.Rule != nil && !c.options.Rule(call.Provider, call.Request)) {
+		c.bypasses.Add(1)
+		return next(ctx, call)
+	}
+
+	key, err := cacheKey(call)
+	if err != nil {
+		c.errors.Add(1)
+		return next(ctx, call)
+	}
+	if response, ok := c.lookup(ctx, key, call.Operation); ok {
+		c.hits.Add(1)
+		return response, nil
+	}
+	c.misses.Add(1)
+
+	response, err := next(ctx, call)
+	if err != nil {
+		return response, err
+	}
+	if events, ok := response.(<-chan SSEvent); ok {
+		return c.record(ctx, key, call.Operation, events), nil
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		c.errors.Add(1)
+		return response, nil
+	}
+	c.store(ctx, key, cacheEntry{Response: data})
+	return response, nil
+}
+
+// cacheable reports whether the cache applies to an operation.
+func cacheable(operation Operation) bool {
+	switch operation {
+	case OperationGenerateContent, OperationGenerateContentStream,
+		OperationCreateMessage, OperationCreateMessageStream,
+		OperationCreateResponse, OperationCreateResponseStream:
+		return true
+	}
+	return false
+}
+
+// cacheKey hashes the provider, operation and request of a call.
+func cacheKey(call *Call) (string, error) {
+	data, err := json.Marshal(struct {
+		Version   int       `json:"version"`
+		Provider  Provider  `json:"provider"`
+		Operation Operation `json:"operation"`
+		Request   any       `json:"request"`
+	}{cacheKeyVersion, call.Provider, call.Operation, call.Request})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cache key: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// lookup returns the cached response of key, decoded for operation.
+func (c *ResponseCache) lookup(ctx context.Context, key string, operation Operation) (any, bool) {
+	data, ok, err := c.options.Store.Get(ctx, key)
+	if err != nil {
+		c.errors.Add(1)
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		c.errors.Add(1)
+		return nil, false
+	}
+	if !entry.Expires.IsZero() && time.Now().After(entry.Expires) {
+		if err := c.options.Store.Delete(ctx, key); err != nil {
+			c.errors.Add(1)
+		}
+		return nil, false
+	}
+
+	if operation.Streaming() {
+		return replay(ctx, entry.Events), true
+	}
+	var response any
+	switch operation {
+	case OperationGenerateContent:
+		response = &CreateChatCompletionResponse{}
+	case OperationCreateMessage:
+		response = &MessagesResponse{}
+	case OperationCreateResponse:
+		response = &Response{}
+	}
+	if err := json.Unmarshal(entry.Response, response); err != nil {
+		c.errors.Add(1)
+		return nil, false
+	}
+	return response, true
+}
+
+// store writes an entry with the cache's TTL.
+func (c *ResponseCache) store(ctx context.Context, key string, entry cacheEntry) {
+	if c.options.TTL > 0 {
+		entry.Expires = time.Now().Add(c.options.TTL)
+	}
+	data, err := json.Marshal(entry)
+	if err == nil {
+		err = c.options.Store.Set(ctx, key, data)
+	}
+	if err != nil {
+		c.errors.Add(1)
+		return
+	}
+	c.writes.Add(1)
+}
+
+// record passes the events of a stream through and stores them once the
+// stream finishes without errors.
+func (c *ResponseCache) record(ctx context.Context, key string, operation Operation, events <-chan SSEvent) <-chan SSEvent {
+	out := make(chan SSEvent, 100)
+	go func() {
+		defer close(out)
+
+		var recorded []SSEvent
+		complete := true
+		for event := range events {
+			if event.Event == nil {
+				complete = false
+			}
+			recorded = append(recorded, copyEvent(event))
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if !complete || ctx.Err() != nil || !finishedStream(recorded, operation.API()) {
+			return
+		}
+		c.store(context.WithoutCancel(ctx), key, cacheEntry{Events: recorded})
+	}()
+	return out
+}
+
+// finishedStream reports whether events add up to a finished response, so
+// that streams cut short aren't stored.
+func finishedStream(events []SSEvent, api API) bool {
+	acc := newStreamAccumulator(api)
+	for _, event := range events {
+		if event.Event != nil && *event.Event == ContentDelta && event.Data != nil {
+			if _, err := acc.add(*event.Data); err != nil {
+				return false
+			}
+		}
+	}
+	response, err := acc.result()
+	if err != nil {
+		return false
+	}
+	switch raw := response.Raw.(type) {
+	case *CreateChatCompletionResponse:
+		return response.FinishReason != ""
+	case *MessagesResponse:
+		return raw.StopReason != ""
+	}
+	return true
+}
+
+// replay returns a stream of the events of a cached stream.
+func replay(ctx context.Context, events []SSEvent) <-chan SSEvent {
+	out := make(chan SSEvent, 100)
+	go func() {
+		defer close(out)
+		for _, event := range events {
+			select {
+			case out <- copyEvent(event):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// copyEvent copies the data of an event, which its receiver may keep.
+func copyEvent(event SSEvent) SSEvent {
+	if event.Data != nil {
+		data := append([]byte(nil), *event.Data...)
+		event.Data = &data
+	}
+	return event
+}
+
+// MemoryCacheStore is an in-memory CacheStore that evicts the least
+// recently used entries beyond its capacity.
+type MemoryCacheStore struct {
+	capacity int
+
+	mu      sync.Mutex
+	order   *list.List // of *memoryCacheItem, most recently used first
+	entries map[string]*list.Element
+}
+
+type memoryCacheItem struct {
+	key   string
+	value []byte
+}
+
+// NewMemoryCacheStore creates a MemoryCacheStore of up to capacity
+// entries, 1000 if capacity isn't positive.
+func NewMemoryCacheStore(capacity int) *MemoryCacheStore {
+	if capacity <= 0 {
+		capacity = defaultMemoryCacheEntries
+	}
+	return &MemoryCacheStore{capacity: capacity, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Get implements CacheStore.
+func (s *MemoryCacheStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	element, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	s.order.MoveToFront(element)
+	return element.Value.(*memoryCacheItem).value, true, nil
+}
+
+// Set implements CacheStore.
+func (s *MemoryCacheStore) Set(_ context.Context, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.entries[key]; ok {
+		element.Value.(*memoryCacheItem).value = value
+		s.order.MoveToFront(element)
+		return nil
+	}
+	s.entries[key] = s.order.PushFront(&memoryCacheItem{key: key, value: value})
+	for s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*memoryCacheItem).key)
+	}
+	return nil
+}
+
+// Delete implements CacheStore.
+func (s *MemoryCacheStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.entries[key]; ok {
+		s.order.Remove(element)
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// Len returns the number of entries stored.
+func (s *MemoryCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// FileCacheStore is a CacheStore keeping one file per entry in a
+// directory, so that entries outlive the process, e.g. between CI runs.
+type FileCacheStore struct {
+	dir string
+}
+
+// NewFileCacheStore creates a FileCacheStore in dir, creating it if needed.
+func NewFileCacheStore(dir string) (*FileCacheStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &FileCacheStore{dir: dir}, nil
+}
+
+func (s *FileCacheStore) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
+
+// Get implements CacheStore.
+func (s *FileCacheStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Set implements CacheStore. Entries are written to a temporary file and
+// renamed, so that readers never see partial entries.
+func (s *FileCacheStore) Set(_ context.Context, key string, value []byte) error {
+	file, err := os.CreateTemp(s.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(value)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), s.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+	}
+	return err
+}
+
+// Delete implements CacheStore.
+func (s *FileCacheStore) Delete(_ context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
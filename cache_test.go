@@ -0,0 +1,217 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// newCacheGateway answers chat completions, streaming or not, and Messages
+// API calls, for a client caching in cache.
+func newCacheGateway(t *testing.T, cache *ResponseCache) (*testGateway, Client) {
+	t.Helper()
+	gateway := newTestGateway(t)
+	gateway.handle("POST /v1/messages", func(w http.ResponseWriter, r *gatewayRequest) {
+		jsonReply(`{
+			"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-sonnet-5",
+			"content": [{"type": "text", "text": "Hi from messages"}],
+			"stop_reason": "end_turn", "usage": {"input_tokens": 3, "output_tokens": 4}
+		}`)(w)
+	})
+	chat := chatRoute("Hi", nil)
+	gateway.handle("POST /v1/chat/completions", func(w http.ResponseWriter, r *gatewayRequest) {
+		switch {
+		case r.stream() && r.model == "broken":
+			sseReply(`{"id":"1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"content":"Hi"}}]}`)(w)
+			_, _ = w.Write([]byte("data: {\"id\":"))
+		case r.stream():
+			sseReply(
+				`{"id":"1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"content":"Hi"}}]}`,
+				`{"id":"1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"content":" there"},"finish_reason":"stop"}]}`,
+				"[DONE]",
+			)(w)
+		default:
+			chat(w, r)
+		}
+	})
+	return gateway, gateway.client(&ClientOptions{Cache: cache})
+}
+
+func collectEvents(t *testing.T, stream <-chan SSEvent) []string {
+	t.Helper()
+	var events []string
+	for event := range stream {
+		entry := ""
+		if event.Event != nil {
+			entry = string(*event.Event)
+		}
+		if event.Data != nil {
+			entry += " " + string(*event.Data)
+		}
+		events = append(events, entry)
+	}
+	return events
+}
+
+func TestResponseCache_GenerateContent(t *testing.T) {
+	cache := NewResponseCache(nil)
+	gateway, client := newCacheGateway(t, cache)
+	ctx := context.Background()
+
+	first, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	second, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.NotSame(t, first, second, "hits are decoded afresh")
+	assert.Equal(t, 1, gateway.count(""))
+
+	// Any change to the provider or the request misses.
+	_, err = client.GenerateContent(ctx, Groq, "gpt-4o", hello())
+	require.NoError(t, err)
+	_, err = client.WithOptions(&CreateChatCompletionRequest{Temperature: new(float32(0.2))}).GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Equal(t, 3, gateway.count(""))
+
+	message, err := client.CreateMessage(ctx, Anthropic, CreateMessagesRequest{Model: "claude-sonnet-5", MaxTokens: 100, Messages: []MessagesMessage{}})
+	require.NoError(t, err)
+	cached, err := client.CreateMessage(ctx, Anthropic, CreateMessagesRequest{Model: "claude-sonnet-5", MaxTokens: 100, Messages: []MessagesMessage{}})
+	require.NoError(t, err)
+	want, err := json.Marshal(message)
+	require.NoError(t, err)
+	got, err := json.Marshal(cached)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(want), string(got))
+	assert.Equal(t, 4, gateway.count(""))
+
+	stats := cache.Stats()
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 4, Writes: 4}, stats)
+	assert.InDelta(t, 1.0/3, stats.HitRate(), 0.001)
+}
+
+func TestResponseCache_Streams(t *testing.T) {
+	cache := NewResponseCache(nil)
+	gateway, client := newCacheGateway(t, cache)
+	ctx := context.Background()
+
+	stream, err := client.GenerateContentStream(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	live := collectEvents(t, stream)
+	require.Len(t, live, 3)
+
+	require.Eventually(t, func() bool { return cache.Stats().Writes == 1 }, time.Second, 10*time.Millisecond)
+	stream, err = client.GenerateContentStream(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Equal(t, live, collectEvents(t, stream))
+	assert.Equal(t, 1, gateway.count(""))
+
+	// Streams and plain calls are cached apart.
+	_, err = client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Equal(t, 2, gateway.count(""))
+
+	// Streams cut short aren't stored.
+	for range 2 {
+		stream, err = client.GenerateContentStream(ctx, Openai, "broken", hello())
+		require.NoError(t, err)
+		collectEvents(t, stream)
+	}
+	assert.Equal(t, 4, gateway.count(""))
+}
+
+func TestResponseCache_Bypass(t *testing.T) {
+	cache := NewResponseCache(&CacheOptions{Rule: CacheDeterministic})
+	gateway, client := newCacheGateway(t, cache)
+	ctx := context.Background()
+
+	for range 2 {
+		_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, gateway.count(""), "sampled requests aren't cached")
+
+	deterministic := client.WithOptions(&CreateChatCompletionRequest{Seed: new(42)})
+	for range 2 {
+		_, err := deterministic.GenerateContent(ctx, Openai, "gpt-4o", hello())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, gateway.count(""))
+
+	_, err := deterministic.GenerateContent(WithCacheBypass(ctx), Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Equal(t, 4, gateway.count(""))
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 1, Bypasses: 3, Writes: 1}, cache.Stats())
+
+	assert.True(t, CacheDeterministic(Anthropic, &CreateMessagesRequest{Temperature: new(float32(0))}))
+	assert.False(t, CacheDeterministic(Anthropic, &CreateMessagesRequest{}))
+	assert.False(t, CacheDeterministic(Openai, &CreateImageRequest{}))
+}
+
+func TestResponseCache_TTL(t *testing.T) {
+	store := NewMemoryCacheStore(10)
+	cache := NewResponseCache(&CacheOptions{Store: store, TTL: 50 * time.Millisecond})
+	gateway, client := newCacheGateway(t, cache)
+	ctx := context.Background()
+
+	for range 2 {
+		_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, gateway.count(""))
+
+	time.Sleep(60 * time.Millisecond)
+	_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Equal(t, 2, gateway.count(""))
+	assert.Equal(t, 1, store.Len(), "the expired entry was replaced")
+}
+
+func TestFileCacheStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileCacheStore(dir)
+	require.NoError(t, err)
+	gateway, client := newCacheGateway(t, NewResponseCache(&CacheOptions{Store: store}))
+	ctx := context.Background()
+
+	_, err = client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+
+	// Another store on the same directory sees the entry.
+	reopened, err := NewFileCacheStore(dir)
+	require.NoError(t, err)
+	_, client = newCacheGateway(t, NewResponseCache(&CacheOptions{Store: reopened}))
+	response, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	text, err := messageText(response.Choices[0].Message.Content)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", text)
+	assert.Equal(t, 1, gateway.count(""))
+
+	_, ok, err := store.Get(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.NoError(t, store.Delete(ctx, "missing"))
+}
+
+func TestMemoryCacheStore_LRU(t *testing.T) {
+	store := NewMemoryCacheStore(2)
+	ctx := context.Background()
+
+	require.NoError(t, store.Set(ctx, "a", []byte("1")))
+	require.NoError(t, store.Set(ctx, "b", []byte("2")))
+	_, ok, _ := store.Get(ctx, "a")
+	require.True(t, ok)
+	require.NoError(t, store.Set(ctx, "c", []byte("3")))
+
+	_, ok, _ = store.Get(ctx, "b")
+	assert.False(t, ok, "the least recently used entry is evicted")
+	value, ok, _ := store.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+	assert.Equal(t, 2, store.Len())
+}
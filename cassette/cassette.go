@@ -0,0 +1,557 @@
+// Package cassette records the HTTP traffic of sdk clients to files and
+// replays it, so that tests run without a gateway.
+//
+// A Recorder wraps ClientOptions.Transport. In record mode it passes
+// requests on and stores each request and response, including the chunks
+// of streamed responses and the time between them. In replay mode it
+// serves the stored responses to the requests that match them, without
+// touching the network.
+//
+// Example:
+//
+//	recorder, err := cassette.New("testdata/chat.json", &cassette.Options{Mode: cassette.ModeAuto})
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	t.Cleanup(func() { _ = recorder.Save() })
+//	client := sdk.NewClient(&sdk.ClientOptions{
+//		BaseURL:   "http://localhost:8080/v1",
+//		Transport: recorder.Transport(nil),
+//	})
+package cassette
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Version is the version of the cassette file format.
+const Version = 1
+
+// Redacted replaces the values of redacted headers.
+const Redacted = "[REDACTED]"
+
+// ErrNoInteraction is returned, wrapped, by replaying transports for
+// requests that match none of the unused recorded interactions.
+var ErrNoInteraction = errors.New("cassette: no recorded interaction matches the request")
+
+// Mode selects whether a Recorder records or replays.
+type Mode int
+
+const (
+	// ModeReplay serves requests from the cassette file, which must exist.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests on and records them, replacing the
+	// cassette file on Save.
+	ModeRecord
+	// ModeAuto replays if the cassette file exists and records otherwise.
+	ModeAuto
+)
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitzero"`
+}
+
+// Response is a recorded response. Streamed responses, those of type
+// text/event-stream, are recorded as Chunks, and others as Body.
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitzero"`
+	Chunks []Chunk     `json:"chunks,omitempty"`
+}
+
+// Chunk is a part of a streamed response as it was read.
+type Chunk struct {
+	// Delay is the time since the previous chunk, or since the request
+	// was sent for the first one.
+	Delay time.Duration `json:"delay"`
+	Data  Body          `json:"data"`
+}
+
+// Body is a request or response body. It is stored as text when it is
+// valid UTF-8, and base64 encoded otherwise.
+type Body []byte
+
+// MarshalJSON implements json.Marshaler.
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(struct {
+		Base64 []byte `json:"base64"`
+	}{b})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*b = Body(text)
+		return nil
+	}
+	var encoded struct {
+		Base64 []byte `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return fmt.Errorf("failed to decode body: %w", err)
+	}
+	*b = encoded.Base64
+	return nil
+}
+
+// Matcher reports whether a request matches a recorded one.
+type Matcher func(request, recorded *Request) bool
+
+// MatchMethod matches requests of the same method.
+func MatchMethod(request, recorded *Request) bool {
+	return request.Method == recorded.Method
+}
+
+// MatchPath matches requests to the same path, whatever the host, so
+// that cassettes replay against any base URL.
+func MatchPath(request, recorded *Request) bool {
+	a, b := parseURL(request.URL), parseURL(recorded.URL)
+	return a.Path == b.Path
+}
+
+// MatchQuery matches requests with the same query parameters, in any
+// order.
+func MatchQuery(request, recorded *Request) bool {
+	a, b := parseURL(request.URL), parseURL(recorded.URL)
+	return reflect.DeepEqual(a.Query(), b.Query())
+}
+
+// MatchBody matches requests with equal bodies. JSON bodies are compared
+// as values, so that key order and spacing don't matter; others byte by
+// byte. Multipart bodies have random boundaries, so leave MatchBody out
+// to replay image edits and variations.
+func MatchBody(request, recorded *Request) bool {
+	if bytes.Equal(request.Body, recorded.Body) {
+		return true
+	}
+	a, ok := decodeJSON(request.Body)
+	if !ok {
+		return false
+	}
+	b, ok := decodeJSON(recorded.Body)
+	return ok && reflect.DeepEqual(a, b)
+}
+
+// DefaultMatchers are the matchers used when Options.Matchers is empty.
+var DefaultMatchers = []Matcher{MatchMethod, MatchPath, MatchQuery, MatchBody}
+
+// DefaultRedactedHeaders are the request headers redacted when
+// Options.RedactHeaders is empty.
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization"}
+
+// Options configures a Recorder.
+type Options struct {
+	// Mode defaults to ModeReplay.
+	Mode Mode
+	// Matchers must all match for a recorded interaction to answer a
+	// request. Defaults to DefaultMatchers.
+	Matchers []Matcher
+	// RedactHeaders are the request headers whose values are replaced by
+	// Redacted in recorded interactions. Defaults to
+	// DefaultRedactedHeaders.
+	RedactHeaders []string
+	// Realtime replays streamed responses with their recorded delays
+	// between chunks. By default they are replayed at once.
+	Realtime bool
+}
+
+// Recorder records and replays the interactions of a cassette file. It is
+// safe for concurrent use.
+type Recorder struct {
+	path    string
+	options Options
+	mode    Mode
+
+	mu           sync.Mutex
+	interactions []*interaction
+}
+
+// interaction is an interaction being recorded or replayed.
+type interaction struct {
+	Interaction
+	// done is set once a recorded response has been read to the end or
+	// closed, and once a replayed interaction has been used.
+	done bool
+}
+
+// New creates a Recorder for the cassette file at path. In replay mode,
+// or in ModeAuto when the file exists, it reads the file.
+func New(path string, options *Options) (*Recorder, error) {
+	r := &Recorder{path: path}
+	if options != nil {
+		r.options = *options
+	}
+	if len(r.options.Matchers) == 0 {
+		r.options.Matchers = DefaultMatchers
+	}
+	if len(r.options.RedactHeaders) == 0 {
+		r.options.RedactHeaders = DefaultRedactedHeaders
+	}
+
+	r.mode = r.options.Mode
+	data, err := os.ReadFile(path)
+	switch {
+	case r.mode == ModeAuto && errors.Is(err, fs.ErrNotExist):
+		r.mode = ModeRecord
+		return r, nil
+	case r.mode == ModeAuto:
+		r.mode = ModeReplay
+	case r.mode == ModeRecord:
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to decode cassette %s: %w", path, err)
+	}
+	if cassette.Version != Version {
+		return nil, fmt.Errorf("cassette %s has version %d, want %d", path, cassette.Version, Version)
+	}
+	for _, recorded := range cassette.Interactions {
+		r.interactions = append(r.interactions, &interaction{Interaction: recorded})
+	}
+	return r, nil
+}
+
+// Recording reports whether the recorder records, which in ModeAuto
+// depends on whether the cassette file existed.
+func (r *Recorder) Recording() bool {
+	return r.mode == ModeRecord
+}
+
+// Interactions returns the interactions recorded so far, or those of the
+// cassette when replaying.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []Interaction
+	for _, i := range r.interactions {
+		if i.done || !r.Recording() {
+			out = append(out, i.Interaction)
+		}
+	}
+	return out
+}
+
+// Save writes the recorded interactions to the cassette file, in the
+// order their requests were sent. Streamed responses still being read
+// are left out. It does nothing when replaying.
+func (r *Recorder) Save() error {
+	if !r.Recording() {
+		return nil
+	}
+	data, err := json.MarshalIndent(Cassette{Version: Version, Interactions: r.Interactions()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), ".cassette-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// Transport returns a RoundTripper that records the requests it sends
+// through base, which defaults to http.DefaultTransport, or replays them.
+func (r *Recorder) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{recorder: r, base: base}
+}
+
+type transport struct {
+	recorder *Recorder
+	base     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+	if t.recorder.Recording() {
+		return t.record(req, body)
+	}
+	return t.recorder.replay(req, body)
+}
+
+// request returns the recorded form of req.
+func (r *Recorder) request(req *http.Request, body []byte) Request {
+	header := req.Header.Clone()
+	for _, name := range r.options.RedactHeaders {
+		if header.Get(name) != "" {
+			header.Set(name, Redacted)
+		}
+	}
+	return Request{Method: req.Method, URL: req.URL.String(), Header: header, Body: body}
+}
+
+// record sends req on and records its response.
+func (t *transport) record(req *http.Request, body []byte) (*http.Response, error) {
+	r := t.recorder
+	recorded := &interaction{Interaction: Interaction{Request: r.request(req, body)}}
+	r.mu.Lock()
+	r.interactions = append(r.interactions, recorded)
+	r.mu.Unlock()
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	sent := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	response := Response{Status: resp.StatusCode, Header: resp.Header.Clone()}
+	if streamed(resp.Header) {
+		resp.Body = &recordingBody{body: resp.Body, recorder: r, recorded: recorded, response: response, last: sent}
+		return resp, nil
+	}
+	data, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	response.Body = data
+	r.finish(recorded, response)
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
+
+// finish completes a recorded interaction with its response.
+func (r *Recorder) finish(recorded *interaction, response Response) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	recorded.Response = response
+	recorded.done = true
+}
+
+// recordingBody records the chunks of a streamed response as they are
+// read. The interaction is done at the end of the body or when the client
+// closes it, with the chunks read until then; the client stops reading at
+// [DONE], usually before the end.
+type recordingBody struct {
+	body     io.ReadCloser
+	recorder *Recorder
+	recorded *interaction
+	once     sync.Once
+
+	// mu guards the response, which Close may finish while a Read is
+	// blocked.
+	mu       sync.Mutex
+	response Response
+	last     time.Time
+	finished bool
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		b.record(p[:n])
+	}
+	if errors.Is(err, io.EOF) {
+		b.once.Do(b.finish)
+	}
+	return n, err
+}
+
+// Close finishes the interaction without reading the rest of the stream,
+// which may never end.
+func (b *recordingBody) Close() error {
+	b.once.Do(b.finish)
+	return b.body.Close()
+}
+
+// record adds data read from the body as a chunk, unless the interaction is
+// already finished.
+func (b *recordingBody) record(data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.finished {
+		return
+	}
+	now := time.Now()
+	b.response.Chunks = append(b.response.Chunks, Chunk{Delay: now.Sub(b.last), Data: bytes.Clone(data)})
+	b.last = now
+}
+
+func (b *recordingBody) finish() {
+	b.mu.Lock()
+	b.finished = true
+	response := b.response
+	b.mu.Unlock()
+	b.recorder.finish(b.recorded, response)
+}
+
+// replay answers req with the first unused recorded interaction that
+// matches it.
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	request := r.request(req, body)
+	r.mu.Lock()
+	var match *interaction
+	for _, recorded := range r.interactions {
+		if !recorded.done && r.matches(&request, &recorded.Request) {
+			match = recorded
+			match.done = true
+			break
+		}
+	}
+	r.mu.Unlock()
+	if match == nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, req.URL)
+	}
+
+	response := match.Response
+	resp := &http.Response{
+		Status:     fmt.Sprintf("%d %s", response.Status, http.StatusText(response.Status)),
+		StatusCode: response.Status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     response.Header.Clone(),
+		Request:    req,
+	}
+	if resp.Header == nil {
+		resp.Header = http.Header{}
+	}
+	if response.Chunks != nil {
+		resp.ContentLength = -1
+		resp.Body = &replayBody{ctx: req.Context(), chunks: response.Chunks, realtime: r.options.Realtime}
+		return resp, nil
+	}
+	resp.ContentLength = int64(len(response.Body))
+	resp.Body = io.NopCloser(bytes.NewReader(response.Body))
+	return resp, nil
+}
+
+// matches reports whether all matchers match.
+func (r *Recorder) matches(request, recorded *Request) bool {
+	for _, match := range r.options.Matchers {
+		if !match(request, recorded) {
+			return false
+		}
+	}
+	return true
+}
+
+// replayBody serves recorded chunks one per read, after their delay when
+// replaying in real time.
+type replayBody struct {
+	ctx      context.Context
+	chunks   []Chunk
+	pending  []byte
+	realtime bool
+}
+
+func (b *replayBody) Read(p []byte) (int, error) {
+	if len(b.pending) == 0 {
+		if len(b.chunks) == 0 {
+			return 0, io.EOF
+		}
+		chunk := b.chunks[0]
+		b.chunks = b.chunks[1:]
+		if b.realtime && chunk.Delay > 0 {
+			timer := time.NewTimer(chunk.Delay)
+			select {
+			case <-timer.C:
+			case <-b.ctx.Done():
+				timer.Stop()
+				return 0, b.ctx.Err()
+			}
+		}
+		b.pending = chunk.Data
+	}
+	n := copy(p, b.pending)
+	b.pending = b.pending[n:]
+	return n, nil
+}
+
+func (b *replayBody) Close() error {
+	b.chunks, b.pending = nil, nil
+	return nil
+}
+
+// streamed reports whether a response is an event stream.
+func streamed(header http.Header) bool {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	return strings.EqualFold(mediaType, "text/event-stream")
+}
+
+func parseURL(raw string) *url.URL {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return &url.URL{}
+	}
+	return u
+}
+
+func decodeJSON(data []byte) (any, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, false
+	}
+	return value, true
+}
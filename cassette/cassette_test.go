@@ -0,0 +1,252 @@
+package cassette
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sdk "github.com/inference-gateway/sdk"
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+const (
+	chatCompletion = `{
+		"id": "chatcmpl-1", "object": "chat.completion", "created": 1, "model": "gpt-4o",
+		"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hi"}}]
+	}`
+	chunkDelay = 30 * time.Millisecond
+)
+
+// newGateway answers chat completions, streaming or not, pausing between
+// the chunks of streams, and counts the calls it gets.
+func newGateway(t *testing.T) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body["stream"] != true {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprint(w, chatCompletion)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i, chunk := range []string{
+			`{"id":"1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"content":"Hi"}}]}`,
+			`{"id":"1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"content":" there"},"finish_reason":"stop"}]}`,
+			"[DONE]",
+		} {
+			if i > 0 {
+				time.Sleep(chunkDelay)
+			}
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", chunk)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func newClient(baseURL string, recorder *Recorder) sdk.Client {
+	return sdk.NewClient(&sdk.ClientOptions{
+		BaseURL:     baseURL + "/v1",
+		APIKey:      "sk-secret-key",
+		RetryConfig: &sdk.RetryConfig{Enabled: false},
+		Transport:   recorder.Transport(nil),
+	})
+}
+
+func hello() []sdk.Message {
+	return []sdk.Message{{Role: sdk.User, Content: sdk.NewMessageContent("Hello")}}
+}
+
+func streamText(t *testing.T, stream <-chan sdk.SSEvent) string {
+	t.Helper()
+	var text strings.Builder
+	for event := range stream {
+		require.NotNil(t, event.Event, "stream failed")
+		if *event.Event != sdk.ContentDelta {
+			continue
+		}
+		var chunk sdk.CreateChatCompletionStreamResponse
+		require.NoError(t, json.Unmarshal(*event.Data, &chunk))
+		for _, choice := range chunk.Choices {
+			text.WriteString(choice.Delta.Content)
+		}
+	}
+	return text.String()
+}
+
+// record records a chat completion and a stream to a new cassette.
+func record(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "testdata", "chat.json")
+	server, calls := newGateway(t)
+	recorder, err := New(path, &Options{Mode: ModeAuto})
+	require.NoError(t, err)
+	require.True(t, recorder.Recording())
+	client := newClient(server.URL, recorder)
+
+	_, err = client.GenerateContent(context.Background(), sdk.Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	stream, err := client.GenerateContentStream(context.Background(), sdk.Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there", streamText(t, stream))
+	assert.Equal(t, int64(2), calls.Load())
+
+	// The stream is complete once the client closes it at [DONE].
+	require.Len(t, recorder.Interactions(), 2)
+	require.NoError(t, recorder.Save())
+	return path
+}
+
+func TestRecorder_Record(t *testing.T) {
+	path := record(t)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "sk-secret-key")
+	var cassette Cassette
+	require.NoError(t, json.Unmarshal(data, &cassette))
+	assert.Equal(t, Version, cassette.Version)
+	require.Len(t, cassette.Interactions, 2)
+
+	plain, streamed := cassette.Interactions[0], cassette.Interactions[1]
+	assert.Equal(t, http.MethodPost, plain.Request.Method)
+	assert.True(t, strings.HasSuffix(plain.Request.URL, "/v1/chat/completions?provider=openai"), plain.Request.URL)
+	assert.Equal(t, Redacted, plain.Request.Header.Get("Authorization"))
+	assert.Equal(t, http.StatusOK, plain.Response.Status)
+	assert.JSONEq(t, chatCompletion, string(plain.Response.Body))
+	assert.Empty(t, plain.Response.Chunks)
+
+	assert.Empty(t, streamed.Response.Body)
+	require.Len(t, streamed.Response.Chunks, 3)
+	assert.Contains(t, string(streamed.Response.Chunks[0].Data), `"Hi"`)
+	assert.GreaterOrEqual(t, streamed.Response.Chunks[1].Delay, chunkDelay)
+}
+
+func TestRecorder_Replay(t *testing.T) {
+	path := record(t)
+	recorder, err := New(path, &Options{Mode: ModeAuto})
+	require.NoError(t, err)
+	require.False(t, recorder.Recording())
+
+	// Nothing listens on the base URL.
+	client := newClient("http://127.0.0.1:1", recorder)
+	ctx := context.Background()
+
+	response, err := client.GenerateContent(ctx, sdk.Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Equal(t, "chatcmpl-1", response.ID)
+
+	start := time.Now()
+	stream, err := client.GenerateContentStream(ctx, sdk.Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there", streamText(t, stream))
+	assert.Less(t, time.Since(start), chunkDelay, "streams replay at once by default")
+
+	// Each interaction answers once, and other requests match none.
+	_, err = client.GenerateContent(ctx, sdk.Openai, "gpt-4o", hello())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), ErrNoInteraction.Error())
+	_, err = client.GenerateContent(ctx, sdk.Groq, "gpt-4o", hello())
+	require.Error(t, err)
+}
+
+func TestRecorder_ReplayRealtime(t *testing.T) {
+	path := record(t)
+	recorder, err := New(path, &Options{Realtime: true})
+	require.NoError(t, err)
+	client := newClient("http://127.0.0.1:1", recorder)
+
+	start := time.Now()
+	stream, err := client.GenerateContentStream(context.Background(), sdk.Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there", streamText(t, stream))
+	assert.GreaterOrEqual(t, time.Since(start), 2*chunkDelay)
+}
+
+func TestRecorder_CloseStream(t *testing.T) {
+	// The gateway sends one chunk and then nothing until the test ends.
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, "data: {}\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	recorder, err := New(filepath.Join(t.TempDir(), "stream.json"), &Options{Mode: ModeRecord})
+	require.NoError(t, err)
+	client := &http.Client{Transport: recorder.Transport(nil)}
+	resp, err := client.Post(server.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{"stream":true}`))
+	require.NoError(t, err)
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "data: {}\n", line)
+	assert.Empty(t, recorder.Interactions(), "the stream is still being read")
+
+	// Closing doesn't wait for the rest of the stream, and records what was
+	// read.
+	closed := make(chan error)
+	go func() { closed <- resp.Body.Close() }()
+	select {
+	case err := <-closed:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked on the stream")
+	}
+	interactions := recorder.Interactions()
+	require.Len(t, interactions, 1)
+	require.Len(t, interactions[0].Response.Chunks, 1)
+	assert.Equal(t, "data: {}\n\n", string(interactions[0].Response.Chunks[0].Data))
+}
+
+func TestRecorder_Matchers(t *testing.T) {
+	recorded := &Request{
+		Method: http.MethodPost,
+		URL:    "http://localhost:8080/v1/chat/completions?provider=openai&x=1",
+		Body:   Body(`{"model": "gpt-4o", "stream": false}`),
+	}
+	request := &Request{
+		Method: http.MethodPost,
+		URL:    "http://127.0.0.1:9999/v1/chat/completions?x=1&provider=openai",
+		Body:   Body(`{"stream":false,"model":"gpt-4o"}`),
+	}
+	for _, match := range DefaultMatchers {
+		assert.True(t, match(request, recorded))
+	}
+
+	assert.False(t, MatchBody(&Request{Body: Body(`{"model":"gpt-4o-mini","stream":false}`)}, recorded))
+	assert.False(t, MatchQuery(&Request{URL: "/v1/chat/completions?provider=groq&x=1"}, recorded))
+	assert.False(t, MatchBody(&Request{Body: Body("not json")}, &Request{Body: Body("not JSON")}))
+
+	_, err := New(filepath.Join(t.TempDir(), "missing.json"), nil)
+	assert.Error(t, err, "replaying needs a cassette")
+}
+
+func TestBody_JSON(t *testing.T) {
+	for _, body := range []Body{Body("data: {}\n\n"), {0x89, 'P', 'N', 'G', 0xff}} {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		var decoded Body
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, body, decoded)
+	}
+}
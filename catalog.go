@@ -0,0 +1,297 @@
+package sdk
+
+import (
+	"context"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// catalogRetryDelay is how long a failed listing is returned to callers
+// before ListModels is tried again.
+const catalogRetryDelay = 30 * time.Second
+
+// ModelCatalogOptions configures a ModelCatalog.
+type ModelCatalogOptions struct {
+	// RefreshInterval is how long a listing is used before ListModels is
+	// called again. Defaults to 10 minutes.
+	RefreshInterval time.Duration
+}
+
+// ModelCatalog caches ListModels with every include, so callers can query
+// models by capability without listing them again. Models are loaded on
+// first use and refreshed once RefreshInterval has passed. A failed listing
+// is returned for a short while before models are listed again. It is safe
+// for concurrent use.
+type ModelCatalog struct {
+	client   Client
+	interval time.Duration
+	include  []ListModelsParamsInclude
+
+	mu        sync.Mutex
+	models    []Model
+	byID      map[string]Model
+	fetchedAt time.Time
+	// loading is closed when the listing in flight ends, nil when there is
+	// none.
+	loading  chan struct{}
+	err      error
+	failedAt time.Time
+}
+
+// NewModelCatalog creates a ModelCatalog that lists models through client.
+//
+// Example:
+//
+//	catalog := sdk.NewModelCatalog(client, nil)
+//	vision, err := catalog.Find(ctx, sdk.ModelQuery{
+//		ServedBy: sdk.Anthropic,
+//		Input:    []sdk.Modality{sdk.ModalityImage},
+//	})
+func NewModelCatalog(client Client, options *ModelCatalogOptions) *ModelCatalog {
+	interval := defaultModelsTTL
+	if options != nil && options.RefreshInterval > 0 {
+		interval = options.RefreshInterval
+	}
+	return newModelCatalog(client, interval,
+		ListModelsParamsIncludeContextWindow,
+		ListModelsParamsIncludeModalities,
+		ListModelsParamsIncludePricing,
+	)
+}
+
+// newModelCatalog creates a catalog that only asks for include, for callers
+// that need a single detail.
+func newModelCatalog(client Client, interval time.Duration, include ...ListModelsParamsInclude) *ModelCatalog {
+	return &ModelCatalog{client: client, interval: interval, include: include}
+}
+
+// load returns the cached models, listing them again once they're stale.
+// Concurrent callers wait for the same listing.
+func (c *ModelCatalog) load(ctx context.Context) ([]Model, map[string]Model, error) {
+	for {
+		c.mu.Lock()
+		if c.byID != nil && time.Since(c.fetchedAt) <= c.interval {
+			models, byID := c.models, c.byID
+			c.mu.Unlock()
+			return models, byID, nil
+		}
+		if c.err != nil && time.Since(c.failedAt) < catalogRetryDelay {
+			err := c.err
+			c.mu.Unlock()
+			return nil, nil, err
+		}
+		loading := c.loading
+		if loading == nil {
+			loading = make(chan struct{})
+			c.loading = loading
+			c.mu.Unlock()
+			if err := c.fetch(ctx, loading); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-loading:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+// fetch lists the models without holding the lock, then closes loading.
+// Failures are kept for catalogRetryDelay unless ctx ended them.
+func (c *ModelCatalog) fetch(ctx context.Context, loading chan struct{}) error {
+	response, err := c.client.ListModels(ctx, c.include...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loading == loading {
+		c.loading = nil
+	}
+	close(loading)
+
+	if err != nil {
+		if ctx.Err() == nil {
+			c.err, c.failedAt = err, time.Now()
+		}
+		return err
+	}
+	models := slices.SortedFunc(slices.Values(response.Data), func(a, b Model) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	byID := make(map[string]Model, len(models))
+	for _, model := range models {
+		byID[model.ID] = model
+	}
+	c.models, c.byID, c.fetchedAt, c.err = models, byID, time.Now(), nil
+	return nil
+}
+
+// Refresh lists models now, regardless of RefreshInterval or a recent
+// failure.
+func (c *ModelCatalog) Refresh(ctx context.Context) error {
+	c.mu.Lock()
+	loading := make(chan struct{})
+	c.loading = loading
+	c.mu.Unlock()
+	return c.fetch(ctx, loading)
+}
+
+// Models returns every model, sorted by ID.
+func (c *ModelCatalog) Models(ctx context.Context) ([]Model, error) {
+	models, _, err := c.load(ctx)
+	return slices.Clone(models), err
+}
+
+// Lookup finds model by its gateway ID, e.g. openai/gpt-4o, or by its name
+// under provider. It returns nil when the gateway doesn't list the model.
+func (c *ModelCatalog) Lookup(ctx context.Context, provider Provider, model string) (*Model, error) {
+	found, ok, err := c.lookup(ctx, provider, model)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &found, nil
+}
+
+func (c *ModelCatalog) lookup(ctx context.Context, provider Provider, model string) (Model, bool, error) {
+	_, byID, err := c.load(ctx)
+	if err != nil {
+		return Model{}, false, err
+	}
+	// Gateway model IDs are prefixed with the provider, e.g. openai/gpt-4o.
+	if found, ok := byID[string(provider)+"/"+model]; ok {
+		return found, true, nil
+	}
+	found, ok := byID[model]
+	return found, ok, nil
+}
+
+// ModelQuery selects models from a ModelCatalog. Empty fields match every
+// model.
+type ModelQuery struct {
+	// Pattern is a glob such as "openai/gpt-4*", matched with path.Match.
+	// Patterns without a slash match the model name without its provider
+	// prefix, so "claude-*" matches anthropic/claude-sonnet-4.
+	Pattern string
+	// ServedBy keeps models served by this provider.
+	ServedBy Provider
+	// Input keeps models that accept all of these modalities.
+	Input []Modality
+	// Output keeps models that produce all of these modalities.
+	Output []Modality
+	// ExcludeOutput drops models that produce any of these modalities; with
+	// Output image and ExcludeOutput text it selects image-only generators.
+	ExcludeOutput []Modality
+	// MinContextWindow keeps models with at least this many tokens of
+	// context window.
+	MinContextWindow int
+}
+
+// Matches reports whether model satisfies q. Models without modalities or
+// a context window don't match queries that need them.
+func (q ModelQuery) Matches(model Model) bool {
+	if q.Pattern != "" && !matchModelPattern(q.Pattern, model.ID) {
+		return false
+	}
+	if q.ServedBy != "" && model.ServedBy != q.ServedBy {
+		return false
+	}
+	for _, modality := range q.Input {
+		if !model.AcceptsInput(modality) {
+			return false
+		}
+	}
+	for _, modality := range q.Output {
+		if !model.ProducesOutput(modality) {
+			return false
+		}
+	}
+	for _, modality := range q.ExcludeOutput {
+		if model.ProducesOutput(modality) {
+			return false
+		}
+	}
+	if q.MinContextWindow > 0 && (model.ContextWindow == nil || model.ContextWindow.Tokens < q.MinContextWindow) {
+		return false
+	}
+	return true
+}
+
+func matchModelPattern(pattern, id string) bool {
+	if !strings.Contains(pattern, "/") {
+		if _, name, ok := strings.Cut(id, "/"); ok {
+			id = name
+		}
+	}
+	matched, _ := path.Match(pattern, id)
+	return matched
+}
+
+// Find returns the models matching query, sorted by ID.
+func (c *ModelCatalog) Find(ctx context.Context, query ModelQuery) ([]Model, error) {
+	models, _, err := c.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var found []Model
+	for _, model := range models {
+		if query.Matches(model) {
+			found = append(found, model)
+		}
+	}
+	return found, nil
+}
+
+// Cheapest returns the model matching query with the lowest input plus
+// output price per token, or nil when none has per-token pricing. Ties go
+// to the lowest ID.
+func (c *ModelCatalog) Cheapest(ctx context.Context, query ModelQuery) (*Model, error) {
+	models, err := c.Find(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	var cheapest *Model
+	var lowest Decimal
+	for i, model := range models {
+		price, ok := tokenPrice(model.Pricing)
+		if ok && (cheapest == nil || price.Cmp(lowest) < 0) {
+			cheapest, lowest = &models[i], price
+		}
+	}
+	return cheapest, nil
+}
+
+// tokenPrice is the input plus output price of pricing. Subscription models
+// and invalid prices have none.
+func tokenPrice(pricing *Pricing) (Decimal, bool) {
+	if pricing == nil || (pricing.Subscription != nil && *pricing.Subscription) {
+		return Decimal{}, false
+	}
+	parsed, err := pricing.parse()
+	if err != nil {
+		return Decimal{}, false
+	}
+	return parsed.input.Add(parsed.output), true
+}
+
+// AcceptsInput reports whether the model accepts modality as input, e.g.
+// ModalityImage for vision models.
+func (m Model) AcceptsInput(modality Modality) bool {
+	return m.Modalities != nil && slices.Contains(m.Modalities.Input, modality)
+}
+
+// ProducesOutput reports whether the model generates modality.
+func (m Model) ProducesOutput(modality Modality) bool {
+	return m.Modalities != nil && slices.Contains(m.Modalities.Output, modality)
+}
+
+// ImageOnly reports whether the model generates images but not text, so it
+// can't be used for chat.
+func (m Model) ImageOnly() bool {
+	return m.ProducesOutput(ModalityImage) && !m.ProducesOutput(ModalityText)
+}
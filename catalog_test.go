@@ -0,0 +1,224 @@
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+const catalogModels = `{"object": "list", "data": [
+	{"id": "openai/gpt-4o", "object": "model", "created": 1, "owned_by": "openai", "served_by": "openai",
+	 "context_window": {"tokens": 128000, "source": "provider"},
+	 "modalities": {"input": ["text", "image"], "output": ["text"]},
+	 "pricing": {"currency": "USD", "input_per_token": "0.0000025", "output_per_token": "0.00001"}},
+	{"id": "openai/gpt-4o-mini", "object": "model", "created": 1, "owned_by": "openai", "served_by": "openai",
+	 "context_window": {"tokens": 128000, "source": "provider"},
+	 "modalities": {"input": ["text", "image"], "output": ["text"]},
+	 "pricing": {"currency": "USD", "input_per_token": "0.00000015", "output_per_token": "0.0000006"}},
+	{"id": "openai/gpt-image-1", "object": "model", "created": 1, "owned_by": "openai", "served_by": "openai",
+	 "modalities": {"input": ["text", "image"], "output": ["image"]}},
+	{"id": "anthropic/claude-sonnet-4", "object": "model", "created": 1, "owned_by": "anthropic", "served_by": "anthropic",
+	 "context_window": {"tokens": 200000, "source": "provider"},
+	 "modalities": {"input": ["text", "image"], "output": ["text"]},
+	 "pricing": {"currency": "USD", "input_per_token": "0.000003", "output_per_token": "0.000015"}},
+	{"id": "anthropic/claude-3-haiku", "object": "model", "created": 1, "owned_by": "anthropic", "served_by": "anthropic",
+	 "context_window": {"tokens": 200000, "source": "provider"},
+	 "modalities": {"input": ["text"], "output": ["text"]}},
+	{"id": "ollama/llama3", "object": "model", "created": 1, "owned_by": "ollama", "served_by": "ollama",
+	 "context_window": {"tokens": 8192, "source": "provider"},
+	 "pricing": {"currency": "USD", "input_per_token": "0", "output_per_token": "0", "subscription": true}}
+]}`
+
+// newCatalogServer lists catalogModels and counts how often it was asked.
+func newCatalogServer(t *testing.T) (*atomic.Int32, Client) {
+	t.Helper()
+	var listed atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/models", r.URL.Path)
+		listed.Add(1)
+		jsonReply(catalogModels)(w)
+	}))
+	t.Cleanup(server.Close)
+	return &listed, NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+}
+
+func modelIDs(models []Model) []string {
+	ids := make([]string, len(models))
+	for i, model := range models {
+		ids[i] = model.ID
+	}
+	return ids
+}
+
+func TestModelCatalog_Find(t *testing.T) {
+	_, client := newCatalogServer(t)
+	catalog := NewModelCatalog(client, nil)
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		query ModelQuery
+		want  []string
+	}{
+		{"all", ModelQuery{}, []string{"anthropic/claude-3-haiku", "anthropic/claude-sonnet-4", "ollama/llama3", "openai/gpt-4o", "openai/gpt-4o-mini", "openai/gpt-image-1"}},
+		{"vision served by anthropic", ModelQuery{ServedBy: Anthropic, Input: []Modality{ModalityImage}}, []string{"anthropic/claude-sonnet-4"}},
+		{"image only", ModelQuery{Output: []Modality{ModalityImage}, ExcludeOutput: []Modality{ModalityText}}, []string{"openai/gpt-image-1"}},
+		{"large context", ModelQuery{MinContextWindow: 150000}, []string{"anthropic/claude-3-haiku", "anthropic/claude-sonnet-4"}},
+		{"glob with provider", ModelQuery{Pattern: "openai/gpt-4o*"}, []string{"openai/gpt-4o", "openai/gpt-4o-mini"}},
+		{"glob without provider", ModelQuery{Pattern: "claude-*"}, []string{"anthropic/claude-3-haiku", "anthropic/claude-sonnet-4"}},
+		{"no match", ModelQuery{Pattern: "gemini-*"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models, err := catalog.Find(ctx, tt.query)
+			require.NoError(t, err)
+			if tt.want == nil {
+				assert.Empty(t, models)
+				return
+			}
+			assert.Equal(t, tt.want, modelIDs(models))
+		})
+	}
+}
+
+func TestModelCatalog_Cheapest(t *testing.T) {
+	_, client := newCatalogServer(t)
+	catalog := NewModelCatalog(client, nil)
+	ctx := context.Background()
+
+	cheapest, err := catalog.Cheapest(ctx, ModelQuery{MinContextWindow: 128000})
+	require.NoError(t, err)
+	require.NotNil(t, cheapest)
+	assert.Equal(t, "openai/gpt-4o-mini", cheapest.ID)
+
+	cheapest, err = catalog.Cheapest(ctx, ModelQuery{ServedBy: Anthropic})
+	require.NoError(t, err)
+	require.NotNil(t, cheapest)
+	assert.Equal(t, "anthropic/claude-sonnet-4", cheapest.ID, "unpriced models are skipped")
+
+	cheapest, err = catalog.Cheapest(ctx, ModelQuery{ServedBy: Ollama})
+	require.NoError(t, err)
+	assert.Nil(t, cheapest, "subscription models have no per-token price")
+}
+
+func TestModelCatalog_Lookup(t *testing.T) {
+	_, client := newCatalogServer(t)
+	catalog := NewModelCatalog(client, nil)
+	ctx := context.Background()
+
+	model, err := catalog.Lookup(ctx, Openai, "gpt-image-1")
+	require.NoError(t, err)
+	require.NotNil(t, model)
+	assert.True(t, model.ImageOnly())
+	assert.True(t, model.AcceptsInput(ModalityImage))
+
+	model, err = catalog.Lookup(ctx, "", "anthropic/claude-3-haiku")
+	require.NoError(t, err)
+	require.NotNil(t, model)
+	assert.False(t, model.AcceptsInput(ModalityImage))
+	assert.False(t, model.ImageOnly())
+
+	model, err = catalog.Lookup(ctx, Openai, "gpt-5")
+	require.NoError(t, err)
+	assert.Nil(t, model)
+}
+
+func TestModelCatalog_Refresh(t *testing.T) {
+	listed, client := newCatalogServer(t)
+	catalog := NewModelCatalog(client, &ModelCatalogOptions{RefreshInterval: 50 * time.Millisecond})
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			models, err := catalog.Models(ctx)
+			assert.NoError(t, err)
+			assert.Len(t, models, 6)
+		})
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), listed.Load(), "concurrent callers share one listing")
+
+	require.NoError(t, catalog.Refresh(ctx))
+	assert.Equal(t, int32(2), listed.Load())
+
+	time.Sleep(60 * time.Millisecond)
+	_, err := catalog.Models(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), listed.Load(), "stale listings are refreshed")
+}
+
+func TestModelCatalog_Failures(t *testing.T) {
+	gateway := newTestGateway(t)
+	gateway.fail("", http.StatusServiceUnavailable)
+	catalog := NewModelCatalog(gateway.client(nil), nil)
+	ctx := context.Background()
+
+	for range 3 {
+		_, err := catalog.Models(ctx)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 1, gateway.count(""), "failed listings are kept for a while")
+
+	gateway.fail("", http.StatusOK)
+	require.NoError(t, catalog.Refresh(ctx))
+	_, err := catalog.Models(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, gateway.count(""))
+}
+
+func TestModelCatalog_SlowListing(t *testing.T) {
+	gateway := newTestGateway(t)
+	gateway.slow("", time.Minute)
+	catalog := NewModelCatalog(gateway.client(nil), nil)
+	ctx := context.Background()
+
+	slow, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		_, err := catalog.Models(slow)
+		done <- err
+	}()
+	require.Eventually(t, func() bool { return gateway.count("") == 1 }, time.Second, 5*time.Millisecond)
+
+	// Callers waiting for the listing in flight can still give up.
+	expired, stop := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer stop()
+	_, err := catalog.Models(expired)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, gateway.count(""), "callers share the listing in flight")
+
+	// A cancelled listing isn't kept as a failure.
+	cancel()
+	assert.Error(t, <-done)
+	gateway.slow("", 0)
+	_, err = catalog.Models(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, gateway.count(""))
+}
+
+func TestModelCatalog_Shared(t *testing.T) {
+	listed, client := newCatalogServer(t)
+	catalog := NewModelCatalog(client, nil)
+	ctx := context.Background()
+
+	costs := NewCostCalculator(client, &CostCalculatorOptions{Catalog: catalog})
+	cost, err := costs.Cost(ctx, Openai, "gpt-4o", GenerateUsage{InputTokens: 1000, OutputTokens: 100})
+	require.NoError(t, err)
+	require.NotNil(t, cost)
+	assert.Equal(t, "0.0035", cost.Total.String())
+
+	manager := NewContextManager(client, &ContextManagerOptions{Catalog: catalog})
+	window, err := manager.ContextWindow(ctx, Anthropic, "claude-sonnet-4")
+	require.NoError(t, err)
+	assert.Equal(t, 200000, window)
+
+	assert.Equal(t, int32(1), listed.Load())
+}
@@ -0,0 +1,332 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is wrapped by errors of calls a CircuitBreaker rejected
+// without sending them.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// CircuitState is the state of one circuit.
+type CircuitState string
+
+const (
+	// CircuitClosed lets calls through and counts their failures.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen rejects calls until the cool-down has passed.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen lets a few probe calls through; they decide whether
+	// the circuit closes or opens again.
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+const (
+	defaultFailureRate      = 0.5
+	defaultCircuitMinCalls  = 10
+	defaultCircuitWindow    = time.Minute
+	defaultCircuitCoolDown  = 30 * time.Second
+	defaultHalfOpenRequests = 1
+)
+
+// CircuitKey identifies a circuit. Endpoint is the API path, e.g.
+// chat/completions or images/generations. Model is only set with
+// CircuitBreakerOptions.PerModel.
+type CircuitKey struct {
+	Provider Provider
+	Endpoint string
+	Model    string
+}
+
+func (k CircuitKey) String() string {
+	name := string(k.Provider) + " " + k.Endpoint
+	if k.Model != "" {
+		name += " " + k.Model
+	}
+	return name
+}
+
+// CircuitOpenError is returned for calls rejected by an open circuit. It
+// unwraps to ErrCircuitOpen.
+type CircuitOpenError struct {
+	Key CircuitKey
+	// RetryAt is when the circuit lets a probe call through again.
+	RetryAt time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit open for %s until %s", e.Key, e.RetryAt.Format(time.RFC3339))
+}
+
+func (e *CircuitOpenError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+// CircuitStateChange reports a circuit moving between states.
+type CircuitStateChange struct {
+	Key  CircuitKey
+	From CircuitState
+	To   CircuitState
+	// FailureRate is the failure rate that opened the circuit, or the rate
+	// at the time of the change.
+	FailureRate float64
+}
+
+// CircuitBreakerOptions configures a CircuitBreaker.
+type CircuitBreakerOptions struct {
+	// FailureRate opens a circuit once this share of its calls in Window
+	// failed. Defaults to 0.5.
+	FailureRate float64
+	// MinCalls is how many calls a circuit needs in Window before it may
+	// open. Defaults to 10.
+	MinCalls int
+	// Window is how far back calls are counted. Defaults to one minute.
+	Window time.Duration
+	// CoolDown is how long an open circuit rejects calls before it lets
+	// probes through. Defaults to 30 seconds.
+	CoolDown time.Duration
+	// HalfOpenRequests is how many probe calls a half-open circuit lets
+	// through at once. Defaults to 1.
+	HalfOpenRequests int
+	// PerModel keeps a circuit per provider, endpoint and model instead of
+	// per provider and endpoint.
+	PerModel bool
+	// IsFailure decides whether a call failed. statusCode is 0 when no
+	// response was received. Defaults to network errors, 429 and 5xx;
+	// cancelled calls are not counted.
+	IsFailure func(statusCode int, err error) bool
+	// OnStateChange is called whenever a circuit changes state, e.g. to
+	// alert on providers going down.
+	OnStateChange func(change CircuitStateChange)
+}
+
+// defaultIsFailure counts network errors, rate limits and server errors.
+func defaultIsFailure(statusCode int, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// CircuitBreaker fails calls fast while a provider is failing, instead of
+// making each call pay for its retries. Set it as
+// ClientOptions.CircuitBreaker; it keeps a circuit per provider and
+// endpoint. A circuit opens once the failure rate in the window crosses the
+// threshold, rejects calls with ErrCircuitOpen for the cool-down, then lets
+// probe calls through and closes again when they succeed. Every attempt of
+// a call is counted, and retries stop once the circuit opens. It is safe for
+// concurrent use and may be shared by clients.
+type CircuitBreaker struct {
+	options CircuitBreakerOptions
+
+	mu       sync.Mutex
+	circuits map[CircuitKey]*circuit
+}
+
+// circuit is the state of one key. Calls in the window are kept in order
+// of completion. generation counts state changes, so that calls admitted
+// before the latest one don't count.
+type circuit struct {
+	state      CircuitState
+	generation int
+	calls      []circuitCall
+	openedAt   time.Time
+	probes     int
+}
+
+type circuitCall struct {
+	at     time.Time
+	failed bool
+}
+
+// NewCircuitBreaker creates a CircuitBreaker.
+//
+// Example:
+//
+//	breaker := sdk.NewCircuitBreaker(&sdk.CircuitBreakerOptions{
+//		CoolDown: time.Minute,
+//		OnStateChange: func(change sdk.CircuitStateChange) {
+//			log.Printf("circuit %s: %s -> %s", change.Key, change.From, change.To)
+//		},
+//	})
+//	client := sdk.NewClient(&sdk.ClientOptions{
+//		BaseURL:        "http://localhost:8080/v1",
+//		CircuitBreaker: breaker,
+//	})
+func NewCircuitBreaker(options *CircuitBreakerOptions) *CircuitBreaker {
+	b := &CircuitBreaker{circuits: make(map[CircuitKey]*circuit)}
+	if options != nil {
+		b.options = *options
+	}
+	if b.options.FailureRate <= 0 {
+		b.options.FailureRate = defaultFailureRate
+	}
+	if b.options.MinCalls <= 0 {
+		b.options.MinCalls = defaultCircuitMinCalls
+	}
+	if b.options.Window <= 0 {
+		b.options.Window = defaultCircuitWindow
+	}
+	if b.options.CoolDown <= 0 {
+		b.options.CoolDown = defaultCircuitCoolDown
+	}
+	if b.options.HalfOpenRequests <= 0 {
+		b.options.HalfOpenRequests = defaultHalfOpenRequests
+	}
+	if b.options.IsFailure == nil {
+		b.options.IsFailure = defaultIsFailure
+	}
+	return b
+}
+
+func (b *CircuitBreaker) key(provider Provider, endpoint, model string) CircuitKey {
+	key := CircuitKey{Provider: provider, Endpoint: endpoint}
+	if b.options.PerModel {
+		key.Model = model
+	}
+	return key
+}
+
+// State returns the state of the circuit for provider, endpoint and model.
+// Model is ignored unless PerModel is set.
+func (b *CircuitBreaker) State(provider Provider, endpoint, model string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[b.key(provider, endpoint, model)]
+	if !ok {
+		return CircuitClosed
+	}
+	if c.state == CircuitOpen && time.Since(c.openedAt) >= b.options.CoolDown {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// Reset closes every circuit and forgets their calls.
+func (b *CircuitBreaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	clear(b.circuits)
+}
+
+// allow admits a call for key. The caller reports the outcome through done.
+func (b *CircuitBreaker) allow(key CircuitKey) (done func(statusCode int, err error), err error) {
+	b.mu.Lock()
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{state: CircuitClosed}
+		b.circuits[key] = c
+	}
+
+	var changes []CircuitStateChange
+	if c.state == CircuitOpen {
+		if time.Since(c.openedAt) < b.options.CoolDown {
+			b.mu.Unlock()
+			return nil, &CircuitOpenError{Key: key, RetryAt: c.openedAt.Add(b.options.CoolDown)}
+		}
+		changes = append(changes, b.transition(key, c, CircuitHalfOpen))
+	}
+	if c.state == CircuitHalfOpen {
+		if c.probes >= b.options.HalfOpenRequests {
+			b.mu.Unlock()
+			b.notify(changes)
+			return nil, &CircuitOpenError{Key: key, RetryAt: time.Now()}
+		}
+		c.probes++
+	}
+	generation := c.generation
+	b.mu.Unlock()
+	b.notify(changes)
+
+	return func(statusCode int, err error) {
+		b.record(key, c, generation, b.options.IsFailure(statusCode, err), errors.Is(err, context.Canceled))
+	}, nil
+}
+
+// record counts the outcome of a call admitted in generation and moves the
+// circuit on. Calls that finish after the circuit changed state are
+// ignored: a call let through while closed must not close a half-open
+// circuit whose probe is still running.
+func (b *CircuitBreaker) record(key CircuitKey, c *circuit, generation int, failed, cancelled bool) {
+	b.mu.Lock()
+	if c.generation != generation {
+		b.mu.Unlock()
+		return
+	}
+	var changes []CircuitStateChange
+	switch c.state {
+	case CircuitHalfOpen:
+		c.probes--
+		switch {
+		case cancelled:
+		case failed:
+			changes = append(changes, b.transition(key, c, CircuitOpen))
+		case c.probes == 0:
+			changes = append(changes, b.transition(key, c, CircuitClosed))
+		}
+	case CircuitClosed:
+		if cancelled {
+			break
+		}
+		now := time.Now()
+		c.calls = append(c.calls, circuitCall{at: now, failed: failed})
+		b.prune(c, now)
+		if rate := failureRate(c.calls); len(c.calls) >= b.options.MinCalls && rate >= b.options.FailureRate {
+			changes = append(changes, b.transition(key, c, CircuitOpen))
+		}
+	}
+	b.mu.Unlock()
+	b.notify(changes)
+}
+
+// transition moves c to state. It must be called with the lock held.
+func (b *CircuitBreaker) transition(key CircuitKey, c *circuit, state CircuitState) CircuitStateChange {
+	change := CircuitStateChange{Key: key, From: c.state, To: state, FailureRate: failureRate(c.calls)}
+	c.state = state
+	c.generation++
+	switch state {
+	case CircuitOpen:
+		c.openedAt = time.Now()
+		c.probes = 0
+	case CircuitClosed:
+		c.calls = nil
+	}
+	return change
+}
+
+func (b *CircuitBreaker) notify(changes []CircuitStateChange) {
+	if b.options.OnStateChange == nil {
+		return
+	}
+	for _, change := range changes {
+		b.options.OnStateChange(change)
+	}
+}
+
+// prune drops calls that have left the window.
+func (b *CircuitBreaker) prune(c *circuit, now time.Time) {
+	cutoff := now.Add(-b.options.Window)
+	i := 0
+	for i < len(c.calls) && c.calls[i].at.Before(cutoff) {
+		i++
+	}
+	c.calls = c.calls[i:]
+}
+
+func failureRate(calls []circuitCall) float64 {
+	if len(calls) == 0 {
+		return 0
+	}
+	failed := 0
+	for _, call := range calls {
+		if call.failed {
+			failed++
+		}
+	}
+	return float64(failed) / float64(len(calls))
+}
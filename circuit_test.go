@@ -0,0 +1,247 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// newCircuitGateway answers chat completions with a greeting from the
+// provider, through a client guarded by breaker.
+func newCircuitGateway(t *testing.T, breaker *CircuitBreaker) (*testGateway, Client) {
+	t.Helper()
+	gateway := newTestGateway(t)
+	gateway.handle("POST /v1/chat/completions", func(w http.ResponseWriter, r *gatewayRequest) {
+		chatRoute("Hi from "+r.provider, nil)(w, r)
+	})
+	return gateway, gateway.client(&ClientOptions{CircuitBreaker: breaker})
+}
+
+func TestCircuitBreaker_Opens(t *testing.T) {
+	var changes []CircuitStateChange
+	breaker := NewCircuitBreaker(&CircuitBreakerOptions{
+		MinCalls:      4,
+		FailureRate:   0.5,
+		CoolDown:      time.Hour,
+		OnStateChange: func(change CircuitStateChange) { changes = append(changes, change) },
+	})
+	server, client := newCircuitGateway(t, breaker)
+	ctx := context.Background()
+
+	// One failure in four stays below the threshold.
+	server.fail(string(Openai), http.StatusServiceUnavailable)
+	_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.Error(t, err)
+	server.fail(string(Openai), http.StatusOK)
+	for range 3 {
+		_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, CircuitClosed, breaker.State(Openai, "chat/completions", ""))
+
+	// Two more failures make three in six.
+	server.fail(string(Openai), http.StatusInternalServerError)
+	for range 2 {
+		_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+		var apiErr *APIError
+		require.True(t, errors.As(err, &apiErr))
+	}
+	assert.Equal(t, CircuitOpen, breaker.State(Openai, "chat/completions", ""))
+	require.Len(t, changes, 1)
+	assert.Equal(t, CircuitStateChange{
+		Key:         CircuitKey{Provider: Openai, Endpoint: "chat/completions"},
+		From:        CircuitClosed,
+		To:          CircuitOpen,
+		FailureRate: 0.5,
+	}, changes[0])
+
+	calls := server.count(string(Openai))
+	_, err = client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	var openErr *CircuitOpenError
+	require.True(t, errors.As(err, &openErr))
+	assert.Equal(t, Openai, openErr.Key.Provider)
+	events, err := client.GenerateContentStream(ctx, Openai, "gpt-4o", hello())
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	_, open := <-events
+	assert.False(t, open)
+	assert.Equal(t, calls, server.count(string(Openai)), "open circuits don't send calls")
+
+	// Other providers have circuits of their own.
+	_, err = client.GenerateContent(ctx, Anthropic, "claude-sonnet-4", hello())
+	assert.NoError(t, err)
+
+	breaker.Reset()
+	assert.Equal(t, CircuitClosed, breaker.State(Openai, "chat/completions", ""))
+}
+
+func TestCircuitBreaker_HalfOpen(t *testing.T) {
+	var mu sync.Mutex
+	var states []CircuitState
+	breaker := NewCircuitBreaker(&CircuitBreakerOptions{
+		MinCalls: 2,
+		CoolDown: 50 * time.Millisecond,
+		OnStateChange: func(change CircuitStateChange) {
+			mu.Lock()
+			defer mu.Unlock()
+			states = append(states, change.To)
+		},
+	})
+	server, client := newCircuitGateway(t, breaker)
+	ctx := context.Background()
+
+	server.fail(string(Openai), http.StatusBadGateway)
+	for range 2 {
+		_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+		require.Error(t, err)
+	}
+	assert.Equal(t, CircuitOpen, breaker.State(Openai, "chat/completions", ""))
+
+	// A failed probe opens the circuit again.
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, CircuitHalfOpen, breaker.State(Openai, "chat/completions", ""))
+	_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, CircuitOpen, breaker.State(Openai, "chat/completions", ""))
+
+	// A successful probe closes it.
+	time.Sleep(60 * time.Millisecond)
+	server.fail(string(Openai), http.StatusOK)
+	_, err = client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Equal(t, CircuitClosed, breaker.State(Openai, "chat/completions", ""))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitOpen, CircuitHalfOpen, CircuitClosed}, states)
+}
+
+func TestCircuitBreaker_HalfOpenLimit(t *testing.T) {
+	breaker := NewCircuitBreaker(&CircuitBreakerOptions{MinCalls: 1, CoolDown: time.Millisecond})
+	key := CircuitKey{Provider: Openai, Endpoint: "chat/completions"}
+
+	done, err := breaker.allow(key)
+	require.NoError(t, err)
+	done(http.StatusInternalServerError, nil)
+	time.Sleep(5 * time.Millisecond)
+
+	probe, err := breaker.allow(key)
+	require.NoError(t, err)
+	_, err = breaker.allow(key)
+	assert.ErrorIs(t, err, ErrCircuitOpen, "only one probe at a time")
+
+	// Cancelled probes don't decide anything.
+	probe(0, context.Canceled)
+	assert.Equal(t, CircuitHalfOpen, breaker.State(Openai, "chat/completions", ""))
+}
+
+func TestCircuitBreaker_StaleResults(t *testing.T) {
+	breaker := NewCircuitBreaker(&CircuitBreakerOptions{MinCalls: 1, CoolDown: time.Millisecond})
+	key := CircuitKey{Provider: Openai, Endpoint: "chat/completions"}
+
+	slow, err := breaker.allow(key)
+	require.NoError(t, err)
+	failed, err := breaker.allow(key)
+	require.NoError(t, err)
+	failed(http.StatusInternalServerError, nil)
+	time.Sleep(5 * time.Millisecond)
+	probe, err := breaker.allow(key)
+	require.NoError(t, err)
+
+	// A call let through before the circuit opened finishes while the
+	// probe is still running; it decides nothing either way.
+	slow(http.StatusOK, nil)
+	assert.Equal(t, CircuitHalfOpen, breaker.State(Openai, "chat/completions", ""))
+	_, err = breaker.allow(key)
+	assert.ErrorIs(t, err, ErrCircuitOpen, "the probe is still running")
+
+	probe(http.StatusOK, nil)
+	assert.Equal(t, CircuitClosed, breaker.State(Openai, "chat/completions", ""))
+
+	// Nor does a stale failure reopen the closed circuit.
+	stale, err := breaker.allow(key)
+	require.NoError(t, err)
+	failed, err = breaker.allow(key)
+	require.NoError(t, err)
+	failed(http.StatusInternalServerError, nil)
+	time.Sleep(5 * time.Millisecond)
+	probe, err = breaker.allow(key)
+	require.NoError(t, err)
+	probe(http.StatusOK, nil)
+	stale(http.StatusInternalServerError, nil)
+	assert.Equal(t, CircuitClosed, breaker.State(Openai, "chat/completions", ""))
+}
+
+func TestCircuitBreaker_PerModel(t *testing.T) {
+	breaker := NewCircuitBreaker(&CircuitBreakerOptions{MinCalls: 1, PerModel: true, CoolDown: time.Hour})
+	server, client := newCircuitGateway(t, breaker)
+	ctx := context.Background()
+
+	server.fail(string(Openai), http.StatusInternalServerError)
+	_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.Error(t, err)
+	assert.Equal(t, CircuitOpen, breaker.State(Openai, "chat/completions", "gpt-4o"))
+	assert.Equal(t, CircuitClosed, breaker.State(Openai, "chat/completions", "gpt-4o-mini"))
+
+	server.fail(string(Openai), http.StatusOK)
+	_, err = client.GenerateContent(ctx, Openai, "gpt-4o-mini", hello())
+	assert.NoError(t, err)
+}
+
+func TestCircuitBreaker_Fallback(t *testing.T) {
+	breaker := NewCircuitBreaker(&CircuitBreakerOptions{MinCalls: 1, CoolDown: time.Hour})
+	server, client := newCircuitGateway(t, breaker)
+	ctx := context.Background()
+
+	server.fail(string(Openai), http.StatusInternalServerError)
+	_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.Error(t, err)
+
+	// Even a policy that only fails over on 429 skips open circuits.
+	var skipped atomic.Int32
+	fallbackClient := NewFallbackClient(client, FallbackOptions{
+		Targets:    []FallbackTarget{{Provider: Openai}, {Provider: Anthropic, Model: "claude-sonnet-4"}},
+		Failover:   FailoverOnStatus(http.StatusTooManyRequests),
+		OnFailover: func(FallbackFailure) { skipped.Add(1) },
+	})
+	var report FallbackReport
+	response, err := fallbackClient.GenerateContent(WithFallbackReport(ctx, &report), "", "gpt-4o", hello())
+	require.NoError(t, err)
+	text, err := messageText(response.Choices[0].Message.Content)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi from anthropic", text)
+	assert.Equal(t, 1, server.count(string(Openai)), "the open circuit was skipped")
+	assert.Equal(t, int32(1), skipped.Load())
+	assert.ErrorIs(t, report.Failures[0].Err, ErrCircuitOpen)
+}
+
+func TestCircuitBreaker_Retries(t *testing.T) {
+	breaker := NewCircuitBreaker(&CircuitBreakerOptions{MinCalls: 2, FailureRate: 0.5, CoolDown: 50 * time.Millisecond})
+	gateway := newTestGateway(t)
+	client := gateway.client(&ClientOptions{
+		RetryConfig:    &RetryConfig{Enabled: true, MaxAttempts: 5, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+		CircuitBreaker: breaker,
+	})
+	gateway.fail(string(Openai), http.StatusServiceUnavailable)
+	ctx := context.Background()
+
+	// Every attempt counts, so retries stop once the circuit opens.
+	_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 2, gateway.count(string(Openai)))
+
+	// A half-open probe is a single request.
+	time.Sleep(60 * time.Millisecond)
+	_, err = client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 3, gateway.count(string(Openai)))
+	assert.Equal(t, CircuitOpen, breaker.State(Openai, "chat/completions", ""))
+}
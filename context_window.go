@@ -0,0 +1,309 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TruncationStrategy decides what a ContextManager does with turns that no
+// longer fit the model's context window.
+type TruncationStrategy string
+
+const (
+	// TruncateOldest drops the oldest turns.
+	TruncateOldest TruncationStrategy = "drop_oldest"
+	// TruncateSummarize replaces the oldest turns with a summary written by
+	// ContextManagerOptions.SummaryModel.
+	TruncateSummarize TruncationStrategy = "summarize"
+)
+
+const (
+	defaultReserveTokens = 1024
+	defaultModelsTTL     = 10 * time.Minute
+	maxSummaryAttempts   = 3
+)
+
+// summaryPrompt instructs the summary model. The transcript follows as the
+// user message.
+const summaryPrompt = "Summarize the following conversation so it can replace the original turns. " +
+	"Keep facts, decisions, open questions and tool results that later turns may rely on. Be concise."
+
+// ContextManagerOptions configures a ContextManager.
+type ContextManagerOptions struct {
+	// Strategy defaults to TruncateOldest.
+	Strategy TruncationStrategy
+	// ReserveTokens is kept free for the completion. Defaults to 1024.
+	ReserveTokens int
+	// Pinned marks messages that must never be dropped. System messages and
+	// the latest turn are always kept.
+	Pinned func(index int, message Message) bool
+	// SummaryProvider and SummaryModel write summaries for TruncateSummarize;
+	// a small, cheap model is usually enough.
+	SummaryProvider Provider
+	SummaryModel    string
+	// CountTokens estimates the prompt tokens of messages, e.g. a
+	// TokenCounter's CountMessages. Defaults to a TokenCounter using
+	// HeuristicTokenizer.
+	CountTokens func(messages []Message) int
+	// ModelsTTL is how long context windows fetched from ListModels are
+	// cached. Defaults to 10 minutes.
+	ModelsTTL time.Duration
+	// Catalog, when set, is used to look up context windows instead of a
+	// catalog of the manager's own; ModelsTTL is then ignored.
+	Catalog *ModelCatalog
+}
+
+// ContextWindowError is returned when the messages that must be kept don't
+// fit the context window on their own.
+type ContextWindowError struct {
+	Model         string
+	PromptTokens  int
+	ContextWindow int
+}
+
+func (e *ContextWindowError) Error() string {
+	return fmt.Sprintf("prompt needs %d tokens but %s has a context window of %d tokens", e.PromptTokens, e.Model, e.ContextWindow)
+}
+
+// FittedRequest is a conversation trimmed to fit a context window.
+type FittedRequest struct {
+	Messages []Message
+	// PromptTokens is the estimated size of Messages.
+	PromptTokens int
+	// MaxCompletionTokens is what remains of the context window. Zero when
+	// the window is unknown.
+	MaxCompletionTokens int
+	// Dropped is the number of original messages left out.
+	Dropped int
+	// Summary replaces the dropped messages under TruncateSummarize.
+	Summary string
+}
+
+// ContextManager trims conversations to the model's context window before
+// each call. Context windows come from ListModels with
+// include=context_window and are cached. It is safe for concurrent use.
+type ContextManager struct {
+	client  Client
+	options ContextManagerOptions
+	models  *ModelCatalog
+}
+
+// NewContextManager creates a ContextManager that looks up models and sends
+// requests through client.
+func NewContextManager(client Client, options *ContextManagerOptions) *ContextManager {
+	m := &ContextManager{client: client}
+	if options != nil {
+		m.options = *options
+	}
+	if m.options.Strategy == "" {
+		m.options.Strategy = TruncateOldest
+	}
+	if m.options.ReserveTokens <= 0 {
+		m.options.ReserveTokens = defaultReserveTokens
+	}
+	if m.options.CountTokens == nil {
+		m.options.CountTokens = NewTokenCounter(nil).CountMessages
+	}
+	if m.options.ModelsTTL <= 0 {
+		m.options.ModelsTTL = defaultModelsTTL
+	}
+	m.models = m.options.Catalog
+	if m.models == nil {
+		m.models = newModelCatalog(client, m.options.ModelsTTL, ListModelsParamsIncludeContextWindow)
+	}
+	return m
+}
+
+// ContextWindow returns the context window of model in tokens, or 0 when
+// the gateway doesn't report one.
+func (m *ContextManager) ContextWindow(ctx context.Context, provider Provider, model string) (int, error) {
+	found, ok, err := m.models.lookup(ctx, provider, model)
+	if err != nil || !ok || found.ContextWindow == nil {
+		return 0, err
+	}
+	return found.ContextWindow.Tokens, nil
+}
+
+// Fit trims messages to the context window of model using the configured
+// strategy, and works out how many tokens remain for the completion.
+// Messages are returned unchanged when the window is unknown.
+func (m *ContextManager) Fit(ctx context.Context, provider Provider, model string, messages []Message) (*FittedRequest, error) {
+	window, err := m.ContextWindow(ctx, provider, model)
+	if err != nil {
+		return nil, err
+	}
+
+	fitted := &FittedRequest{Messages: messages, PromptTokens: m.options.CountTokens(messages)}
+	if window == 0 {
+		return fitted, nil
+	}
+
+	budget := window - m.options.ReserveTokens
+	if fitted.PromptTokens > budget {
+		fitted, err = m.truncate(ctx, messages, budget)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if fitted.PromptTokens > budget {
+		return nil, &ContextWindowError{Model: model, PromptTokens: fitted.PromptTokens, ContextWindow: window}
+	}
+
+	fitted.MaxCompletionTokens = window - fitted.PromptTokens
+	return fitted, nil
+}
+
+// GenerateContent fits messages to the context window, then runs a chat
+// completion with MaxCompletionTokens capped to the remaining budget.
+func (m *ContextManager) GenerateContent(ctx context.Context, provider Provider, model string, messages []Message) (*CreateChatCompletionResponse, error) {
+	fitted, err := m.Fit(ctx, provider, model, messages)
+	if err != nil {
+		return nil, err
+	}
+	return m.client.GenerateContent(fittedContext(ctx, fitted), provider, model, fitted.Messages)
+}
+
+// GenerateContentStream is GenerateContent in streaming mode.
+func (m *ContextManager) GenerateContentStream(ctx context.Context, provider Provider, model string, messages []Message) (<-chan SSEvent, error) {
+	fitted, err := m.Fit(ctx, provider, model, messages)
+	if err != nil {
+		return nil, err
+	}
+	return m.client.GenerateContentStream(fittedContext(ctx, fitted), provider, model, fitted.Messages)
+}
+
+// fittedContext caps the MaxCompletionTokens of the chat completion sent
+// with the returned context to what fitted leaves of the context window. A
+// smaller cap set with WithChatOptions is kept.
+func fittedContext(ctx context.Context, fitted *FittedRequest) context.Context {
+	if fitted.MaxCompletionTokens == 0 {
+		return ctx
+	}
+	if options := ChatOptions(ctx); options != nil && options.MaxCompletionTokens != nil && *options.MaxCompletionTokens <= fitted.MaxCompletionTokens {
+		return ctx
+	}
+	return WithChatOptions(ctx, &CreateChatCompletionRequest{MaxCompletionTokens: &fitted.MaxCompletionTokens})
+}
+
+// contextTurn is a run of messages that must be kept or dropped together:
+// an assistant message with tool calls and the tool results answering it.
+type contextTurn struct {
+	start, end int
+	keep       bool
+}
+
+func (m *ContextManager) truncate(ctx context.Context, messages []Message, budget int) (*FittedRequest, error) {
+	turns := m.turns(messages)
+
+	target, previous := budget, -1
+	for attempt := 1; ; attempt++ {
+		kept, removed := m.drop(messages, turns, target)
+		fitted := &FittedRequest{Messages: kept, Dropped: len(removed)}
+		if m.options.Strategy == TruncateSummarize && len(removed) > 0 {
+			summary, err := m.summarize(ctx, removed)
+			if err != nil {
+				return nil, err
+			}
+			fitted.Summary = summary
+			fitted.Messages = insertSummary(kept, summary)
+		}
+		fitted.PromptTokens = m.options.CountTokens(fitted.Messages)
+
+		over := fitted.PromptTokens - budget
+		if over <= 0 || fitted.Summary == "" || fitted.Dropped == previous || attempt == maxSummaryAttempts {
+			return fitted, nil
+		}
+		// The summary itself pushed the prompt over budget: drop more turns
+		// to make room for it and summarize again.
+		target, previous = target-over, fitted.Dropped
+	}
+}
+
+// drop removes the oldest turns that may be dropped until messages fit
+// target, returning the messages kept and removed.
+func (m *ContextManager) drop(messages []Message, turns []contextTurn, target int) (kept, removed []Message) {
+	tokens := m.options.CountTokens(messages)
+	dropped := make([]bool, len(turns))
+	for i, turn := range turns {
+		if tokens <= target {
+			break
+		}
+		if turn.keep {
+			continue
+		}
+		dropped[i] = true
+		tokens -= m.options.CountTokens(messages[turn.start:turn.end])
+	}
+
+	for i, turn := range turns {
+		if dropped[i] {
+			removed = append(removed, messages[turn.start:turn.end]...)
+		} else {
+			kept = append(kept, messages[turn.start:turn.end]...)
+		}
+	}
+	return kept, removed
+}
+
+// turns groups messages so a tool call is never separated from its results,
+// and marks the groups that must be kept.
+func (m *ContextManager) turns(messages []Message) []contextTurn {
+	var turns []contextTurn
+	for i, message := range messages {
+		if message.Role == Tool && len(turns) > 0 {
+			turns[len(turns)-1].end = i + 1
+		} else {
+			turns = append(turns, contextTurn{start: i, end: i + 1})
+		}
+
+		turn := &turns[len(turns)-1]
+		if message.Role == System || (m.options.Pinned != nil && m.options.Pinned(i, message)) {
+			turn.keep = true
+		}
+	}
+	if len(turns) > 0 {
+		turns[len(turns)-1].keep = true
+	}
+	return turns
+}
+
+func (m *ContextManager) summarize(ctx context.Context, messages []Message) (string, error) {
+	if m.options.SummaryModel == "" {
+		return "", fmt.Errorf("summarize strategy requires a SummaryModel")
+	}
+
+	var transcript strings.Builder
+	for _, message := range messages {
+		text, _ := messageText(message.Content)
+		fmt.Fprintf(&transcript, "%s: %s\n", message.Role, text)
+		if message.ToolCalls != nil {
+			for _, call := range *message.ToolCalls {
+				fmt.Fprintf(&transcript, "%s called %s(%s)\n", message.Role, call.Function.Name, call.Function.Arguments)
+			}
+		}
+	}
+
+	response, err := m.client.GenerateContent(ctx, m.options.SummaryProvider, m.options.SummaryModel, []Message{
+		{Role: System, Content: NewMessageContent(summaryPrompt)},
+		{Role: User, Content: NewMessageContent(transcript.String())},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("failed to summarize conversation: response has no choices")
+	}
+	return messageText(response.Choices[0].Message.Content)
+}
+
+// insertSummary places the summary after the leading system messages.
+func insertSummary(messages []Message, summary string) []Message {
+	at := 0
+	for at < len(messages) && messages[at].Role == System {
+		at++
+	}
+	note := Message{Role: System, Content: NewMessageContent("Summary of the earlier conversation:\n" + summary)}
+	return append(append(append([]Message(nil), messages[:at]...), note), messages[at:]...)
+}
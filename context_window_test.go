@@ -0,0 +1,204 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// contextServer lists one model with the given context window and answers
+// chat completions with reply, recording every chat request body.
+type contextServer struct {
+	mu         sync.Mutex
+	listModels int
+	bodies     []map[string]any
+}
+
+func newContextServer(t *testing.T, window int, reply func(w http.ResponseWriter)) (*contextServer, Client) {
+	t.Helper()
+	cs := &contextServer{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cs.mu.Lock()
+		defer cs.mu.Unlock()
+
+		switch r.URL.Path {
+		case "/v1/models":
+			cs.listModels++
+			assert.Equal(t, "context_window", r.URL.Query().Get("include"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"object": "list", "data": [{
+				"id": "openai/gpt-4o", "object": "model", "created": 1, "owned_by": "openai", "served_by": "openai",
+				"context_window": {"tokens": %d, "source": "provider"}
+			}]}`, window)
+		case "/v1/chat/completions":
+			var body map[string]any
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			cs.bodies = append(cs.bodies, body)
+			reply(w)
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return cs, NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+}
+
+// tenTokensEach counts every message as ten tokens.
+func tenTokensEach(messages []Message) int {
+	return 10 * len(messages)
+}
+
+func textMessage(role MessageRole, text string) Message {
+	return Message{Role: role, Content: NewMessageContent(text)}
+}
+
+func contextHistory() []Message {
+	toolCalls := []ChatCompletionMessageToolCall{{
+		ID:       "call_1",
+		Type:     Function,
+		Function: ChatCompletionMessageToolCallFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+	}}
+	return []Message{
+		textMessage(System, "Be brief."),
+		textMessage(User, "Weather in Paris?"),
+		{Role: Assistant, Content: NewMessageContent(""), ToolCalls: &toolCalls},
+		{Role: Tool, Content: NewMessageContent("18C"), ToolCallID: new("call_1")},
+		textMessage(Assistant, "It's 18C."),
+		textMessage(User, "Remember: I'm vegetarian."),
+		textMessage(Assistant, "Noted."),
+		textMessage(User, "Suggest a dinner."),
+	}
+}
+
+func TestContextManager_FitDropsOldest(t *testing.T) {
+	_, client := newContextServer(t, 80, chatReply("unused"))
+	manager := NewContextManager(client, &ContextManagerOptions{
+		ReserveTokens: 20,
+		CountTokens:   tenTokensEach,
+		Pinned: func(_ int, message Message) bool {
+			text, _ := messageText(message.Content)
+			return text == "Remember: I'm vegetarian."
+		},
+	})
+
+	fitted, err := manager.Fit(context.Background(), Openai, "gpt-4o", contextHistory())
+	require.NoError(t, err)
+
+	// 80 tokens minus 20 reserved leaves room for six messages. The tool
+	// call and its result are dropped together.
+	var texts []string
+	for _, message := range fitted.Messages {
+		text, _ := messageText(message.Content)
+		texts = append(texts, text)
+	}
+	assert.Equal(t, []string{"Be brief.", "It's 18C.", "Remember: I'm vegetarian.", "Noted.", "Suggest a dinner."}, texts)
+	assert.Equal(t, 3, fitted.Dropped)
+	assert.Equal(t, 50, fitted.PromptTokens)
+	assert.Equal(t, 30, fitted.MaxCompletionTokens)
+}
+
+func TestContextManager_FitWithinWindow(t *testing.T) {
+	_, client := newContextServer(t, 128000, chatReply("unused"))
+	manager := NewContextManager(client, nil)
+
+	messages := contextHistory()
+	fitted, err := manager.Fit(context.Background(), Openai, "gpt-4o", messages)
+	require.NoError(t, err)
+	assert.Equal(t, messages, fitted.Messages)
+	assert.Zero(t, fitted.Dropped)
+	assert.Equal(t, 128000-fitted.PromptTokens, fitted.MaxCompletionTokens)
+}
+
+func TestContextManager_UnknownWindow(t *testing.T) {
+	_, client := newContextServer(t, 80, chatReply("unused"))
+	manager := NewContextManager(client, &ContextManagerOptions{CountTokens: tenTokensEach})
+
+	messages := contextHistory()
+	fitted, err := manager.Fit(context.Background(), Anthropic, "claude-sonnet-4", messages)
+	require.NoError(t, err)
+	assert.Equal(t, messages, fitted.Messages)
+	assert.Zero(t, fitted.MaxCompletionTokens)
+}
+
+func TestContextManager_TooLarge(t *testing.T) {
+	_, client := newContextServer(t, 25, chatReply("unused"))
+	manager := NewContextManager(client, &ContextManagerOptions{ReserveTokens: 10, CountTokens: tenTokensEach})
+
+	_, err := manager.Fit(context.Background(), Openai, "gpt-4o", contextHistory())
+	var windowErr *ContextWindowError
+	require.True(t, errors.As(err, &windowErr))
+	assert.Equal(t, 25, windowErr.ContextWindow)
+	assert.Equal(t, 20, windowErr.PromptTokens)
+}
+
+func TestContextManager_Summarize(t *testing.T) {
+	server, client := newContextServer(t, 80, chatReply("User asked about the weather in Paris (18C)."))
+	manager := NewContextManager(client, &ContextManagerOptions{
+		Strategy:        TruncateSummarize,
+		ReserveTokens:   20,
+		CountTokens:     tenTokensEach,
+		SummaryProvider: Openai,
+		SummaryModel:    "gpt-4o-mini",
+	})
+
+	fitted, err := manager.Fit(context.Background(), Openai, "gpt-4o", contextHistory())
+	require.NoError(t, err)
+	assert.Equal(t, "User asked about the weather in Paris (18C).", fitted.Summary)
+	// The summary takes the place of the dropped turns.
+	require.Len(t, fitted.Messages, 6)
+	assert.Equal(t, 3, fitted.Dropped)
+	assert.Equal(t, System, fitted.Messages[1].Role)
+	summary, _ := messageText(fitted.Messages[1].Content)
+	assert.Contains(t, summary, "weather in Paris")
+
+	require.Len(t, server.bodies, 1)
+	assert.Equal(t, "gpt-4o-mini", server.bodies[0]["model"])
+	transcript := server.bodies[0]["messages"].([]any)[1].(map[string]any)["content"].(string)
+	assert.Contains(t, transcript, `assistant called get_weather({"city":"Paris"})`)
+}
+
+func TestContextManager_GenerateContent(t *testing.T) {
+	server, client := newContextServer(t, 80, chatReply("Mushroom risotto."))
+	manager := NewContextManager(client, &ContextManagerOptions{ReserveTokens: 20, CountTokens: tenTokensEach})
+
+	for range 2 {
+		response, err := manager.GenerateContent(context.Background(), Openai, "gpt-4o", contextHistory())
+		require.NoError(t, err)
+		text, err := messageText(response.Choices[0].Message.Content)
+		require.NoError(t, err)
+		assert.Equal(t, "Mushroom risotto.", text)
+	}
+
+	assert.Equal(t, 1, server.listModels)
+	require.Len(t, server.bodies, 2)
+	assert.Len(t, server.bodies[0]["messages"], 5)
+	assert.Equal(t, float64(30), server.bodies[0]["max_completion_tokens"])
+}
+
+func TestContextManager_WrappedClientKeepsItsOptions(t *testing.T) {
+	server, client := newContextServer(t, 80, chatReply("Mushroom risotto."))
+	temperature := float32(0.3)
+	wrapped := NewFallbackClient(client.WithOptions(&CreateChatCompletionRequest{Temperature: &temperature}),
+		FallbackOptions{Targets: []FallbackTarget{{Provider: Openai}}})
+	manager := NewContextManager(wrapped, &ContextManagerOptions{ReserveTokens: 20, CountTokens: tenTokensEach})
+
+	_, err := manager.GenerateContent(context.Background(), Openai, "gpt-4o", contextHistory())
+	require.NoError(t, err)
+	_, err = wrapped.GenerateContent(context.Background(), Openai, "gpt-4o", contextHistory())
+	require.NoError(t, err)
+
+	require.Len(t, server.bodies, 2)
+	assert.Equal(t, float64(30), server.bodies[0]["max_completion_tokens"])
+	assert.InDelta(t, 0.3, server.bodies[0]["temperature"], 0.001)
+	assert.Nil(t, server.bodies[1]["max_completion_tokens"], "the fitted budget must not stick to the client")
+	assert.InDelta(t, 0.3, server.bodies[1]["temperature"], 0.001)
+}
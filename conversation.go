@@ -0,0 +1,318 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// ConversationVersion is the version of the JSON form written by
+// Conversation.MarshalJSON. UnmarshalJSON accepts this version and older.
+const ConversationVersion = 1
+
+// Conversation is a chat history bound to a client, provider and model. It
+// appends turns, runs completions and records the assistant's replies,
+// including tool calls, reasoning and provider extra content, so the next
+// call sends the full history. It is safe for concurrent use.
+type Conversation struct {
+	client Client
+
+	mu       sync.Mutex
+	provider Provider
+	model    string
+	messages []Message
+	metadata map[string]string
+}
+
+// conversationJSON is the persisted form of a Conversation.
+type conversationJSON struct {
+	Version  int               `json:"version"`
+	Provider Provider          `json:"provider"`
+	Model    string            `json:"model"`
+	Messages []Message         `json:"messages"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// NewConversation starts an empty conversation.
+//
+// Example:
+//
+//	conversation := sdk.NewConversation(client, sdk.Openai, "gpt-4o")
+//	conversation.AddSystem("You are a helpful assistant.")
+//	conversation.AddUser("What is Go?")
+//	response, err := conversation.Send(ctx)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	conversation.AddUser("Show me an example.")
+//	response, err = conversation.Send(ctx)
+func NewConversation(client Client, provider Provider, model string) *Conversation {
+	return &Conversation{client: client, provider: provider, model: model}
+}
+
+// LoadConversation restores a conversation saved with json.Marshal and binds
+// it to client.
+func LoadConversation(client Client, data []byte) (*Conversation, error) {
+	conversation := &Conversation{client: client}
+	if err := json.Unmarshal(data, conversation); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// Provider returns the provider the conversation is sent to.
+func (c *Conversation) Provider() Provider {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.provider
+}
+
+// Model returns the model the conversation is sent to.
+func (c *Conversation) Model() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.model
+}
+
+// SetModel switches the provider and model used by later calls. The
+// history is kept.
+func (c *Conversation) SetModel(provider Provider, model string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.provider = provider
+	c.model = model
+}
+
+// Messages returns a copy of the history.
+func (c *Conversation) Messages() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.messages)
+}
+
+// Len returns the number of messages in the history.
+func (c *Conversation) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.messages)
+}
+
+// Metadata returns the value stored under key, for bookkeeping such as a
+// session or tenant ID. It is persisted with the conversation.
+func (c *Conversation) Metadata(key string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.metadata[key]
+}
+
+// SetMetadata stores a value under key.
+func (c *Conversation) SetMetadata(key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.metadata == nil {
+		c.metadata = make(map[string]string)
+	}
+	c.metadata[key] = value
+}
+
+// Append adds messages to the history as they are.
+func (c *Conversation) Append(messages ...Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, messages...)
+}
+
+// AddSystem adds a system message.
+func (c *Conversation) AddSystem(text string) {
+	c.Append(Message{Role: System, Content: NewMessageContent(text)})
+}
+
+// AddUser adds a user text message.
+func (c *Conversation) AddUser(text string) {
+	c.Append(Message{Role: User, Content: NewMessageContent(text)})
+}
+
+// AddUserParts adds a multimodal user message, e.g. text and images.
+func (c *Conversation) AddUserParts(parts ...ContentPart) {
+	c.Append(Message{Role: User, Content: NewMessageContent(parts)})
+}
+
+// AddToolResult adds the result of the tool call with the given ID.
+func (c *Conversation) AddToolResult(toolCallID, content string) {
+	c.Append(Message{Role: Tool, Content: NewMessageContent(content), ToolCallID: &toolCallID})
+}
+
+// PendingToolCalls returns the tool calls of the last assistant message that
+// have no tool result yet.
+func (c *Conversation) PendingToolCalls() []ChatCompletionMessageToolCall {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	answered := make(map[string]bool)
+	for i := len(c.messages) - 1; i >= 0; i-- {
+		message := c.messages[i]
+		switch message.Role {
+		case Tool:
+			if message.ToolCallID != nil {
+				answered[*message.ToolCallID] = true
+			}
+		case Assistant:
+			if message.ToolCalls == nil {
+				return nil
+			}
+			var pending []ChatCompletionMessageToolCall
+			for _, call := range *message.ToolCalls {
+				if !answered[call.ID] {
+					pending = append(pending, call)
+				}
+			}
+			return pending
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// Send runs a chat completion over the history and records the assistant's
+// reply.
+func (c *Conversation) Send(ctx context.Context) (*CreateChatCompletionResponse, error) {
+	c.mu.Lock()
+	provider, model, messages := c.provider, c.model, slices.Clone(c.messages)
+	c.mu.Unlock()
+
+	response, err := c.client.GenerateContent(ctx, provider, model, messages)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Choices) == 0 {
+		return response, fmt.Errorf("response has no choices")
+	}
+
+	c.Append(response.Choices[0].Message)
+	return response, nil
+}
+
+// SendStream runs a streaming chat completion over the history. Events are
+// passed through unchanged; once the stream ends successfully the assembled
+// assistant reply is recorded. The channel must be drained for the reply to
+// be recorded.
+func (c *Conversation) SendStream(ctx context.Context) (<-chan SSEvent, error) {
+	c.mu.Lock()
+	provider, model, messages := c.provider, c.model, slices.Clone(c.messages)
+	c.mu.Unlock()
+
+	events, err := c.client.GenerateContentStream(ctx, provider, model, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan SSEvent, 100)
+	go func() {
+		defer close(out)
+
+		acc := &chatStreamAccumulator{}
+		failed := false
+		for event := range events {
+			switch {
+			case event.Event == nil && event.Data != nil:
+				// readSSEStream reports read errors as an event without a type.
+				failed = true
+			case event.Event != nil && *event.Event == ContentDelta && event.Data != nil:
+				if _, err := acc.add(*event.Data); err != nil {
+					failed = true
+				}
+			}
+
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if failed || ctx.Err() != nil {
+			return
+		}
+		response, err := acc.result()
+		if err != nil || (response.Text == "" && response.Reasoning == "" && len(response.ToolCalls) == 0) {
+			return
+		}
+		c.Append(response.Message())
+	}()
+	return out, nil
+}
+
+// Fork returns an independent conversation with the first n messages of the
+// history, for branching from an earlier turn. The fork shares the client,
+// provider, model and a copy of the metadata.
+func (c *Conversation) Fork(n int) (*Conversation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if n < 0 || n > len(c.messages) {
+		return nil, fmt.Errorf("fork point %d out of range [0, %d]", n, len(c.messages))
+	}
+
+	return &Conversation{
+		client:   c.client,
+		provider: c.provider,
+		model:    c.model,
+		messages: slices.Clone(c.messages[:n]),
+		metadata: maps.Clone(c.metadata),
+	}, nil
+}
+
+// Truncate drops every message after the first n, e.g. to retry a turn.
+func (c *Conversation) Truncate(n int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if n < 0 || n > len(c.messages) {
+		return fmt.Errorf("truncate point %d out of range [0, %d]", n, len(c.messages))
+	}
+	c.messages = c.messages[:n:n]
+	return nil
+}
+
+// MarshalJSON writes the conversation with a version number, so stores can
+// persist it and LoadConversation can resume it later.
+func (c *Conversation) MarshalJSON() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	messages := c.messages
+	if messages == nil {
+		messages = []Message{}
+	}
+	return json.Marshal(conversationJSON{
+		Version:  ConversationVersion,
+		Provider: c.provider,
+		Model:    c.model,
+		Messages: messages,
+		Metadata: c.metadata,
+	})
+}
+
+// UnmarshalJSON restores a conversation written by MarshalJSON. The client
+// is left as is; use LoadConversation to bind one.
+func (c *Conversation) UnmarshalJSON(data []byte) error {
+	var stored conversationJSON
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to parse conversation: %w", err)
+	}
+	if stored.Version < 1 || stored.Version > ConversationVersion {
+		return fmt.Errorf("unsupported conversation version %d", stored.Version)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.provider = stored.Provider
+	c.model = stored.Model
+	c.messages = stored.Messages
+	c.metadata = stored.Metadata
+	return nil
+}
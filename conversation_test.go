@@ -0,0 +1,141 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+func TestConversation_Send(t *testing.T) {
+	server, client := newGenerateServer(t, map[string]func(w http.ResponseWriter){
+		"/v1/chat/completions": jsonReply(`{
+			"id": "chatcmpl-1", "object": "chat.completion", "created": 1, "model": "gemini-2.5-pro",
+			"choices": [{"index": 0, "finish_reason": "tool_calls", "message": {
+				"role": "assistant", "content": "", "reasoning_content": "Need the weather.",
+				"tool_calls": [{"id": "call_1", "type": "function",
+					"function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"},
+					"extra_content": {"google": {"thought_signature": "sig"}}}]
+			}}]
+		}`),
+	})
+
+	conversation := NewConversation(client, Google, "gemini-2.5-pro")
+	conversation.AddSystem("Be brief.")
+	conversation.AddUser("Weather in Paris?")
+
+	_, err := conversation.Send(context.Background())
+	require.NoError(t, err)
+
+	messages := conversation.Messages()
+	require.Len(t, messages, 3)
+	reply := messages[2]
+	assert.Equal(t, Assistant, reply.Role)
+	assert.Equal(t, "Need the weather.", *reply.ReasoningContent)
+	require.NotNil(t, reply.ToolCalls)
+	assert.NotNil(t, (*reply.ToolCalls)[0].ExtraContent)
+
+	pending := conversation.PendingToolCalls()
+	require.Len(t, pending, 1)
+	conversation.AddToolResult(pending[0].ID, "18C")
+	assert.Empty(t, conversation.PendingToolCalls())
+
+	_, err = conversation.Send(context.Background())
+	require.NoError(t, err)
+
+	// The second request replays the reasoning, tool call and its extra
+	// content verbatim.
+	sent := server.bodies[1]["messages"].([]any)
+	require.Len(t, sent, 4)
+	assert.Equal(t, "Need the weather.", sent[2].(map[string]any)["reasoning_content"])
+	call := sent[2].(map[string]any)["tool_calls"].([]any)[0].(map[string]any)
+	assert.Equal(t, map[string]any{"google": map[string]any{"thought_signature": "sig"}}, call["extra_content"])
+	assert.Equal(t, "call_1", sent[3].(map[string]any)["tool_call_id"])
+}
+
+func TestConversation_SendStream(t *testing.T) {
+	_, client := newGenerateServer(t, map[string]func(w http.ResponseWriter){
+		"/v1/chat/completions": sseReply(
+			`{"id":"c1","model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}`,
+			`{"id":"c1","model":"gpt-4o","choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+			`[DONE]`,
+		),
+	})
+
+	conversation := NewConversation(client, Openai, "gpt-4o")
+	conversation.AddUser("Hi")
+
+	events, err := conversation.SendStream(context.Background())
+	require.NoError(t, err)
+	count := 0
+	for range events {
+		count++
+	}
+	assert.Equal(t, 3, count)
+
+	messages := conversation.Messages()
+	require.Len(t, messages, 2)
+	text, err := messages[1].Content.AsMessageContent0()
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", text)
+}
+
+func TestConversation_Fork(t *testing.T) {
+	conversation := NewConversation(nil, Openai, "gpt-4o")
+	conversation.SetMetadata("tenant", "acme")
+	conversation.AddUser("one")
+	conversation.Append(Message{Role: Assistant, Content: NewMessageContent("two")})
+	conversation.AddUser("three")
+
+	fork, err := conversation.Fork(2)
+	require.NoError(t, err)
+	fork.AddUser("branch")
+	fork.SetMetadata("tenant", "other")
+
+	assert.Equal(t, 3, conversation.Len())
+	assert.Equal(t, 3, fork.Len())
+	assert.Equal(t, "acme", conversation.Metadata("tenant"))
+	last, err := fork.Messages()[2].Content.AsMessageContent0()
+	require.NoError(t, err)
+	assert.Equal(t, "branch", last)
+
+	_, err = conversation.Fork(4)
+	assert.EqualError(t, err, "fork point 4 out of range [0, 3]")
+
+	require.NoError(t, conversation.Truncate(1))
+	assert.Equal(t, 1, conversation.Len())
+	assert.Equal(t, 3, fork.Len())
+}
+
+func TestConversation_JSON(t *testing.T) {
+	conversation := NewConversation(nil, Anthropic, "claude-sonnet-5")
+	conversation.SetMetadata("session", "s-1")
+	conversation.AddSystem("Be brief.")
+	conversation.AddUser("Hi")
+
+	data, err := json.Marshal(conversation)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"version": 1,
+		"provider": "anthropic",
+		"model": "claude-sonnet-5",
+		"messages": [
+			{"role": "system", "content": "Be brief."},
+			{"role": "user", "content": "Hi"}
+		],
+		"metadata": {"session": "s-1"}
+	}`, string(data))
+
+	restored, err := LoadConversation(nil, data)
+	require.NoError(t, err)
+	assert.Equal(t, Anthropic, restored.Provider())
+	assert.Equal(t, "claude-sonnet-5", restored.Model())
+	assert.Equal(t, "s-1", restored.Metadata("session"))
+	assert.Equal(t, conversation.Messages(), restored.Messages())
+
+	_, err = LoadConversation(nil, []byte(`{"version": 2, "messages": []}`))
+	assert.EqualError(t, err, "unsupported conversation version 2")
+}
@@ -0,0 +1,884 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Converters between the three conversation formats the gateway speaks:
+// Message for /chat/completions, MessagesMessage for /messages and
+// ResponseInputItem/ResponseOutputItem for /responses.
+//
+// Conversions are lossless where the target format can represent the
+// source. Anything it cannot - a tool call in a Responses input, a thinking
+// block without a signature in a chat transcript, cache_control outside the
+// Messages API - is left out and listed in the returned ConversionReport,
+// with a path into the source transcript.
+
+// DroppedField is a piece of a conversation that could not be represented
+// in the target format.
+type DroppedField struct {
+	// Path locates the field in the source, e.g. `messages[2].tool_calls[0]`.
+	Path string `json:"path"`
+	// Reason explains why it was dropped.
+	Reason string `json:"reason"`
+}
+
+// ConversionReport lists everything a conversion dropped, including tool
+// arguments it sent repaired rather than as they were.
+type ConversionReport struct {
+	Dropped []DroppedField `json:"dropped,omitempty"`
+}
+
+// Lossless reports whether nothing was dropped.
+func (r *ConversionReport) Lossless() bool {
+	return len(r.Dropped) == 0
+}
+
+func (r *ConversionReport) drop(path, format string, args ...any) {
+	r.Dropped = append(r.Dropped, DroppedField{Path: path, Reason: fmt.Sprintf(format, args...)})
+}
+
+// ChatToMessages converts a chat completion transcript to the Messages API.
+// System messages become the system prompt, tool messages become
+// tool_result blocks and tool calls become tool_use blocks.
+func ChatToMessages(messages []Message) (*CreateMessagesRequest_System, []MessagesMessage, *ConversionReport) {
+	report := &ConversionReport{}
+	system, out := emitMessages(parseChat(messages, report), report)
+	return system, out, report
+}
+
+// ChatToResponses converts a chat completion transcript to Responses API
+// input. Leading system messages become the instructions.
+func ChatToResponses(messages []Message) (*string, []ResponseInputItem, *ConversionReport) {
+	report := &ConversionReport{}
+	instructions, items := emitResponses(parseChat(messages, report), report)
+	return instructions, items, report
+}
+
+// MessagesToChat converts a Messages API system prompt and transcript to
+// chat completion messages. tool_result blocks become tool messages and
+// thinking blocks become reasoning content.
+func MessagesToChat(system *CreateMessagesRequest_System, messages []MessagesMessage) ([]Message, *ConversionReport) {
+	report := &ConversionReport{}
+	return emitChat(parseMessages(system, messages, report), report), report
+}
+
+// MessagesToResponses converts a Messages API system prompt and transcript
+// to Responses API instructions and input.
+func MessagesToResponses(system *CreateMessagesRequest_System, messages []MessagesMessage) (*string, []ResponseInputItem, *ConversionReport) {
+	report := &ConversionReport{}
+	instructions, items := emitResponses(parseMessages(system, messages, report), report)
+	return instructions, items, report
+}
+
+// ResponsesToChat converts Responses API instructions and input to chat
+// completion messages.
+func ResponsesToChat(instructions *string, input ResponseInput) ([]Message, *ConversionReport) {
+	report := &ConversionReport{}
+	return emitChat(parseResponses(instructions, input, report), report), report
+}
+
+// ResponsesToMessages converts Responses API instructions and input to a
+// Messages API system prompt and transcript.
+func ResponsesToMessages(instructions *string, input ResponseInput) (*CreateMessagesRequest_System, []MessagesMessage, *ConversionReport) {
+	report := &ConversionReport{}
+	system, out := emitMessages(parseResponses(instructions, input, report), report)
+	return system, out, report
+}
+
+// ResponseOutputToChat converts the output of a Responses API call to the
+// assistant message(s) to append to a chat transcript. Function calls become
+// tool calls and reasoning summaries become reasoning content.
+func ResponseOutputToChat(output []ResponseOutputItem) ([]Message, *ConversionReport) {
+	report := &ConversionReport{}
+	return emitChat(parseResponseOutput(output, report), report), report
+}
+
+// ResponseOutputToMessages converts the output of a Responses API call to
+// the assistant message to append to a Messages API transcript.
+func ResponseOutputToMessages(output []ResponseOutputItem) ([]MessagesMessage, *ConversionReport) {
+	report := &ConversionReport{}
+	_, out := emitMessages(parseResponseOutput(output, report), report)
+	return out, report
+}
+
+// ResponseOutputToInput converts the output of a Responses API call to
+// input items, for continuing the conversation without
+// previous_response_id.
+func ResponseOutputToInput(output []ResponseOutputItem) ([]ResponseInputItem, *ConversionReport) {
+	report := &ConversionReport{}
+	_, items := emitResponses(parseResponseOutput(output, report), report)
+	return items, report
+}
+
+// MessagesResponseToMessage turns a Messages API response into the
+// assistant message to append to the transcript for the next request.
+func MessagesResponseToMessage(response MessagesResponse) MessagesMessage {
+	blocks := make([]MessagesRequestContentBlock, 0, len(response.Content))
+	for _, block := range response.Content {
+		// Every response block type is also a request block type with the
+		// same JSON shape.
+		blocks = append(blocks, MessagesRequestContentBlock{union: block.union})
+	}
+	var content MessagesMessage_Content
+	mustBuildUnion(content.FromMessagesMessageContent1(blocks))
+	return MessagesMessage{Role: MessagesMessageRoleAssistant, Content: content}
+}
+
+// convTurn and convPart are the format-neutral form every converter goes
+// through: a parser turns a source transcript into turns and an emitter
+// renders them in the target format, reporting what it cannot express.
+type convTurn struct {
+	role  string // system, developer, user, assistant or tool
+	path  string
+	parts []convPart
+}
+
+type convPartKind int
+
+const (
+	convText convPartKind = iota
+	convImage
+	convToolCall
+	convToolResult
+	convReasoning
+	convRedactedReasoning
+	convRefusal
+	convUnsupported
+)
+
+type convPart struct {
+	kind convPartKind
+	path string
+
+	text string // text, refusal, reasoning, tool result content
+
+	imageURL string // http(s) or data URL
+	detail   string
+
+	toolID    string
+	toolName  string
+	arguments string
+	isError   bool
+
+	signature string // thinking block signature
+	data      string // redacted thinking data
+
+	cacheControl bool // the source block carried cache_control
+	extraContent bool // the source tool call carried provider extra content
+}
+
+func parseChat(messages []Message, report *ConversionReport) []convTurn {
+	turns := make([]convTurn, 0, len(messages))
+	for i, message := range messages {
+		path := fmt.Sprintf("messages[%d]", i)
+		turn := convTurn{role: string(message.Role), path: path}
+
+		if reasoning := firstNonEmpty(message.ReasoningContent, message.Reasoning); reasoning != "" {
+			turn.parts = append(turn.parts, convPart{kind: convReasoning, path: path + ".reasoning_content", text: reasoning})
+		}
+
+		content, err := message.Content.Value()
+		if err != nil {
+			report.drop(path+".content", "invalid content: %v", err)
+		}
+		switch c := content.(type) {
+		case string:
+			if message.Role == Tool {
+				id := ""
+				if message.ToolCallID != nil {
+					id = *message.ToolCallID
+				}
+				turn.parts = append(turn.parts, convPart{kind: convToolResult, path: path, toolID: id, text: c})
+			} else if c != "" {
+				turn.parts = append(turn.parts, convPart{kind: convText, path: path + ".content", text: c})
+			}
+		case []ContentPart:
+			parts := parseChatParts(c, path+".content", report)
+			if message.Role == Tool {
+				id := ""
+				if message.ToolCallID != nil {
+					id = *message.ToolCallID
+				}
+				turn.parts = append(turn.parts, convPart{kind: convToolResult, path: path, toolID: id, text: joinPartText(parts, path, report)})
+			} else {
+				turn.parts = append(turn.parts, parts...)
+			}
+		}
+
+		if message.ToolCalls != nil {
+			for j, call := range *message.ToolCalls {
+				turn.parts = append(turn.parts, convPart{
+					kind:         convToolCall,
+					path:         fmt.Sprintf("%s.tool_calls[%d]", path, j),
+					toolID:       call.ID,
+					toolName:     call.Function.Name,
+					arguments:    call.Function.Arguments,
+					extraContent: call.ExtraContent != nil,
+				})
+			}
+		}
+
+		turns = append(turns, turn)
+	}
+	return turns
+}
+
+func parseChatParts(parts []ContentPart, path string, report *ConversionReport) []convPart {
+	out := make([]convPart, 0, len(parts))
+	for j, part := range parts {
+		partPath := fmt.Sprintf("%s[%d]", path, j)
+		v, err := part.Value()
+		if err != nil {
+			report.drop(partPath, "invalid content part: %v", err)
+			continue
+		}
+		switch p := v.(type) {
+		case TextContentPart:
+			out = append(out, convPart{kind: convText, path: partPath, text: p.Text})
+		case ImageContentPart:
+			image := convPart{kind: convImage, path: partPath, imageURL: p.ImageURL.URL}
+			if p.ImageURL.Detail != nil {
+				image.detail = string(*p.ImageURL.Detail)
+			}
+			out = append(out, image)
+		}
+	}
+	return out
+}
+
+func parseMessages(system *CreateMessagesRequest_System, messages []MessagesMessage, report *ConversionReport) []convTurn {
+	var turns []convTurn
+
+	if system != nil {
+		v, err := system.Value()
+		if err != nil {
+			report.drop("system", "invalid system prompt: %v", err)
+		}
+		switch s := v.(type) {
+		case string:
+			turns = append(turns, convTurn{role: "system", path: "system", parts: []convPart{{kind: convText, path: "system", text: s}}})
+		case []MessagesTextBlock:
+			turn := convTurn{role: "system", path: "system"}
+			for j, block := range s {
+				turn.parts = append(turn.parts, convPart{
+					kind:         convText,
+					path:         fmt.Sprintf("system[%d]", j),
+					text:         block.Text,
+					cacheControl: block.CacheControl != nil,
+				})
+			}
+			turns = append(turns, turn)
+		}
+	}
+
+	for i, message := range messages {
+		path := fmt.Sprintf("messages[%d]", i)
+		turn := convTurn{role: string(message.Role), path: path}
+
+		v, err := message.Content.Value()
+		if err != nil {
+			report.drop(path+".content", "invalid content: %v", err)
+		}
+		switch c := v.(type) {
+		case string:
+			turn.parts = append(turn.parts, convPart{kind: convText, path: path + ".content", text: c})
+		case []MessagesRequestContentBlock:
+			for j, block := range c {
+				blockPath := fmt.Sprintf("%s.content[%d]", path, j)
+				if part, ok := parseMessagesBlock(block, blockPath, report); ok {
+					turn.parts = append(turn.parts, part)
+				}
+			}
+		}
+
+		turns = append(turns, turn)
+	}
+	return turns
+}
+
+func parseMessagesBlock(block MessagesRequestContentBlock, path string, report *ConversionReport) (convPart, bool) {
+	v, err := block.Value()
+	if err != nil {
+		report.drop(path, "invalid content block: %v", err)
+		return convPart{}, false
+	}
+
+	switch b := v.(type) {
+	case MessagesTextBlock:
+		return convPart{kind: convText, path: path, text: b.Text, cacheControl: b.CacheControl != nil}, true
+	case MessagesImageBlock:
+		part := convPart{kind: convImage, path: path, cacheControl: b.CacheControl != nil}
+		switch {
+		case b.Source.Type == MessagesImageSourceTypeURL && b.Source.URL != nil:
+			part.imageURL = *b.Source.URL
+		case b.Source.Data != nil && b.Source.MediaType != nil:
+			part.imageURL = fmt.Sprintf("data:%s;base64,%s", *b.Source.MediaType, *b.Source.Data)
+		default:
+			report.drop(path, "image block has no usable source")
+			return convPart{}, false
+		}
+		return part, true
+	case MessagesToolUseBlock:
+		arguments, err := json.Marshal(b.Input)
+		if err != nil {
+			report.drop(path+".input", "tool input is not serializable: %v", err)
+			arguments = []byte("{}")
+		}
+		return convPart{kind: convToolCall, path: path, toolID: b.ID, toolName: b.Name, arguments: string(arguments)}, true
+	case MessagesToolResultBlock:
+		part := convPart{
+			kind:         convToolResult,
+			path:         path,
+			toolID:       b.ToolUseID,
+			isError:      b.IsError != nil && *b.IsError,
+			cacheControl: b.CacheControl != nil,
+		}
+		if b.Content != nil {
+			content, err := b.Content.Value()
+			if err != nil {
+				report.drop(path+".content", "invalid tool result content: %v", err)
+			}
+			switch c := content.(type) {
+			case string:
+				part.text = c
+			case []MessagesTextBlock:
+				texts := make([]string, len(c))
+				for k, text := range c {
+					texts[k] = text.Text
+				}
+				part.text = strings.Join(texts, "\n\n")
+			}
+		}
+		return part, true
+	case MessagesThinkingBlock:
+		return convPart{kind: convReasoning, path: path, text: b.Thinking, signature: b.Signature}, true
+	case MessagesRedactedThinkingBlock:
+		return convPart{kind: convRedactedReasoning, path: path, data: b.Data}, true
+	case MessagesDocumentBlock:
+		return convPart{kind: convUnsupported, path: path, text: "document blocks"}, true
+	}
+	return convPart{}, false
+}
+
+func parseResponses(instructions *string, input ResponseInput, report *ConversionReport) []convTurn {
+	var turns []convTurn
+	if instructions != nil && *instructions != "" {
+		turns = append(turns, convTurn{role: "system", path: "instructions", parts: []convPart{{kind: convText, path: "instructions", text: *instructions}}})
+	}
+
+	v, err := input.Value()
+	if err != nil {
+		report.drop("input", "invalid input: %v", err)
+	}
+	switch in := v.(type) {
+	case string:
+		turns = append(turns, convTurn{role: "user", path: "input", parts: []convPart{{kind: convText, path: "input", text: in}}})
+	case []ResponseInputItem:
+		for i, item := range in {
+			path := fmt.Sprintf("input[%d]", i)
+			turn := convTurn{role: string(item.Role), path: path}
+
+			content, err := item.Content.Value()
+			if err != nil {
+				report.drop(path+".content", "invalid content: %v", err)
+			}
+			switch c := content.(type) {
+			case string:
+				turn.parts = append(turn.parts, convPart{kind: convText, path: path + ".content", text: c})
+			case []ResponseInputContentPart:
+				for j, part := range c {
+					partPath := fmt.Sprintf("%s.content[%d]", path, j)
+					pv, err := part.Value()
+					if err != nil {
+						report.drop(partPath, "invalid content part: %v", err)
+						continue
+					}
+					switch p := pv.(type) {
+					case ResponseInputText:
+						turn.parts = append(turn.parts, convPart{kind: convText, path: partPath, text: p.Text})
+					case ResponseInputImage:
+						if p.ImageURL == nil {
+							report.drop(partPath, "image has no image_url")
+							continue
+						}
+						image := convPart{kind: convImage, path: partPath, imageURL: *p.ImageURL}
+						if p.Detail != nil {
+							image.detail = string(*p.Detail)
+						}
+						turn.parts = append(turn.parts, image)
+					}
+				}
+			}
+
+			turns = append(turns, turn)
+		}
+	}
+	return turns
+}
+
+func parseResponseOutput(output []ResponseOutputItem, report *ConversionReport) []convTurn {
+	turn := convTurn{role: "assistant", path: "output"}
+	for i, item := range output {
+		path := fmt.Sprintf("output[%d]", i)
+		v, err := item.Value()
+		if err != nil {
+			report.drop(path, "invalid output item: %v", err)
+			continue
+		}
+		switch o := v.(type) {
+		case ResponseOutputMessage:
+			for j, content := range o.Content {
+				contentPath := fmt.Sprintf("%s.content[%d]", path, j)
+				cv, err := content.Value()
+				if err != nil {
+					report.drop(contentPath, "invalid output content: %v", err)
+					continue
+				}
+				switch c := cv.(type) {
+				case ResponseOutputText:
+					turn.parts = append(turn.parts, convPart{kind: convText, path: contentPath, text: c.Text})
+				case ResponseOutputRefusal:
+					turn.parts = append(turn.parts, convPart{kind: convRefusal, path: contentPath, text: c.Refusal})
+				}
+			}
+		case ResponseFunctionToolCall:
+			turn.parts = append(turn.parts, convPart{kind: convToolCall, path: path, toolID: o.CallID, toolName: o.Name, arguments: o.Arguments})
+		case ResponseReasoningItem:
+			summaries := make([]string, len(o.Summary))
+			for j, summary := range o.Summary {
+				summaries[j] = summary.Text
+			}
+			turn.parts = append(turn.parts, convPart{kind: convReasoning, path: path, text: strings.Join(summaries, "\n\n")})
+		}
+	}
+	if len(turn.parts) == 0 {
+		return nil
+	}
+	return []convTurn{turn}
+}
+
+func emitChat(turns []convTurn, report *ConversionReport) []Message {
+	var out []Message
+	for _, turn := range turns {
+		switch turn.role {
+		case "system", "developer":
+			if turn.role == "developer" {
+				report.drop(turn.path+".role", "chat completions have no developer role, sent as system")
+			}
+			text := joinPartText(turn.parts, turn.path, report)
+			out = append(out, Message{Role: System, Content: NewMessageContent(text)})
+
+		case "tool", "user":
+			var parts []ContentPart
+			for _, part := range turn.parts {
+				if part.cacheControl {
+					report.drop(part.path+".cache_control", "chat completions have no prompt caching controls")
+				}
+				switch part.kind {
+				case convToolResult:
+					if part.isError {
+						report.drop(part.path+".is_error", "chat tool messages cannot flag errors")
+					}
+					id := part.toolID
+					out = append(out, Message{Role: Tool, Content: NewMessageContent(part.text), ToolCallID: &id})
+				case convText:
+					parts = append(parts, ContentPart{union: mustMarshalUnion(TextContentPart{Type: TextContentPartTypeText, Text: part.text})})
+				case convImage:
+					parts = append(parts, chatImagePart(part))
+				default:
+					report.drop(part.path, "%s cannot be represented in a chat user message", part.describe())
+				}
+			}
+			if len(parts) > 0 {
+				out = append(out, Message{Role: User, Content: chatContent(parts)})
+			}
+
+		case "assistant":
+			message := Message{Role: Assistant}
+			var texts []string
+			var calls []ChatCompletionMessageToolCall
+			var reasoning []string
+			for _, part := range turn.parts {
+				if part.cacheControl {
+					report.drop(part.path+".cache_control", "chat completions have no prompt caching controls")
+				}
+				switch part.kind {
+				case convText:
+					texts = append(texts, part.text)
+				case convToolCall:
+					calls = append(calls, ChatCompletionMessageToolCall{
+						ID:       part.toolID,
+						Type:     Function,
+						Function: ChatCompletionMessageToolCallFunction{Name: part.toolName, Arguments: part.arguments},
+					})
+				case convReasoning:
+					if part.signature != "" {
+						report.drop(part.path+".signature", "chat completions cannot carry thinking signatures")
+					}
+					reasoning = append(reasoning, part.text)
+				default:
+					report.drop(part.path, "%s cannot be represented in a chat assistant message", part.describe())
+				}
+			}
+			if len(texts) == 0 && len(calls) == 0 && len(reasoning) == 0 {
+				continue
+			}
+			message.Content = NewMessageContent(strings.Join(texts, ""))
+			if len(calls) > 0 {
+				message.ToolCalls = &calls
+			}
+			if len(reasoning) > 0 {
+				joined := strings.Join(reasoning, "\n\n")
+				message.ReasoningContent = &joined
+			}
+			out = append(out, message)
+
+		default:
+			report.drop(turn.path, "unknown role %q", turn.role)
+		}
+	}
+	return out
+}
+
+func emitMessages(turns []convTurn, report *ConversionReport) (*CreateMessagesRequest_System, []MessagesMessage) {
+	var systemBlocks []MessagesTextBlock
+	var systemCached bool
+
+	type pending struct {
+		role   MessagesMessageRole
+		blocks []MessagesRequestContentBlock
+	}
+	var messages []pending
+	add := func(role MessagesMessageRole, blocks []MessagesRequestContentBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		// Consecutive turns of the same role are merged: Messages API
+		// transcripts alternate, and tool results must share one user turn.
+		if n := len(messages); n > 0 && messages[n-1].role == role {
+			messages[n-1].blocks = append(messages[n-1].blocks, blocks...)
+			return
+		}
+		messages = append(messages, pending{role: role, blocks: blocks})
+	}
+
+	for i, turn := range turns {
+		switch turn.role {
+		case "system", "developer":
+			if len(messages) > 0 {
+				report.drop(turn.path, "the Messages API has no mid-conversation system messages, moved to the system prompt")
+			} else if turn.role == "developer" && i > 0 {
+				report.drop(turn.path+".role", "the Messages API has no developer role, moved to the system prompt")
+			}
+			for _, part := range turn.parts {
+				if part.kind != convText {
+					report.drop(part.path, "%s cannot be part of a system prompt", part.describe())
+					continue
+				}
+				block := MessagesTextBlock{Type: MessagesTextBlockTypeText, Text: part.text}
+				if part.cacheControl {
+					block.CacheControl = &CacheControl{Type: Ephemeral}
+					systemCached = true
+				}
+				systemBlocks = append(systemBlocks, block)
+			}
+
+		case "user", "tool":
+			var results, blocks []MessagesRequestContentBlock
+			for _, part := range turn.parts {
+				switch part.kind {
+				case convToolResult:
+					results = append(results, messagesToolResultBlock(part))
+				case convText:
+					blocks = append(blocks, messagesTextBlock(part))
+				case convImage:
+					if block, ok := messagesImageBlock(part, report); ok {
+						blocks = append(blocks, block)
+					}
+				default:
+					report.drop(part.path, "%s cannot be represented in a Messages API user turn", part.describe())
+				}
+			}
+			// tool_result blocks must come first in their user turn.
+			add(MessagesMessageRoleUser, append(results, blocks...))
+
+		case "assistant":
+			var blocks []MessagesRequestContentBlock
+			for _, part := range turn.parts {
+				switch part.kind {
+				case convText:
+					blocks = append(blocks, messagesTextBlock(part))
+				case convToolCall:
+					blocks = append(blocks, messagesToolUseBlock(part, report))
+				case convReasoning:
+					if part.signature == "" {
+						report.drop(part.path, "reasoning without a signature cannot be replayed as a thinking block")
+						continue
+					}
+					blocks = append(blocks, MessagesRequestContentBlock{union: mustMarshalUnion(MessagesThinkingBlock{Type: Thinking, Thinking: part.text, Signature: part.signature})})
+				case convRedactedReasoning:
+					blocks = append(blocks, MessagesRequestContentBlock{union: mustMarshalUnion(MessagesRedactedThinkingBlock{Type: RedactedThinking, Data: part.data})})
+				default:
+					report.drop(part.path, "%s cannot be represented in a Messages API assistant turn", part.describe())
+				}
+			}
+			add(MessagesMessageRoleAssistant, blocks)
+
+		default:
+			report.drop(turn.path, "unknown role %q", turn.role)
+		}
+	}
+
+	var system *CreateMessagesRequest_System
+	switch {
+	case len(systemBlocks) == 1 && !systemCached:
+		system = SystemText(systemBlocks[0].Text)
+	case len(systemBlocks) > 0:
+		system = SystemBlocks(systemBlocks...)
+	}
+
+	out := make([]MessagesMessage, len(messages))
+	for i, m := range messages {
+		var content MessagesMessage_Content
+		if text, ok := plainMessagesText(m.blocks); ok {
+			mustBuildUnion(content.FromMessagesMessageContent0(text))
+		} else {
+			mustBuildUnion(content.FromMessagesMessageContent1(m.blocks))
+		}
+		out[i] = MessagesMessage{Role: m.role, Content: content}
+	}
+	return system, out
+}
+
+func emitResponses(turns []convTurn, report *ConversionReport) (*string, []ResponseInputItem) {
+	var instructions []string
+	var items []ResponseInputItem
+
+	for _, turn := range turns {
+		switch turn.role {
+		case "system", "developer":
+			text := joinPartText(turn.parts, turn.path, report)
+			if len(items) == 0 && turn.role == "system" {
+				instructions = append(instructions, text)
+				continue
+			}
+			items = append(items, responseInputMessage(ResponseRole(turn.role), text))
+
+		case "user", "assistant":
+			var parts []ResponseInputContentPart
+			for _, part := range turn.parts {
+				if part.cacheControl {
+					report.drop(part.path+".cache_control", "the Responses API has no prompt caching controls")
+				}
+				switch {
+				case part.kind == convText:
+					parts = append(parts, ResponseInputContentPart{union: mustMarshalUnion(ResponseInputText{Type: InputText, Text: part.text})})
+				case part.kind == convImage && turn.role == "user":
+					image := ResponseInputImage{Type: InputImage, ImageURL: &part.imageURL}
+					if part.detail != "" {
+						detail := ResponseInputImageDetail(part.detail)
+						image.Detail = &detail
+					}
+					parts = append(parts, ResponseInputContentPart{union: mustMarshalUnion(image)})
+				default:
+					report.drop(part.path, "%s cannot be represented in Responses API input messages", part.describe())
+				}
+			}
+			if len(parts) == 0 {
+				continue
+			}
+			var content ResponseInputMessageContent
+			if text, ok := plainResponsesText(parts); ok {
+				mustBuildUnion(content.FromResponseInputMessageContent0(text))
+			} else {
+				mustBuildUnion(content.FromResponseInputMessageContent1(parts))
+			}
+			items = append(items, ResponseInputItem{Role: ResponseRole(turn.role), Content: content})
+
+		case "tool":
+			for _, part := range turn.parts {
+				report.drop(part.path, "%s cannot be represented in Responses API input messages", part.describe())
+			}
+
+		default:
+			report.drop(turn.path, "unknown role %q", turn.role)
+		}
+	}
+
+	if len(instructions) == 0 {
+		return nil, items
+	}
+	joined := strings.Join(instructions, "\n\n")
+	return &joined, items
+}
+
+func (p convPart) describe() string {
+	switch p.kind {
+	case convText:
+		return "text"
+	case convImage:
+		return "an image"
+	case convToolCall:
+		return "a tool call"
+	case convToolResult:
+		return "a tool result"
+	case convReasoning:
+		return "reasoning"
+	case convRedactedReasoning:
+		return "redacted reasoning"
+	case convRefusal:
+		return "a refusal"
+	}
+	return p.text
+}
+
+// joinPartText flattens parts that must become a single string, dropping
+// anything that is not text.
+func joinPartText(parts []convPart, path string, report *ConversionReport) string {
+	var texts []string
+	for _, part := range parts {
+		if part.kind != convText {
+			report.drop(part.path, "%s cannot be part of %s", part.describe(), path)
+			continue
+		}
+		if part.cacheControl {
+			report.drop(part.path+".cache_control", "prompt caching controls are only supported by the Messages API")
+		}
+		texts = append(texts, part.text)
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+func chatContent(parts []ContentPart) MessageContent {
+	if len(parts) == 1 {
+		if text, err := parts[0].AsTextContentPart(); err == nil && text.Type == TextContentPartTypeText {
+			return NewMessageContent(text.Text)
+		}
+	}
+	return NewMessageContent(parts)
+}
+
+func chatImagePart(part convPart) ContentPart {
+	image := ImageContentPart{Type: ImageContentPartTypeImageURL, ImageURL: ImageURL{URL: part.imageURL}}
+	if part.detail != "" {
+		detail := ImageURLDetail(part.detail)
+		image.ImageURL.Detail = &detail
+	}
+	return ContentPart{union: mustMarshalUnion(image)}
+}
+
+func messagesTextBlock(part convPart) MessagesRequestContentBlock {
+	block := MessagesTextBlock{Type: MessagesTextBlockTypeText, Text: part.text}
+	if part.cacheControl {
+		block.CacheControl = &CacheControl{Type: Ephemeral}
+	}
+	return MessagesRequestContentBlock{union: mustMarshalUnion(block)}
+}
+
+func messagesImageBlock(part convPart, report *ConversionReport) (MessagesRequestContentBlock, bool) {
+	if part.detail != "" {
+		report.drop(part.path+".detail", "the Messages API has no image detail setting")
+	}
+
+	block := MessagesImageBlock{Type: MessagesImageBlockTypeImage}
+	if part.cacheControl {
+		block.CacheControl = &CacheControl{Type: Ephemeral}
+	}
+	if rest, ok := strings.CutPrefix(part.imageURL, "data:"); ok {
+		mediaType, data, ok := strings.Cut(rest, ";base64,")
+		if !ok {
+			report.drop(part.path, "only base64 data URLs can be sent to the Messages API")
+			return MessagesRequestContentBlock{}, false
+		}
+		block.Source = MessagesImageSource{Type: MessagesImageSourceTypeBase64, MediaType: &mediaType, Data: &data}
+	} else {
+		url := part.imageURL
+		block.Source = MessagesImageSource{Type: MessagesImageSourceTypeURL, URL: &url}
+	}
+	return MessagesRequestContentBlock{union: mustMarshalUnion(block)}, true
+}
+
+func messagesToolUseBlock(part convPart, report *ConversionReport) MessagesRequestContentBlock {
+	if part.extraContent {
+		report.drop(part.path+".extra_content", "provider-specific tool call content cannot be carried over")
+	}
+
+	input := map[string]any{}
+	if strings.TrimSpace(part.arguments) != "" {
+		repaired := RepairJSON(part.arguments)
+		if err := json.Unmarshal([]byte(repaired), &input); err != nil {
+			report.drop(part.path+".function.arguments", "arguments are not a JSON object: %v", err)
+			input = map[string]any{}
+		} else if repaired != part.arguments {
+			report.drop(part.path+".function.arguments", "arguments are not valid JSON and were repaired to %s", repaired)
+		}
+	}
+	return MessagesRequestContentBlock{union: mustMarshalUnion(MessagesToolUseBlock{
+		Type:  MessagesToolUseBlockTypeToolUse,
+		ID:    part.toolID,
+		Name:  part.toolName,
+		Input: input,
+	})}
+}
+
+func messagesToolResultBlock(part convPart) MessagesRequestContentBlock {
+	var content MessagesToolResultBlock_Content
+	mustBuildUnion(content.FromMessagesToolResultBlockContent0(part.text))
+	block := MessagesToolResultBlock{Type: ToolResult, ToolUseID: part.toolID, Content: &content}
+	if part.isError {
+		block.IsError = boolPtr(true)
+	}
+	if part.cacheControl {
+		block.CacheControl = &CacheControl{Type: Ephemeral}
+	}
+	return MessagesRequestContentBlock{union: mustMarshalUnion(block)}
+}
+
+// plainMessagesText reports whether blocks are a single uncached text block,
+// which is sent in the shorter string form.
+func plainMessagesText(blocks []MessagesRequestContentBlock) (string, bool) {
+	if len(blocks) != 1 {
+		return "", false
+	}
+	v, err := blocks[0].Value()
+	if err != nil {
+		return "", false
+	}
+	text, ok := v.(MessagesTextBlock)
+	if !ok || text.CacheControl != nil {
+		return "", false
+	}
+	return text.Text, true
+}
+
+func plainResponsesText(parts []ResponseInputContentPart) (string, bool) {
+	if len(parts) != 1 {
+		return "", false
+	}
+	v, err := parts[0].Value()
+	if err != nil {
+		return "", false
+	}
+	text, ok := v.(ResponseInputText)
+	return text.Text, ok
+}
+
+// mustMarshalUnion marshals a union variant that is known to be
+// serializable.
+func mustMarshalUnion(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	mustBuildUnion(err)
+	return data
+}
+
+func firstNonEmpty(values ...*string) string {
+	for _, v := range values {
+		if v != nil && *v != "" {
+			return *v
+		}
+	}
+	return ""
+}
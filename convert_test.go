@@ -0,0 +1,262 @@
+package sdk
+
+import (
+	"encoding/json"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+func chatTranscript(t *testing.T) []Message {
+	t.Helper()
+	detail := ImageURLDetailHigh
+	text, err := NewTextContentPart("What is in this image?")
+	require.NoError(t, err)
+	image, err := NewImageContentPart("data:image/png;base64,iVBORw0KGgo=", &detail)
+	require.NoError(t, err)
+	calls := []ChatCompletionMessageToolCall{{
+		ID:       "call_1",
+		Type:     Function,
+		Function: ChatCompletionMessageToolCallFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+	}}
+	return []Message{
+		{Role: System, Content: NewMessageContent("Be brief.")},
+		{Role: User, Content: NewMessageContent([]ContentPart{text, image})},
+		{Role: Assistant, Content: NewMessageContent(""), ToolCalls: &calls, ReasoningContent: new("Need the weather.")},
+		{Role: Tool, Content: NewMessageContent("18C and sunny"), ToolCallID: new("call_1")},
+		{Role: Assistant, Content: NewMessageContent("It is sunny.")},
+	}
+}
+
+func marshalJSON(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestChatToMessages(t *testing.T) {
+	system, messages, report := ChatToMessages(chatTranscript(t))
+
+	require.NotNil(t, system)
+	text, err := system.Text()
+	require.NoError(t, err)
+	assert.Equal(t, "Be brief.", text)
+
+	assert.JSONEq(t, `[
+		{"role": "user", "content": [
+			{"type": "text", "text": "What is in this image?"},
+			{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="}}
+		]},
+		{"role": "assistant", "content": [
+			{"type": "tool_use", "id": "call_1", "name": "get_weather", "input": {"city": "Paris"}}
+		]},
+		{"role": "user", "content": [
+			{"type": "tool_result", "tool_use_id": "call_1", "content": "18C and sunny"}
+		]},
+		{"role": "assistant", "content": "It is sunny."}
+	]`, marshalJSON(t, messages))
+
+	assert.Equal(t, []DroppedField{
+		{Path: "messages[1].content[1].detail", Reason: "the Messages API has no image detail setting"},
+		{Path: "messages[2].reasoning_content", Reason: "reasoning without a signature cannot be replayed as a thinking block"},
+	}, report.Dropped)
+	assert.False(t, report.Lossless())
+}
+
+func TestChatToResponses(t *testing.T) {
+	instructions, items, report := ChatToResponses(chatTranscript(t))
+
+	require.NotNil(t, instructions)
+	assert.Equal(t, "Be brief.", *instructions)
+	assert.JSONEq(t, `[
+		{"role": "user", "content": [
+			{"type": "input_text", "text": "What is in this image?"},
+			{"type": "input_image", "image_url": "data:image/png;base64,iVBORw0KGgo=", "detail": "high"}
+		]},
+		{"role": "assistant", "content": "It is sunny."}
+	]`, marshalJSON(t, items))
+
+	paths := make([]string, len(report.Dropped))
+	for i, dropped := range report.Dropped {
+		paths[i] = dropped.Path
+	}
+	assert.Equal(t, []string{"messages[2].reasoning_content", "messages[2].tool_calls[0]", "messages[3]"}, paths)
+}
+
+func TestMessagesToChat(t *testing.T) {
+	system := SystemBlocks(MessagesTextBlock{Text: "Be brief.", CacheControl: &CacheControl{Type: Ephemeral}})
+	var messages []MessagesMessage
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"role": "user", "content": [
+			{"type": "image", "source": {"type": "url", "url": "https://example.com/cat.png"}},
+			{"type": "text", "text": "Weather?"}
+		]},
+		{"role": "assistant", "content": [
+			{"type": "thinking", "thinking": "Check the tool.", "signature": "sig"},
+			{"type": "redacted_thinking", "data": "opaque"},
+			{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}
+		]},
+		{"role": "user", "content": [
+			{"type": "tool_result", "tool_use_id": "toolu_1", "content": [{"type": "text", "text": "18C"}], "is_error": true},
+			{"type": "text", "text": "Thanks"}
+		]}
+	]`), &messages))
+
+	out, report := MessagesToChat(system, messages)
+
+	assert.JSONEq(t, `[
+		{"role": "system", "content": "Be brief."},
+		{"role": "user", "content": [
+			{"type": "image_url", "image_url": {"url": "https://example.com/cat.png"}},
+			{"type": "text", "text": "Weather?"}
+		]},
+		{"role": "assistant", "content": "", "reasoning_content": "Check the tool.", "tool_calls": [
+			{"id": "toolu_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}
+		]},
+		{"role": "tool", "content": "18C", "tool_call_id": "toolu_1"},
+		{"role": "user", "content": "Thanks"}
+	]`, marshalJSON(t, out))
+
+	assert.Equal(t, []DroppedField{
+		{Path: "system[0].cache_control", Reason: "prompt caching controls are only supported by the Messages API"},
+		{Path: "messages[1].content[0].signature", Reason: "chat completions cannot carry thinking signatures"},
+		{Path: "messages[1].content[1]", Reason: "redacted reasoning cannot be represented in a chat assistant message"},
+		{Path: "messages[2].content[0].is_error", Reason: "chat tool messages cannot flag errors"},
+	}, report.Dropped)
+}
+
+func TestMessagesRoundTrip(t *testing.T) {
+	var messages []MessagesMessage
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"role": "user", "content": "Weather in Paris?"},
+		{"role": "assistant", "content": [
+			{"type": "text", "text": "Checking."},
+			{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}
+		]},
+		{"role": "user", "content": [
+			{"type": "tool_result", "tool_use_id": "toolu_1", "content": "18C"}
+		]}
+	]`), &messages))
+
+	chat, report := MessagesToChat(SystemText("Be brief."), messages)
+	require.True(t, report.Lossless(), report.Dropped)
+
+	system, back, report := ChatToMessages(chat)
+	require.True(t, report.Lossless(), report.Dropped)
+	assert.Equal(t, marshalJSON(t, SystemText("Be brief.")), marshalJSON(t, system))
+	assert.JSONEq(t, marshalJSON(t, messages), marshalJSON(t, back))
+}
+
+func TestResponsesToMessages(t *testing.T) {
+	var input ResponseInput
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"role": "user", "content": "Hello"},
+		{"role": "developer", "content": "Answer in French."},
+		{"role": "user", "content": [
+			{"type": "input_image", "image_url": "https://example.com/cat.png"}
+		]}
+	]`), &input))
+
+	system, messages, report := ResponsesToMessages(new("Be brief."), input)
+
+	require.NotNil(t, system)
+	text, err := system.Text()
+	require.NoError(t, err)
+	assert.Equal(t, "Be brief.\n\nAnswer in French.", text)
+	assert.JSONEq(t, `[
+		{"role": "user", "content": [
+			{"type": "text", "text": "Hello"},
+			{"type": "image", "source": {"type": "url", "url": "https://example.com/cat.png"}}
+		]}
+	]`, marshalJSON(t, messages))
+	assert.Equal(t, []DroppedField{
+		{Path: "input[1]", Reason: "the Messages API has no mid-conversation system messages, moved to the system prompt"},
+	}, report.Dropped)
+}
+
+func TestResponsesToChat(t *testing.T) {
+	var input ResponseInput
+	require.NoError(t, json.Unmarshal([]byte(`"Hello"`), &input))
+
+	out, report := ResponsesToChat(nil, input)
+	assert.True(t, report.Lossless())
+	assert.JSONEq(t, `[{"role": "user", "content": "Hello"}]`, marshalJSON(t, out))
+}
+
+func TestResponseOutputConversions(t *testing.T) {
+	var output []ResponseOutputItem
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"type": "reasoning", "id": "rs_1", "summary": [{"type": "summary_text", "text": "Look it up."}]},
+		{"type": "message", "id": "msg_1", "role": "assistant", "status": "completed", "content": [
+			{"type": "output_text", "text": "Checking.", "annotations": []}
+		]},
+		{"type": "function_call", "call_id": "call_1", "name": "get_weather", "arguments": "{\"city\":\"Paris\"}", "status": "completed"}
+	]`), &output))
+
+	chat, report := ResponseOutputToChat(output)
+	assert.True(t, report.Lossless())
+	assert.JSONEq(t, `[
+		{"role": "assistant", "content": "Checking.", "reasoning_content": "Look it up.", "tool_calls": [
+			{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}
+		]}
+	]`, marshalJSON(t, chat))
+
+	messages, report := ResponseOutputToMessages(output)
+	assert.Equal(t, []DroppedField{
+		{Path: "output[0]", Reason: "reasoning without a signature cannot be replayed as a thinking block"},
+	}, report.Dropped)
+	assert.JSONEq(t, `[
+		{"role": "assistant", "content": [
+			{"type": "text", "text": "Checking."},
+			{"type": "tool_use", "id": "call_1", "name": "get_weather", "input": {"city": "Paris"}}
+		]}
+	]`, marshalJSON(t, messages))
+
+	items, report := ResponseOutputToInput(output)
+	assert.Len(t, report.Dropped, 2)
+	assert.JSONEq(t, `[{"role": "assistant", "content": "Checking."}]`, marshalJSON(t, items))
+}
+
+func TestChatToMessages_RepairsArguments(t *testing.T) {
+	calls := []ChatCompletionMessageToolCall{
+		{ID: "a", Type: Function, Function: ChatCompletionMessageToolCallFunction{Name: "f", Arguments: "```json\n{\"x\":1}\n```"}},
+		{ID: "b", Type: Function, Function: ChatCompletionMessageToolCallFunction{Name: "f", Arguments: "[1,2]"}},
+	}
+	_, messages, report := ChatToMessages([]Message{{Role: Assistant, Content: NewMessageContent(""), ToolCalls: &calls}})
+
+	assert.JSONEq(t, `[
+		{"role": "assistant", "content": [
+			{"type": "tool_use", "id": "a", "name": "f", "input": {"x": 1}},
+			{"type": "tool_use", "id": "b", "name": "f", "input": {}}
+		]}
+	]`, marshalJSON(t, messages))
+	require.Len(t, report.Dropped, 2)
+	assert.Equal(t, DroppedField{
+		Path:   "messages[0].tool_calls[0].function.arguments",
+		Reason: `arguments are not valid JSON and were repaired to {"x":1}`,
+	}, report.Dropped[0])
+	assert.Equal(t, "messages[0].tool_calls[1].function.arguments", report.Dropped[1].Path)
+
+	// Valid arguments are not reported.
+	calls = calls[:1]
+	calls[0].Function.Arguments = `{"x": 1}`
+	_, _, report = ChatToMessages([]Message{{Role: Assistant, Content: NewMessageContent(""), ToolCalls: &calls}})
+	assert.True(t, report.Lossless())
+}
+
+func TestMessagesResponseToMessage(t *testing.T) {
+	var response MessagesResponse
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"id": "msg_1", "type": "message", "role": "assistant", "model": "claude",
+		"content": [{"type": "text", "text": "Hi"}, {"type": "tool_use", "id": "toolu_1", "name": "f", "input": {}}],
+		"stop_reason": "tool_use", "usage": {"input_tokens": 1, "output_tokens": 1}
+	}`), &response))
+
+	message := MessagesResponseToMessage(response)
+	assert.JSONEq(t, `{"role": "assistant", "content": [
+		{"type": "text", "text": "Hi"},
+		{"type": "tool_use", "id": "toolu_1", "name": "f", "input": {}}
+	]}`, marshalJSON(t, message))
+}
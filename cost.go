@@ -0,0 +1,308 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Decimal is an exact decimal number, used for prices and costs so sums
+// don't drift the way float64 does. The zero value is 0.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+// ParseDecimal parses a decimal string such as "0.0000025" or "2.5e-6".
+func ParseDecimal(s string) (Decimal, error) {
+	mantissa, exponent := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa = s[:i]
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+		}
+		exponent = e
+	}
+
+	whole, fraction, _ := strings.Cut(mantissa, ".")
+	unscaled, ok := new(big.Int).SetString(whole+fraction, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+
+	d := Decimal{unscaled: unscaled, scale: len(fraction) - exponent}
+	if d.scale < 0 {
+		d.unscaled.Mul(d.unscaled, pow10(-d.scale))
+		d.scale = 0
+	}
+	return d, nil
+}
+
+// MustParseDecimal is ParseDecimal for constants; it panics on invalid
+// input.
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func (d Decimal) value() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+// rescale returns the unscaled value of d at the given, larger scale.
+func (d Decimal) rescale(scale int) *big.Int {
+	return new(big.Int).Mul(d.value(), pow10(scale-d.scale))
+}
+
+// Add returns d + other.
+func (d Decimal) Add(other Decimal) Decimal {
+	scale := max(d.scale, other.scale)
+	return Decimal{unscaled: new(big.Int).Add(d.rescale(scale), other.rescale(scale)), scale: scale}
+}
+
+// Mul returns d * n, e.g. a per-token price times a token count.
+func (d Decimal) Mul(n int64) Decimal {
+	return Decimal{unscaled: new(big.Int).Mul(d.value(), big.NewInt(n)), scale: d.scale}
+}
+
+// Cmp compares d and other, returning -1, 0 or +1.
+func (d Decimal) Cmp(other Decimal) int {
+	scale := max(d.scale, other.scale)
+	return d.rescale(scale).Cmp(other.rescale(scale))
+}
+
+// IsZero reports whether d is 0.
+func (d Decimal) IsZero() bool {
+	return d.value().Sign() == 0
+}
+
+// Rat returns d as an exact rational.
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.value(), pow10(d.scale))
+}
+
+// Float64 returns the nearest float64, for display or metrics.
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// String formats d exactly, without trailing zeros.
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.value()).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		at := len(digits) - d.scale
+		digits = strings.TrimRight(digits[:at]+"."+digits[at:], "0")
+		digits = strings.TrimSuffix(digits, ".")
+	}
+	if d.value().Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// MarshalJSON writes d as a JSON string, keeping every digit.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+// UnmarshalJSON reads a JSON string or number.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	parsed, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Cost is what a call cost, broken down the way providers invoice it.
+type Cost struct {
+	// Currency is the pricing currency, e.g. USD.
+	Currency string `json:"currency"`
+	// Input is the cost of uncached input tokens.
+	Input Decimal `json:"input"`
+	// CachedInput is the cost of input tokens read from the prompt cache.
+	CachedInput Decimal `json:"cached_input"`
+	// CacheWrite is the cost of input tokens written to the prompt cache.
+	CacheWrite Decimal `json:"cache_write"`
+	// Output is the cost of output tokens, reasoning included.
+	Output Decimal `json:"output"`
+	// Reasoning is the part of Output spent on reasoning tokens.
+	Reasoning Decimal `json:"reasoning"`
+	// Total is Input + CachedInput + CacheWrite + Output.
+	Total Decimal `json:"total"`
+	// Subscription is set for models without per-token prices; their costs
+	// are zero.
+	Subscription bool `json:"subscription,omitempty"`
+}
+
+// prices is Pricing with its decimal strings parsed.
+type prices struct {
+	input, output, cacheRead, cacheWrite Decimal
+}
+
+func (p Pricing) parse() (prices, error) {
+	var parsed prices
+	var err error
+	if parsed.input, err = ParseDecimal(p.InputPerToken); err != nil {
+		return parsed, fmt.Errorf("invalid input price: %w", err)
+	}
+	if parsed.output, err = ParseDecimal(p.OutputPerToken); err != nil {
+		return parsed, fmt.Errorf("invalid output price: %w", err)
+	}
+	// Without a cache price, cached tokens are billed as regular input.
+	parsed.cacheRead, parsed.cacheWrite = parsed.input, parsed.input
+	if p.CacheReadPerToken != nil && *p.CacheReadPerToken != "" {
+		if parsed.cacheRead, err = ParseDecimal(*p.CacheReadPerToken); err != nil {
+			return parsed, fmt.Errorf("invalid cache read price: %w", err)
+		}
+	}
+	if p.CacheWritePerToken != nil && *p.CacheWritePerToken != "" {
+		if parsed.cacheWrite, err = ParseDecimal(*p.CacheWritePerToken); err != nil {
+			return parsed, fmt.Errorf("invalid cache write price: %w", err)
+		}
+	}
+	return parsed, nil
+}
+
+// Cost prices usage. Cached input is billed at CacheReadPerToken and cache
+// writes at CacheWritePerToken, falling back to InputPerToken.
+func (p Pricing) Cost(usage GenerateUsage) (*Cost, error) {
+	cost := &Cost{Currency: p.Currency}
+	if p.Subscription != nil && *p.Subscription {
+		cost.Subscription = true
+		return cost, nil
+	}
+
+	parsed, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	uncached := max(usage.InputTokens-usage.CachedInputTokens-usage.CacheWriteTokens, 0)
+	cost.Input = parsed.input.Mul(uncached)
+	cost.CachedInput = parsed.cacheRead.Mul(usage.CachedInputTokens)
+	cost.CacheWrite = parsed.cacheWrite.Mul(usage.CacheWriteTokens)
+	cost.Output = parsed.output.Mul(usage.OutputTokens)
+	cost.Reasoning = parsed.output.Mul(usage.ReasoningTokens)
+	cost.Total = cost.Input.Add(cost.CachedInput).Add(cost.CacheWrite).Add(cost.Output)
+	return cost, nil
+}
+
+// ChatCost prices the usage of a chat completion.
+func (p Pricing) ChatCost(usage CompletionUsage) (*Cost, error) {
+	return p.Cost(chatUsage(usage))
+}
+
+// MessagesCost prices the usage of a Messages API response, including cache
+// reads and writes.
+func (p Pricing) MessagesCost(usage MessagesUsage) (*Cost, error) {
+	return p.Cost(messagesUsage(usage))
+}
+
+// ResponseCost prices the usage of a Responses API response.
+func (p Pricing) ResponseCost(usage ResponseUsage) (*Cost, error) {
+	return p.Cost(responsesUsage(usage))
+}
+
+// CostCalculatorOptions configures a CostCalculator.
+type CostCalculatorOptions struct {
+	// ModelsTTL is how long prices fetched from ListModels are cached.
+	// Defaults to 10 minutes.
+	ModelsTTL time.Duration
+	// Catalog, when set, is used to look up prices instead of a catalog of
+	// the calculator's own; ModelsTTL is then ignored.
+	Catalog *ModelCatalog
+}
+
+// CostCalculator prices responses with the per-model pricing the gateway
+// reports with include=pricing. It is safe for concurrent use.
+type CostCalculator struct {
+	models *ModelCatalog
+}
+
+// NewCostCalculator creates a CostCalculator that looks up prices through
+// client.
+//
+// Example:
+//
+//	costs := sdk.NewCostCalculator(client, nil)
+//	response, err := client.GenerateContent(ctx, sdk.Openai, "gpt-4o", messages)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	cost, err := costs.ChatCost(ctx, sdk.Openai, response)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(cost.Total, cost.Currency)
+func NewCostCalculator(client Client, options *CostCalculatorOptions) *CostCalculator {
+	ttl := defaultModelsTTL
+	if options != nil {
+		if options.Catalog != nil {
+			return &CostCalculator{models: options.Catalog}
+		}
+		if options.ModelsTTL > 0 {
+			ttl = options.ModelsTTL
+		}
+	}
+	return &CostCalculator{models: newModelCatalog(client, ttl, ListModelsParamsIncludePricing)}
+}
+
+// Pricing returns the pricing of model, or nil when the gateway doesn't
+// report one.
+func (c *CostCalculator) Pricing(ctx context.Context, provider Provider, model string) (*Pricing, error) {
+	found, ok, err := c.models.lookup(ctx, provider, model)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return found.Pricing, nil
+}
+
+// Cost prices usage of model. It returns nil when the model has no pricing.
+func (c *CostCalculator) Cost(ctx context.Context, provider Provider, model string, usage GenerateUsage) (*Cost, error) {
+	pricing, err := c.Pricing(ctx, provider, model)
+	if err != nil || pricing == nil {
+		return nil, err
+	}
+	return pricing.Cost(usage)
+}
+
+// ChatCost prices a chat completion response.
+func (c *CostCalculator) ChatCost(ctx context.Context, provider Provider, response *CreateChatCompletionResponse) (*Cost, error) {
+	if response.Usage == nil {
+		return nil, fmt.Errorf("response has no usage")
+	}
+	return c.Cost(ctx, provider, response.Model, chatUsage(*response.Usage))
+}
+
+// MessagesCost prices a Messages API response.
+func (c *CostCalculator) MessagesCost(ctx context.Context, provider Provider, response *MessagesResponse) (*Cost, error) {
+	return c.Cost(ctx, provider, response.Model, messagesUsage(response.Usage))
+}
+
+// ResponseCost prices a Responses API response.
+func (c *CostCalculator) ResponseCost(ctx context.Context, provider Provider, response *Response) (*Cost, error) {
+	if response.Usage == nil {
+		return nil, fmt.Errorf("response has no usage")
+	}
+	return c.Cost(ctx, provider, response.Model, responsesUsage(*response.Usage))
+}
@@ -0,0 +1,204 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+func TestDecimal(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"0.0000025", "0.0000025"},
+		{"2.5e-6", "0.0000025"},
+		{"1.50", "1.5"},
+		{"15", "15"},
+		{"1.5E3", "1500"},
+		{"-0.25", "-0.25"},
+		{".5", "0.5"},
+		{"0", "0"},
+	}
+	for _, tt := range tests {
+		d, err := ParseDecimal(tt.input)
+		require.NoError(t, err, tt.input)
+		assert.Equal(t, tt.want, d.String(), tt.input)
+	}
+
+	for _, input := range []string{"", "abc", "1.2.3", "1e", "--1", "1.-5"} {
+		_, err := ParseDecimal(input)
+		assert.Error(t, err, input)
+	}
+
+	// Sums are exact where float64 drifts.
+	sum := Decimal{}
+	for range 10 {
+		sum = sum.Add(MustParseDecimal("0.1"))
+	}
+	assert.Equal(t, "1", sum.String())
+	assert.Zero(t, sum.Cmp(MustParseDecimal("1.000")))
+	assert.Equal(t, -1, MustParseDecimal("0.0000025").Cmp(MustParseDecimal("0.00001")))
+	assert.Equal(t, "0.0025", MustParseDecimal("0.0000025").Mul(1000).String())
+	assert.Equal(t, 0.0025, MustParseDecimal("0.0025").Float64())
+	assert.True(t, Decimal{}.IsZero())
+	assert.Equal(t, "0", Decimal{}.String())
+
+	data, err := json.Marshal(MustParseDecimal("0.0000025"))
+	require.NoError(t, err)
+	assert.Equal(t, `"0.0000025"`, string(data))
+	var d Decimal
+	require.NoError(t, json.Unmarshal([]byte(`0.125`), &d))
+	assert.Equal(t, "0.125", d.String())
+}
+
+func testPricing() Pricing {
+	return Pricing{
+		Currency:           "USD",
+		InputPerToken:      "0.0000025",
+		OutputPerToken:     "0.00001",
+		CacheReadPerToken:  new("0.00000125"),
+		CacheWritePerToken: new("0.000003125"),
+		Source:             "provider",
+	}
+}
+
+func TestPricing_ChatCost(t *testing.T) {
+	var usage CompletionUsage
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"prompt_tokens": 1000, "completion_tokens": 500, "total_tokens": 1500,
+		"prompt_tokens_details": {"cached_tokens": 400},
+		"completion_tokens_details": {"reasoning_tokens": 200}
+	}`), &usage))
+
+	cost, err := testPricing().ChatCost(usage)
+	require.NoError(t, err)
+	assert.Equal(t, "USD", cost.Currency)
+	assert.Equal(t, "0.0015", cost.Input.String())
+	assert.Equal(t, "0.0005", cost.CachedInput.String())
+	assert.Equal(t, "0", cost.CacheWrite.String())
+	assert.Equal(t, "0.005", cost.Output.String())
+	assert.Equal(t, "0.002", cost.Reasoning.String())
+	assert.Equal(t, "0.007", cost.Total.String())
+}
+
+func TestPricing_MessagesCost(t *testing.T) {
+	cost, err := testPricing().MessagesCost(MessagesUsage{
+		InputTokens:              100,
+		OutputTokens:             50,
+		CacheReadInputTokens:     new(int64(2000)),
+		CacheCreationInputTokens: new(int64(1000)),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "0.00025", cost.Input.String())
+	assert.Equal(t, "0.0025", cost.CachedInput.String())
+	assert.Equal(t, "0.003125", cost.CacheWrite.String())
+	assert.Equal(t, "0.0005", cost.Output.String())
+	assert.Equal(t, "0.006375", cost.Total.String())
+}
+
+func TestPricing_ResponseCost(t *testing.T) {
+	var usage ResponseUsage
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"input_tokens": 1000, "output_tokens": 100, "total_tokens": 1100,
+		"input_tokens_details": {"cached_tokens": 1000},
+		"output_tokens_details": {"reasoning_tokens": 40}
+	}`), &usage))
+
+	// Without a cache read price, cached tokens cost as much as input.
+	pricing := testPricing()
+	pricing.CacheReadPerToken = nil
+	cost, err := pricing.ResponseCost(usage)
+	require.NoError(t, err)
+	assert.True(t, cost.Input.IsZero())
+	assert.Equal(t, "0.0025", cost.CachedInput.String())
+	assert.Equal(t, "0.0004", cost.Reasoning.String())
+	assert.Equal(t, "0.0035", cost.Total.String())
+}
+
+func TestPricing_Subscription(t *testing.T) {
+	pricing := Pricing{Currency: "USD", InputPerToken: "", OutputPerToken: "", Subscription: new(true)}
+	cost, err := pricing.Cost(GenerateUsage{InputTokens: 1000, OutputTokens: 1000})
+	require.NoError(t, err)
+	assert.True(t, cost.Subscription)
+	assert.True(t, cost.Total.IsZero())
+}
+
+func TestPricing_InvalidPrice(t *testing.T) {
+	pricing := testPricing()
+	pricing.OutputPerToken = "free"
+	_, err := pricing.Cost(GenerateUsage{})
+	assert.EqualError(t, err, `invalid output price: invalid decimal "free"`)
+}
+
+// pricingServer lists gpt-4o with testPricing and answers chat completions
+// with a fixed usage.
+func pricingServer(t *testing.T) Client {
+	t.Helper()
+	pricing, err := json.Marshal(testPricing())
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/models":
+			assert.Equal(t, "pricing", r.URL.Query().Get("include"))
+			_, _ = fmt.Fprintf(w, `{"object": "list", "data": [{
+				"id": "openai/gpt-4o", "object": "model", "created": 1, "owned_by": "openai", "served_by": "openai",
+				"pricing": %s
+			}]}`, pricing)
+		case "/v1/chat/completions":
+			_, _ = fmt.Fprint(w, `{
+				"id": "chatcmpl-1", "object": "chat.completion", "created": 1, "model": "gpt-4o",
+				"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hi"}}],
+				"usage": {"prompt_tokens": 1000, "completion_tokens": 100, "total_tokens": 1100}
+			}`)
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+}
+
+func TestCostCalculator(t *testing.T) {
+	client := pricingServer(t)
+	costs := NewCostCalculator(client, nil)
+	ctx := context.Background()
+
+	response, err := client.GenerateContent(ctx, Openai, "gpt-4o", []Message{{Role: User, Content: NewMessageContent("Hi")}})
+	require.NoError(t, err)
+
+	cost, err := costs.ChatCost(ctx, Openai, response)
+	require.NoError(t, err)
+	assert.Equal(t, "0.0035", cost.Total.String())
+
+	cost, err = costs.Cost(ctx, Anthropic, "claude-sonnet-4", GenerateUsage{InputTokens: 10})
+	require.NoError(t, err)
+	assert.Nil(t, cost)
+
+	_, err = costs.ChatCost(ctx, Openai, &CreateChatCompletionResponse{Model: "gpt-4o"})
+	assert.EqualError(t, err, "response has no usage")
+}
+
+func TestGenerator_Cost(t *testing.T) {
+	client := pricingServer(t)
+	generator := NewGenerator(client, &GeneratorOptions{Costs: NewCostCalculator(client, nil)})
+
+	response, err := generator.Generate(context.Background(), GenerateRequest{
+		Provider: Openai,
+		Model:    "gpt-4o",
+		API:      APIChat,
+		Messages: []Message{{Role: User, Content: NewMessageContent("Hi")}},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, response.Cost)
+	assert.Equal(t, "0.0035", response.Cost.Total.String())
+	assert.Equal(t, "USD", response.Cost.Currency)
+}
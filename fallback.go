@@ -0,0 +1,476 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrStreamStart is wrapped by errors of streams that failed before their
+// first event. Those streams produced no output, so a FallbackClient may
+// still move them to another target.
+var ErrStreamStart = errors.New("stream failed before the first event")
+
+// FailoverPolicy decides whether err, returned by a fallback target, moves
+// the call on to the next target. Errors it rejects are returned as is.
+type FailoverPolicy func(err error) bool
+
+// DefaultFailoverPolicy fails over on rate limits (429), timeouts (408),
+// server errors (5xx), endpoints the provider doesn't support, network
+// errors, open circuits and streams that failed before their first event.
+// Cancelled contexts, budget and validation errors, and other client errors
+// don't fail over.
+func DefaultFailoverPolicy(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrBudgetExceeded) {
+		return false
+	}
+	var validationErr *RequestValidationError
+	if errors.As(err, &validationErr) {
+		return false
+	}
+	if errors.Is(err, ErrStreamStart) || errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests ||
+			apiErr.StatusCode == http.StatusRequestTimeout ||
+			apiErr.StatusCode >= http.StatusInternalServerError ||
+			IsNotSupported(err)
+	}
+	return isRetryableError(err)
+}
+
+// FailoverOnStatus fails over on API errors with one of codes.
+func FailoverOnStatus(codes ...int) FailoverPolicy {
+	return func(err error) bool {
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			return false
+		}
+		for _, code := range codes {
+			if apiErr.StatusCode == code {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// FailoverOnAny fails over when any of policies does.
+func FailoverOnAny(policies ...FailoverPolicy) FailoverPolicy {
+	return func(err error) bool {
+		for _, policy := range policies {
+			if policy(err) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// FallbackTarget is one provider and model a FallbackClient may send a call
+// to.
+type FallbackTarget struct {
+	// Provider serves the call. Empty keeps the caller's provider.
+	Provider Provider
+	// Model replaces the caller's model. Empty keeps the caller's model,
+	// renamed through FallbackOptions.Models.
+	Model string
+	// RetryConfig, when set, replaces the wrapped client's retry
+	// configuration for this target. It only applies to clients created by
+	// NewClient.
+	RetryConfig *RetryConfig
+	// Failover, when set, replaces FallbackOptions.Failover for errors from
+	// this target.
+	Failover FailoverPolicy
+}
+
+func (t FallbackTarget) String() string {
+	switch {
+	case t.Provider == "":
+		return t.Model
+	case t.Model == "":
+		return string(t.Provider)
+	}
+	return string(t.Provider) + "/" + t.Model
+}
+
+// FallbackOptions configures a FallbackClient.
+type FallbackOptions struct {
+	// Targets are tried in order. At least one is required.
+	Targets []FallbackTarget
+	// Models renames the caller's model per provider, for targets without a
+	// Model, e.g. {sdk.Anthropic: {"gpt-4o": "claude-sonnet-4"}}.
+	Models map[Provider]map[string]string
+	// Failover decides which errors move a call to the next target.
+	// Defaults to DefaultFailoverPolicy.
+	Failover FailoverPolicy
+	// OnFailover is called each time a target fails and the call moves on.
+	OnFailover func(failure FallbackFailure)
+}
+
+// FallbackFailure is a target that failed a call.
+type FallbackFailure struct {
+	// Target is the failed target, with its provider and model resolved.
+	Target FallbackTarget
+	Err    error
+}
+
+// FallbackReport tells which target served a call. Pass one to
+// WithFallbackReport to have it filled.
+type FallbackReport struct {
+	// Target is the target that served the call, with its provider and
+	// model resolved.
+	Target FallbackTarget
+	// Index is the position of Target in FallbackOptions.Targets.
+	Index int
+	// Failures lists the targets that failed before it.
+	Failures []FallbackFailure
+}
+
+type fallbackReportKey struct{}
+
+// WithFallbackReport returns a context that makes a FallbackClient record
+// in report which target served the call.
+//
+// Example:
+//
+//	var report sdk.FallbackReport
+//	response, err := client.GenerateContent(sdk.WithFallbackReport(ctx, &report), "", "gpt-4o", messages)
+//	if err == nil {
+//		fmt.Println("served by", report.Target)
+//	}
+func WithFallbackReport(ctx context.Context, report *FallbackReport) context.Context {
+	return context.WithValue(ctx, fallbackReportKey{}, report)
+}
+
+// FallbackError is returned when every target failed over. It unwraps to
+// each target's error.
+type FallbackError struct {
+	Failures []FallbackFailure
+}
+
+func (e *FallbackError) Error() string {
+	parts := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		parts[i] = fmt.Sprintf("%s: %v", failure.Target, failure.Err)
+	}
+	return "all fallback targets failed: " + strings.Join(parts, "; ")
+}
+
+func (e *FallbackError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, failure := range e.Failures {
+		errs[i] = failure.Err
+	}
+	return errs
+}
+
+// FallbackClient is a Client that sends each call to the first of its
+// targets and moves on to the next one when the call fails with an error
+// its failover policy accepts. Streams only fail over until their first
+// event; once output has started it is passed through as is. Targets
+// whose circuit is open (see CircuitBreaker) are always skipped.
+//
+// Listing models and tools and health checks go to the wrapped client
+// directly.
+type FallbackClient struct {
+	client  Client
+	options FallbackOptions
+}
+
+var _ Client = (*FallbackClient)(nil)
+
+// NewFallbackClient wraps client, which sends calls to every target.
+//
+// Example:
+//
+//	client := sdk.NewFallbackClient(sdk.NewClient(&sdk.ClientOptions{BaseURL: baseURL}), sdk.FallbackOptions{
+//		Targets: []sdk.FallbackTarget{
+//			{Provider: sdk.Openai, Model: "gpt-4o"},
+//			{Provider: sdk.Anthropic, Model: "claude-sonnet-4"},
+//			{Provider: sdk.Groq, Model: "llama-3.3-70b-versatile"},
+//		},
+//	})
+//	response, err := client.GenerateContent(ctx, "", "", messages)
+func NewFallbackClient(client Client, options FallbackOptions) *FallbackClient {
+	if options.Failover == nil {
+		options.Failover = DefaultFailoverPolicy
+	}
+	return &FallbackClient{client: client, options: options}
+}
+
+// resolve works out the provider and model target serves a call for.
+func (f *FallbackClient) resolve(target FallbackTarget, provider Provider, model string) FallbackTarget {
+	if target.Provider == "" {
+		target.Provider = provider
+	}
+	if target.Model == "" {
+		target.Model = model
+		if renamed, ok := f.options.Models[target.Provider][model]; ok {
+			target.Model = renamed
+		}
+	}
+	return target
+}
+
+// targetClient returns the wrapped client with target's retry
+// configuration.
+func (f *FallbackClient) targetClient(target FallbackTarget) Client {
+	impl, ok := f.client.(*clientImpl)
+	if !ok || target.RetryConfig == nil {
+		return f.client
+	}
+	clone := *impl
+	clone.retryConfig = target.RetryConfig
+	return &clone
+}
+
+// fallback runs call against each target in turn.
+func fallback[T any](ctx context.Context, f *FallbackClient, provider Provider, model string, call func(client Client, target FallbackTarget) (T, error)) (T, error) {
+	var zero T
+	if len(f.options.Targets) == 0 {
+		return zero, fmt.Errorf("fallback client has no targets")
+	}
+
+	var failures []FallbackFailure
+	for i, target := range f.options.Targets {
+		resolved := f.resolve(target, provider, model)
+		result, err := call(f.targetClient(target), resolved)
+		if err == nil {
+			if report, ok := ctx.Value(fallbackReportKey{}).(*FallbackReport); ok {
+				*report = FallbackReport{Target: resolved, Index: i, Failures: failures}
+			}
+			return result, nil
+		}
+
+		policy := f.options.Failover
+		if target.Failover != nil {
+			policy = target.Failover
+		}
+		// Open circuits are always skipped; the call was never sent.
+		if ctx.Err() != nil || !(errors.Is(err, ErrCircuitOpen) || policy(err)) {
+			return zero, err
+		}
+		failure := FallbackFailure{Target: resolved, Err: err}
+		failures = append(failures, failure)
+		if f.options.OnFailover != nil {
+			f.options.OnFailover(failure)
+		}
+	}
+	return zero, &FallbackError{Failures: failures}
+}
+
+// startStream waits for the first event of events, so streams that fail
+// before producing output can still fail over.
+func startStream(ctx context.Context, events <-chan SSEvent) (<-chan SSEvent, error) {
+	var first SSEvent
+	var ok bool
+	select {
+	case first, ok = <-events:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: stream closed", ErrStreamStart)
+	}
+	if err := streamEventError(first); err != nil {
+		// Let the reader finish; it stops on its own at the end of the body.
+		go func() {
+			for range events {
+			}
+		}()
+		return nil, err
+	}
+
+	out := make(chan SSEvent, 100)
+	go func() {
+		defer close(out)
+		for event, ok := first, true; ok; event, ok = <-events {
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// streamEventError reports an event that carries an error instead of
+// output: a read error, or an error payload from the gateway.
+func streamEventError(event SSEvent) error {
+	if event.Data == nil {
+		return nil
+	}
+	var payload struct {
+		Type  string          `json:"type"`
+		Error json.RawMessage `json:"error"`
+	}
+	_ = json.Unmarshal(*event.Data, &payload)
+	if event.Event == nil || payload.Type == "error" || (len(payload.Error) > 0 && string(payload.Error) != "null") {
+		return fmt.Errorf("%w: %s", ErrStreamStart, string(*event.Data))
+	}
+	return nil
+}
+
+// WithAuthToken sets the token of the wrapped client.
+func (f *FallbackClient) WithAuthToken(token string) Client {
+	f.client = f.client.WithAuthToken(token)
+	return f
+}
+
+// WithTools sets the tools of the wrapped client.
+func (f *FallbackClient) WithTools(tools *[]ChatCompletionTool) Client {
+	f.client = f.client.WithTools(tools)
+	return f
+}
+
+// WithOptions sets the chat options of the wrapped client. A Model set in
+// options overrides every target's model.
+func (f *FallbackClient) WithOptions(options *CreateChatCompletionRequest) Client {
+	f.client = f.client.WithOptions(options)
+	return f
+}
+
+// WithHeaders sets headers on the wrapped client.
+func (f *FallbackClient) WithHeaders(headers map[string]string) Client {
+	f.client = f.client.WithHeaders(headers)
+	return f
+}
+
+// WithHeader sets a header on the wrapped client.
+func (f *FallbackClient) WithHeader(name, value string) Client {
+	f.client = f.client.WithHeader(name, value)
+	return f
+}
+
+// WithMiddlewareOptions sets the middleware options of the wrapped client.
+func (f *FallbackClient) WithMiddlewareOptions(options *MiddlewareOptions) Client {
+	f.client = f.client.WithMiddlewareOptions(options)
+	return f
+}
+
+// ListModels lists models through the wrapped client.
+func (f *FallbackClient) ListModels(ctx context.Context, include ...ListModelsParamsInclude) (*ListModelsResponse, error) {
+	return f.client.ListModels(ctx, include...)
+}
+
+// ListProviderModels lists the models of provider through the wrapped
+// client.
+func (f *FallbackClient) ListProviderModels(ctx context.Context, provider Provider, include ...ListModelsParamsInclude) (*ListModelsResponse, error) {
+	return f.client.ListProviderModels(ctx, provider, include...)
+}
+
+// ListTools lists tools through the wrapped client.
+func (f *FallbackClient) ListTools(ctx context.Context) (*ListToolsResponse, error) {
+	return f.client.ListTools(ctx)
+}
+
+// HealthCheck checks the gateway through the wrapped client.
+func (f *FallbackClient) HealthCheck(ctx context.Context) error {
+	return f.client.HealthCheck(ctx)
+}
+
+// GenerateContent runs a chat completion against each target in turn.
+func (f *FallbackClient) GenerateContent(ctx context.Context, provider Provider, model string, messages []Message) (*CreateChatCompletionResponse, error) {
+	return fallback(ctx, f, provider, model, func(client Client, target FallbackTarget) (*CreateChatCompletionResponse, error) {
+		return client.GenerateContent(ctx, target.Provider, target.Model, messages)
+	})
+}
+
+// GenerateContentStream is GenerateContent in streaming mode.
+func (f *FallbackClient) GenerateContentStream(ctx context.Context, provider Provider, model string, messages []Message) (<-chan SSEvent, error) {
+	return fallback(ctx, f, provider, model, func(client Client, target FallbackTarget) (<-chan SSEvent, error) {
+		events, err := client.GenerateContentStream(ctx, target.Provider, target.Model, messages)
+		if err != nil {
+			return nil, err
+		}
+		return startStream(ctx, events)
+	})
+}
+
+// CreateMessage sends a Messages API request to each target in turn.
+func (f *FallbackClient) CreateMessage(ctx context.Context, provider Provider, request CreateMessagesRequest) (*MessagesResponse, error) {
+	return fallback(ctx, f, provider, request.Model, func(client Client, target FallbackTarget) (*MessagesResponse, error) {
+		request.Model = target.Model
+		return client.CreateMessage(ctx, target.Provider, request)
+	})
+}
+
+// CreateMessageStream is CreateMessage in streaming mode.
+func (f *FallbackClient) CreateMessageStream(ctx context.Context, provider Provider, request CreateMessagesRequest) (<-chan SSEvent, error) {
+	return fallback(ctx, f, provider, request.Model, func(client Client, target FallbackTarget) (<-chan SSEvent, error) {
+		request.Model = target.Model
+		events, err := client.CreateMessageStream(ctx, target.Provider, request)
+		if err != nil {
+			return nil, err
+		}
+		return startStream(ctx, events)
+	})
+}
+
+// CreateResponse sends a Responses API request to each target in turn.
+func (f *FallbackClient) CreateResponse(ctx context.Context, provider Provider, request CreateResponseRequest) (*Response, error) {
+	return fallback(ctx, f, provider, request.Model, func(client Client, target FallbackTarget) (*Response, error) {
+		request.Model = target.Model
+		return client.CreateResponse(ctx, target.Provider, request)
+	})
+}
+
+// CreateResponseStream is CreateResponse in streaming mode.
+func (f *FallbackClient) CreateResponseStream(ctx context.Context, provider Provider, request CreateResponseRequest) (<-chan SSEvent, error) {
+	return fallback(ctx, f, provider, request.Model, func(client Client, target FallbackTarget) (<-chan SSEvent, error) {
+		request.Model = target.Model
+		events, err := client.CreateResponseStream(ctx, target.Provider, request)
+		if err != nil {
+			return nil, err
+		}
+		return startStream(ctx, events)
+	})
+}
+
+// imageModel returns the model of an image request, which is optional.
+func imageModel(model *string) string {
+	if model == nil {
+		return ""
+	}
+	return *model
+}
+
+// setImageModel sets the model of an image request, leaving it unset when
+// the target doesn't name one.
+func setImageModel(model string) *string {
+	if model == "" {
+		return nil
+	}
+	return &model
+}
+
+// CreateImage generates images with each target in turn.
+func (f *FallbackClient) CreateImage(ctx context.Context, provider Provider, request CreateImageRequest) (*ImagesResponse, error) {
+	return fallback(ctx, f, provider, imageModel(request.Model), func(client Client, target FallbackTarget) (*ImagesResponse, error) {
+		request.Model = setImageModel(target.Model)
+		return client.CreateImage(ctx, target.Provider, request)
+	})
+}
+
+// CreateImageEdit edits an image with each target in turn.
+func (f *FallbackClient) CreateImageEdit(ctx context.Context, provider Provider, request CreateImageEditMultipartBody) (*ImagesResponse, error) {
+	return fallback(ctx, f, provider, imageModel(request.Model), func(client Client, target FallbackTarget) (*ImagesResponse, error) {
+		request.Model = setImageModel(target.Model)
+		return client.CreateImageEdit(ctx, target.Provider, request)
+	})
+}
+
+// CreateImageVariation creates image variations with each target in turn.
+func (f *FallbackClient) CreateImageVariation(ctx context.Context, provider Provider, request CreateImageVariationMultipartBody) (*ImagesResponse, error) {
+	return fallback(ctx, f, provider, imageModel(request.Model), func(client Client, target FallbackTarget) (*ImagesResponse, error) {
+		request.Model = setImageModel(target.Model)
+		return client.CreateImageVariation(ctx, target.Provider, request)
+	})
+}
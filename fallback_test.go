@@ -0,0 +1,174 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// fallbackServer answers each provider with the handler registered for it
+// and records which provider and model every call asked for.
+type fallbackServer struct {
+	mu       sync.Mutex
+	calls    []string
+	handlers map[string]func(w http.ResponseWriter)
+}
+
+func newFallbackServer(t *testing.T, handlers map[string]func(w http.ResponseWriter)) (*fallbackServer, Client) {
+	t.Helper()
+	fs := &fallbackServer{handlers: handlers}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		provider := r.URL.Query().Get("provider")
+		fs.mu.Lock()
+		fs.calls = append(fs.calls, provider+"/"+body.Model)
+		fs.mu.Unlock()
+
+		handler, ok := fs.handlers[provider]
+		if !ok {
+			t.Errorf("unexpected call to %s", provider)
+			return
+		}
+		handler(w)
+	}))
+	t.Cleanup(server.Close)
+	return fs, NewClient(&ClientOptions{BaseURL: server.URL + "/v1", RetryConfig: &RetryConfig{Enabled: false}})
+}
+
+func TestFallbackClient_GenerateContent(t *testing.T) {
+	server, client := newFallbackServer(t, map[string]func(w http.ResponseWriter){
+		"openai":    errorReply(http.StatusTooManyRequests, "rate limited"),
+		"anthropic": errorReply(http.StatusServiceUnavailable, "overloaded"),
+		"groq":      chatReply("Hi from groq"),
+	})
+	var failures []FallbackFailure
+	fallbackClient := NewFallbackClient(client, FallbackOptions{
+		Targets: []FallbackTarget{
+			// Retries apply per target.
+			{Provider: Openai, Model: "gpt-4o", RetryConfig: &RetryConfig{Enabled: true, MaxAttempts: 2, BackoffMultiplier: 1}},
+			{Provider: Anthropic},
+			{Provider: Groq, Model: "llama-3.3-70b-versatile"},
+		},
+		Models:     map[Provider]map[string]string{Anthropic: {"gpt-4o": "claude-sonnet-4"}},
+		OnFailover: func(failure FallbackFailure) { failures = append(failures, failure) },
+	})
+
+	var report FallbackReport
+	response, err := fallbackClient.GenerateContent(WithFallbackReport(context.Background(), &report), "", "gpt-4o", hello())
+	require.NoError(t, err)
+	text, err := messageText(response.Choices[0].Message.Content)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi from groq", text)
+
+	assert.Equal(t, []string{"openai/gpt-4o", "openai/gpt-4o", "anthropic/claude-sonnet-4", "groq/llama-3.3-70b-versatile"}, server.calls)
+	assert.Equal(t, 2, report.Index)
+	assert.Equal(t, "groq/llama-3.3-70b-versatile", report.Target.String())
+	require.Len(t, report.Failures, 2)
+	assert.Equal(t, "anthropic/claude-sonnet-4", report.Failures[1].Target.String())
+	assert.Equal(t, report.Failures, failures)
+}
+
+func TestFallbackClient_Errors(t *testing.T) {
+	_, client := newFallbackServer(t, map[string]func(w http.ResponseWriter){
+		"openai":    errorReply(http.StatusBadRequest, "invalid messages"),
+		"anthropic": errorReply(http.StatusInternalServerError, "boom"),
+		"groq":      errorReply(http.StatusBadGateway, "bad gateway"),
+	})
+	ctx := context.Background()
+
+	// Client errors are returned without failing over.
+	fallbackClient := NewFallbackClient(client, FallbackOptions{Targets: []FallbackTarget{{Provider: Openai}, {Provider: Groq}}})
+	_, err := fallbackClient.GenerateContent(ctx, "", "gpt-4o", hello())
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+
+	fallbackClient = NewFallbackClient(client, FallbackOptions{Targets: []FallbackTarget{{Provider: Anthropic}, {Provider: Groq}}})
+	_, err = fallbackClient.GenerateContent(ctx, "", "m", hello())
+	var fallbackErr *FallbackError
+	require.True(t, errors.As(err, &fallbackErr))
+	assert.Len(t, fallbackErr.Failures, 2)
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	assert.EqualError(t, err, "all fallback targets failed: anthropic/m: API error: boom (status code: 500); groq/m: API error: bad gateway (status code: 502)")
+
+	// Per-target policies replace the default one.
+	fallbackClient = NewFallbackClient(client, FallbackOptions{Targets: []FallbackTarget{
+		{Provider: Openai, Failover: FailoverOnAny(FailoverOnStatus(http.StatusBadRequest), DefaultFailoverPolicy)},
+		{Provider: Anthropic, Failover: FailoverOnStatus(http.StatusTooManyRequests)},
+		{Provider: Groq},
+	}})
+	_, err = fallbackClient.GenerateContent(ctx, "", "m", hello())
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	assert.False(t, errors.As(err, &fallbackErr))
+}
+
+func TestFallbackClient_NotSupported(t *testing.T) {
+	server, client := newFallbackServer(t, map[string]func(w http.ResponseWriter){
+		"groq": func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"type": "error", "error": {"type": "not_supported_error", "message": "Messages API is not supported by provider groq"}}`))
+		},
+		"anthropic": jsonReply(`{
+			"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-sonnet-4",
+			"content": [{"type": "text", "text": "Hi"}], "stop_reason": "end_turn",
+			"usage": {"input_tokens": 1, "output_tokens": 1}
+		}`),
+	})
+	fallbackClient := NewFallbackClient(client, FallbackOptions{
+		Targets: []FallbackTarget{{Provider: Groq, Model: "llama"}, {Provider: Anthropic, Model: "claude-sonnet-4"}},
+	})
+
+	var content MessagesMessage_Content
+	require.NoError(t, content.FromMessagesMessageContent0("Hi"))
+	response, err := fallbackClient.CreateMessage(context.Background(), "", CreateMessagesRequest{
+		MaxTokens: 16,
+		Messages:  []MessagesMessage{{Role: MessagesMessageRoleUser, Content: content}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "claude-sonnet-4", response.Model)
+	assert.Equal(t, []string{"groq/llama", "anthropic/claude-sonnet-4"}, server.calls)
+}
+
+func TestFallbackClient_Stream(t *testing.T) {
+	server, client := newFallbackServer(t, map[string]func(w http.ResponseWriter){
+		// Fails before the first delta, so the stream moves on.
+		"openai": sseReply(`{"error":"upstream overloaded"}`),
+		// Fails after the first delta, which is passed through.
+		"anthropic": sseReply(
+			`{"id":"c1","model":"m","choices":[{"index":0,"delta":{"content":"Hi"}}]}`,
+			`{"error":"connection lost"}`,
+		),
+	})
+	fallbackClient := NewFallbackClient(client, FallbackOptions{
+		Targets: []FallbackTarget{{Provider: Openai}, {Provider: Anthropic}, {Provider: Groq}},
+	})
+
+	var report FallbackReport
+	events, err := fallbackClient.GenerateContentStream(WithFallbackReport(context.Background(), &report), "", "m", hello())
+	require.NoError(t, err)
+	var data []string
+	for event := range events {
+		data = append(data, string(*event.Data))
+	}
+	assert.Equal(t, []string{
+		`{"id":"c1","model":"m","choices":[{"index":0,"delta":{"content":"Hi"}}]}`,
+		`{"error":"connection lost"}`,
+	}, data)
+	assert.Equal(t, []string{"openai/m", "anthropic/m"}, server.calls)
+	assert.Equal(t, Anthropic, report.Target.Provider)
+	require.Len(t, report.Failures, 1)
+	assert.ErrorIs(t, report.Failures[0].Err, ErrStreamStart)
+}
@@ -0,0 +1,276 @@
+package sdk
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/assert"
+)
+
+// gatewayRequest is a request the test gateway got.
+type gatewayRequest struct {
+	method   string
+	path     string
+	provider string
+	model    string
+	header   http.Header
+	body     map[string]any
+	// cancelled is set when the client gave up while the gateway was slow.
+	cancelled bool
+}
+
+// target is the provider and model of the request, as "provider/model".
+func (r *gatewayRequest) target() string {
+	return r.provider + "/" + r.model
+}
+
+func (r *gatewayRequest) stream() bool {
+	return r.body["stream"] == true
+}
+
+// gatewayHandler answers a request to the test gateway.
+type gatewayHandler func(w http.ResponseWriter, r *gatewayRequest)
+
+// testGateway is a fake gateway for client tests. By default it lists no
+// models and answers chat completions with "Hi", streamed when asked.
+// Tests change the answers per path, per provider or target, or for the
+// next calls, and every request is recorded.
+type testGateway struct {
+	t      *testing.T
+	url    string
+	header http.Header // set on every response
+
+	mu       sync.Mutex
+	routes   map[string]gatewayHandler
+	queue    []func(w http.ResponseWriter)
+	replies  map[string]func(w http.ResponseWriter)
+	latency  map[string]time.Duration
+	requests []*gatewayRequest
+}
+
+func newTestGateway(t *testing.T) *testGateway {
+	t.Helper()
+	g := &testGateway{
+		t:       t,
+		header:  http.Header{},
+		replies: map[string]func(w http.ResponseWriter){},
+		latency: map[string]time.Duration{},
+		routes: map[string]gatewayHandler{
+			"GET /v1/models":            listModelsRoute(),
+			"POST /v1/chat/completions": chatRoute("Hi", nil),
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(g.serve))
+	t.Cleanup(server.Close)
+	g.url = server.URL + "/v1"
+	return g
+}
+
+// client returns a client of the gateway with options. Retries are off
+// unless options configure them.
+func (g *testGateway) client(options *ClientOptions) Client {
+	var o ClientOptions
+	if options != nil {
+		o = *options
+	}
+	o.BaseURL = g.url
+	if o.RetryConfig == nil {
+		o.RetryConfig = &RetryConfig{Enabled: false}
+	}
+	return NewClient(&o)
+}
+
+// handle answers requests to pattern, a method and path such as
+// "POST /v1/messages", with handler.
+func (g *testGateway) handle(pattern string, handler gatewayHandler) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.routes[pattern] = handler
+}
+
+// enqueue answers the next calls with replies, one each, in order.
+func (g *testGateway) enqueue(replies ...func(w http.ResponseWriter)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.queue = append(g.queue, replies...)
+}
+
+// reply answers calls to key, a provider or a "provider/model" target,
+// with reply until it is reset with nil.
+func (g *testGateway) reply(key string, reply func(w http.ResponseWriter)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if reply == nil {
+		delete(g.replies, key)
+		return
+	}
+	g.replies[key] = reply
+}
+
+// fail answers calls to key with status, or as usual again for 200.
+func (g *testGateway) fail(key string, status int) {
+	if status == http.StatusOK {
+		g.reply(key, nil)
+		return
+	}
+	g.reply(key, errorReply(status, http.StatusText(status)))
+}
+
+// slow delays the answers to calls to key, a provider or a
+// "provider/model" target.
+func (g *testGateway) slow(key string, latency time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.latency[key] = latency
+}
+
+// calls returns a copy of the requests so far.
+func (g *testGateway) calls() []gatewayRequest {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	calls := make([]gatewayRequest, len(g.requests))
+	for i, r := range g.requests {
+		calls[i] = *r
+	}
+	return calls
+}
+
+// count returns the number of requests to key, a provider or a
+// "provider/model" target, or of all requests for "".
+func (g *testGateway) count(key string) int {
+	n := 0
+	for _, r := range g.calls() {
+		if key == "" || key == r.provider || key == r.target() {
+			n++
+		}
+	}
+	return n
+}
+
+// requestsTo returns the requests to path so far.
+func (g *testGateway) requestsTo(path string) []gatewayRequest {
+	var requests []gatewayRequest
+	for _, r := range g.calls() {
+		if r.path == path {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+// bodies returns the decoded JSON bodies of the requests so far.
+func (g *testGateway) bodies() []map[string]any {
+	var bodies []map[string]any
+	for _, r := range g.calls() {
+		bodies = append(bodies, r.body)
+	}
+	return bodies
+}
+
+func (g *testGateway) serve(w http.ResponseWriter, r *http.Request) {
+	request := &gatewayRequest{
+		method:   r.Method,
+		path:     r.URL.Path,
+		provider: r.URL.Query().Get("provider"),
+		header:   r.Header.Clone(),
+	}
+	if r.Header.Get("Content-Type") == "application/json" {
+		assert.NoError(g.t, json.NewDecoder(r.Body).Decode(&request.body))
+		request.model, _ = request.body["model"].(string)
+	}
+
+	g.mu.Lock()
+	g.requests = append(g.requests, request)
+	latency := max(g.latency[request.provider], g.latency[request.target()])
+	g.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			g.mu.Lock()
+			request.cancelled = true
+			g.mu.Unlock()
+			return
+		}
+	}
+
+	for name, values := range g.header {
+		w.Header()[name] = values
+	}
+	g.mu.Lock()
+	var reply func(w http.ResponseWriter)
+	if len(g.queue) > 0 {
+		reply, g.queue = g.queue[0], g.queue[1:]
+	} else if targeted, ok := g.replies[request.target()]; ok {
+		reply = targeted
+	} else if targeted, ok := g.replies[request.provider]; ok {
+		reply = targeted
+	}
+	route, ok := g.routes[r.Method+" "+r.URL.Path]
+	g.mu.Unlock()
+
+	switch {
+	case reply != nil:
+		reply(w)
+	case ok:
+		route(w, request)
+	default:
+		g.t.Errorf("unexpected request to %s %s", r.Method, r.URL.Path)
+		http.NotFound(w, r)
+	}
+}
+
+// listModelsRoute lists models, given as JSON objects.
+func listModelsRoute(models ...string) gatewayHandler {
+	return func(w http.ResponseWriter, r *gatewayRequest) {
+		data := make([]json.RawMessage, len(models))
+		for i, model := range models {
+			data[i] = json.RawMessage(model)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"object": "list", "data": data})
+	}
+}
+
+// chatRoute answers chat completions with content, in one chunk when
+// streamed, reporting usage when it is set.
+func chatRoute(content string, usage *CompletionUsage) gatewayHandler {
+	return func(w http.ResponseWriter, r *gatewayRequest) {
+		if !r.stream() {
+			response := map[string]any{
+				"id": "chatcmpl-1", "object": "chat.completion", "created": 1, "model": r.model,
+				"choices": []any{map[string]any{
+					"index": 0, "finish_reason": "stop",
+					"message": map[string]any{"role": "assistant", "content": content},
+				}},
+			}
+			if usage != nil {
+				response["usage"] = usage
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		chunk := func(choices []any, usage *CompletionUsage) string {
+			data, _ := json.Marshal(map[string]any{
+				"id": "chatcmpl-1", "object": "chat.completion.chunk", "created": 1, "model": r.model,
+				"choices": choices, "usage": usage,
+			})
+			return string(data)
+		}
+		events := []string{chunk([]any{map[string]any{
+			"index": 0, "finish_reason": "stop",
+			"delta": map[string]any{"role": "assistant", "content": content},
+		}}, nil)}
+		if usage != nil {
+			events = append(events, chunk([]any{}, usage))
+		}
+		sseReply(append(events, "[DONE]")...)(w)
+	}
+}
@@ -0,0 +1,1023 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API identifies one of the gateway's generation endpoints.
+type API string
+
+const (
+	// APIChat is /chat/completions, which every provider supports.
+	APIChat API = "chat"
+	// APIMessages is the Anthropic-compatible /messages endpoint.
+	APIMessages API = "messages"
+	// APIResponses is the OpenAI-compatible /responses endpoint.
+	APIResponses API = "responses"
+)
+
+// defaultGenerateAPIs is the capability table a Generator starts from: the
+// native endpoint of each provider that has one. Providers that aren't
+// listed use /chat/completions.
+var defaultGenerateAPIs = map[Provider][]API{
+	Anthropic: {APIMessages},
+	Openai:    {APIResponses},
+}
+
+// defaultUnsupportedTTL is how long a Generator skips an endpoint a
+// provider answered is not supported.
+const defaultUnsupportedTTL = 10 * time.Minute
+
+// defaultGenerateMaxTokens is sent to the Messages API, which requires
+// max_tokens, when a request leaves MaxTokens unset.
+const defaultGenerateMaxTokens = 4096
+
+// reasoningBudgets maps reasoning efforts to Messages API thinking budgets.
+var reasoningBudgets = map[string]int{
+	"minimal": 1024,
+	"low":     2048,
+	"medium":  8192,
+	"high":    16384,
+}
+
+// ToolChoiceMode controls whether the model may call tools.
+type ToolChoiceMode string
+
+const (
+	ToolChoiceModeAuto     ToolChoiceMode = "auto"
+	ToolChoiceModeNone     ToolChoiceMode = "none"
+	ToolChoiceModeRequired ToolChoiceMode = "required"
+)
+
+// GenerateReasoning enables reasoning for models that support it.
+type GenerateReasoning struct {
+	// Effort is minimal, low, medium or high.
+	Effort string
+	// BudgetTokens caps thinking tokens on the Messages API. Zero derives a
+	// budget from Effort.
+	BudgetTokens int
+}
+
+// GenerateRequest is a provider-neutral generation request. Generator
+// translates it for whichever endpoint serves the call.
+type GenerateRequest struct {
+	Provider Provider
+	Model    string
+	// API forces an endpoint. Empty lets the Generator choose.
+	API API
+
+	// System is the system prompt, sent as instructions to the Responses API.
+	System string
+	// Messages is the conversation so far, in chat completion form.
+	Messages []Message
+
+	Tools      []FunctionObject
+	ToolChoice ToolChoiceMode
+	// ToolName forces a call to the named tool, overriding ToolChoice.
+	ToolName string
+
+	MaxTokens   int
+	Temperature *float32
+	TopP        *float32
+	Stop        []string
+	Reasoning   *GenerateReasoning
+}
+
+// GenerateUsage is token usage in a form common to all endpoints.
+type GenerateUsage struct {
+	// InputTokens counts every prompt token, cached or not.
+	InputTokens       int64 `json:"input_tokens"`
+	OutputTokens      int64 `json:"output_tokens"`
+	TotalTokens       int64 `json:"total_tokens"`
+	CachedInputTokens int64 `json:"cached_input_tokens,omitempty"`
+	CacheWriteTokens  int64 `json:"cache_write_tokens,omitempty"`
+	ReasoningTokens   int64 `json:"reasoning_tokens,omitempty"`
+}
+
+// GenerateResponse is the result of Generate, whichever endpoint served it.
+type GenerateResponse struct {
+	// API is the endpoint that served the call.
+	API   API
+	ID    string
+	Model string
+
+	Text      string
+	Reasoning string
+	Refusal   string
+	ToolCalls []ChatCompletionMessageToolCall
+
+	// FinishReason uses the chat completion vocabulary: stop, length,
+	// tool_calls or content_filter.
+	FinishReason FinishReason
+	Usage        GenerateUsage
+
+	// Cost is set when GeneratorOptions.Costs is and the model has pricing.
+	Cost *Cost
+
+	// Dropped lists what the request lost in translation for API.
+	Dropped []DroppedField
+	// Raw is the endpoint's own response: a *CreateChatCompletionResponse,
+	// *MessagesResponse or *Response.
+	Raw any
+}
+
+// Message returns the assistant message to append to the conversation.
+func (r *GenerateResponse) Message() Message {
+	message := Message{Role: Assistant, Content: NewMessageContent(r.Text)}
+	if len(r.ToolCalls) > 0 {
+		calls := slices.Clone(r.ToolCalls)
+		message.ToolCalls = &calls
+	}
+	if r.Reasoning != "" {
+		reasoning := r.Reasoning
+		message.ReasoningContent = &reasoning
+	}
+	return message
+}
+
+// GenerateEvent is one event of a GenerateStream. Deltas carry Text or
+// Reasoning; the last event carries either the complete Response or Err.
+type GenerateEvent struct {
+	Text      string
+	Reasoning string
+	Response  *GenerateResponse
+	Err       error
+}
+
+// GeneratorOptions configures a Generator.
+type GeneratorOptions struct {
+	// APIs lists, per provider, the endpoints to try in order of preference.
+	// /chat/completions is always tried last. Nil uses a built-in table that
+	// prefers /messages for Anthropic and /responses for OpenAI.
+	APIs map[Provider][]API
+	// Costs, when set, prices every response into GenerateResponse.Cost.
+	Costs *CostCalculator
+	// UnsupportedTTL is how long an endpoint a provider doesn't support is
+	// skipped before it is tried again. Defaults to 10 minutes.
+	UnsupportedTTL time.Duration
+}
+
+// Generator sends provider-neutral requests to the best endpoint each
+// provider supports. When the gateway answers that a provider doesn't
+// implement an endpoint, the Generator falls back to the next one, ending
+// with /chat/completions, and skips the endpoint for
+// GeneratorOptions.UnsupportedTTL. It is safe for concurrent use.
+type Generator struct {
+	client         Client
+	apis           map[Provider][]API
+	costs          *CostCalculator
+	unsupportedTTL time.Duration
+
+	mu sync.Mutex
+	// unsupported holds when each endpoint was found unsupported.
+	unsupported map[generatorKey]time.Time
+}
+
+type generatorKey struct {
+	provider Provider
+	api      API
+}
+
+// NewGenerator creates a Generator that sends requests through client.
+func NewGenerator(client Client, options *GeneratorOptions) *Generator {
+	apis := defaultGenerateAPIs
+	var costs *CostCalculator
+	ttl := defaultUnsupportedTTL
+	if options != nil {
+		if options.APIs != nil {
+			apis = options.APIs
+		}
+		costs = options.Costs
+		if options.UnsupportedTTL > 0 {
+			ttl = options.UnsupportedTTL
+		}
+	}
+	return &Generator{
+		client:         client,
+		apis:           apis,
+		costs:          costs,
+		unsupportedTTL: ttl,
+		unsupported:    make(map[generatorKey]time.Time),
+	}
+}
+
+// Reset forgets the endpoints found unsupported, so that the next calls try
+// them again.
+func (g *Generator) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	clear(g.unsupported)
+}
+
+// Generate runs request on the first endpoint that accepts it.
+//
+// Example:
+//
+//	generator := sdk.NewGenerator(client, nil)
+//	response, err := generator.Generate(ctx, sdk.GenerateRequest{
+//		Provider: sdk.Anthropic,
+//		Model:    "claude-sonnet-5",
+//		System:   "You are a helpful assistant.",
+//		Messages: []sdk.Message{{Role: sdk.User, Content: sdk.NewMessageContent("What is Go?")}},
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(response.Text)
+func (g *Generator) Generate(ctx context.Context, request GenerateRequest) (*GenerateResponse, error) {
+	candidates, err := g.candidates(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for i, api := range candidates {
+		call := prepareGenerate(api, request)
+		if !call.report.Lossless() && i < len(candidates)-1 {
+			continue
+		}
+
+		response, err := call.generate(ctx, g.client, request.Provider)
+		if err != nil {
+			if g.fallBack(request, api, err) {
+				lastErr = err
+				continue
+			}
+			return nil, err
+		}
+		g.finish(ctx, request, call, response)
+		return response, nil
+	}
+	return nil, lastErr
+}
+
+// GenerateStream runs request in streaming mode on the first endpoint that
+// accepts it. Falling back only happens before the stream starts.
+func (g *Generator) GenerateStream(ctx context.Context, request GenerateRequest) (<-chan GenerateEvent, error) {
+	candidates, err := g.candidates(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for i, api := range candidates {
+		call := prepareGenerate(api, request)
+		if !call.report.Lossless() && i < len(candidates)-1 {
+			continue
+		}
+
+		events, err := call.stream(ctx, g.client, request.Provider)
+		if err != nil {
+			if g.fallBack(request, api, err) {
+				lastErr = err
+				continue
+			}
+			return nil, err
+		}
+
+		out := make(chan GenerateEvent, 100)
+		finish := func(response *GenerateResponse) { g.finish(ctx, request, call, response) }
+		go pumpGenerateStream(ctx, events, out, newStreamAccumulator(api), finish)
+		return out, nil
+	}
+	return nil, lastErr
+}
+
+// candidates lists the endpoints to try for request, in order.
+func (g *Generator) candidates(request GenerateRequest) ([]API, error) {
+	switch request.API {
+	case APIChat, APIMessages, APIResponses:
+		return []API{request.API}, nil
+	case "":
+	default:
+		return nil, fmt.Errorf("unknown API %q", request.API)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var apis []API
+	for _, api := range g.apis[request.Provider] {
+		if api == APIChat {
+			continue
+		}
+		key := generatorKey{request.Provider, api}
+		if since, ok := g.unsupported[key]; ok {
+			if time.Since(since) < g.unsupportedTTL {
+				continue
+			}
+			delete(g.unsupported, key)
+		}
+		apis = append(apis, api)
+	}
+	return append(apis, APIChat), nil
+}
+
+// fallBack reports whether err means the provider doesn't implement api and
+// the next endpoint should be tried, remembering it for later calls.
+func (g *Generator) fallBack(request GenerateRequest, api API, err error) bool {
+	if request.API != "" || api == APIChat || !IsNotSupported(err) {
+		return false
+	}
+
+	g.mu.Lock()
+	g.unsupported[generatorKey{request.Provider, api}] = time.Now()
+	g.mu.Unlock()
+	return true
+}
+
+// finish adds what the endpoint's response can't know to a response.
+// Pricing failures leave Cost unset rather than failing the call.
+func (g *Generator) finish(ctx context.Context, request GenerateRequest, call generateCall, response *GenerateResponse) {
+	response.Dropped = call.report.Dropped
+	if g.costs != nil {
+		response.Cost, _ = g.costs.Cost(ctx, request.Provider, call.model, response.Usage)
+	}
+}
+
+// generateCall is a GenerateRequest translated for one endpoint.
+type generateCall struct {
+	api   API
+	model string
+
+	chat        []Message
+	chatOptions CreateChatCompletionRequest
+	messages    CreateMessagesRequest
+	responses   CreateResponseRequest
+
+	report *ConversionReport
+}
+
+func prepareGenerate(api API, request GenerateRequest) generateCall {
+	transcript := request.Messages
+	if request.System != "" {
+		transcript = append([]Message{{Role: System, Content: NewMessageContent(request.System)}}, transcript...)
+	}
+
+	call := generateCall{api: api, model: request.Model}
+	switch api {
+	case APIMessages:
+		system, messages, report := ChatToMessages(transcript)
+		call.report = report
+		call.messages = messagesGenerateRequest(request, system, messages, report)
+	case APIResponses:
+		instructions, items, report := ChatToResponses(transcript)
+		call.report = report
+		call.responses = responsesGenerateRequest(request, instructions, items, report)
+	default:
+		call.report = &ConversionReport{}
+		call.chat = transcript
+		if len(request.Stop) > maxStopSequences {
+			call.report.drop("stop", "the chat completions API allows at most %d stop sequences, so the rest were not sent", maxStopSequences)
+			request.Stop = request.Stop[:maxStopSequences]
+		}
+		applyChatGenerateOptions(&call.chatOptions, request)
+	}
+	return call
+}
+
+func applyChatGenerateOptions(options *CreateChatCompletionRequest, request GenerateRequest) {
+	if len(request.Tools) > 0 {
+		tools := make([]ChatCompletionTool, len(request.Tools))
+		for i, tool := range request.Tools {
+			tools[i] = ChatCompletionTool{Type: Function, Function: tool}
+		}
+		options.Tools = &tools
+	}
+	switch {
+	case request.ToolName != "":
+		options.ToolChoice = ToolChoiceFunction(request.ToolName)
+	case request.ToolChoice == ToolChoiceModeAuto:
+		options.ToolChoice = ToolChoiceAuto()
+	case request.ToolChoice == ToolChoiceModeNone:
+		options.ToolChoice = ToolChoiceNone()
+	case request.ToolChoice == ToolChoiceModeRequired:
+		options.ToolChoice = ToolChoiceRequired()
+	}
+	if request.MaxTokens > 0 {
+		options.MaxTokens = &request.MaxTokens
+	}
+	if request.Temperature != nil {
+		options.Temperature = request.Temperature
+	}
+	if request.TopP != nil {
+		options.TopP = request.TopP
+	}
+	if len(request.Stop) > 0 {
+		options.Stop = StopSequences(request.Stop...)
+	}
+	if request.Reasoning != nil && request.Reasoning.Effort != "" {
+		effort := CreateChatCompletionRequestReasoningEffort(request.Reasoning.Effort)
+		options.ReasoningEffort = &effort
+	}
+}
+
+func messagesGenerateRequest(request GenerateRequest, system *CreateMessagesRequest_System, messages []MessagesMessage, report *ConversionReport) CreateMessagesRequest {
+	out := CreateMessagesRequest{
+		Model:       request.Model,
+		MaxTokens:   request.MaxTokens,
+		System:      system,
+		Messages:    messages,
+		Temperature: request.Temperature,
+		TopP:        request.TopP,
+	}
+	if out.MaxTokens == 0 {
+		out.MaxTokens = defaultGenerateMaxTokens
+	}
+	if len(request.Stop) > 0 {
+		out.StopSequences = &request.Stop
+	}
+
+	if len(request.Tools) > 0 && request.ToolChoice == ToolChoiceModeNone && request.ToolName == "" {
+		report.drop("tool_choice", "the Messages API cannot disable tools, so they were not sent")
+	} else if len(request.Tools) > 0 {
+		tools := make([]MessagesTool, len(request.Tools))
+		for i, tool := range request.Tools {
+			tools[i] = MessagesTool{Name: tool.Name, Description: tool.Description, InputSchema: FunctionParameters{"type": "object"}}
+			if tool.Parameters != nil {
+				tools[i].InputSchema = *tool.Parameters
+			}
+		}
+		out.Tools = &tools
+
+		switch {
+		case request.ToolName != "":
+			out.ToolChoice = MessagesToolChoiceTool(request.ToolName)
+		case request.ToolChoice == ToolChoiceModeAuto:
+			out.ToolChoice = MessagesToolChoiceAuto()
+		case request.ToolChoice == ToolChoiceModeRequired:
+			out.ToolChoice = MessagesToolChoiceAny()
+		}
+	}
+
+	if request.Reasoning != nil {
+		budget := request.Reasoning.BudgetTokens
+		if budget == 0 {
+			budget = reasoningBudgets[request.Reasoning.Effort]
+		}
+		if budget > 0 {
+			out.Thinking = &struct {
+				BudgetTokens int                               `json:"budget_tokens"`
+				Type         CreateMessagesRequestThinkingType `json:"type"`
+			}{BudgetTokens: budget, Type: Enabled}
+		}
+	}
+	return out
+}
+
+func responsesGenerateRequest(request GenerateRequest, instructions *string, items []ResponseInputItem, report *ConversionReport) CreateResponseRequest {
+	out := CreateResponseRequest{
+		Model:        request.Model,
+		Instructions: instructions,
+		Temperature:  request.Temperature,
+		TopP:         request.TopP,
+	}
+	mustBuildUnion(out.Input.FromResponseInput1(items))
+	if request.MaxTokens > 0 {
+		out.MaxOutputTokens = &request.MaxTokens
+	}
+
+	if len(request.Tools) > 0 {
+		tools := make([]ResponseTool, len(request.Tools))
+		for i, tool := range request.Tools {
+			tools[i] = ResponseTool{
+				Type:        ResponseToolTypeFunction,
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+				Strict:      tool.Strict,
+			}
+		}
+		out.Tools = &tools
+	}
+	switch {
+	case request.ToolName != "":
+		out.ToolChoice = ResponseToolChoiceFunction(request.ToolName)
+	case request.ToolChoice == ToolChoiceModeAuto:
+		out.ToolChoice = ResponseToolChoiceAuto()
+	case request.ToolChoice == ToolChoiceModeNone:
+		out.ToolChoice = ResponseToolChoiceNone()
+	case request.ToolChoice == ToolChoiceModeRequired:
+		out.ToolChoice = ResponseToolChoiceRequired()
+	}
+
+	if request.Reasoning != nil && request.Reasoning.Effort != "" {
+		effort := ResponseReasoningEffort(request.Reasoning.Effort)
+		summary := ResponseReasoningSummaryAuto
+		out.Reasoning = &ResponseReasoning{Effort: &effort, Summary: &summary}
+	}
+	if len(request.Stop) > 0 {
+		report.drop("stop", "the Responses API has no stop sequences")
+	}
+	return out
+}
+
+func (call generateCall) generate(ctx context.Context, client Client, provider Provider) (*GenerateResponse, error) {
+	switch call.api {
+	case APIMessages:
+		response, err := client.CreateMessage(ctx, provider, call.messages)
+		if err != nil {
+			return nil, err
+		}
+		return messagesResult(response), nil
+	case APIResponses:
+		response, err := client.CreateResponse(ctx, provider, call.responses)
+		if err != nil {
+			return nil, err
+		}
+		return responsesResult(response)
+	}
+	response, err := client.GenerateContent(WithChatOptions(ctx, &call.chatOptions), provider, call.model, call.chat)
+	if err != nil {
+		return nil, err
+	}
+	return chatResult(response), nil
+}
+
+func (call generateCall) stream(ctx context.Context, client Client, provider Provider) (<-chan SSEvent, error) {
+	switch call.api {
+	case APIMessages:
+		return client.CreateMessageStream(ctx, provider, call.messages)
+	case APIResponses:
+		return client.CreateResponseStream(ctx, provider, call.responses)
+	}
+	options := call.chatOptions
+	options.StreamOptions = &ChatCompletionStreamOptions{IncludeUsage: true}
+	return client.GenerateContentStream(WithChatOptions(ctx, &options), provider, call.model, call.chat)
+}
+
+// ResultOf maps a Client response to a GenerateResponse, the way the
+// Generator reports it: *CreateChatCompletionResponse, *MessagesResponse and
+// *Response give their message, finish reason and usage, *ImagesResponse
+// its usage. It returns nil for other responses, failed Responses API
+// responses and images responses without usage.
+func ResultOf(response any) *GenerateResponse {
+	switch response := response.(type) {
+	case *GenerateResponse:
+		return response
+	case *CreateChatCompletionResponse:
+		if response != nil {
+			return chatResult(response)
+		}
+	case *MessagesResponse:
+		if response != nil {
+			return messagesResult(response)
+		}
+	case *Response:
+		if response != nil {
+			result, _ := responsesResult(response)
+			return result
+		}
+	case *ImagesResponse:
+		if response != nil && response.Usage != nil {
+			return &GenerateResponse{Usage: imagesUsage(response)}
+		}
+	}
+	return nil
+}
+
+func chatResult(response *CreateChatCompletionResponse) *GenerateResponse {
+	out := &GenerateResponse{API: APIChat, ID: response.ID, Model: response.Model, Raw: response}
+	if response.Usage != nil {
+		out.Usage = chatUsage(*response.Usage)
+	}
+	if len(response.Choices) > 0 {
+		choice := response.Choices[0]
+		setGenerateMessage(out, choice.Message)
+		out.FinishReason = choice.FinishReason
+	}
+	return out
+}
+
+func messagesResult(response *MessagesResponse) *GenerateResponse {
+	out := &GenerateResponse{API: APIMessages, ID: response.ID, Model: response.Model, Raw: response}
+	out.Usage = messagesUsage(response.Usage)
+
+	if messages, _ := MessagesToChat(nil, []MessagesMessage{MessagesResponseToMessage(*response)}); len(messages) > 0 {
+		setGenerateMessage(out, messages[0])
+	}
+
+	switch response.StopReason {
+	case MessagesResponseStopReasonMaxTokens:
+		out.FinishReason = Length
+	case MessagesResponseStopReasonToolUse:
+		out.FinishReason = ToolCalls
+	case MessagesResponseStopReasonRefusal:
+		out.FinishReason = ContentFilter
+	default:
+		out.FinishReason = Stop
+	}
+	return out
+}
+
+func responsesResult(response *Response) (*GenerateResponse, error) {
+	if response.Error != nil {
+		return nil, fmt.Errorf("response failed: %s (code: %s)", response.Error.Message, response.Error.Code)
+	}
+
+	out := &GenerateResponse{API: APIResponses, ID: response.ID, Model: response.Model, Raw: response}
+	if response.Usage != nil {
+		out.Usage = responsesUsage(*response.Usage)
+	}
+
+	if messages, _ := ResponseOutputToChat(response.Output); len(messages) > 0 {
+		setGenerateMessage(out, messages[0])
+	}
+	for _, item := range response.Output {
+		if message, err := item.AsResponseOutputMessage(); err == nil && message.Type == ResponseOutputMessageTypeMessage {
+			for _, content := range message.Content {
+				if refusal, err := content.AsResponseOutputRefusal(); err == nil && refusal.Type == Refusal {
+					out.Refusal += refusal.Refusal
+				}
+			}
+		}
+	}
+
+	switch {
+	case response.Status == ResponseStatusIncomplete:
+		out.FinishReason = Length
+		if details := response.IncompleteDetails; details != nil && details.Reason != nil && *details.Reason == string(ContentFilter) {
+			out.FinishReason = ContentFilter
+		}
+	case len(out.ToolCalls) > 0:
+		out.FinishReason = ToolCalls
+	case out.Refusal != "":
+		out.FinishReason = ContentFilter
+	default:
+		out.FinishReason = Stop
+	}
+	return out, nil
+}
+
+// chatUsage, messagesUsage and responsesUsage map each endpoint's usage to
+// GenerateUsage.
+func chatUsage(usage CompletionUsage) GenerateUsage {
+	out := GenerateUsage{
+		InputTokens:  usage.PromptTokens,
+		OutputTokens: usage.CompletionTokens,
+		TotalTokens:  usage.TotalTokens,
+	}
+	if usage.PromptTokensDetails != nil && usage.PromptTokensDetails.CachedTokens != nil {
+		out.CachedInputTokens = *usage.PromptTokensDetails.CachedTokens
+	}
+	if usage.CompletionTokensDetails != nil && usage.CompletionTokensDetails.ReasoningTokens != nil {
+		out.ReasoningTokens = *usage.CompletionTokensDetails.ReasoningTokens
+	}
+	return out
+}
+
+func messagesUsage(usage MessagesUsage) GenerateUsage {
+	out := GenerateUsage{InputTokens: usage.InputTokens, OutputTokens: usage.OutputTokens}
+	// Anthropic reports cache reads and writes apart from input_tokens.
+	if usage.CacheReadInputTokens != nil {
+		out.CachedInputTokens = *usage.CacheReadInputTokens
+		out.InputTokens += *usage.CacheReadInputTokens
+	}
+	if usage.CacheCreationInputTokens != nil {
+		out.CacheWriteTokens = *usage.CacheCreationInputTokens
+		out.InputTokens += *usage.CacheCreationInputTokens
+	}
+	out.TotalTokens = out.InputTokens + out.OutputTokens
+	return out
+}
+
+func responsesUsage(usage ResponseUsage) GenerateUsage {
+	out := GenerateUsage{
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		TotalTokens:  usage.TotalTokens,
+	}
+	if usage.InputTokensDetails != nil && usage.InputTokensDetails.CachedTokens != nil {
+		out.CachedInputTokens = *usage.InputTokensDetails.CachedTokens
+	}
+	if usage.OutputTokensDetails != nil && usage.OutputTokensDetails.ReasoningTokens != nil {
+		out.ReasoningTokens = *usage.OutputTokensDetails.ReasoningTokens
+	}
+	return out
+}
+
+func setGenerateMessage(out *GenerateResponse, message Message) {
+	out.Text, _ = messageText(message.Content)
+	out.Reasoning = firstNonEmpty(message.ReasoningContent, message.Reasoning)
+	if message.ToolCalls != nil {
+		out.ToolCalls = *message.ToolCalls
+	}
+}
+
+// streamAccumulator folds an endpoint's stream payloads into deltas and,
+// once the stream ends, the complete response.
+type streamAccumulator interface {
+	add(data []byte) (GenerateEvent, error)
+	result() (*GenerateResponse, error)
+}
+
+func newStreamAccumulator(api API) streamAccumulator {
+	switch api {
+	case APIMessages:
+		return &messagesStreamAccumulator{}
+	case APIResponses:
+		return &responsesStreamAccumulator{}
+	}
+	return &chatStreamAccumulator{}
+}
+
+func pumpGenerateStream(ctx context.Context, events <-chan SSEvent, out chan<- GenerateEvent, acc streamAccumulator, finish func(*GenerateResponse)) {
+	defer close(out)
+
+	send := func(event GenerateEvent) bool {
+		select {
+		case out <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for event := range events {
+		if event.Data == nil {
+			continue
+		}
+		// readSSEStream reports read errors as an event without a type.
+		if event.Event == nil {
+			var streamErr struct {
+				Error string `json:"error"`
+			}
+			_ = json.Unmarshal(*event.Data, &streamErr)
+			send(GenerateEvent{Err: fmt.Errorf("stream error: %s", streamErr.Error)})
+			return
+		}
+
+		delta, err := acc.add(*event.Data)
+		if err != nil {
+			send(GenerateEvent{Err: err})
+			return
+		}
+		if delta.Text != "" || delta.Reasoning != "" {
+			if !send(delta) {
+				return
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		send(GenerateEvent{Err: ctx.Err()})
+		return
+	}
+
+	response, err := acc.result()
+	if err != nil {
+		send(GenerateEvent{Err: err})
+		return
+	}
+	finish(response)
+	send(GenerateEvent{Response: response})
+}
+
+type chatStreamAccumulator struct {
+	response  CreateChatCompletionResponse
+	finish    FinishReason
+	text      strings.Builder
+	reasoning strings.Builder
+	calls     []ChatCompletionMessageToolCall
+}
+
+func (a *chatStreamAccumulator) add(data []byte) (GenerateEvent, error) {
+	var chunk CreateChatCompletionStreamResponse
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return GenerateEvent{}, fmt.Errorf("failed to parse stream chunk: %w", err)
+	}
+
+	if chunk.ID != "" {
+		a.response.ID = chunk.ID
+	}
+	if chunk.Model != "" {
+		a.response.Model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		a.response.Usage = chunk.Usage
+	}
+	if len(chunk.Choices) == 0 {
+		return GenerateEvent{}, nil
+	}
+
+	choice := chunk.Choices[0]
+	if choice.FinishReason != "" {
+		a.finish = choice.FinishReason
+	}
+
+	delta := choice.Delta
+	event := GenerateEvent{
+		Text:      delta.Content,
+		Reasoning: firstNonEmpty(delta.ReasoningContent, delta.Reasoning),
+	}
+	a.text.WriteString(event.Text)
+	a.reasoning.WriteString(event.Reasoning)
+
+	if delta.ToolCalls != nil {
+		for _, chunk := range *delta.ToolCalls {
+			for len(a.calls) <= chunk.Index {
+				a.calls = append(a.calls, ChatCompletionMessageToolCall{Type: Function})
+			}
+			call := &a.calls[chunk.Index]
+			if chunk.ID != nil {
+				call.ID = *chunk.ID
+			}
+			if chunk.Function != nil {
+				if call.Function.Name == "" {
+					call.Function.Name = chunk.Function.Name
+				}
+				call.Function.Arguments += chunk.Function.Arguments
+			}
+			if chunk.ExtraContent != nil {
+				call.ExtraContent = chunk.ExtraContent
+			}
+		}
+	}
+	return event, nil
+}
+
+func (a *chatStreamAccumulator) result() (*GenerateResponse, error) {
+	message := Message{Role: Assistant, Content: NewMessageContent(a.text.String())}
+	if len(a.calls) > 0 {
+		message.ToolCalls = &a.calls
+	}
+	if a.reasoning.Len() > 0 {
+		reasoning := a.reasoning.String()
+		message.ReasoningContent = &reasoning
+	}
+
+	response := a.response
+	response.Object = "chat.completion"
+	response.Choices = []ChatCompletionChoice{{FinishReason: a.finish, Message: message}}
+	return chatResult(&response), nil
+}
+
+type messagesStreamAccumulator struct {
+	response MessagesResponse
+	blocks   []messagesStreamBlock
+}
+
+type messagesStreamBlock struct {
+	kind      string
+	text      strings.Builder
+	signature string
+	data      string
+	id        string
+	name      string
+	input     strings.Builder
+}
+
+func (a *messagesStreamAccumulator) add(data []byte) (GenerateEvent, error) {
+	var event MessagesStreamEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return GenerateEvent{}, fmt.Errorf("failed to parse stream event: %w", err)
+	}
+
+	switch event.Type {
+	case MessagesStreamEventTypeMessageStart:
+		if event.Message != nil {
+			a.response = *event.Message
+		}
+	case MessagesStreamEventTypeContentBlockStart:
+		if event.Index == nil || event.ContentBlock == nil {
+			return GenerateEvent{}, nil
+		}
+		block := a.block(*event.Index)
+		v, err := event.ContentBlock.Value()
+		if err != nil {
+			return GenerateEvent{}, fmt.Errorf("failed to parse content block: %w", err)
+		}
+		switch b := v.(type) {
+		case MessagesTextBlock:
+			block.kind = string(b.Type)
+			block.text.WriteString(b.Text)
+		case MessagesThinkingBlock:
+			block.kind = string(b.Type)
+			block.text.WriteString(b.Thinking)
+			block.signature = b.Signature
+		case MessagesRedactedThinkingBlock:
+			block.kind = string(b.Type)
+			block.data = b.Data
+		case MessagesToolUseBlock:
+			block.kind = string(b.Type)
+			block.id = b.ID
+			block.name = b.Name
+		}
+	case MessagesStreamEventTypeContentBlockDelta:
+		if event.Index == nil || event.Delta == nil {
+			return GenerateEvent{}, nil
+		}
+		block := a.block(*event.Index)
+		delta := event.Delta
+		switch {
+		case delta.Text != nil:
+			block.text.WriteString(*delta.Text)
+			return GenerateEvent{Text: *delta.Text}, nil
+		case delta.Thinking != nil:
+			block.text.WriteString(*delta.Thinking)
+			return GenerateEvent{Reasoning: *delta.Thinking}, nil
+		case delta.Signature != nil:
+			block.signature += *delta.Signature
+		case delta.PartialJSON != nil:
+			block.input.WriteString(*delta.PartialJSON)
+		}
+	case MessagesStreamEventTypeMessageDelta:
+		if event.Delta != nil && event.Delta.StopReason != nil {
+			a.response.StopReason = MessagesResponseStopReason(*event.Delta.StopReason)
+		}
+		if usage := event.Usage; usage != nil {
+			a.response.Usage.OutputTokens = usage.OutputTokens
+			if usage.InputTokens > 0 {
+				a.response.Usage.InputTokens = usage.InputTokens
+			}
+			if usage.CacheReadInputTokens != nil {
+				a.response.Usage.CacheReadInputTokens = usage.CacheReadInputTokens
+			}
+			if usage.CacheCreationInputTokens != nil {
+				a.response.Usage.CacheCreationInputTokens = usage.CacheCreationInputTokens
+			}
+		}
+	case MessagesStreamEventTypeError:
+		if event.Error != nil {
+			return GenerateEvent{}, fmt.Errorf("stream error: %s", event.Error.Error.Message)
+		}
+		return GenerateEvent{}, fmt.Errorf("stream error")
+	}
+	return GenerateEvent{}, nil
+}
+
+func (a *messagesStreamAccumulator) block(index int) *messagesStreamBlock {
+	for len(a.blocks) <= index {
+		a.blocks = append(a.blocks, messagesStreamBlock{})
+	}
+	return &a.blocks[index]
+}
+
+func (a *messagesStreamAccumulator) result() (*GenerateResponse, error) {
+	response := a.response
+	response.Content = nil
+	for i := range a.blocks {
+		block := &a.blocks[i]
+		var v any
+		switch block.kind {
+		case string(MessagesTextBlockTypeText):
+			v = MessagesTextBlock{Type: MessagesTextBlockTypeText, Text: block.text.String()}
+		case string(Thinking):
+			v = MessagesThinkingBlock{Type: Thinking, Thinking: block.text.String(), Signature: block.signature}
+		case string(RedactedThinking):
+			v = MessagesRedactedThinkingBlock{Type: RedactedThinking, Data: block.data}
+		case string(MessagesToolUseBlockTypeToolUse):
+			input := map[string]any{}
+			if raw := strings.TrimSpace(block.input.String()); raw != "" {
+				if err := json.Unmarshal([]byte(raw), &input); err != nil {
+					return nil, fmt.Errorf("failed to parse input of tool %s: %w", block.name, err)
+				}
+			}
+			v = MessagesToolUseBlock{Type: MessagesToolUseBlockTypeToolUse, ID: block.id, Name: block.name, Input: input}
+		default:
+			continue
+		}
+		response.Content = append(response.Content, MessagesResponseContentBlock{union: mustMarshalUnion(v)})
+	}
+	return messagesResult(&response), nil
+}
+
+type responsesStreamAccumulator struct {
+	final *Response
+}
+
+func (a *responsesStreamAccumulator) add(data []byte) (GenerateEvent, error) {
+	var event ResponseStreamEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return GenerateEvent{}, fmt.Errorf("failed to parse stream event: %w", err)
+	}
+
+	switch event.Type {
+	case "response.output_text.delta":
+		if event.Delta != nil {
+			return GenerateEvent{Text: *event.Delta}, nil
+		}
+	case "response.reasoning_summary_text.delta", "response.reasoning_text.delta":
+		if event.Delta != nil {
+			return GenerateEvent{Reasoning: *event.Delta}, nil
+		}
+	case "response.completed", "response.incomplete", "response.failed":
+		a.final = event.Response
+	case "error":
+		return GenerateEvent{}, fmt.Errorf("stream error: %s", string(data))
+	}
+	return GenerateEvent{}, nil
+}
+
+func (a *responsesStreamAccumulator) result() (*GenerateResponse, error) {
+	if a.final == nil {
+		return nil, fmt.Errorf("stream ended before the response completed")
+	}
+	return responsesResult(a.final)
+}
@@ -0,0 +1,433 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// generateServer answers each path with its handler and records the path and
+// body of every request.
+type generateServer struct {
+	mu     sync.Mutex
+	paths  []string
+	bodies []map[string]any
+}
+
+func newGenerateServer(t *testing.T, handlers map[string]func(w http.ResponseWriter)) (*generateServer, Client) {
+	t.Helper()
+	gs := &generateServer{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		gs.mu.Lock()
+		gs.paths = append(gs.paths, r.URL.Path)
+		gs.bodies = append(gs.bodies, body)
+		gs.mu.Unlock()
+
+		handler, ok := handlers[r.URL.Path]
+		if !assert.True(t, ok, "unexpected request to %s", r.URL.Path) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		handler(w)
+	}))
+	t.Cleanup(server.Close)
+	return gs, NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+}
+
+func sseReply(events ...string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+	}
+}
+
+func jsonReply(body string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, body)
+	}
+}
+
+func weatherTool() FunctionObject {
+	return FunctionObject{
+		Name:       "get_weather",
+		Parameters: &FunctionParameters{"type": "object", "properties": map[string]any{"city": map[string]any{"type": "string"}}},
+	}
+}
+
+func TestGenerator_Messages(t *testing.T) {
+	server, client := newGenerateServer(t, map[string]func(w http.ResponseWriter){
+		"/v1/messages": jsonReply(`{
+			"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-sonnet-5",
+			"content": [
+				{"type": "thinking", "thinking": "Use the tool.", "signature": "sig"},
+				{"type": "text", "text": "Checking."},
+				{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}
+			],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 10, "output_tokens": 5, "cache_read_input_tokens": 20}
+		}`),
+	})
+
+	response, err := NewGenerator(client, nil).Generate(context.Background(), GenerateRequest{
+		Provider:   Anthropic,
+		Model:      "claude-sonnet-5",
+		System:     "Be brief.",
+		Messages:   []Message{{Role: User, Content: NewMessageContent("Weather in Paris?")}},
+		Tools:      []FunctionObject{weatherTool()},
+		ToolChoice: ToolChoiceModeRequired,
+		Reasoning:  &GenerateReasoning{Effort: "low"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"/v1/messages"}, server.paths)
+	body := server.bodies[0]
+	assert.Equal(t, "Be brief.", body["system"])
+	assert.Equal(t, float64(defaultGenerateMaxTokens), body["max_tokens"])
+	assert.Equal(t, "any", body["tool_choice"])
+	assert.Equal(t, map[string]any{"type": "enabled", "budget_tokens": float64(2048)}, body["thinking"])
+	assert.Equal(t, "get_weather", body["tools"].([]any)[0].(map[string]any)["name"])
+
+	assert.Equal(t, APIMessages, response.API)
+	assert.Equal(t, "Checking.", response.Text)
+	assert.Equal(t, "Use the tool.", response.Reasoning)
+	assert.Equal(t, ToolCalls, response.FinishReason)
+	require.Len(t, response.ToolCalls, 1)
+	assert.Equal(t, "toolu_1", response.ToolCalls[0].ID)
+	assert.JSONEq(t, `{"city":"Paris"}`, response.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, GenerateUsage{InputTokens: 30, OutputTokens: 5, TotalTokens: 35, CachedInputTokens: 20}, response.Usage)
+	assert.Empty(t, response.Dropped)
+	assert.IsType(t, &MessagesResponse{}, response.Raw)
+
+	message := response.Message()
+	assert.Equal(t, Assistant, message.Role)
+	require.NotNil(t, message.ToolCalls)
+	assert.Len(t, *message.ToolCalls, 1)
+}
+
+func TestGenerator_FallsBackWhenNotSupported(t *testing.T) {
+	server, client := newGenerateServer(t, map[string]func(w http.ResponseWriter){
+		"/v1/responses":        errorReply(http.StatusBadRequest, "The Responses API is not supported by this provider yet."),
+		"/v1/chat/completions": chatReply("Hello!"),
+	})
+	generator := NewGenerator(client, nil)
+	request := GenerateRequest{
+		Provider:    Openai,
+		Model:       "gpt-4o",
+		Messages:    []Message{{Role: User, Content: NewMessageContent("Hi")}},
+		Temperature: new(float32(0.2)),
+		MaxTokens:   64,
+	}
+
+	response, err := generator.Generate(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, APIChat, response.API)
+	assert.Equal(t, "Hello!", response.Text)
+	assert.Equal(t, Stop, response.FinishReason)
+
+	_, err = generator.Generate(context.Background(), request)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"/v1/responses", "/v1/chat/completions", "/v1/chat/completions"}, server.paths)
+	chat := server.bodies[1]
+	assert.Equal(t, "gpt-4o", chat["model"])
+	assert.Equal(t, float64(64), chat["max_tokens"])
+	assert.InDelta(t, 0.2, chat["temperature"], 0.0001)
+}
+
+func TestGenerator_RetriesUnsupportedEndpoints(t *testing.T) {
+	server, client := newGenerateServer(t, map[string]func(w http.ResponseWriter){
+		"/v1/responses":        errorReply(http.StatusBadRequest, "The Responses API is not supported by this provider yet."),
+		"/v1/chat/completions": chatReply("Hello!"),
+	})
+	generator := NewGenerator(client, &GeneratorOptions{UnsupportedTTL: 50 * time.Millisecond})
+	request := GenerateRequest{Provider: Openai, Model: "gpt-4o", Messages: []Message{{Role: User, Content: NewMessageContent("Hi")}}}
+	generate := func() {
+		t.Helper()
+		_, err := generator.Generate(context.Background(), request)
+		require.NoError(t, err)
+	}
+
+	generate()
+	generate()
+	assert.Equal(t, []string{"/v1/responses", "/v1/chat/completions", "/v1/chat/completions"}, server.paths)
+
+	// The endpoint is tried again once the TTL passes, or after Reset.
+	time.Sleep(60 * time.Millisecond)
+	generate()
+	generator.Reset()
+	generate()
+	assert.Equal(t, []string{
+		"/v1/responses", "/v1/chat/completions", "/v1/chat/completions",
+		"/v1/responses", "/v1/chat/completions",
+		"/v1/responses", "/v1/chat/completions",
+	}, server.paths)
+}
+
+func TestGenerator_PrefersLosslessEndpoint(t *testing.T) {
+	server, client := newGenerateServer(t, map[string]func(w http.ResponseWriter){
+		"/v1/chat/completions": chatReply("It is 18C."),
+	})
+	calls := []ChatCompletionMessageToolCall{{
+		ID:       "call_1",
+		Type:     Function,
+		Function: ChatCompletionMessageToolCallFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+	}}
+
+	response, err := NewGenerator(client, nil).Generate(context.Background(), GenerateRequest{
+		Provider: Openai,
+		Model:    "gpt-4o",
+		Messages: []Message{
+			{Role: User, Content: NewMessageContent("Weather in Paris?")},
+			{Role: Assistant, Content: NewMessageContent(""), ToolCalls: &calls},
+			{Role: Tool, Content: NewMessageContent("18C"), ToolCallID: new("call_1")},
+		},
+		Tools: []FunctionObject{weatherTool()},
+	})
+	require.NoError(t, err)
+
+	// The Responses API can't carry tool results as input, so the
+	// conversation goes to /chat/completions untouched.
+	assert.Equal(t, []string{"/v1/chat/completions"}, server.paths)
+	assert.Equal(t, APIChat, response.API)
+	assert.Len(t, server.bodies[0]["messages"], 3)
+	assert.Len(t, server.bodies[0]["tools"], 1)
+}
+
+func TestGenerator_ForcedAPI(t *testing.T) {
+	server, client := newGenerateServer(t, map[string]func(w http.ResponseWriter){
+		"/v1/responses": errorReply(http.StatusBadRequest, "The Responses API is not supported by this provider yet."),
+	})
+	generator := NewGenerator(client, nil)
+
+	_, err := generator.Generate(context.Background(), GenerateRequest{
+		Provider: Groq,
+		Model:    "llama-3.3-70b",
+		API:      APIResponses,
+		Messages: []Message{{Role: User, Content: NewMessageContent("Hi")}},
+	})
+	require.Error(t, err)
+	assert.True(t, IsNotSupported(err))
+	assert.Equal(t, []string{"/v1/responses"}, server.paths)
+
+	_, err = generator.Generate(context.Background(), GenerateRequest{API: "completions"})
+	assert.EqualError(t, err, `unknown API "completions"`)
+}
+
+func TestGenerator_StopSequences(t *testing.T) {
+	gateway := newTestGateway(t)
+
+	response, err := NewGenerator(gateway.client(nil), nil).Generate(context.Background(), GenerateRequest{
+		Provider: Openai,
+		Model:    "gpt-4o",
+		API:      APIChat,
+		Messages: []Message{{Role: User, Content: NewMessageContent("Count to ten.")}},
+		Stop:     []string{"1", "2", "3", "4", "5"},
+	})
+	require.NoError(t, err)
+
+	// The chat completions API takes at most four stop sequences.
+	assert.Equal(t, []any{"1", "2", "3", "4"}, gateway.bodies()[0]["stop"])
+	assert.Equal(t, []DroppedField{{Path: "stop", Reason: "the chat completions API allows at most 4 stop sequences, so the rest were not sent"}}, response.Dropped)
+}
+
+func TestGenerator_Responses(t *testing.T) {
+	server, client := newGenerateServer(t, map[string]func(w http.ResponseWriter){
+		"/v1/responses": jsonReply(`{
+			"id": "resp_1", "object": "response", "created_at": 1700000000, "model": "gpt-5", "status": "completed",
+			"output": [
+				{"type": "reasoning", "id": "rs_1", "summary": [{"type": "summary_text", "text": "Simple."}]},
+				{"type": "message", "id": "msg_1", "role": "assistant", "content": [{"type": "output_text", "text": "Hi there", "annotations": []}]}
+			],
+			"usage": {"input_tokens": 8, "output_tokens": 4, "total_tokens": 12, "output_tokens_details": {"reasoning_tokens": 2}}
+		}`),
+	})
+
+	response, err := NewGenerator(client, nil).Generate(context.Background(), GenerateRequest{
+		Provider:  Openai,
+		Model:     "gpt-5",
+		System:    "Be brief.",
+		Messages:  []Message{{Role: User, Content: NewMessageContent("Hi")}},
+		Reasoning: &GenerateReasoning{Effort: "minimal"},
+	})
+	require.NoError(t, err)
+
+	body := server.bodies[0]
+	assert.Equal(t, "Be brief.", body["instructions"])
+	assert.Equal(t, map[string]any{"effort": "minimal", "summary": "auto"}, body["reasoning"])
+
+	assert.Equal(t, APIResponses, response.API)
+	assert.Equal(t, "Hi there", response.Text)
+	assert.Equal(t, "Simple.", response.Reasoning)
+	assert.Equal(t, Stop, response.FinishReason)
+	assert.Equal(t, GenerateUsage{InputTokens: 8, OutputTokens: 4, TotalTokens: 12, ReasoningTokens: 2}, response.Usage)
+}
+
+func TestGenerator_WrappedClientKeepsItsOptions(t *testing.T) {
+	server, client := newGenerateServer(t, map[string]func(w http.ResponseWriter){
+		"/v1/chat/completions": chatReply("Sunny."),
+	})
+	wrapped := NewFallbackClient(client, FallbackOptions{Targets: []FallbackTarget{{Provider: Groq}}})
+
+	response, err := NewGenerator(wrapped, nil).Generate(context.Background(), GenerateRequest{
+		Provider:  Groq,
+		Model:     "llama",
+		API:       APIChat,
+		Messages:  []Message{{Role: User, Content: NewMessageContent("Weather?")}},
+		Tools:     []FunctionObject{weatherTool()},
+		MaxTokens: 100,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Sunny.", response.Text)
+
+	_, err = wrapped.GenerateContent(context.Background(), Groq, "llama", []Message{{Role: User, Content: NewMessageContent("Hi")}})
+	require.NoError(t, err)
+
+	require.Len(t, server.bodies, 2)
+	assert.NotNil(t, server.bodies[0]["tools"])
+	assert.InDelta(t, 100, server.bodies[0]["max_tokens"], 0)
+	assert.Nil(t, server.bodies[1]["tools"], "the generate options must not stick to the client")
+	assert.Nil(t, server.bodies[1]["max_tokens"])
+}
+
+func collectGenerateStream(t *testing.T, events <-chan GenerateEvent) (string, string, *GenerateResponse) {
+	t.Helper()
+	var text, reasoning strings.Builder
+	var response *GenerateResponse
+	for event := range events {
+		require.NoError(t, event.Err)
+		text.WriteString(event.Text)
+		reasoning.WriteString(event.Reasoning)
+		if event.Response != nil {
+			response = event.Response
+		}
+	}
+	require.NotNil(t, response, "stream ended without a response")
+	return text.String(), reasoning.String(), response
+}
+
+func TestGenerateStream_Chat(t *testing.T) {
+	server, client := newGenerateServer(t, map[string]func(w http.ResponseWriter){
+		"/v1/chat/completions": sseReply(
+			`{"id":"c1","model":"llama","choices":[{"index":0,"delta":{"role":"assistant","reasoning_content":"Think."}}]}`,
+			`{"id":"c1","model":"llama","choices":[{"index":0,"delta":{"content":"Let me check."}}]}`,
+			`{"id":"c1","model":"llama","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"ci"}}]}}]}`,
+			`{"id":"c1","model":"llama","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"ty\":\"Paris\"}"}}]},"finish_reason":"tool_calls"}]}`,
+			`{"id":"c1","model":"llama","choices":[],"usage":{"prompt_tokens":7,"completion_tokens":3,"total_tokens":10}}`,
+			`[DONE]`,
+		),
+	})
+
+	events, err := NewGenerator(client, nil).GenerateStream(context.Background(), GenerateRequest{
+		Provider: Groq,
+		Model:    "llama",
+		Messages: []Message{{Role: User, Content: NewMessageContent("Weather?")}},
+		Tools:    []FunctionObject{weatherTool()},
+	})
+	require.NoError(t, err)
+	text, reasoning, response := collectGenerateStream(t, events)
+
+	assert.Equal(t, map[string]any{"include_usage": true}, server.bodies[0]["stream_options"])
+	assert.Equal(t, "Let me check.", text)
+	assert.Equal(t, "Think.", reasoning)
+	assert.Equal(t, "Let me check.", response.Text)
+	assert.Equal(t, ToolCalls, response.FinishReason)
+	require.Len(t, response.ToolCalls, 1)
+	assert.Equal(t, `{"city":"Paris"}`, response.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, GenerateUsage{InputTokens: 7, OutputTokens: 3, TotalTokens: 10}, response.Usage)
+}
+
+func TestGenerateStream_Messages(t *testing.T) {
+	_, client := newGenerateServer(t, map[string]func(w http.ResponseWriter){
+		"/v1/messages": sseReply(
+			`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[],"stop_reason":"end_turn","usage":{"input_tokens":12,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}`,
+			`{"type":"content_block_stop","index":1}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"input_tokens":0,"output_tokens":9}}`,
+			`{"type":"message_stop"}`,
+		),
+	})
+
+	events, err := NewGenerator(client, nil).GenerateStream(context.Background(), GenerateRequest{
+		Provider: Anthropic,
+		Model:    "claude",
+		Messages: []Message{{Role: User, Content: NewMessageContent("Weather?")}},
+		Tools:    []FunctionObject{weatherTool()},
+	})
+	require.NoError(t, err)
+	text, _, response := collectGenerateStream(t, events)
+
+	assert.Equal(t, "Hello", text)
+	assert.Equal(t, APIMessages, response.API)
+	assert.Equal(t, "Hello", response.Text)
+	assert.Equal(t, ToolCalls, response.FinishReason)
+	require.Len(t, response.ToolCalls, 1)
+	assert.JSONEq(t, `{"city":"Paris"}`, response.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, GenerateUsage{InputTokens: 12, OutputTokens: 9, TotalTokens: 21}, response.Usage)
+}
+
+func TestGenerateStream_Responses(t *testing.T) {
+	_, client := newGenerateServer(t, map[string]func(w http.ResponseWriter){
+		"/v1/responses": sseReply(
+			`{"type":"response.created","sequence_number":0}`,
+			`{"type":"response.output_text.delta","delta":"Hi "}`,
+			`{"type":"response.output_text.delta","delta":"there"}`,
+			`{"type":"response.completed","response":{"id":"resp_1","object":"response","created_at":1,"model":"gpt-5","status":"completed","output":[{"type":"message","id":"msg_1","role":"assistant","content":[{"type":"output_text","text":"Hi there","annotations":[]}]}],"usage":{"input_tokens":3,"output_tokens":2,"total_tokens":5}}}`,
+		),
+	})
+
+	events, err := NewGenerator(client, nil).GenerateStream(context.Background(), GenerateRequest{
+		Provider: Openai,
+		Model:    "gpt-5",
+		Messages: []Message{{Role: User, Content: NewMessageContent("Hi")}},
+	})
+	require.NoError(t, err)
+	text, _, response := collectGenerateStream(t, events)
+
+	assert.Equal(t, "Hi there", text)
+	assert.Equal(t, APIResponses, response.API)
+	assert.Equal(t, "Hi there", response.Text)
+	assert.Equal(t, int64(5), response.Usage.TotalTokens)
+}
+
+func TestGenerateStream_FallsBackBeforeStreaming(t *testing.T) {
+	server, client := newGenerateServer(t, map[string]func(w http.ResponseWriter){
+		"/v1/messages": func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprint(w, `{"type":"error","error":{"type":"not_supported_error","message":"The Messages API is not supported by this provider yet."}}`)
+		},
+		"/v1/chat/completions": sseReply(`{"id":"c1","model":"m","choices":[{"index":0,"delta":{"content":"ok"},"finish_reason":"stop"}]}`, `[DONE]`),
+	})
+
+	events, err := NewGenerator(client, &GeneratorOptions{APIs: map[Provider][]API{Mistral: {APIMessages}}}).GenerateStream(context.Background(), GenerateRequest{
+		Provider: Mistral,
+		Model:    "m",
+		Messages: []Message{{Role: User, Content: NewMessageContent("Hi")}},
+	})
+	require.NoError(t, err)
+	text, _, response := collectGenerateStream(t, events)
+
+	assert.Equal(t, []string{"/v1/messages", "/v1/chat/completions"}, server.paths)
+	assert.Equal(t, "ok", text)
+	assert.Equal(t, APIChat, response.API)
+}
@@ -0,0 +1,341 @@
+package sdk
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHedgePercentile = 0.95
+	defaultHedgeMinSamples = 20
+	defaultMaxHedges       = 1
+
+	// Latencies are counted in buckets growing by a quarter from 1ms, up to
+	// about half an hour. Counts are halved once they reach
+	// maxLatencySamples, so recent latencies weigh the most.
+	latencyBuckets    = 64
+	latencyGrowth     = 1.25
+	maxLatencySamples = 1000
+)
+
+// HedgeTarget is where a hedged request goes. Empty fields mean the
+// provider and model of the original call.
+type HedgeTarget struct {
+	Provider Provider
+	Model    string
+}
+
+// HedgeStats reports the hedging of the calls to one provider and model.
+type HedgeStats struct {
+	Provider Provider
+	Model    string
+	// Calls counts the calls made; Hedged those that sent at least one
+	// hedge, and Hedges the hedges sent.
+	Calls  int64
+	Hedged int64
+	Hedges int64
+	// Wins counts the hedged calls a hedge answered first.
+	Wins int64
+}
+
+// WinRate is the share of hedged calls a hedge won, or 0 when none were
+// hedged.
+func (s HedgeStats) WinRate() float64 {
+	if s.Hedged == 0 {
+		return 0
+	}
+	return float64(s.Wins) / float64(s.Hedged)
+}
+
+// HedgingPolicyOptions configures a HedgingPolicy.
+type HedgingPolicyOptions struct {
+	// Percentile of the latencies of a provider and model after which a
+	// hedge is sent. Defaults to 0.95.
+	Percentile float64
+	// MinSamples is how many latencies a provider and model needs before
+	// the percentile is used. Defaults to 20.
+	MinSamples int
+	// Delay is the hedging delay until then; zero sends no hedges until
+	// enough latencies are known.
+	Delay time.Duration
+	// MaxHedges is how many hedges a call sends at most, each one delay
+	// after the previous request. Defaults to 1.
+	MaxHedges int
+	// Targets are where the hedges go, in order. Hedges beyond the targets,
+	// or all of them when there are none, repeat the original call.
+	Targets []HedgeTarget
+}
+
+// HedgingPolicy protects latency-sensitive calls from slow responses. Set it
+// as ClientOptions.Hedging. When GenerateContent, CreateMessage or
+// CreateResponse hasn't answered within the hedging delay, the client sends
+// the same request again, optionally to another provider or model, returns
+// whichever answers first and cancels the rest. Errors don't send hedges,
+// and a call only fails once none of its requests is left; use a
+// FallbackClient to fail over. Streams are not hedged.
+//
+// The delay is a percentile of the latencies seen per provider and model.
+// Requests cancelled because a hedge answered first count with the time
+// they ran, a lower bound of their latency.
+// It is safe for concurrent use and may be shared by clients.
+type HedgingPolicy struct {
+	options HedgingPolicyOptions
+
+	mu        sync.Mutex
+	latencies map[hedgeKey]*latencyHistogram
+	stats     map[hedgeKey]*HedgeStats
+}
+
+// NewHedgingPolicy creates a HedgingPolicy.
+//
+// Example:
+//
+//	hedging := sdk.NewHedgingPolicy(&sdk.HedgingPolicyOptions{
+//		Percentile: 0.9,
+//		Delay:      2 * time.Second,
+//		Targets:    []sdk.HedgeTarget{{Provider: sdk.Groq, Model: "llama-3.3-70b-versatile"}},
+//	})
+//	client := sdk.NewClient(&sdk.ClientOptions{
+//		BaseURL: "http://localhost:8080/v1",
+//		Hedging: hedging,
+//	})
+func NewHedgingPolicy(options *HedgingPolicyOptions) *HedgingPolicy {
+	p := &HedgingPolicy{
+		latencies: make(map[hedgeKey]*latencyHistogram),
+		stats:     make(map[hedgeKey]*HedgeStats),
+	}
+	if options != nil {
+		p.options = *options
+		p.options.Targets = slices.Clone(options.Targets)
+	}
+	if p.options.Percentile <= 0 || p.options.Percentile > 1 {
+		p.options.Percentile = defaultHedgePercentile
+	}
+	if p.options.MinSamples <= 0 {
+		p.options.MinSamples = defaultHedgeMinSamples
+	}
+	if p.options.MaxHedges <= 0 {
+		p.options.MaxHedges = defaultMaxHedges
+	}
+	return p
+}
+
+// Delay returns how long calls to provider and model wait before sending a
+// hedge, and false when they don't hedge yet.
+func (p *HedgingPolicy) Delay(provider Provider, model string) (time.Duration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if h := p.latencies[hedgeKey{provider: provider, model: model}]; h != nil && h.total >= p.options.MinSamples {
+		return h.percentile(p.options.Percentile), true
+	}
+	return p.options.Delay, p.options.Delay > 0
+}
+
+// Stats returns the hedging of every provider and model called so far.
+func (p *HedgingPolicy) Stats() []HedgeStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make([]HedgeStats, 0, len(p.stats))
+	for _, s := range p.stats {
+		stats = append(stats, *s)
+	}
+	slices.SortFunc(stats, func(a, b HedgeStats) int {
+		return cmp.Or(cmp.Compare(a.Provider, b.Provider), cmp.Compare(a.Model, b.Model))
+	})
+	return stats
+}
+
+// Reset forgets the latencies and stats.
+func (p *HedgingPolicy) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	clear(p.latencies)
+	clear(p.stats)
+}
+
+// target returns where hedge n, counted from 1, goes for a call to provider
+// and model.
+func (p *HedgingPolicy) target(n int, provider Provider, model string) (Provider, string) {
+	if n > len(p.options.Targets) {
+		return provider, model
+	}
+	target := p.options.Targets[n-1]
+	return cmp.Or(target.Provider, provider), cmp.Or(target.Model, model)
+}
+
+// observe records the latency of an answer of provider and model.
+func (p *HedgingPolicy) observe(provider Provider, model string, latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := hedgeKey{provider: provider, model: model}
+	h := p.latencies[key]
+	if h == nil {
+		h = &latencyHistogram{}
+		p.latencies[key] = h
+	}
+	h.add(latency)
+}
+
+// count adds a finished call to the stats of provider and model.
+func (p *HedgingPolicy) count(provider Provider, model string, hedges int, won bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := hedgeKey{provider: provider, model: model}
+	s := p.stats[key]
+	if s == nil {
+		s = &HedgeStats{Provider: provider, Model: model}
+		p.stats[key] = s
+	}
+	s.Calls++
+	s.Hedges += int64(hedges)
+	if hedges > 0 {
+		s.Hedged++
+	}
+	if won {
+		s.Wins++
+	}
+}
+
+type hedgeKey struct {
+	provider Provider
+	model    string
+}
+
+// hedgeAttempt is the outcome of one request of a hedged call.
+type hedgeAttempt[T any] struct {
+	n        int
+	provider Provider
+	model    string
+	latency  time.Duration
+	result   T
+	err      error
+}
+
+// hedgeRequest is a request of a hedged call that is still running.
+type hedgeRequest struct {
+	provider Provider
+	model    string
+	start    time.Time
+}
+
+// hedge runs call for provider and model, and again for the next hedge
+// target whenever the hedging delay passes without an answer. The first
+// answer wins and the other requests are cancelled. An error ends the call
+// when no other request is left, with the first error seen.
+func hedge[T any](ctx context.Context, p *HedgingPolicy, provider Provider, model string, call func(ctx context.Context, provider Provider, model string) (T, error)) (T, error) {
+	delay, ok := p.Delay(provider, model)
+	if !ok {
+		start := time.Now()
+		result, err := call(ctx, provider, model)
+		if err == nil {
+			p.observe(provider, model, time.Since(start))
+		}
+		p.count(provider, model, 0, false)
+		return result, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	attempts := make(chan hedgeAttempt[T], p.options.MaxHedges+1)
+	// requests holds the requests sent by number, nil once they answered.
+	var requests []*hedgeRequest
+	sent, pending := 0, 0
+	send := func() {
+		n, provider, model := sent, provider, model
+		if n > 0 {
+			provider, model = p.target(n, provider, model)
+		}
+		start := time.Now()
+		requests = append(requests, &hedgeRequest{provider: provider, model: model, start: start})
+		sent++
+		pending++
+		go func() {
+			result, err := call(ctx, provider, model)
+			attempts <- hedgeAttempt[T]{n: n, provider: provider, model: model, latency: time.Since(start), result: result, err: err}
+		}()
+	}
+
+	send()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	var firstErr error
+	for {
+		select {
+		case attempt := <-attempts:
+			pending--
+			requests[attempt.n] = nil
+			if attempt.err == nil {
+				p.observe(attempt.provider, attempt.model, attempt.latency)
+				// The requests the winner cancels took at least as long as
+				// they ran. Learning only the winners would pull the
+				// percentile, and so the delay, ever lower.
+				for _, request := range requests {
+					if request != nil {
+						p.observe(request.provider, request.model, time.Since(request.start))
+					}
+				}
+				p.count(provider, model, sent-1, attempt.n > 0)
+				return attempt.result, nil
+			}
+			if firstErr == nil {
+				firstErr = attempt.err
+			}
+			if pending == 0 {
+				p.count(provider, model, sent-1, false)
+				var zero T
+				return zero, firstErr
+			}
+		case <-timer.C:
+			if firstErr != nil {
+				continue
+			}
+			send()
+			if sent <= p.options.MaxHedges {
+				timer.Reset(delay)
+			}
+		case <-ctx.Done():
+			p.count(provider, model, sent-1, false)
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// latencyHistogram counts latencies in exponentially growing buckets.
+type latencyHistogram struct {
+	counts [latencyBuckets]int
+	total  int
+}
+
+func (h *latencyHistogram) add(latency time.Duration) {
+	i := 0
+	if latency > time.Millisecond {
+		i = min(latencyBuckets-1, int(math.Ceil(math.Log(float64(latency)/float64(time.Millisecond))/math.Log(latencyGrowth))))
+	}
+	h.counts[i]++
+	h.total++
+	if h.total >= maxLatencySamples {
+		h.total = 0
+		for i := range h.counts {
+			h.counts[i] /= 2
+			h.total += h.counts[i]
+		}
+	}
+}
+
+// percentile returns the upper bound of the bucket holding percentile p.
+func (h *latencyHistogram) percentile(p float64) time.Duration {
+	target := int(math.Ceil(p * float64(h.total)))
+	seen := 0
+	for i, count := range h.counts {
+		seen += count
+		if seen >= target {
+			return time.Duration(float64(time.Millisecond) * math.Pow(latencyGrowth, float64(i)))
+		}
+	}
+	return time.Duration(float64(time.Millisecond) * math.Pow(latencyGrowth, latencyBuckets-1))
+}
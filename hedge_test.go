@@ -0,0 +1,130 @@
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// newHedgeGateway answers chat completions with a greeting from the target,
+// through a client hedging with hedging.
+func newHedgeGateway(t *testing.T, hedging *HedgingPolicy) (*testGateway, Client) {
+	t.Helper()
+	gateway := newTestGateway(t)
+	gateway.handle("POST /v1/chat/completions", func(w http.ResponseWriter, r *gatewayRequest) {
+		chatRoute("Hi from "+r.target(), nil)(w, r)
+	})
+	return gateway, gateway.client(&ClientOptions{Hedging: hedging})
+}
+
+// hedgeCalls returns the targets of the gateway's requests so far, and of
+// those that were cancelled.
+func hedgeCalls(gateway *testGateway) (calls, cancelled []string) {
+	for _, r := range gateway.calls() {
+		calls = append(calls, r.target())
+		if r.cancelled {
+			cancelled = append(cancelled, r.target())
+		}
+	}
+	return calls, cancelled
+}
+
+func TestHedgingPolicy_GenerateContent(t *testing.T) {
+	hedging := NewHedgingPolicy(&HedgingPolicyOptions{
+		Delay:   50 * time.Millisecond,
+		Targets: []HedgeTarget{{Provider: Groq, Model: "llama-3.3-70b-versatile"}},
+	})
+	server, client := newHedgeGateway(t, hedging)
+	server.slow("openai/gpt-4o", time.Second)
+	ctx := context.Background()
+
+	start := time.Now()
+	response, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	text, err := messageText(response.Choices[0].Message.Content)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi from groq/llama-3.3-70b-versatile", text)
+
+	// The cancelled request counts as at least as slow as it ran.
+	slow := hedging.latencies[hedgeKey{provider: Openai, model: "gpt-4o"}]
+	require.NotNil(t, slow)
+	assert.Equal(t, 1, slow.total)
+	assert.GreaterOrEqual(t, slow.percentile(1), 50*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		_, cancelled := hedgeCalls(server)
+		return len(cancelled) == 1
+	}, time.Second, 10*time.Millisecond, "the slow request is cancelled")
+	calls, cancelled := hedgeCalls(server)
+	assert.Equal(t, []string{"openai/gpt-4o", "groq/llama-3.3-70b-versatile"}, calls)
+	assert.Equal(t, []string{"openai/gpt-4o"}, cancelled)
+
+	// Fast answers send no hedge.
+	_, err = client.GenerateContent(ctx, Openai, "gpt-4o-mini", hello())
+	require.NoError(t, err)
+
+	stats := hedging.Stats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, HedgeStats{Provider: Openai, Model: "gpt-4o", Calls: 1, Hedged: 1, Hedges: 1, Wins: 1}, stats[0])
+	assert.Equal(t, 1.0, stats[0].WinRate())
+	assert.Equal(t, HedgeStats{Provider: Openai, Model: "gpt-4o-mini", Calls: 1}, stats[1])
+	assert.Equal(t, 0.0, stats[1].WinRate())
+}
+
+func TestHedgingPolicy_MaxHedges(t *testing.T) {
+	hedging := NewHedgingPolicy(&HedgingPolicyOptions{Delay: 30 * time.Millisecond, MaxHedges: 2})
+	server, client := newHedgeGateway(t, hedging)
+	server.slow("openai/gpt-4o", 200*time.Millisecond)
+
+	start := time.Now()
+	_, err := client.GenerateContent(context.Background(), Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 250*time.Millisecond, "the first request answers first")
+	calls, _ := hedgeCalls(server)
+	assert.Equal(t, []string{"openai/gpt-4o", "openai/gpt-4o", "openai/gpt-4o"}, calls)
+	assert.Equal(t, []HedgeStats{{Provider: Openai, Model: "gpt-4o", Calls: 1, Hedged: 1, Hedges: 2}}, hedging.Stats())
+}
+
+func TestHedgingPolicy_Errors(t *testing.T) {
+	hedging := NewHedgingPolicy(&HedgingPolicyOptions{
+		Delay:   30 * time.Millisecond,
+		Targets: []HedgeTarget{{Model: "gpt-4o-mini"}},
+	})
+	server, client := newHedgeGateway(t, hedging)
+	server.reply("openai/gpt-4o", errorReply(http.StatusBadRequest, "invalid request"))
+	ctx := context.Background()
+
+	// Errors are returned without sending hedges.
+	_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	assert.ErrorContains(t, err, "invalid request")
+	calls, _ := hedgeCalls(server)
+	assert.Equal(t, []string{"openai/gpt-4o"}, calls)
+
+	// A request failing after a hedge was sent leaves the hedge to answer.
+	server.slow("openai/gpt-4o", 60*time.Millisecond)
+	server.slow("openai/gpt-4o-mini", 60*time.Millisecond)
+	_, err = client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	assert.NoError(t, err)
+}
+
+func TestHedgingPolicy_Delay(t *testing.T) {
+	hedging := NewHedgingPolicy(&HedgingPolicyOptions{Percentile: 0.9, MinSamples: 10})
+	_, ok := hedging.Delay(Openai, "gpt-4o")
+	assert.False(t, ok, "no hedging before enough latencies are known")
+
+	for i := range 100 {
+		hedging.observe(Openai, "gpt-4o", time.Duration(i+1)*10*time.Millisecond)
+	}
+	delay, ok := hedging.Delay(Openai, "gpt-4o")
+	require.True(t, ok)
+	assert.InDelta(t, 900*time.Millisecond, delay, float64(250*time.Millisecond))
+
+	hedging.Reset()
+	_, ok = hedging.Delay(Openai, "gpt-4o")
+	assert.False(t, ok)
+}
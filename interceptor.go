@@ -0,0 +1,155 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+)
+
+// Operation names the Client method a call was made through.
+type Operation string
+
+const (
+	OperationListModels            Operation = "ListModels"
+	OperationListProviderModels    Operation = "ListProviderModels"
+	OperationListTools             Operation = "ListTools"
+	OperationGenerateContent       Operation = "GenerateContent"
+	OperationGenerateContentStream Operation = "GenerateContentStream"
+	OperationCreateMessage         Operation = "CreateMessage"
+	OperationCreateMessageStream   Operation = "CreateMessageStream"
+	OperationCreateResponse        Operation = "CreateResponse"
+	OperationCreateResponseStream  Operation = "CreateResponseStream"
+	OperationCreateImage           Operation = "CreateImage"
+	OperationCreateImageEdit       Operation = "CreateImageEdit"
+	OperationCreateImageVariation  Operation = "CreateImageVariation"
+	OperationHealthCheck           Operation = "HealthCheck"
+)
+
+// Streaming reports whether the operation returns a stream of events.
+func (o Operation) Streaming() bool {
+	switch o {
+	case OperationGenerateContentStream, OperationCreateMessageStream, OperationCreateResponseStream:
+		return true
+	}
+	return false
+}
+
+// API returns the endpoint a generation operation calls, or "" for the
+// other operations.
+func (o Operation) API() API {
+	switch o {
+	case OperationGenerateContent, OperationGenerateContentStream:
+		return APIChat
+	case OperationCreateMessage, OperationCreateMessageStream:
+		return APIMessages
+	case OperationCreateResponse, OperationCreateResponseStream:
+		return APIResponses
+	}
+	return ""
+}
+
+// Call is one Client call as interceptors see it. Interceptors may change
+// Provider and Request before passing the call on.
+//
+// Request points to the typed request of the operation:
+//
+//   - ListModels, ListProviderModels: *ListModelsParams
+//   - ListTools, HealthCheck: nil
+//   - GenerateContent, GenerateContentStream: *CreateChatCompletionRequest,
+//     with the client's tools and options applied
+//   - CreateMessage, CreateMessageStream: *CreateMessagesRequest
+//   - CreateResponse, CreateResponseStream: *CreateResponseRequest
+//   - CreateImage: *CreateImageRequest
+//   - CreateImageEdit: *CreateImageEditMultipartBody
+//   - CreateImageVariation: *CreateImageVariationMultipartBody
+//
+// The response is *ListModelsResponse, *ListToolsResponse,
+// *CreateChatCompletionResponse, *MessagesResponse, *Response or
+// *ImagesResponse, <-chan SSEvent for streams, and nil for HealthCheck.
+type Call struct {
+	Operation Operation
+	// Endpoint is the API path, e.g. chat/completions.
+	Endpoint string
+	Provider Provider
+	Request  any
+}
+
+// Invoker runs a call: the next interceptor, or the client itself.
+type Invoker func(ctx context.Context, call *Call) (any, error)
+
+// Interceptor sees every call of a client. It may rewrite the call before
+// invoking next, inspect or replace the response and error next returns,
+// or answer without invoking next at all, e.g. from a cache. Interceptors
+// answering streams return a <-chan SSEvent they close when done.
+//
+// Example, adding a system prompt to every chat completion:
+//
+//	func systemPrompt(prompt string) sdk.Interceptor {
+//		return func(ctx context.Context, call *sdk.Call, next sdk.Invoker) (any, error) {
+//			if request, ok := call.Request.(*sdk.CreateChatCompletionRequest); ok {
+//				system := sdk.Message{Role: sdk.System, Content: sdk.NewMessageContent(prompt)}
+//				request.Messages = append([]sdk.Message{system}, request.Messages...)
+//			}
+//			return next(ctx, call)
+//		}
+//	}
+type Interceptor func(ctx context.Context, call *Call, next Invoker) (any, error)
+
+// intercept runs invoke through the client's interceptors. The first
+// interceptor sees the call first and the response last. The call is logged
+// around the interceptors, so that answers they give without invoking next,
+// such as cache hits, are logged too.
+func intercept[Req, Resp any](ctx context.Context, c *clientImpl, operation Operation, endpoint string, provider Provider, request *Req, invoke func(ctx context.Context, provider Provider, request *Req) (Resp, error)) (Resp, error) {
+	return logged(c, operation, endpoint, func(ctx context.Context, provider Provider, request *Req) (Resp, error) {
+		return runInterceptors(ctx, c, operation, endpoint, provider, request, invoke)
+	})(ctx, provider, request)
+}
+
+// runInterceptors runs invoke through the client's interceptors.
+func runInterceptors[Req, Resp any](ctx context.Context, c *clientImpl, operation Operation, endpoint string, provider Provider, request *Req, invoke func(ctx context.Context, provider Provider, request *Req) (Resp, error)) (Resp, error) {
+	if len(c.interceptors) == 0 {
+		return invoke(ctx, provider, request)
+	}
+
+	call := &Call{Operation: operation, Endpoint: endpoint, Provider: provider}
+	needsRequest := request != nil
+	if needsRequest {
+		call.Request = request
+	}
+	var next Invoker = func(ctx context.Context, call *Call) (any, error) {
+		request, ok := call.Request.(*Req)
+		if (!ok && call.Request != nil) || (needsRequest && request == nil) {
+			return nil, fmt.Errorf("interceptor passed a %T request to %s, want %T", call.Request, call.Operation, request)
+		}
+		loggedCall(ctx).setProvider(call.Provider)
+		return invoke(ctx, call.Provider, request)
+	}
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := c.interceptors[i], next
+		next = func(ctx context.Context, call *Call) (any, error) {
+			return interceptor(ctx, call, inner)
+		}
+	}
+
+	var zero Resp
+	response, err := next(ctx, call)
+	if response == nil {
+		return zero, err
+	}
+	typed, ok := response.(Resp)
+	if !ok {
+		return zero, fmt.Errorf("interceptor returned a %T response from %s, want %T", response, operation, zero)
+	}
+	return typed, err
+}
+
+// interceptStream is intercept for streams. The channel returned is never
+// nil, so that callers ranging over it on errors don't block.
+func interceptStream[Req any](ctx context.Context, c *clientImpl, operation Operation, endpoint string, provider Provider, request *Req, invoke func(ctx context.Context, provider Provider, request *Req) (<-chan SSEvent, error)) (<-chan SSEvent, error) {
+	events, err := intercept(ctx, c, operation, endpoint, provider, request, invoke)
+	if events == nil {
+		closed := make(chan SSEvent)
+		close(closed)
+		events = closed
+	}
+	return events, err
+}
@@ -0,0 +1,176 @@
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// newInterceptGateway lists a model and answers image edits as well as chat
+// completions, through a client with interceptors.
+func newInterceptGateway(t *testing.T, interceptors ...Interceptor) (*testGateway, Client) {
+	t.Helper()
+	gateway := newTestGateway(t)
+	gateway.handle("GET /v1/models", listModelsRoute(`{"id": "openai/gpt-4o", "object": "model", "created": 1, "owned_by": "openai", "served_by": "openai"}`))
+	gateway.handle("POST /v1/images/edits", func(w http.ResponseWriter, _ *gatewayRequest) {
+		jsonReply(`{"created": 1, "data": [{"url": "https://example.com/edited.png"}]}`)(w)
+	})
+	return gateway, gateway.client(&ClientOptions{Interceptors: interceptors})
+}
+
+// requestPaths returns the path and provider of every request so far, as
+// "path?provider".
+func requestPaths(gateway *testGateway) []string {
+	var paths []string
+	for _, r := range gateway.calls() {
+		paths = append(paths, r.path+"?"+r.provider)
+	}
+	return paths
+}
+
+func TestInterceptors_Order(t *testing.T) {
+	var trace []string
+	record := func(name string) Interceptor {
+		return func(ctx context.Context, call *Call, next Invoker) (any, error) {
+			trace = append(trace, name+" "+string(call.Operation)+" "+call.Endpoint)
+			response, err := next(ctx, call)
+			trace = append(trace, name+" done")
+			return response, err
+		}
+	}
+	_, client := newInterceptGateway(t, record("outer"), record("inner"))
+
+	_, err := client.GenerateContent(context.Background(), Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"outer GenerateContent chat/completions",
+		"inner GenerateContent chat/completions",
+		"inner done",
+		"outer done",
+	}, trace)
+}
+
+func TestInterceptors_RewriteRequest(t *testing.T) {
+	systemPrompt := func(ctx context.Context, call *Call, next Invoker) (any, error) {
+		if request, ok := call.Request.(*CreateChatCompletionRequest); ok {
+			system := Message{Role: System, Content: NewMessageContent("Be brief.")}
+			request.Messages = append([]Message{system}, request.Messages...)
+		}
+		call.Provider = Groq
+		return next(ctx, call)
+	}
+	server, client := newInterceptGateway(t, systemPrompt)
+
+	_, err := client.GenerateContent(context.Background(), Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	require.Len(t, server.bodies(), 1)
+	assert.Equal(t, []string{"/v1/chat/completions?groq"}, requestPaths(server))
+	messages := server.bodies()[0]["messages"].([]any)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "system", messages[0].(map[string]any)["role"])
+	assert.Equal(t, "Be brief.", messages[0].(map[string]any)["content"])
+}
+
+func TestInterceptors_ShortCircuit(t *testing.T) {
+	cached := &ListModelsResponse{Object: "list"}
+	cache := func(ctx context.Context, call *Call, next Invoker) (any, error) {
+		if params, ok := call.Request.(*ListModelsParams); ok && params.Provider == nil {
+			return cached, nil
+		}
+		return next(ctx, call)
+	}
+	server, client := newInterceptGateway(t, cache)
+	ctx := context.Background()
+
+	models, err := client.ListModels(ctx)
+	require.NoError(t, err)
+	assert.Same(t, cached, models)
+	assert.Empty(t, requestPaths(server))
+
+	models, err = client.ListProviderModels(ctx, Openai)
+	require.NoError(t, err)
+	require.Len(t, models.Data, 1)
+	assert.Equal(t, []string{"/v1/models?openai"}, requestPaths(server))
+}
+
+func TestInterceptors_Streams(t *testing.T) {
+	var events int
+	count := func(ctx context.Context, call *Call, next Invoker) (any, error) {
+		response, err := next(ctx, call)
+		if !call.Operation.Streaming() || err != nil {
+			return response, err
+		}
+		counted := make(chan SSEvent)
+		go func() {
+			defer close(counted)
+			for event := range response.(<-chan SSEvent) {
+				events++
+				counted <- event
+			}
+		}()
+		return (<-chan SSEvent)(counted), nil
+	}
+	_, client := newInterceptGateway(t, count)
+
+	stream, err := client.GenerateContentStream(context.Background(), Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	var received int
+	for range stream {
+		received++
+	}
+	assert.Positive(t, received)
+	assert.Equal(t, received, events)
+}
+
+func TestInterceptors_ImageEdit(t *testing.T) {
+	var prompt string
+	inspect := func(ctx context.Context, call *Call, next Invoker) (any, error) {
+		if request, ok := call.Request.(*CreateImageEditMultipartBody); ok {
+			prompt = request.Prompt
+			request.Prompt += " in watercolor"
+		}
+		return next(ctx, call)
+	}
+	server, client := newInterceptGateway(t, inspect)
+
+	var image openapi_types.File
+	image.InitFromBytes([]byte("png-bytes"), "cat.png")
+	response, err := client.CreateImageEdit(context.Background(), Openai, CreateImageEditMultipartBody{Image: image, Prompt: "Add a hat"})
+	require.NoError(t, err)
+	require.Len(t, response.Data, 1)
+	assert.Equal(t, "Add a hat", prompt)
+	assert.Equal(t, []string{"/v1/images/edits?openai"}, requestPaths(server))
+}
+
+func TestInterceptors_WrongTypes(t *testing.T) {
+	wrongRequest := func(ctx context.Context, call *Call, next Invoker) (any, error) {
+		call.Request = &CreateMessagesRequest{}
+		return next(ctx, call)
+	}
+	server, client := newInterceptGateway(t, wrongRequest)
+	_, err := client.GenerateContent(context.Background(), Openai, "gpt-4o", hello())
+	assert.ErrorContains(t, err, "interceptor passed a *sdk.CreateMessagesRequest request to GenerateContent")
+	assert.Empty(t, requestPaths(server))
+
+	wrongResponse := func(ctx context.Context, call *Call, next Invoker) (any, error) {
+		return "cached", nil
+	}
+	_, client = newInterceptGateway(t, wrongResponse)
+	_, err = client.GenerateContent(context.Background(), Openai, "gpt-4o", hello())
+	assert.ErrorContains(t, err, "interceptor returned a string response from GenerateContent")
+
+	stream, err := client.GenerateContentStream(context.Background(), Openai, "gpt-4o", hello())
+	assert.Error(t, err)
+	_, open := <-stream
+	assert.False(t, open, "streams are closed on errors")
+
+	failing := func(ctx context.Context, call *Call, next Invoker) (any, error) {
+		return nil, assert.AnError
+	}
+	_, client = newInterceptGateway(t, failing)
+	assert.ErrorIs(t, client.HealthCheck(context.Background()), assert.AnError)
+}
@@ -0,0 +1,272 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"sync"
+	"time"
+
+	resty "github.com/go-resty/resty/v2"
+)
+
+// RequestIDHeader is the response header that carries the gateway's ID of a
+// request.
+const RequestIDHeader = "X-Request-Id"
+
+// Redactor masks sensitive text in logged prompts and completions.
+type Redactor func(text string) string
+
+var (
+	apiKeyPattern = regexp.MustCompile(`\b(?:sk|gsk|xai|pk|rk)[-_][A-Za-z0-9_-]{16,}|\bAIza[0-9A-Za-z_-]{35}|(?i:bearer)\s+[A-Za-z0-9._~+/-]{16,}=*`)
+	emailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+)
+
+// RedactAPIKeys masks API keys of the common providers and bearer tokens.
+func RedactAPIKeys(text string) string {
+	return apiKeyPattern.ReplaceAllString(text, "[REDACTED]")
+}
+
+// RedactEmails masks email addresses.
+func RedactEmails(text string) string {
+	return emailPattern.ReplaceAllString(text, "[EMAIL]")
+}
+
+// RedactPattern returns a Redactor that replaces the matches of pattern
+// with replacement, which may refer to submatches as in
+// regexp.Regexp.ReplaceAllString.
+func RedactPattern(pattern *regexp.Regexp, replacement string) Redactor {
+	return func(text string) string {
+		return pattern.ReplaceAllString(text, replacement)
+	}
+}
+
+// LoggingOptions configures what ClientOptions.Logger logs besides the
+// outcome of each call.
+type LoggingOptions struct {
+	// Content adds the prompt and completion of chat, Messages, Responses
+	// and image calls to their records. Off by default.
+	Content bool
+	// Redactors are applied in order to logged prompts and completions.
+	Redactors []Redactor
+}
+
+// redact applies the redactors to text.
+func (o LoggingOptions) redact(text string) string {
+	for _, redactor := range o.Redactors {
+		text = redactor(text)
+	}
+	return text
+}
+
+type callLogContextKey struct{}
+
+// callLog collects the record of one call. Hedged calls record their
+// attempts from several goroutines.
+type callLog struct {
+	logger    *slog.Logger
+	options   LoggingOptions
+	operation Operation
+	endpoint  string
+	provider  Provider
+	start     time.Time
+
+	mu        sync.Mutex
+	model     string
+	attempts  int
+	status    int
+	requestID string
+}
+
+// loggedCall returns the callLog of the call made with ctx, if the client
+// logs.
+func loggedCall(ctx context.Context) *callLog {
+	call, _ := ctx.Value(callLogContextKey{}).(*callLog)
+	return call
+}
+
+// logged wraps invoke so that it logs one record per call, once the
+// response is read or, for streams, once the stream ends.
+func logged[Req, Resp any](c *clientImpl, operation Operation, endpoint string, invoke func(ctx context.Context, provider Provider, request *Req) (Resp, error)) func(ctx context.Context, provider Provider, request *Req) (Resp, error) {
+	if c.logger == nil {
+		return invoke
+	}
+	return func(ctx context.Context, provider Provider, request *Req) (Resp, error) {
+		call := &callLog{
+			logger:    c.logger,
+			options:   c.logging,
+			operation: operation,
+			endpoint:  endpoint,
+			provider:  provider,
+			model:     callModel(request),
+			start:     time.Now(),
+		}
+		response, err := invoke(context.WithValue(ctx, callLogContextKey{}, call), provider, request)
+		if events, ok := any(response).(<-chan SSEvent); ok && err == nil {
+			events = WatchStream(ctx, events, operation.API(), func(result *GenerateResponse) {
+				call.log(context.WithoutCancel(ctx), request, result, nil)
+			})
+			return any(events).(Resp), nil
+		}
+		call.log(ctx, request, response, err)
+		return response, err
+	}
+}
+
+// setProvider records the provider the call was sent to, which interceptors
+// may have changed.
+func (l *callLog) setProvider(provider Provider) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.provider = provider
+}
+
+// setModel records the model the call was sent to.
+func (l *callLog) setModel(model string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.model = model
+}
+
+// observe wraps an attempt's request to record its status and request ID.
+func (l *callLog) observe(request func() (*resty.Response, error)) func() (*resty.Response, error) {
+	if l == nil {
+		return request
+	}
+	return func() (*resty.Response, error) {
+		resp, err := request()
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.attempts++
+		if resp != nil && resp.RawResponse != nil {
+			l.status = resp.StatusCode()
+			l.requestID = resp.Header().Get(RequestIDHeader)
+		}
+		return resp, err
+	}
+}
+
+// attrs returns the attributes identifying the call and its last attempt.
+func (l *callLog) attrs() []slog.Attr {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	attrs := []slog.Attr{
+		slog.String("operation", string(l.operation)),
+		slog.String("endpoint", l.endpoint),
+	}
+	if l.provider != "" {
+		attrs = append(attrs, slog.String("provider", string(l.provider)))
+	}
+	if l.model != "" {
+		attrs = append(attrs, slog.String("model", l.model))
+	}
+	if l.status != 0 {
+		attrs = append(attrs, slog.Int("status", l.status))
+	}
+	attrs = append(attrs, slog.Int("attempts", l.attempts))
+	if l.requestID != "" {
+		attrs = append(attrs, slog.String("request_id", l.requestID))
+	}
+	return attrs
+}
+
+// retry logs that the call is retried after delay.
+func (l *callLog) retry(ctx context.Context, attempt int, err error, delay time.Duration) {
+	if l == nil {
+		return
+	}
+	attrs := append(l.attrs(), slog.Int("retry", attempt), slog.Duration("delay", delay))
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	l.logger.LogAttrs(ctx, slog.LevelWarn, "retrying gateway call", attrs...)
+}
+
+// log writes the record of the finished call: at error level if it
+// failed, otherwise at info level.
+func (l *callLog) log(ctx context.Context, request, response any, err error) {
+	attrs := append(l.attrs(), slog.Duration("latency", time.Since(l.start)))
+
+	var result *GenerateResponse
+	if err == nil {
+		result = ResultOf(response)
+	}
+	if result != nil && result.Usage != (GenerateUsage{}) {
+		attrs = append(attrs, slog.Group("usage",
+			slog.Int64("input_tokens", result.Usage.InputTokens),
+			slog.Int64("output_tokens", result.Usage.OutputTokens),
+			slog.Int64("total_tokens", result.Usage.TotalTokens),
+		))
+	}
+	if l.options.Content {
+		if prompt := callPrompt(request); prompt != "" {
+			attrs = append(attrs, slog.String("prompt", l.options.redact(prompt)))
+		}
+		if result != nil && result.Text != "" {
+			attrs = append(attrs, slog.String("completion", l.options.redact(result.Text)))
+		}
+	}
+
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		l.logger.LogAttrs(ctx, slog.LevelError, "gateway call failed", attrs...)
+		return
+	}
+	l.logger.LogAttrs(ctx, slog.LevelInfo, "gateway call", attrs...)
+}
+
+// callModel returns the model of a request, until the call records the
+// model it was sent to.
+func callModel(request any) string {
+	switch request := request.(type) {
+	case *CreateChatCompletionRequest:
+		return request.Model
+	case *CreateMessagesRequest:
+		return request.Model
+	case *CreateResponseRequest:
+		return request.Model
+	case *CreateImageRequest:
+		if request.Model != nil {
+			return *request.Model
+		}
+	}
+	return ""
+}
+
+// callPrompt returns the prompt of a request as logged: the prompt of
+// image requests and the JSON of the messages of the others.
+func callPrompt(request any) string {
+	var prompt any
+	switch request := request.(type) {
+	case *CreateChatCompletionRequest:
+		prompt = request.Messages
+	case *CreateMessagesRequest:
+		prompt = struct {
+			System   *CreateMessagesRequest_System `json:"system,omitempty"`
+			Messages []MessagesMessage             `json:"messages"`
+		}{request.System, request.Messages}
+	case *CreateResponseRequest:
+		prompt = struct {
+			Instructions *string       `json:"instructions,omitempty"`
+			Input        ResponseInput `json:"input"`
+		}{request.Instructions, request.Input}
+	case *CreateImageRequest:
+		return request.Prompt
+	case *CreateImageEditMultipartBody:
+		return request.Prompt
+	default:
+		return ""
+	}
+	data, err := json.Marshal(prompt)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
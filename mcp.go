@@ -0,0 +1,818 @@
+package sdk
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MCPProtocolVersion is the Model Context Protocol revision the MCP client
+// requests during the initialize handshake.
+const MCPProtocolVersion = "2025-06-18"
+
+const (
+	mcpDefaultTimeout = 30 * time.Second
+	mcpClientName     = "inference-gateway-sdk"
+	mcpSessionHeader  = "Mcp-Session-Id"
+	mcpVersionHeader  = "MCP-Protocol-Version"
+)
+
+// errMCPDisconnected marks transport failures after which the MCP client
+// reconnects and repeats the initialize handshake.
+var errMCPDisconnected = errors.New("mcp: server disconnected")
+
+// MCPClientOptions configures an MCPClient.
+type MCPClientOptions struct {
+	// Name identifies the server; it is reported as MCPTool.Server. Defaults
+	// to the name the server announces during initialize.
+	Name string
+	// Timeout bounds each JSON-RPC request, including the initialize
+	// handshake. Defaults to 30 seconds.
+	Timeout time.Duration
+	// MaxReconnects is how many times a request is retried on a fresh
+	// connection after the server disconnects. Defaults to 1; set a negative
+	// value to disable reconnecting.
+	MaxReconnects int
+	// Headers are sent with every request to a streamable HTTP server.
+	Headers map[string]string
+	// HTTPClient overrides the HTTP client used for streamable HTTP servers.
+	HTTPClient *http.Client
+	// Env is appended to the current environment of a stdio server process.
+	Env []string
+	// Stderr receives the stdio server's standard error. Discarded when nil.
+	Stderr io.Writer
+	// RepairArguments lets HandleToolCall fix malformed tool call arguments
+	// (see RepairJSON) instead of rejecting them.
+	RepairArguments bool
+}
+
+// MCPError is a JSON-RPC error returned by an MCP server.
+type MCPError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *MCPError) Error() string {
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+// MCPContent is a single content item of a tool result.
+type MCPContent struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	// Resource holds embedded resource contents for `resource` items.
+	Resource map[string]any `json:"resource,omitempty"`
+}
+
+// MCPToolResult is the result of a tools/call request.
+type MCPToolResult struct {
+	Content           []MCPContent   `json:"content"`
+	StructuredContent map[string]any `json:"structuredContent,omitempty"`
+	IsError           bool           `json:"isError,omitempty"`
+}
+
+// Text renders the result as a single string: text items are joined with
+// newlines and any other item is included as JSON.
+func (r *MCPToolResult) Text() string {
+	parts := make([]string, 0, len(r.Content))
+	for _, item := range r.Content {
+		if item.Type == "text" {
+			parts = append(parts, item.Text)
+			continue
+		}
+		data, err := json.Marshal(item)
+		if err != nil {
+			continue
+		}
+		parts = append(parts, string(data))
+	}
+	if len(parts) == 0 && r.StructuredContent != nil {
+		if data, err := json.Marshal(r.StructuredContent); err == nil {
+			parts = append(parts, string(data))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// MCPServerInfo describes the server as reported by the initialize handshake.
+type MCPServerInfo struct {
+	Name            string         `json:"name"`
+	Version         string         `json:"version"`
+	ProtocolVersion string         `json:"-"`
+	Capabilities    map[string]any `json:"-"`
+}
+
+// jsonrpcMessage is a JSON-RPC 2.0 request, notification or response.
+type jsonrpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  any             `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *MCPError       `json:"error,omitempty"`
+}
+
+// mcpTransport moves JSON-RPC messages to and from one MCP server.
+type mcpTransport interface {
+	// start opens the connection; it is called again after a disconnect.
+	start(ctx context.Context) error
+	// call sends a request and waits for the response with the same ID.
+	call(ctx context.Context, msg jsonrpcMessage) (jsonrpcMessage, error)
+	// notify sends a notification, which has no response.
+	notify(ctx context.Context, msg jsonrpcMessage) error
+	close() error
+}
+
+// MCPClient talks to a single MCP server over stdio or streamable HTTP. It
+// performs the initialize handshake lazily on first use, reconnects when the
+// server goes away and is safe for concurrent use.
+//
+// Example:
+//
+//	fs := sdk.NewMCPStdioClient("npx", []string{"-y", "@modelcontextprotocol/server-filesystem", "."}, nil)
+//	defer fs.Close()
+//
+//	tools, err := fs.ChatCompletionTools(ctx)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	resp, err := client.WithTools(&tools).GenerateContent(ctx, sdk.Openai, "gpt-4o", messages)
+//	for _, call := range *resp.Choices[0].Message.ToolCalls {
+//		toolMessage, err := fs.HandleToolCall(ctx, call)
+//		...
+//	}
+type MCPClient struct {
+	transport mcpTransport
+	options   MCPClientOptions
+	nextID    atomic.Int64
+
+	mu          sync.Mutex
+	initialized bool
+	closed      bool
+	serverInfo  MCPServerInfo
+	schemas     map[string]*FunctionParameters
+}
+
+// NewMCPStdioClient creates an MCP client for a local server started as a
+// subprocess that speaks newline-delimited JSON-RPC on stdin and stdout.
+func NewMCPStdioClient(command string, args []string, options *MCPClientOptions) *MCPClient {
+	opts := mcpOptions(options)
+	return &MCPClient{
+		transport: &mcpStdioTransport{command: command, args: args, env: opts.Env, stderr: opts.Stderr},
+		options:   opts,
+	}
+}
+
+// NewMCPHTTPClient creates an MCP client for a server reachable through the
+// streamable HTTP transport at url.
+func NewMCPHTTPClient(url string, options *MCPClientOptions) *MCPClient {
+	opts := mcpOptions(options)
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	return &MCPClient{
+		transport: &mcpHTTPTransport{url: url, headers: opts.Headers, client: httpClient},
+		options:   opts,
+	}
+}
+
+// mcpOptions copies options and fills in defaults.
+func mcpOptions(options *MCPClientOptions) MCPClientOptions {
+	var opts MCPClientOptions
+	if options != nil {
+		opts = *options
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = mcpDefaultTimeout
+	}
+	if opts.MaxReconnects == 0 {
+		opts.MaxReconnects = 1
+	}
+	return opts
+}
+
+// Initialize connects to the server and performs the initialize handshake.
+// Calling it is optional; every other method initializes on demand.
+func (c *MCPClient) Initialize(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.initializeLocked(ctx)
+}
+
+// ServerInfo returns what the server reported during initialize.
+func (c *MCPClient) ServerInfo(ctx context.Context) (MCPServerInfo, error) {
+	if err := c.Initialize(ctx); err != nil {
+		return MCPServerInfo{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.serverInfo, nil
+}
+
+func (c *MCPClient) initializeLocked(ctx context.Context) error {
+	if c.closed {
+		return fmt.Errorf("mcp: client is closed")
+	}
+	if c.initialized {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.options.Timeout)
+	defer cancel()
+
+	if err := c.transport.start(ctx); err != nil {
+		return fmt.Errorf("mcp: failed to connect: %w", err)
+	}
+
+	resp, err := c.transport.call(ctx, c.request("initialize", map[string]any{
+		"protocolVersion": MCPProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": mcpClientName, "version": sdkVersion()},
+	}))
+	if err != nil {
+		_ = c.transport.close()
+		return fmt.Errorf("mcp: initialize failed: %w", err)
+	}
+	if resp.Error != nil {
+		_ = c.transport.close()
+		return fmt.Errorf("mcp: initialize failed: %w", resp.Error)
+	}
+
+	var result struct {
+		ProtocolVersion string         `json:"protocolVersion"`
+		Capabilities    map[string]any `json:"capabilities"`
+		ServerInfo      MCPServerInfo  `json:"serverInfo"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		_ = c.transport.close()
+		return fmt.Errorf("mcp: failed to parse initialize result: %w", err)
+	}
+	result.ServerInfo.ProtocolVersion = result.ProtocolVersion
+	result.ServerInfo.Capabilities = result.Capabilities
+
+	if t, ok := c.transport.(*mcpHTTPTransport); ok {
+		t.setProtocolVersion(result.ProtocolVersion)
+	}
+
+	if err := c.transport.notify(ctx, jsonrpcMessage{JSONRPC: "2.0", Method: "notifications/initialized"}); err != nil {
+		_ = c.transport.close()
+		return fmt.Errorf("mcp: initialized notification failed: %w", err)
+	}
+
+	c.serverInfo = result.ServerInfo
+	c.initialized = true
+	return nil
+}
+
+// request builds a JSON-RPC request with a fresh ID.
+func (c *MCPClient) request(method string, params any) jsonrpcMessage {
+	id := c.nextID.Add(1)
+	return jsonrpcMessage{JSONRPC: "2.0", ID: &id, Method: method, Params: params}
+}
+
+// do sends a request, reconnecting and retrying when the server has gone away.
+func (c *MCPClient) do(ctx context.Context, method string, params any, result any) error {
+	var lastErr error
+	for attempt := 0; attempt <= max(c.options.MaxReconnects, 0); attempt++ {
+		if err := c.Initialize(ctx); err != nil {
+			return err
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, c.options.Timeout)
+		resp, err := c.transport.call(callCtx, c.request(method, params))
+		cancel()
+
+		if err == nil {
+			if resp.Error != nil {
+				return resp.Error
+			}
+			if result == nil {
+				return nil
+			}
+			if err := json.Unmarshal(resp.Result, result); err != nil {
+				return fmt.Errorf("mcp: failed to parse %s result: %w", method, err)
+			}
+			return nil
+		}
+
+		lastErr = err
+		if !errors.Is(err, errMCPDisconnected) || ctx.Err() != nil {
+			break
+		}
+		c.reset()
+	}
+	return fmt.Errorf("mcp: %s failed: %w", method, lastErr)
+}
+
+// reset drops the current connection so the next request reconnects.
+func (c *MCPClient) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.transport.close()
+	c.initialized = false
+}
+
+// ListTools returns every tool the server exposes, following pagination.
+// Each tool's Server field is set to MCPClientOptions.Name, or to the name
+// the server reported when no name was configured.
+func (c *MCPClient) ListTools(ctx context.Context) ([]MCPTool, error) {
+	var tools []MCPTool
+	var cursor string
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+
+		var page struct {
+			Tools []struct {
+				Name        string          `json:"name"`
+				Description string          `json:"description"`
+				InputSchema *map[string]any `json:"inputSchema"`
+			} `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.do(ctx, "tools/list", params, &page); err != nil {
+			return nil, err
+		}
+
+		server := c.serverName()
+		for _, tool := range page.Tools {
+			c.rememberSchema(tool.Name, tool.InputSchema)
+			tools = append(tools, MCPTool{
+				Name:        tool.Name,
+				Description: tool.Description,
+				InputSchema: tool.InputSchema,
+				Server:      server,
+			})
+		}
+
+		if page.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// rememberSchema records a tool's input schema for HandleToolCall.
+func (c *MCPClient) rememberSchema(name string, schema *map[string]any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.schemas == nil {
+		c.schemas = make(map[string]*FunctionParameters)
+	}
+	var params *FunctionParameters
+	if schema != nil {
+		p := FunctionParameters(*schema)
+		params = &p
+	}
+	c.schemas[name] = params
+}
+
+func (c *MCPClient) serverName() string {
+	if c.options.Name != "" {
+		return c.options.Name
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.serverInfo.Name
+}
+
+// CallTool invokes a tool by name. A tool that ran but failed is reported
+// through MCPToolResult.IsError rather than an error.
+func (c *MCPClient) CallTool(ctx context.Context, name string, arguments map[string]any) (*MCPToolResult, error) {
+	if arguments == nil {
+		arguments = map[string]any{}
+	}
+	var result MCPToolResult
+	if err := c.do(ctx, "tools/call", map[string]any{"name": name, "arguments": arguments}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ChatCompletionTools lists the server's tools as function tools ready to
+// pass to Client.WithTools.
+func (c *MCPClient) ChatCompletionTools(ctx context.Context) ([]ChatCompletionTool, error) {
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ChatCompletionTool, 0, len(tools))
+	for _, tool := range tools {
+		function := FunctionObject{Name: tool.Name}
+		if tool.Description != "" {
+			function.Description = new(tool.Description)
+		}
+		if tool.InputSchema != nil {
+			params := FunctionParameters(*tool.InputSchema)
+			function.Parameters = &params
+		}
+		result = append(result, ChatCompletionTool{Type: Function, Function: function})
+	}
+	return result, nil
+}
+
+// HandleToolCall executes a tool call returned by the model and returns the
+// tool message to append to the conversation. Arguments are checked against
+// the tool's input schema when ListTools or ChatCompletionTools has been
+// called; invalid arguments are answered with ToolArgumentsError.ToolMessage
+// without contacting the server. Tool failures become the message content so
+// the model can react to them; only protocol and transport failures are
+// returned as errors.
+func (c *MCPClient) HandleToolCall(ctx context.Context, call ChatCompletionMessageToolCall) (Message, error) {
+	c.mu.Lock()
+	schema := c.schemas[call.Function.Name]
+	c.mu.Unlock()
+
+	arguments, err := ValidateToolArguments(call.Function.Arguments, schema, &ToolArgumentsOptions{Repair: c.options.RepairArguments})
+	if err != nil {
+		var argErr *ToolArgumentsError
+		if errors.As(err, &argErr) {
+			argErr.ToolCallID = call.ID
+			argErr.Name = call.Function.Name
+			return argErr.ToolMessage(), nil
+		}
+		return Message{}, err
+	}
+
+	result, err := c.CallTool(ctx, call.Function.Name, arguments)
+	if err != nil {
+		var rpcErr *MCPError
+		if errors.As(err, &rpcErr) {
+			return toolMessage(call.ID, "Error: "+rpcErr.Message), nil
+		}
+		return Message{}, err
+	}
+
+	text := result.Text()
+	if result.IsError {
+		text = "Error: " + text
+	}
+	return toolMessage(call.ID, text), nil
+}
+
+// Close shuts down the connection and, for stdio servers, the subprocess.
+func (c *MCPClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	c.initialized = false
+	return c.transport.close()
+}
+
+// toolMessage builds a tool-role message answering the tool call with id.
+func toolMessage(id, content string) Message {
+	return Message{
+		Role:       Tool,
+		Content:    NewMessageContent(content),
+		ToolCallID: &id,
+	}
+}
+
+// sdkVersion reports the version of this module as recorded in the build.
+func sdkVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	if info.Main.Path == "github.com/inference-gateway/sdk" {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == "github.com/inference-gateway/sdk" {
+			return dep.Version
+		}
+	}
+	return "(devel)"
+}
+
+// mcpStdioTransport runs the server as a subprocess and exchanges
+// newline-delimited JSON-RPC messages over its stdin and stdout.
+type mcpStdioTransport struct {
+	command string
+	args    []string
+	env     []string
+	stderr  io.Writer
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	pending map[int64]chan jsonrpcMessage
+	done    chan struct{}
+}
+
+func (t *mcpStdioTransport) start(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cmd := exec.Command(t.command, t.args...)
+	if len(t.env) > 0 {
+		cmd.Env = append(os.Environ(), t.env...)
+	}
+	cmd.Stderr = t.stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	t.cmd = cmd
+	t.stdin = stdin
+	t.pending = make(map[int64]chan jsonrpcMessage)
+	t.done = make(chan struct{})
+
+	go t.readLoop(stdout, t.done)
+	return nil
+}
+
+// readLoop dispatches responses to their waiting callers until stdout closes.
+func (t *mcpStdioTransport) readLoop(stdout io.Reader, done chan struct{}) {
+	defer close(done)
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var msg jsonrpcMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil || msg.ID == nil || msg.Method != "" {
+			// Server-initiated requests and notifications are not supported.
+			continue
+		}
+
+		t.mu.Lock()
+		ch, ok := t.pending[*msg.ID]
+		delete(t.pending, *msg.ID)
+		t.mu.Unlock()
+		if ok {
+			ch <- msg
+		}
+	}
+}
+
+func (t *mcpStdioTransport) write(msg jsonrpcMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	stdin := t.stdin
+	t.mu.Unlock()
+	if stdin == nil {
+		return errMCPDisconnected
+	}
+	if _, err := stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("%w: %v", errMCPDisconnected, err)
+	}
+	return nil
+}
+
+func (t *mcpStdioTransport) call(ctx context.Context, msg jsonrpcMessage) (jsonrpcMessage, error) {
+	ch := make(chan jsonrpcMessage, 1)
+
+	t.mu.Lock()
+	if t.pending == nil {
+		t.mu.Unlock()
+		return jsonrpcMessage{}, errMCPDisconnected
+	}
+	t.pending[*msg.ID] = ch
+	done := t.done
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, *msg.ID)
+		t.mu.Unlock()
+	}()
+
+	if err := t.write(msg); err != nil {
+		return jsonrpcMessage{}, err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-done:
+		return jsonrpcMessage{}, errMCPDisconnected
+	case <-ctx.Done():
+		return jsonrpcMessage{}, ctx.Err()
+	}
+}
+
+func (t *mcpStdioTransport) notify(_ context.Context, msg jsonrpcMessage) error {
+	return t.write(msg)
+}
+
+func (t *mcpStdioTransport) close() error {
+	t.mu.Lock()
+	cmd, stdin, done := t.cmd, t.stdin, t.done
+	t.cmd, t.stdin, t.pending = nil, nil, nil
+	t.mu.Unlock()
+
+	if cmd == nil {
+		return nil
+	}
+
+	_ = stdin.Close()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		_ = cmd.Process.Kill()
+	}
+	_ = cmd.Wait()
+	return nil
+}
+
+// mcpHTTPTransport implements the streamable HTTP transport: every message
+// is POSTed to one endpoint and the response arrives either as a JSON body
+// or as a server-sent event stream.
+type mcpHTTPTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	mu              sync.Mutex
+	sessionID       string
+	protocolVersion string
+}
+
+func (t *mcpHTTPTransport) start(context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sessionID = ""
+	t.protocolVersion = ""
+	return nil
+}
+
+func (t *mcpHTTPTransport) setProtocolVersion(version string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.protocolVersion = version
+}
+
+func (t *mcpHTTPTransport) post(ctx context.Context, msg jsonrpcMessage) (*http.Response, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set(mcpSessionHeader, t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		req.Header.Set(mcpVersionHeader, t.protocolVersion)
+	}
+	hadSession := t.sessionID != ""
+	t.mu.Unlock()
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", errMCPDisconnected, err)
+	}
+
+	if sessionID := resp.Header.Get(mcpSessionHeader); sessionID != "" {
+		t.mu.Lock()
+		t.sessionID = sessionID
+		t.mu.Unlock()
+	}
+
+	if resp.StatusCode == http.StatusNotFound && hadSession {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("%w: session expired", errMCPDisconnected)
+	}
+	if resp.StatusCode >= 400 {
+		data, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		errMsg := fmt.Sprintf("mcp request failed with status: %d", resp.StatusCode)
+		if len(data) > 0 {
+			errMsg = fmt.Sprintf("%s, response body: %s", errMsg, string(data))
+		}
+		return nil, fmt.Errorf("%s", errMsg)
+	}
+	return resp, nil
+}
+
+func (t *mcpHTTPTransport) call(ctx context.Context, msg jsonrpcMessage) (jsonrpcMessage, error) {
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return jsonrpcMessage{}, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return readMCPEventStream(resp.Body, *msg.ID)
+	}
+
+	var result jsonrpcMessage
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return jsonrpcMessage{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return result, nil
+}
+
+// readMCPEventStream reads SSE events until the response to id arrives.
+func readMCPEventStream(body io.Reader, id int64) (jsonrpcMessage, error) {
+	reader := bufio.NewReader(body)
+	var data strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		case line == "" && data.Len() > 0:
+			var msg jsonrpcMessage
+			if jsonErr := json.Unmarshal([]byte(data.String()), &msg); jsonErr == nil && msg.ID != nil && *msg.ID == id && msg.Method == "" {
+				return msg, nil
+			}
+			data.Reset()
+		}
+
+		if err != nil {
+			if err == io.EOF {
+				return jsonrpcMessage{}, fmt.Errorf("%w: event stream ended before response", errMCPDisconnected)
+			}
+			return jsonrpcMessage{}, fmt.Errorf("%w: %v", errMCPDisconnected, err)
+		}
+	}
+}
+
+func (t *mcpHTTPTransport) notify(ctx context.Context, msg jsonrpcMessage) error {
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// close ends the session with a DELETE, as the transport recommends.
+func (t *mcpHTTPTransport) close() error {
+	t.mu.Lock()
+	sessionID, protocolVersion := t.sessionID, t.protocolVersion
+	t.sessionID = ""
+	t.mu.Unlock()
+
+	if sessionID == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(mcpSessionHeader, sessionID)
+	if protocolVersion != "" {
+		req.Header.Set(mcpVersionHeader, protocolVersion)
+	}
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil
+	}
+	return resp.Body.Close()
+}
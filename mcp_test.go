@@ -0,0 +1,338 @@
+package sdk
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// fakeMCPServer answers a single JSON-RPC message the way a minimal MCP
+// server would. It returns nil for notifications and for the `hang` tool.
+func fakeMCPServer(msg jsonrpcMessage) *jsonrpcMessage {
+	if msg.ID == nil {
+		return nil
+	}
+	resp := &jsonrpcMessage{JSONRPC: "2.0", ID: msg.ID}
+	params, _ := msg.Params.(map[string]any)
+
+	switch msg.Method {
+	case "initialize":
+		resp.Result = json.RawMessage(`{"protocolVersion":"2025-06-18","capabilities":{"tools":{}},"serverInfo":{"name":"fake","version":"0.0.1"}}`)
+	case "tools/list":
+		if params["cursor"] == nil {
+			resp.Result = json.RawMessage(`{"tools":[{"name":"echo","description":"Echo text back","inputSchema":{"type":"object","properties":{"text":{"type":"string"}},"required":["text"]}}],"nextCursor":"page-2"}`)
+		} else {
+			resp.Result = json.RawMessage(`{"tools":[{"name":"fail","description":"Always fails","inputSchema":{"type":"object"}}]}`)
+		}
+	case "tools/call":
+		args, _ := params["arguments"].(map[string]any)
+		switch params["name"] {
+		case "echo":
+			data, _ := json.Marshal(map[string]any{"content": []map[string]any{{"type": "text", "text": args["text"]}}})
+			resp.Result = data
+		case "fail":
+			resp.Result = json.RawMessage(`{"content":[{"type":"text","text":"boom"}],"isError":true}`)
+		case "hang":
+			return nil
+		case "exit":
+			os.Exit(0)
+		default:
+			resp.Error = &MCPError{Code: -32602, Message: fmt.Sprintf("unknown tool: %v", params["name"])}
+		}
+	default:
+		resp.Error = &MCPError{Code: -32601, Message: "method not found"}
+	}
+	return resp
+}
+
+// TestMCPHelperProcess is not a real test: it is re-executed as a stdio MCP
+// server subprocess by the stdio tests.
+func TestMCPHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_MCP_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	encoder := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		var msg jsonrpcMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		if resp := fakeMCPServer(msg); resp != nil {
+			_ = encoder.Encode(resp)
+		}
+	}
+	os.Exit(0)
+}
+
+func newStdioTestClient(options *MCPClientOptions) *MCPClient {
+	if options == nil {
+		options = &MCPClientOptions{}
+	}
+	options.Env = append(options.Env, "GO_WANT_MCP_HELPER_PROCESS=1")
+	return NewMCPStdioClient(os.Args[0], []string{"-test.run=^TestMCPHelperProcess$"}, options)
+}
+
+// fakeMCPHTTPServer serves fakeMCPServer over the streamable HTTP transport,
+// answering tools/call with an event stream and everything else with JSON.
+type fakeMCPHTTPServer struct {
+	mu       sync.Mutex
+	sessions map[string]bool
+	next     int
+	headers  []http.Header
+}
+
+func (s *fakeMCPHTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.headers = append(s.headers, r.Header.Clone())
+
+	if r.Method == http.MethodDelete {
+		delete(s.sessions, r.Header.Get(mcpSessionHeader))
+		return
+	}
+
+	var msg jsonrpcMessage
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if msg.Method == "initialize" {
+		s.next++
+		sessionID := fmt.Sprintf("session-%d", s.next)
+		s.sessions[sessionID] = true
+		w.Header().Set(mcpSessionHeader, sessionID)
+	} else if !s.sessions[r.Header.Get(mcpSessionHeader)] {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	resp := fakeMCPServer(msg)
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	data, _ := json.Marshal(resp)
+	if msg.Method == "tools/call" {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+		_, _ = fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+func (s *fakeMCPHTTPServer) expireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = map[string]bool{}
+}
+
+func newHTTPTestServer(t *testing.T) (*fakeMCPHTTPServer, *httptest.Server) {
+	fake := &fakeMCPHTTPServer{sessions: map[string]bool{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func TestMCPStdioClient(t *testing.T) {
+	client := newStdioTestClient(nil)
+	defer func() {
+		assert.NoError(t, client.Close())
+	}()
+	ctx := context.Background()
+
+	info, err := client.ServerInfo(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "fake", info.Name)
+	assert.Equal(t, MCPProtocolVersion, info.ProtocolVersion)
+
+	tools, err := client.ListTools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 2, "tools from both pages should be returned")
+	assert.Equal(t, "echo", tools[0].Name)
+	assert.Equal(t, "fake", tools[0].Server)
+	assert.Equal(t, "fail", tools[1].Name)
+
+	result, err := client.CallTool(ctx, "echo", map[string]any{"text": "hello"})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Equal(t, "hello", result.Text())
+
+	result, err = client.CallTool(ctx, "fail", nil)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+
+	_, err = client.CallTool(ctx, "missing", nil)
+	var rpcErr *MCPError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, -32602, rpcErr.Code)
+}
+
+func TestMCPStdioClient_ReconnectsAfterCrash(t *testing.T) {
+	client := newStdioTestClient(&MCPClientOptions{Timeout: 5 * time.Second})
+	defer func() {
+		assert.NoError(t, client.Close())
+	}()
+	ctx := context.Background()
+
+	_, err := client.CallTool(ctx, "exit", nil)
+	require.Error(t, err, "a server that dies on every attempt should surface an error")
+
+	result, err := client.CallTool(ctx, "echo", map[string]any{"text": "back again"})
+	require.NoError(t, err)
+	assert.Equal(t, "back again", result.Text())
+}
+
+func TestMCPStdioClient_Timeout(t *testing.T) {
+	client := newStdioTestClient(&MCPClientOptions{Timeout: 200 * time.Millisecond})
+	defer func() {
+		assert.NoError(t, client.Close())
+	}()
+
+	start := time.Now()
+	_, err := client.CallTool(context.Background(), "hang", nil)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestMCPHTTPClient(t *testing.T) {
+	fake, server := newHTTPTestServer(t)
+	client := NewMCPHTTPClient(server.URL, &MCPClientOptions{
+		Name:    "files",
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+	})
+	ctx := context.Background()
+
+	tools, err := client.ListTools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 2)
+	assert.Equal(t, "files", tools[0].Server, "configured name should win over the server's")
+
+	result, err := client.CallTool(ctx, "echo", map[string]any{"text": "over http"})
+	require.NoError(t, err)
+	assert.Equal(t, "over http", result.Text())
+
+	require.NoError(t, client.Close())
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	require.NotEmpty(t, fake.headers)
+	assert.Empty(t, fake.headers[0].Get(mcpSessionHeader), "initialize must not carry a session")
+	last := fake.headers[len(fake.headers)-1]
+	assert.Equal(t, "session-1", last.Get(mcpSessionHeader))
+	assert.Equal(t, MCPProtocolVersion, last.Get(mcpVersionHeader))
+	assert.Equal(t, "Bearer secret", last.Get("Authorization"))
+	assert.Empty(t, fake.sessions, "Close should delete the session")
+}
+
+func TestMCPHTTPClient_ReconnectsOnExpiredSession(t *testing.T) {
+	fake, server := newHTTPTestServer(t)
+	client := NewMCPHTTPClient(server.URL, nil)
+	ctx := context.Background()
+
+	_, err := client.CallTool(ctx, "echo", map[string]any{"text": "first"})
+	require.NoError(t, err)
+
+	fake.expireSessions()
+
+	result, err := client.CallTool(ctx, "echo", map[string]any{"text": "second"})
+	require.NoError(t, err)
+	assert.Equal(t, "second", result.Text())
+	assert.Equal(t, 2, fake.next, "client should have re-initialized once")
+}
+
+func TestMCPClient_ToolIntegration(t *testing.T) {
+	_, server := newHTTPTestServer(t)
+	client := NewMCPHTTPClient(server.URL, nil)
+	ctx := context.Background()
+
+	tools, err := client.ChatCompletionTools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 2)
+	assert.Equal(t, Function, tools[0].Type)
+	assert.Equal(t, "echo", tools[0].Function.Name)
+	require.NotNil(t, tools[0].Function.Description)
+	assert.Equal(t, "Echo text back", *tools[0].Function.Description)
+	require.NotNil(t, tools[0].Function.Parameters)
+	assert.Equal(t, "object", (*tools[0].Function.Parameters)["type"])
+
+	tests := []struct {
+		name     string
+		call     ChatCompletionMessageToolCall
+		expected string
+	}{
+		{
+			name:     "successful call",
+			call:     ChatCompletionMessageToolCall{ID: "call_1", Type: Function, Function: ChatCompletionMessageToolCallFunction{Name: "echo", Arguments: `{"text":"hi"}`}},
+			expected: "hi",
+		},
+		{
+			name:     "tool reports an error",
+			call:     ChatCompletionMessageToolCall{ID: "call_2", Type: Function, Function: ChatCompletionMessageToolCallFunction{Name: "fail", Arguments: `{}`}},
+			expected: "Error: boom",
+		},
+		{
+			name:     "unknown tool",
+			call:     ChatCompletionMessageToolCall{ID: "call_3", Type: Function, Function: ChatCompletionMessageToolCallFunction{Name: "missing", Arguments: `{}`}},
+			expected: "Error: unknown tool: missing",
+		},
+		{
+			name:     "invalid JSON arguments",
+			call:     ChatCompletionMessageToolCall{ID: "call_4", Type: Function, Function: ChatCompletionMessageToolCallFunction{Name: "echo", Arguments: `{"text":`}},
+			expected: `{"error":"invalid_tool_arguments","tool":"echo","message":"invalid arguments for tool \"echo\": unexpected end of JSON input"}`,
+		},
+		{
+			name:     "arguments violate the input schema",
+			call:     ChatCompletionMessageToolCall{ID: "call_5", Type: Function, Function: ChatCompletionMessageToolCallFunction{Name: "echo", Arguments: `{"text":42}`}},
+			expected: `{"error":"invalid_tool_arguments","tool":"echo","message":"invalid arguments for tool \"echo\": schema validation failed: $.text: expected string, got integer","violations":[{"path":"$.text","message":"expected string, got integer"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := client.HandleToolCall(ctx, tt.call)
+			require.NoError(t, err)
+			assert.Equal(t, Tool, msg.Role)
+			require.NotNil(t, msg.ToolCallID)
+			assert.Equal(t, tt.call.ID, *msg.ToolCallID)
+			content, err := msg.Content.AsMessageContent0()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, content)
+		})
+	}
+}
+
+func TestMCPClient_RepairArguments(t *testing.T) {
+	_, server := newHTTPTestServer(t)
+	client := NewMCPHTTPClient(server.URL, &MCPClientOptions{RepairArguments: true})
+	ctx := context.Background()
+
+	_, err := client.ListTools(ctx)
+	require.NoError(t, err)
+
+	msg, err := client.HandleToolCall(ctx, ChatCompletionMessageToolCall{
+		ID:       "call_1",
+		Type:     Function,
+		Function: ChatCompletionMessageToolCallFunction{Name: "echo", Arguments: `{'text': 'fixed',`},
+	})
+	require.NoError(t, err)
+	content, err := msg.Content.AsMessageContent0()
+	require.NoError(t, err)
+	assert.Equal(t, "fixed", content)
+}
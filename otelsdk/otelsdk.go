@@ -0,0 +1,543 @@
+// Package otelsdk instruments sdk clients with OpenTelemetry, following the
+// GenAI semantic conventions.
+//
+// It creates a client span per call, with the provider, models, request
+// parameters, token usage and finish reasons as attributes, and an event
+// per retry or hedged attempt. It records the duration of calls, the time
+// to the first chunk of streams and the tokens used as metrics.
+//
+// Example:
+//
+//	instrumentation, err := otelsdk.New(nil) // global providers
+//	if err != nil {
+//		return err
+//	}
+//	options := &sdk.ClientOptions{BaseURL: "http://localhost:8080/v1"}
+//	instrumentation.Instrument(options)
+//	client := sdk.NewClient(options)
+package otelsdk
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sdk "github.com/inference-gateway/sdk"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/semconv/v1.40.0/genaiconv"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the tracer and meter.
+const ScopeName = "github.com/inference-gateway/sdk/otelsdk"
+
+const (
+	// SystemKey is gen_ai.system, which newer conventions renamed to
+	// gen_ai.provider.name. Both are set for backends that expect either.
+	SystemKey = attribute.Key("gen_ai.system")
+	// OperationKey is the sdk.Operation of a call, which tells the
+	// chat, Messages and Responses APIs apart.
+	OperationKey = attribute.Key("inference_gateway.operation")
+	// AttemptKey is the attempt number on retry and hedge events.
+	AttemptKey = attribute.Key("inference_gateway.attempt")
+)
+
+const (
+	// RetryEvent is added to the span of a call per retried attempt.
+	RetryEvent = "gen_ai.client.retry"
+	// HedgeEvent is added to the span of a call per hedged request, sent
+	// while an earlier request is still in flight.
+	HedgeEvent = "gen_ai.client.hedge"
+)
+
+// Metric names besides the conventions' gen_ai.client.operation.duration
+// and gen_ai.client.token.usage histograms.
+const (
+	// TimeToFirstChunkMetric is a histogram of the seconds to the first
+	// content event of streams.
+	TimeToFirstChunkMetric = "gen_ai.client.operation.time_to_first_chunk"
+	// TokensMetric counts the tokens used, by gen_ai.token.type.
+	TokensMetric = "inference_gateway.client.tokens"
+)
+
+// Options configures the instrumentation.
+type Options struct {
+	// TracerProvider defaults to the global tracer provider.
+	TracerProvider trace.TracerProvider
+	// MeterProvider defaults to the global meter provider.
+	MeterProvider metric.MeterProvider
+	// Propagator injects the span context into requests to the gateway.
+	// Defaults to the global propagator.
+	Propagator propagation.TextMapPropagator
+}
+
+// Instrumentation creates the spans and metrics of instrumented clients. One
+// Instrumentation may instrument many clients.
+type Instrumentation struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+
+	duration         genaiconv.ClientOperationDuration
+	tokenUsage       genaiconv.ClientTokenUsage
+	timeToFirstChunk metric.Float64Histogram
+	tokens           metric.Int64Counter
+}
+
+// New creates an Instrumentation.
+func New(options *Options) (*Instrumentation, error) {
+	var o Options
+	if options != nil {
+		o = *options
+	}
+	if o.TracerProvider == nil {
+		o.TracerProvider = otel.GetTracerProvider()
+	}
+	if o.MeterProvider == nil {
+		o.MeterProvider = otel.GetMeterProvider()
+	}
+	if o.Propagator == nil {
+		o.Propagator = otel.GetTextMapPropagator()
+	}
+
+	meter := o.MeterProvider.Meter(ScopeName)
+	duration, err := genaiconv.NewClientOperationDuration(meter)
+	if err != nil {
+		return nil, err
+	}
+	tokenUsage, err := genaiconv.NewClientTokenUsage(meter)
+	if err != nil {
+		return nil, err
+	}
+	timeToFirstChunk, err := meter.Float64Histogram(TimeToFirstChunkMetric,
+		metric.WithDescription("Time to the first chunk of streamed GenAI operations."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.01, 0.02, 0.04, 0.08, 0.16, 0.32, 0.64, 1.28, 2.56, 5.12, 10.24, 20.48, 40.96, 81.92),
+	)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := meter.Int64Counter(TokensMetric,
+		metric.WithDescription("Number of input and output tokens used."),
+		metric.WithUnit("{token}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Instrumentation{
+		tracer:           o.TracerProvider.Tracer(ScopeName),
+		propagator:       o.Propagator,
+		duration:         duration,
+		tokenUsage:       tokenUsage,
+		timeToFirstChunk: timeToFirstChunk,
+		tokens:           tokens,
+	}, nil
+}
+
+// Instrument adds the instrumentation to client options: Intercept as the
+// outermost interceptor and Transport around the options' transport.
+func (i *Instrumentation) Instrument(options *sdk.ClientOptions) {
+	options.Interceptors = append([]sdk.Interceptor{i.Intercept}, options.Interceptors...)
+	options.Transport = i.Transport(options.Transport)
+}
+
+type callContextKey struct{}
+
+// call is the state of one instrumented call, shared with the attempts the
+// transport sends for it.
+type call struct {
+	span     trace.Span
+	attempts atomic.Int64
+	inFlight atomic.Int64
+
+	mu      sync.Mutex
+	status  int
+	address string
+	port    int
+}
+
+// Intercept is an sdk.Interceptor that traces and measures calls.
+func (i *Instrumentation) Intercept(ctx context.Context, c *sdk.Call, next sdk.Invoker) (any, error) {
+	operation := operationName(c.Operation)
+	model := requestModel(c.Request)
+	provider := providerName(c.Provider)
+
+	name := string(operation)
+	if model != "" {
+		name += " " + model
+	}
+	attrs := []attribute.KeyValue{
+		semconv.GenAIOperationNameKey.String(string(operation)),
+		OperationKey.String(string(c.Operation)),
+	}
+	if provider != "" {
+		attrs = append(attrs, semconv.GenAIProviderNameKey.String(string(provider)), SystemKey.String(string(provider)))
+	}
+	if model != "" {
+		attrs = append(attrs, semconv.GenAIRequestModel(model))
+	}
+	attrs = append(attrs, requestAttributes(c.Request)...)
+
+	ctx, span := i.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	state := &call{span: span}
+	start := time.Now()
+
+	response, err := next(context.WithValue(ctx, callContextKey{}, state), c)
+	if events, ok := response.(<-chan sdk.SSEvent); ok && err == nil {
+		api := c.Operation.API()
+		first := make(chan sdk.SSEvent)
+		go func() {
+			defer close(first)
+			timed := false
+			for event := range events {
+				if !timed && event.Event != nil && *event.Event == sdk.ContentDelta {
+					timed = true
+					attrs := append(i.metricAttributes(state, provider, model, ""), semconv.GenAIOperationNameKey.String(string(operation)))
+					if provider != "" {
+						attrs = append(attrs, semconv.GenAIProviderNameKey.String(string(provider)))
+					}
+					i.timeToFirstChunk.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+				}
+				select {
+				case first <- event:
+				case <-ctx.Done():
+					// Drain so that the client's stream ends.
+					for range events {
+					}
+					return
+				}
+			}
+		}()
+		return sdk.WatchStream(ctx, first, api, func(result *sdk.GenerateResponse) {
+			i.finish(context.WithoutCancel(ctx), state, operation, provider, model, start, streamResult(result), ctx.Err())
+		}), nil
+	}
+	i.finish(ctx, state, operation, provider, model, start, callResult(response), err)
+	return response, err
+}
+
+// result is what a call's span and metrics report of its response.
+type result struct {
+	id            string
+	model         string
+	finishReasons []string
+	usage         *sdk.GenerateUsage
+}
+
+// finish ends the span of a call and records its metrics.
+func (i *Instrumentation) finish(ctx context.Context, state *call, operation genaiconv.OperationNameAttr, provider genaiconv.ProviderNameAttr, model string, start time.Time, res result, err error) {
+	span := state.span
+	defer span.End()
+
+	if res.id != "" {
+		span.SetAttributes(semconv.GenAIResponseID(res.id))
+	}
+	if res.model != "" {
+		span.SetAttributes(semconv.GenAIResponseModel(res.model))
+	}
+	if len(res.finishReasons) > 0 {
+		span.SetAttributes(semconv.GenAIResponseFinishReasons(res.finishReasons...))
+	}
+	if res.usage != nil {
+		span.SetAttributes(
+			semconv.GenAIUsageInputTokens(int(res.usage.InputTokens)),
+			semconv.GenAIUsageOutputTokens(int(res.usage.OutputTokens)),
+		)
+		if res.usage.CachedInputTokens > 0 {
+			span.SetAttributes(semconv.GenAIUsageCacheReadInputTokens(int(res.usage.CachedInputTokens)))
+		}
+		if res.usage.CacheWriteTokens > 0 {
+			span.SetAttributes(semconv.GenAIUsageCacheCreationInputTokens(int(res.usage.CacheWriteTokens)))
+		}
+	}
+	errorType := ""
+	if err != nil {
+		errorType = errorTypeOf(err)
+		span.SetAttributes(semconv.ErrorTypeKey.String(errorType))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	attrs := i.metricAttributes(state, provider, model, res.model)
+	if errorType != "" {
+		attrs = append(attrs, semconv.ErrorTypeKey.String(errorType))
+	}
+	i.duration.Record(ctx, time.Since(start).Seconds(), operation, provider, attrs...)
+	if res.usage == nil {
+		return
+	}
+	for tokenType, count := range map[genaiconv.TokenTypeAttr]int64{
+		genaiconv.TokenTypeInput:  res.usage.InputTokens,
+		genaiconv.TokenTypeOutput: res.usage.OutputTokens,
+	} {
+		i.tokenUsage.Record(ctx, count, operation, provider, tokenType, attrs...)
+		tokenAttrs := slices.Concat(attrs, []attribute.KeyValue{
+			semconv.GenAIOperationNameKey.String(string(operation)),
+			semconv.GenAITokenTypeKey.String(string(tokenType)),
+		})
+		if provider != "" {
+			tokenAttrs = append(tokenAttrs, semconv.GenAIProviderNameKey.String(string(provider)))
+		}
+		i.tokens.Add(ctx, count, metric.WithAttributes(tokenAttrs...))
+	}
+}
+
+// metricAttributes returns the attributes of a call's metrics besides the
+// operation and provider names the conventions' instruments take.
+func (i *Instrumentation) metricAttributes(state *call, provider genaiconv.ProviderNameAttr, requestModel, responseModel string) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if requestModel != "" {
+		attrs = append(attrs, semconv.GenAIRequestModel(requestModel))
+	}
+	if responseModel != "" {
+		attrs = append(attrs, semconv.GenAIResponseModel(responseModel))
+	}
+	if provider != "" {
+		attrs = append(attrs, SystemKey.String(string(provider)))
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.address != "" {
+		attrs = append(attrs, semconv.ServerAddress(state.address))
+		if state.port != 0 {
+			attrs = append(attrs, semconv.ServerPort(state.port))
+		}
+	}
+	return attrs
+}
+
+// Transport returns an http.RoundTripper that adds retry and hedge events
+// to the spans of calls, and injects the span context into requests. A
+// nil base uses http.DefaultTransport.
+func (i *Instrumentation) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{instrumentation: i, base: base}
+}
+
+type transport struct {
+	instrumentation *Instrumentation
+	base            http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	req = req.Clone(ctx)
+	t.instrumentation.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	state, ok := ctx.Value(callContextKey{}).(*call)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	attempt := state.attempts.Add(1)
+	state.mu.Lock()
+	if state.address == "" {
+		state.address, state.port = hostPort(req)
+		state.span.SetAttributes(semconv.ServerAddress(state.address))
+		if state.port != 0 {
+			state.span.SetAttributes(semconv.ServerPort(state.port))
+		}
+	}
+	previous := state.status
+	state.mu.Unlock()
+
+	if attempt > 1 {
+		event := RetryEvent
+		if state.inFlight.Load() > 0 {
+			event = HedgeEvent
+		}
+		attrs := []attribute.KeyValue{AttemptKey.Int64(attempt)}
+		if event == RetryEvent && previous != 0 {
+			attrs = append(attrs, semconv.HTTPResponseStatusCode(previous))
+		}
+		state.span.AddEvent(event, trace.WithAttributes(attrs...))
+	}
+
+	state.inFlight.Add(1)
+	defer state.inFlight.Add(-1)
+	resp, err := t.base.RoundTrip(req)
+	if resp != nil {
+		state.mu.Lock()
+		state.status = resp.StatusCode
+		state.mu.Unlock()
+	}
+	return resp, err
+}
+
+// hostPort returns the server address and port of a request.
+func hostPort(req *http.Request) (string, int) {
+	host, portText, err := net.SplitHostPort(req.URL.Host)
+	if err != nil {
+		return req.URL.Host, 0
+	}
+	port, _ := strconv.Atoi(portText)
+	return host, port
+}
+
+// operationName maps an sdk operation to gen_ai.operation.name. Operations
+// the conventions don't cover get names of their own.
+func operationName(operation sdk.Operation) genaiconv.OperationNameAttr {
+	switch operation {
+	case sdk.OperationGenerateContent, sdk.OperationGenerateContentStream,
+		sdk.OperationCreateMessage, sdk.OperationCreateMessageStream,
+		sdk.OperationCreateResponse, sdk.OperationCreateResponseStream:
+		return genaiconv.OperationNameChat
+	case sdk.OperationCreateImage, sdk.OperationCreateImageEdit, sdk.OperationCreateImageVariation:
+		return genaiconv.OperationNameGenerateContent
+	case sdk.OperationListModels, sdk.OperationListProviderModels:
+		return "list_models"
+	case sdk.OperationListTools:
+		return "list_tools"
+	}
+	return "health_check"
+}
+
+// providerName maps a provider to gen_ai.provider.name.
+func providerName(provider sdk.Provider) genaiconv.ProviderNameAttr {
+	switch provider {
+	case sdk.Google:
+		return genaiconv.ProviderNameGCPGenAI
+	case sdk.Mistral:
+		return genaiconv.ProviderNameMistralAI
+	}
+	return genaiconv.ProviderNameAttr(provider)
+}
+
+// requestModel returns the model of a call's request.
+func requestModel(request any) string {
+	switch request := request.(type) {
+	case *sdk.CreateChatCompletionRequest:
+		return request.Model
+	case *sdk.CreateMessagesRequest:
+		return request.Model
+	case *sdk.CreateResponseRequest:
+		return request.Model
+	case *sdk.CreateImageRequest:
+		return deref(request.Model)
+	case *sdk.CreateImageEditMultipartBody:
+		return deref(request.Model)
+	case *sdk.CreateImageVariationMultipartBody:
+		return deref(request.Model)
+	}
+	return ""
+}
+
+// requestAttributes returns the gen_ai.request attributes of a request.
+func requestAttributes(request any) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	addInt := func(key attribute.Key, value *int) {
+		if value != nil {
+			attrs = append(attrs, key.Int(*value))
+		}
+	}
+	addFloat := func(key attribute.Key, value *float32) {
+		if value != nil {
+			attrs = append(attrs, key.Float64(float64(*value)))
+		}
+	}
+
+	switch request := request.(type) {
+	case *sdk.CreateChatCompletionRequest:
+		addInt(semconv.GenAIRequestMaxTokensKey, cmpOr(request.MaxCompletionTokens, request.MaxTokens))
+		addFloat(semconv.GenAIRequestTemperatureKey, request.Temperature)
+		addFloat(semconv.GenAIRequestTopPKey, request.TopP)
+		addFloat(semconv.GenAIRequestFrequencyPenaltyKey, request.FrequencyPenalty)
+		addFloat(semconv.GenAIRequestPresencePenaltyKey, request.PresencePenalty)
+		addInt(semconv.GenAIRequestSeedKey, request.Seed)
+		if request.N != nil && *request.N != 1 {
+			addInt(semconv.GenAIRequestChoiceCountKey, request.N)
+		}
+	case *sdk.CreateMessagesRequest:
+		addInt(semconv.GenAIRequestMaxTokensKey, &request.MaxTokens)
+		addFloat(semconv.GenAIRequestTemperatureKey, request.Temperature)
+		addFloat(semconv.GenAIRequestTopPKey, request.TopP)
+		if request.TopK != nil {
+			attrs = append(attrs, semconv.GenAIRequestTopK(float64(*request.TopK)))
+		}
+		if request.StopSequences != nil {
+			attrs = append(attrs, semconv.GenAIRequestStopSequences(*request.StopSequences...))
+		}
+	case *sdk.CreateResponseRequest:
+		addInt(semconv.GenAIRequestMaxTokensKey, request.MaxOutputTokens)
+		addFloat(semconv.GenAIRequestTemperatureKey, request.Temperature)
+		addFloat(semconv.GenAIRequestTopPKey, request.TopP)
+	}
+	return attrs
+}
+
+// callResult returns what the span reports of a response.
+func callResult(response any) result {
+	res := streamResult(sdk.ResultOf(response))
+	if response, ok := response.(*sdk.CreateChatCompletionResponse); ok && response != nil {
+		// Report every choice, and no usage if the gateway sent none.
+		res.finishReasons = nil
+		for _, choice := range response.Choices {
+			res.finishReasons = append(res.finishReasons, string(choice.FinishReason))
+		}
+		if response.Usage == nil {
+			res.usage = nil
+		}
+	}
+	return res
+}
+
+// streamResult returns what the span reports of a stream.
+func streamResult(response *sdk.GenerateResponse) result {
+	if response == nil {
+		return result{}
+	}
+	res := result{id: response.ID, model: response.Model, usage: &response.Usage}
+	if response.FinishReason != "" {
+		res.finishReasons = []string{string(response.FinishReason)}
+	}
+	return res
+}
+
+// errorTypeOf returns the error.type of a failed call: the status code of
+// API errors, and the conventions' fallback otherwise.
+func errorTypeOf(err error) string {
+	if apiErr, ok := errors.AsType[*sdk.APIError](err); ok {
+		return strconv.Itoa(apiErr.StatusCode)
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "cancelled"
+	case errors.Is(err, sdk.ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, sdk.ErrRateLimited):
+		return "rate_limited"
+	}
+	return string(genaiconv.ErrorTypeOther)
+}
+
+func deref(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// cmpOr returns the first of values that is set.
+func cmpOr(values ...*int) *int {
+	for _, value := range values {
+		if value != nil {
+			return value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package otelsdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/inference-gateway/sdk"
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const (
+	chatCompletion = `{
+		"id": "chatcmpl-1", "object": "chat.completion", "created": 1, "model": "gpt-4o-2024-08-06",
+		"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hi"}}],
+		"usage": {"prompt_tokens": 5, "completion_tokens": 3, "total_tokens": 8}
+	}`
+	chatChunk = `{"id":"chatcmpl-2","object":"chat.completion.chunk","created":1,"model":"gpt-4o-2024-08-06","choices":[{"index":0,"delta":{"content":"Hi"}}]}`
+	message   = `{
+		"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-sonnet-4-5",
+		"content": [{"type": "text", "text": "Hi"}], "stop_reason": "end_turn",
+		"usage": {"input_tokens": 4, "output_tokens": 3, "cache_read_input_tokens": 10, "cache_creation_input_tokens": 6}
+	}`
+	lastChunk = `{"id":"chatcmpl-2","object":"chat.completion.chunk","created":1,"model":"gpt-4o-2024-08-06","choices":[{"index":0,"delta":{},"finish_reason":"length"}],"usage":{"prompt_tokens":7,"completion_tokens":2,"total_tokens":9}}`
+)
+
+// telemetry is an instrumented client with in-memory span and metric
+// readers.
+type telemetry struct {
+	client  sdk.Client
+	spans   *tracetest.InMemoryExporter
+	metrics *sdkmetric.ManualReader
+
+	mu          sync.Mutex
+	statuses    []int
+	traceparent []string
+}
+
+func newTelemetry(t *testing.T, statuses ...int) *telemetry {
+	t.Helper()
+	tel := &telemetry{
+		spans:    tracetest.NewInMemoryExporter(),
+		metrics:  sdkmetric.NewManualReader(),
+		statuses: statuses,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tel.mu.Lock()
+		tel.traceparent = append(tel.traceparent, r.Header.Get("Traceparent"))
+		status := http.StatusOK
+		if len(tel.statuses) > 0 {
+			status, tel.statuses = tel.statuses[0], tel.statuses[1:]
+		}
+		tel.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			_, _ = fmt.Fprint(w, `{"error": "overloaded"}`)
+			return
+		}
+		if r.Method == http.MethodGet {
+			_, _ = fmt.Fprint(w, `{"object": "list", "data": []}`)
+			return
+		}
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, chunk := range []string{chatChunk, lastChunk, "[DONE]"} {
+				_, _ = fmt.Fprintf(w, "data: %s\n\n", chunk)
+			}
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/messages") {
+			_, _ = fmt.Fprint(w, message)
+			return
+		}
+		_, _ = fmt.Fprint(w, chatCompletion)
+	}))
+	t.Cleanup(server.Close)
+
+	instrumentation, err := New(&Options{
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSyncer(tel.spans)),
+		MeterProvider:  sdkmetric.NewMeterProvider(sdkmetric.WithReader(tel.metrics)),
+		Propagator:     propagation.TraceContext{},
+	})
+	require.NoError(t, err)
+	options := &sdk.ClientOptions{
+		BaseURL:     server.URL + "/v1",
+		RetryConfig: &sdk.RetryConfig{Enabled: true, MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	}
+	instrumentation.Instrument(options)
+	tel.client = sdk.NewClient(options)
+	return tel
+}
+
+// collect returns the data points of each metric by name.
+func (tel *telemetry) collect(t *testing.T) map[string]metricdata.Aggregation {
+	t.Helper()
+	var data metricdata.ResourceMetrics
+	require.NoError(t, tel.metrics.Collect(context.Background(), &data))
+	out := map[string]metricdata.Aggregation{}
+	for _, scope := range data.ScopeMetrics {
+		assert.Equal(t, ScopeName, scope.Scope.Name)
+		for _, m := range scope.Metrics {
+			out[m.Name] = m.Data
+		}
+	}
+	return out
+}
+
+func attributes(kvs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	out := map[attribute.Key]attribute.Value{}
+	for _, kv := range kvs {
+		out[kv.Key] = kv.Value
+	}
+	return out
+}
+
+func hello() []sdk.Message {
+	return []sdk.Message{{Role: sdk.User, Content: sdk.NewMessageContent("Hello")}}
+}
+
+func TestInstrumentation_Chat(t *testing.T) {
+	tel := newTelemetry(t, http.StatusServiceUnavailable)
+
+	_, err := tel.client.WithOptions(&sdk.CreateChatCompletionRequest{MaxTokens: new(100), Temperature: new(float32(0.5))}).
+		GenerateContent(context.Background(), sdk.Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+
+	spans := tel.spans.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "chat gpt-4o", span.Name)
+	attrs := attributes(span.Attributes)
+	assert.Equal(t, "chat", attrs["gen_ai.operation.name"].AsString())
+	assert.Equal(t, "openai", attrs["gen_ai.system"].AsString())
+	assert.Equal(t, "openai", attrs["gen_ai.provider.name"].AsString())
+	assert.Equal(t, "gpt-4o", attrs["gen_ai.request.model"].AsString())
+	assert.Equal(t, int64(100), attrs["gen_ai.request.max_tokens"].AsInt64())
+	assert.Equal(t, 0.5, attrs["gen_ai.request.temperature"].AsFloat64())
+	assert.Equal(t, "gpt-4o-2024-08-06", attrs["gen_ai.response.model"].AsString())
+	assert.Equal(t, "chatcmpl-1", attrs["gen_ai.response.id"].AsString())
+	assert.Equal(t, []string{"stop"}, attrs["gen_ai.response.finish_reasons"].AsStringSlice())
+	assert.Equal(t, int64(5), attrs["gen_ai.usage.input_tokens"].AsInt64())
+	assert.Equal(t, int64(3), attrs["gen_ai.usage.output_tokens"].AsInt64())
+	assert.Equal(t, "127.0.0.1", attrs["server.address"].AsString())
+	assert.Equal(t, codes.Unset, span.Status.Code)
+
+	require.Len(t, span.Events, 1)
+	assert.Equal(t, RetryEvent, span.Events[0].Name)
+	assert.Equal(t, int64(2), attributes(span.Events[0].Attributes)[AttemptKey].AsInt64())
+	assert.Equal(t, int64(503), attributes(span.Events[0].Attributes)["http.response.status_code"].AsInt64())
+
+	// Both attempts carry the span's trace context.
+	require.Len(t, tel.traceparent, 2)
+	assert.Contains(t, tel.traceparent[0], span.SpanContext.TraceID().String())
+	assert.Equal(t, tel.traceparent[0], tel.traceparent[1])
+
+	metrics := tel.collect(t)
+	duration := metrics["gen_ai.client.operation.duration"].(metricdata.Histogram[float64])
+	require.Len(t, duration.DataPoints, 1)
+	assert.Equal(t, uint64(1), duration.DataPoints[0].Count)
+	model, _ := duration.DataPoints[0].Attributes.Value("gen_ai.response.model")
+	assert.Equal(t, "gpt-4o-2024-08-06", model.AsString())
+
+	usage := metrics["gen_ai.client.token.usage"].(metricdata.Histogram[int64])
+	assert.Len(t, usage.DataPoints, 2)
+	tokens := metrics[TokensMetric].(metricdata.Sum[int64])
+	assert.True(t, tokens.IsMonotonic)
+	byType := map[string]int64{}
+	for _, point := range tokens.DataPoints {
+		tokenType, _ := point.Attributes.Value("gen_ai.token.type")
+		byType[tokenType.AsString()] = point.Value
+	}
+	assert.Equal(t, map[string]int64{"input": 5, "output": 3}, byType)
+}
+
+func TestInstrumentation_Stream(t *testing.T) {
+	tel := newTelemetry(t)
+
+	stream, err := tel.client.GenerateContentStream(context.Background(), sdk.Groq, "llama-3.3-70b-versatile", hello())
+	require.NoError(t, err)
+	var events int
+	for range stream {
+		events++
+	}
+	assert.Positive(t, events)
+
+	require.Eventually(t, func() bool { return len(tel.spans.GetSpans()) == 1 }, time.Second, 10*time.Millisecond)
+	span := tel.spans.GetSpans()[0]
+	assert.Equal(t, "chat llama-3.3-70b-versatile", span.Name)
+	attrs := attributes(span.Attributes)
+	assert.Equal(t, "GenerateContentStream", attrs[OperationKey].AsString())
+	assert.Equal(t, []string{"length"}, attrs["gen_ai.response.finish_reasons"].AsStringSlice())
+	assert.Equal(t, int64(7), attrs["gen_ai.usage.input_tokens"].AsInt64())
+	assert.Equal(t, int64(2), attrs["gen_ai.usage.output_tokens"].AsInt64())
+	assert.Empty(t, span.Events)
+
+	metrics := tel.collect(t)
+	ttft := metrics[TimeToFirstChunkMetric].(metricdata.Histogram[float64])
+	require.Len(t, ttft.DataPoints, 1)
+	assert.Equal(t, uint64(1), ttft.DataPoints[0].Count)
+	provider, _ := ttft.DataPoints[0].Attributes.Value("gen_ai.provider.name")
+	assert.Equal(t, "groq", provider.AsString())
+	duration := metrics["gen_ai.client.operation.duration"].(metricdata.Histogram[float64])
+	require.Len(t, duration.DataPoints, 1)
+	assert.GreaterOrEqual(t, duration.DataPoints[0].Sum, ttft.DataPoints[0].Sum)
+}
+
+func TestInstrumentation_Messages(t *testing.T) {
+	tel := newTelemetry(t)
+	var content sdk.MessagesMessage_Content
+	require.NoError(t, content.FromMessagesMessageContent0("Hello"))
+
+	_, err := tel.client.CreateMessage(context.Background(), sdk.Anthropic, sdk.CreateMessagesRequest{
+		Model:     "claude-sonnet-4-5",
+		MaxTokens: 100,
+		Messages:  []sdk.MessagesMessage{{Role: sdk.MessagesMessageRoleUser, Content: content}},
+	})
+	require.NoError(t, err)
+
+	spans := tel.spans.GetSpans()
+	require.Len(t, spans, 1)
+	attrs := attributes(spans[0].Attributes)
+	assert.Equal(t, "msg_1", attrs["gen_ai.response.id"].AsString())
+	assert.Equal(t, []string{"stop"}, attrs["gen_ai.response.finish_reasons"].AsStringSlice())
+	// Input tokens include the cache reads and writes Anthropic reports apart.
+	assert.Equal(t, int64(20), attrs["gen_ai.usage.input_tokens"].AsInt64())
+	assert.Equal(t, int64(3), attrs["gen_ai.usage.output_tokens"].AsInt64())
+	assert.Equal(t, int64(10), attrs["gen_ai.usage.cache_read.input_tokens"].AsInt64())
+	assert.Equal(t, int64(6), attrs["gen_ai.usage.cache_creation.input_tokens"].AsInt64())
+}
+
+func TestInstrumentation_Error(t *testing.T) {
+	tel := newTelemetry(t, http.StatusBadRequest)
+
+	_, err := tel.client.GenerateContent(context.Background(), sdk.Openai, "gpt-4o", hello())
+	require.Error(t, err)
+
+	spans := tel.spans.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, "400", attributes(spans[0].Attributes)["error.type"].AsString())
+	require.Len(t, spans[0].Events, 1)
+	assert.Equal(t, "exception", spans[0].Events[0].Name)
+
+	duration := tel.collect(t)["gen_ai.client.operation.duration"].(metricdata.Histogram[float64])
+	require.Len(t, duration.DataPoints, 1)
+	errorType, _ := duration.DataPoints[0].Attributes.Value("error.type")
+	assert.Equal(t, "400", errorType.AsString())
+}
+
+func TestInstrumentation_Models(t *testing.T) {
+	tel := newTelemetry(t)
+
+	_ = tel.client.HealthCheck(context.Background())
+	_, _ = tel.client.ListProviderModels(context.Background(), sdk.Google)
+
+	spans := tel.spans.GetSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "health_check", spans[0].Name)
+	assert.Equal(t, "list_models", spans[1].Name)
+	assert.Equal(t, "gcp.gen_ai", attributes(spans[1].Attributes)["gen_ai.provider.name"].AsString())
+}
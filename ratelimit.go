@@ -0,0 +1,480 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	resty "github.com/go-resty/resty/v2"
+)
+
+// ErrRateLimited is wrapped by errors of calls a RateLimiter turned away
+// instead of waiting for them.
+var ErrRateLimited = errors.New("rate limited")
+
+// RateLimitKey identifies the buckets of one provider and model.
+type RateLimitKey struct {
+	Provider Provider
+	Model    string
+}
+
+func (k RateLimitKey) String() string {
+	return string(k.Provider) + "/" + k.Model
+}
+
+// RateLimitError is returned for calls that would have to wait for their
+// buckets while the limiter fails fast, or longer than their context
+// allows. It unwraps to ErrRateLimited.
+type RateLimitError struct {
+	Key RateLimitKey
+	// RetryAfter is how long the call would have had to wait.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit for %s reached, retry after %s", e.Key, e.RetryAfter.Round(time.Millisecond))
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// RateLimit sets the requests and tokens per minute of the calls it
+// covers. Empty Provider and Model cover every provider and model; each
+// provider and model still gets buckets of its own. Zero limits are
+// unlimited.
+type RateLimit struct {
+	Provider          Provider
+	Model             string
+	RequestsPerMinute int
+	TokensPerMinute   int
+}
+
+func (l RateLimit) matches(provider Provider, model string) bool {
+	return (l.Provider == "" || l.Provider == provider) && (l.Model == "" || l.Model == model)
+}
+
+// RateLimitWait reports a call held back by a RateLimiter.
+type RateLimitWait struct {
+	Key    RateLimitKey
+	Delay  time.Duration
+	Tokens int
+}
+
+// RateLimitStatus is the state of the buckets of one provider and model.
+type RateLimitStatus struct {
+	Limit RateLimit
+	// Requests and Tokens are what is left in the buckets; they go negative
+	// while calls wait. They are zero for unlimited buckets.
+	Requests int
+	Tokens   int
+	// BlockedUntil is set while the gateway asked for calls to pause.
+	BlockedUntil time.Time
+}
+
+// RateLimiterOptions configures a RateLimiter.
+type RateLimiterOptions struct {
+	// Limits are matched in order; the first covering a call applies.
+	// Calls no limit covers are not held back.
+	Limits []RateLimit
+	// FailFast returns a *RateLimitError instead of waiting for the
+	// buckets to refill.
+	FailFast bool
+	// Counter estimates the prompt tokens of calls before they are sent.
+	// Defaults to a TokenCounter with the HeuristicTokenizer.
+	Counter *TokenCounter
+	// OnWait is called whenever a call has to wait.
+	OnWait func(wait RateLimitWait)
+}
+
+// RateLimiter keeps batch jobs under the gateway's rate limits instead of
+// running into 429s and sleeping through retries. Set it as
+// ClientOptions.RateLimiter. Each provider and model has a token bucket of
+// requests and one of tokens per minute, refilled continuously.
+//
+// A call takes one request and its estimated tokens: its prompt, counted by
+// the Counter, plus the output tokens it asks for at most. Once the gateway
+// reports the call's usage, the estimate is replaced by the tokens actually
+// used; failed calls give their tokens back. Each retry of a call takes
+// another request. The x-ratelimit-remaining-* headers of responses drain
+// the buckets to what the gateway reports; once it reports nothing left,
+// x-ratelimit-reset-* and Retry-After headers pause the provider and model,
+// retries included, until the gateway's limits reset. It is safe for
+// concurrent use and may be shared by clients.
+type RateLimiter struct {
+	options RateLimiterOptions
+
+	mu      sync.Mutex
+	buckets map[RateLimitKey]*rateBuckets
+}
+
+// rateBuckets are the buckets of one key; nil buckets are unlimited.
+type rateBuckets struct {
+	limit        RateLimit
+	requests     *tokenBucket
+	tokens       *tokenBucket
+	blockedUntil time.Time
+}
+
+// tokenBucket holds up to a minute of its limit and refills continuously.
+// Its level goes negative while calls wait for it.
+type tokenBucket struct {
+	capacity float64
+	level    float64
+	perSec   float64
+	updated  time.Time
+}
+
+func newTokenBucket(perMinute int, now time.Time) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		capacity: float64(perMinute),
+		level:    float64(perMinute),
+		perSec:   float64(perMinute) / 60,
+		updated:  now,
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.level = min(b.capacity, b.level+elapsed*b.perSec)
+		b.updated = now
+	}
+}
+
+// delay returns how long taking n leaves the bucket in deficit.
+func (b *tokenBucket) delay(n float64) time.Duration {
+	if b.level >= n {
+		return 0
+	}
+	return time.Duration((n - b.level) / b.perSec * float64(time.Second))
+}
+
+// NewRateLimiter creates a RateLimiter.
+//
+// Example:
+//
+//	limiter := sdk.NewRateLimiter(&sdk.RateLimiterOptions{
+//		Limits: []sdk.RateLimit{
+//			{Provider: sdk.Openai, Model: "gpt-4o", RequestsPerMinute: 500, TokensPerMinute: 30000},
+//			{RequestsPerMinute: 60},
+//		},
+//	})
+//	client := sdk.NewClient(&sdk.ClientOptions{
+//		BaseURL:     "http://localhost:8080/v1",
+//		RateLimiter: limiter,
+//	})
+func NewRateLimiter(options *RateLimiterOptions) *RateLimiter {
+	l := &RateLimiter{buckets: make(map[RateLimitKey]*rateBuckets)}
+	if options != nil {
+		l.options = *options
+	}
+	if l.options.Counter == nil {
+		l.options.Counter = NewTokenCounter(nil)
+	}
+	return l
+}
+
+// Status returns the state of the buckets of provider and model, and false
+// when no limit covers them.
+func (l *RateLimiter) Status(provider Provider, model string) (RateLimitStatus, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.lookup(RateLimitKey{Provider: provider, Model: model}, time.Now())
+	if b == nil {
+		return RateLimitStatus{}, false
+	}
+	status := RateLimitStatus{Limit: b.limit, BlockedUntil: b.blockedUntil}
+	if b.requests != nil {
+		status.Requests = int(b.requests.level)
+	}
+	if b.tokens != nil {
+		status.Tokens = int(b.tokens.level)
+	}
+	return status, true
+}
+
+// lookup returns the refilled buckets of key, creating them from the first
+// matching limit. It must be called with the lock held.
+func (l *RateLimiter) lookup(key RateLimitKey, now time.Time) *rateBuckets {
+	if b, ok := l.buckets[key]; ok {
+		if b.requests != nil {
+			b.requests.refill(now)
+		}
+		if b.tokens != nil {
+			b.tokens.refill(now)
+		}
+		return b
+	}
+	for _, limit := range l.options.Limits {
+		if !limit.matches(key.Provider, key.Model) {
+			continue
+		}
+		b := &rateBuckets{
+			limit:    limit,
+			requests: newTokenBucket(limit.RequestsPerMinute, now),
+			tokens:   newTokenBucket(limit.TokensPerMinute, now),
+		}
+		l.buckets[key] = b
+		return b
+	}
+	return nil
+}
+
+// estimate counts the tokens request may use: its prompt plus the output
+// tokens it asks for at most.
+func (l *RateLimiter) estimate(request any) int {
+	counter := l.options.Counter
+	switch r := request.(type) {
+	case CreateChatCompletionRequest:
+		tokens := counter.CountRequest(r)
+		if r.MaxCompletionTokens != nil {
+			tokens += *r.MaxCompletionTokens
+		} else if r.MaxTokens != nil {
+			tokens += *r.MaxTokens
+		}
+		return tokens
+	case CreateMessagesRequest:
+		return counter.CountMessagesRequest(r) + r.MaxTokens
+	case CreateResponseRequest:
+		// Responses input comes in many shapes; its JSON is close enough.
+		input, _ := json.Marshal(r.Input)
+		tokens := counter.CountText(string(input))
+		if r.Instructions != nil {
+			tokens += counter.CountText(*r.Instructions)
+		}
+		if r.MaxOutputTokens != nil {
+			tokens += *r.MaxOutputTokens
+		}
+		return tokens
+	case string:
+		return counter.CountText(r)
+	}
+	return 0
+}
+
+// acquire takes a request and the estimated tokens of request from the
+// buckets of provider and model, waiting for them unless the limiter fails
+// fast. The returned call is nil when no limit applies.
+func (l *RateLimiter) acquire(ctx context.Context, provider Provider, model string, request any) (*rateCall, error) {
+	if l == nil {
+		return nil, nil
+	}
+	key := RateLimitKey{Provider: provider, Model: model}
+
+	l.mu.Lock()
+	b := l.lookup(key, time.Now())
+	l.mu.Unlock()
+	if b == nil {
+		return nil, nil
+	}
+	tokens := 0
+	if b.tokens != nil {
+		// A call larger than the bucket would never fit; let it drain it.
+		tokens = min(l.estimate(request), int(b.tokens.capacity))
+	}
+
+	call := &rateCall{limiter: l, key: key, buckets: b, tokens: tokens}
+	if err := call.take(ctx, tokens); err != nil {
+		return nil, err
+	}
+	return call, nil
+}
+
+// take takes a request and tokens from the buckets of the call, waiting for
+// them and for any pause the gateway asked for, unless the limiter fails
+// fast. A cancelled wait gives them back.
+func (c *rateCall) take(ctx context.Context, tokens int) error {
+	l, b := c.limiter, c.buckets
+	l.mu.Lock()
+	now := time.Now()
+	l.lookup(c.key, now)
+	delay := max(0, b.blockedUntil.Sub(now))
+	if b.requests != nil {
+		delay = max(delay, b.requests.delay(1))
+	}
+	if b.tokens != nil {
+		delay = max(delay, b.tokens.delay(float64(tokens)))
+	}
+	if delay > 0 {
+		deadline, ok := ctx.Deadline()
+		if l.options.FailFast || (ok && now.Add(delay).After(deadline)) {
+			l.mu.Unlock()
+			return &RateLimitError{Key: c.key, RetryAfter: delay}
+		}
+	}
+	if b.requests != nil {
+		b.requests.level--
+	}
+	if b.tokens != nil {
+		b.tokens.level -= float64(tokens)
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	if l.options.OnWait != nil {
+		l.options.OnWait(RateLimitWait{Key: c.key, Delay: delay, Tokens: tokens})
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if b.requests != nil {
+			b.requests.level = min(b.requests.capacity, b.requests.level+1)
+		}
+		if b.tokens != nil {
+			b.tokens.level = min(b.tokens.capacity, b.tokens.level+float64(tokens))
+		}
+		return ctx.Err()
+	}
+}
+
+// retry takes a request for another attempt of the call, so retries are
+// held back by the buckets and by pauses like any call. The call's tokens
+// already cover it, as failed attempts used none.
+func (c *rateCall) retry(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+	return c.take(ctx, 0)
+}
+
+// observe adjusts the buckets of provider and model to the rate limit
+// headers of resp. It sees every attempt, retries included.
+func (l *RateLimiter) observe(provider Provider, model string, resp *resty.Response) {
+	if l == nil || resp == nil {
+		return
+	}
+	header := resp.Header()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	b := l.lookup(RateLimitKey{Provider: provider, Model: model}, now)
+	if b == nil {
+		return
+	}
+
+	block := func(delay time.Duration) {
+		if until := now.Add(delay); until.After(b.blockedUntil) {
+			b.blockedUntil = until
+		}
+	}
+	drain := func(bucket *tokenBucket, kind string) {
+		remaining, err := strconv.ParseFloat(header.Get("X-Ratelimit-Remaining-"+kind), 64)
+		if bucket == nil || err != nil {
+			return
+		}
+		if remaining <= 0 {
+			// The gateway's own window resets by then; waiting for the bucket
+			// as well would wait twice.
+			if reset, ok := parseRateLimitReset(header.Get("X-Ratelimit-Reset-" + kind)); ok {
+				block(reset)
+				return
+			}
+		}
+		bucket.level = min(bucket.level, remaining)
+	}
+	drain(b.requests, "Requests")
+	drain(b.tokens, "Tokens")
+
+	switch resp.StatusCode() {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		if delay, ok := parseRetryAfter(header.Get("Retry-After")); ok {
+			block(delay)
+		}
+	}
+}
+
+// parseRateLimitReset parses an x-ratelimit-reset-* header, either a
+// duration such as 6m0s or a number of seconds.
+func parseRateLimitReset(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return d, true
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	return 0, false
+}
+
+// rateCall is one call holding tokens of a RateLimiter. Its methods are
+// no-ops on a nil call, so clients without a limiter skip them.
+type rateCall struct {
+	limiter *RateLimiter
+	key     RateLimitKey
+	buckets *rateBuckets
+	tokens  int
+
+	// finished and streaming are guarded by the limiter's lock.
+	finished  bool
+	streaming bool
+}
+
+// done replaces the estimated tokens by the tokens usage reports. Calls
+// that report no usage keep their estimate.
+func (c *rateCall) done(usage GenerateUsage) {
+	if c == nil {
+		return
+	}
+	actual := usage.TotalTokens
+	if actual == 0 {
+		actual = usage.InputTokens + usage.OutputTokens
+	}
+
+	c.limiter.mu.Lock()
+	defer c.limiter.mu.Unlock()
+	if c.finished {
+		return
+	}
+	c.finished = true
+	if actual > 0 && c.buckets.tokens != nil {
+		bucket := c.buckets.tokens
+		bucket.level = min(bucket.capacity, bucket.level+float64(c.tokens)-float64(actual))
+	}
+}
+
+// cancel gives the tokens of a call that failed back, unless the call
+// finished or went on as a stream. The requests stay taken.
+func (c *rateCall) cancel() {
+	if c == nil {
+		return
+	}
+	c.limiter.mu.Lock()
+	defer c.limiter.mu.Unlock()
+	if c.finished || c.streaming {
+		return
+	}
+	c.finished = true
+	if bucket := c.buckets.tokens; bucket != nil {
+		bucket.level = min(bucket.capacity, bucket.level+float64(c.tokens))
+	}
+}
+
+// stream passes events through and reconciles the tokens once the stream
+// ends.
+func (c *rateCall) stream(ctx context.Context, events <-chan SSEvent, api API) <-chan SSEvent {
+	if c == nil {
+		return events
+	}
+	c.limiter.mu.Lock()
+	c.streaming = true
+	c.limiter.mu.Unlock()
+	return watchUsage(ctx, events, api, c.done)
+}
@@ -0,0 +1,157 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// newRateGateway answers chat completions with 300 tokens of usage,
+// through a client limited by limiter.
+func newRateGateway(t *testing.T, limiter *RateLimiter) (*testGateway, Client) {
+	t.Helper()
+	gateway := newTestGateway(t)
+	gateway.handle("POST /v1/chat/completions", chatRoute("Hi", &CompletionUsage{PromptTokens: 200, CompletionTokens: 100, TotalTokens: 300}))
+	return gateway, gateway.client(&ClientOptions{RateLimiter: limiter})
+}
+
+func TestRateLimiter_Requests(t *testing.T) {
+	limiter := NewRateLimiter(&RateLimiterOptions{
+		Limits:   []RateLimit{{Provider: Openai, RequestsPerMinute: 1}},
+		FailFast: true,
+	})
+	server, client := newRateGateway(t, limiter)
+	ctx := context.Background()
+
+	_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	_, err = client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	assert.ErrorIs(t, err, ErrRateLimited)
+	var limitErr *RateLimitError
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, RateLimitKey{Provider: Openai, Model: "gpt-4o"}, limitErr.Key)
+	assert.InDelta(t, time.Minute.Seconds(), limitErr.RetryAfter.Seconds(), 1)
+	assert.Equal(t, 1, server.count(""), "rejected calls aren't sent")
+
+	// Each model has buckets of its own, and uncovered providers have none.
+	_, err = client.GenerateContent(ctx, Openai, "gpt-4o-mini", hello())
+	assert.NoError(t, err)
+	_, err = client.GenerateContent(ctx, Anthropic, "claude-sonnet-4", hello())
+	assert.NoError(t, err)
+	_, ok := limiter.Status(Anthropic, "claude-sonnet-4")
+	assert.False(t, ok)
+}
+
+func TestRateLimiter_Tokens(t *testing.T) {
+	limiter := NewRateLimiter(&RateLimiterOptions{Limits: []RateLimit{{TokensPerMinute: 6000}}})
+	server, client := newRateGateway(t, limiter)
+	ctx := context.Background()
+
+	// The estimate counts max_tokens; the reported 300 tokens replace it.
+	_, err := client.WithOptions(&CreateChatCompletionRequest{MaxTokens: new(1000)}).GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	status, ok := limiter.Status(Openai, "gpt-4o")
+	require.True(t, ok)
+	assert.InDelta(t, 5700, status.Tokens, 10)
+
+	events, err := client.GenerateContentStream(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	for range events {
+	}
+	status, _ = limiter.Status(Openai, "gpt-4o")
+	assert.InDelta(t, 5400, status.Tokens, 20)
+
+	// Failed calls give their tokens back.
+	server.enqueue(errorReply(http.StatusInternalServerError, "boom"))
+	_, err = client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.Error(t, err)
+	status, _ = limiter.Status(Openai, "gpt-4o")
+	assert.InDelta(t, 5400, status.Tokens, 30)
+}
+
+func TestRateLimiter_Headers(t *testing.T) {
+	var waits []RateLimitWait
+	limiter := NewRateLimiter(&RateLimiterOptions{
+		Limits: []RateLimit{{RequestsPerMinute: 100}},
+		OnWait: func(wait RateLimitWait) { waits = append(waits, wait) },
+	})
+	server, client := newRateGateway(t, limiter)
+	ctx := context.Background()
+
+	// The gateway has no requests left for 100ms, so the next call waits.
+	server.enqueue(func(w http.ResponseWriter) {
+		w.Header().Set("X-Ratelimit-Remaining-Requests", "0")
+		w.Header().Set("X-Ratelimit-Reset-Requests", "100ms")
+		chatReply("Hi")(w)
+	})
+	_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	start := time.Now()
+	_, err = client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	require.Len(t, waits, 1)
+	assert.InDelta(t, 0.1, waits[0].Delay.Seconds(), 0.02)
+
+	// Retry-After pauses the provider and model.
+	server.enqueue(func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "1")
+		errorReply(http.StatusTooManyRequests, "slow down")(w)
+	})
+	_, err = client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.Error(t, err)
+	status, _ := limiter.Status(Openai, "gpt-4o")
+	assert.WithinDuration(t, time.Now().Add(time.Second), status.BlockedUntil, 50*time.Millisecond)
+
+	// Calls that can't wait long enough for their deadline fail at once.
+	deadline, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = client.GenerateContent(deadline, Openai, "gpt-4o", hello())
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	// Cancelled waits give their request back.
+	before, _ := limiter.Status(Openai, "gpt-4o")
+	cancelled, cancel := context.WithCancel(ctx)
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, err = client.GenerateContent(cancelled, Openai, "gpt-4o", hello())
+	assert.ErrorIs(t, err, context.Canceled)
+	after, _ := limiter.Status(Openai, "gpt-4o")
+	assert.InDelta(t, before.Requests, after.Requests, 1)
+	assert.Equal(t, 3, server.count(""), "held back calls aren't sent")
+}
+
+func TestRateLimiter_Retries(t *testing.T) {
+	retries := &RetryConfig{Enabled: true, MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	limiter := NewRateLimiter(&RateLimiterOptions{
+		Limits:   []RateLimit{{Provider: Openai, RequestsPerMinute: 2}},
+		FailFast: true,
+	})
+	gateway := newTestGateway(t)
+	client := gateway.client(&ClientOptions{RetryConfig: retries, RateLimiter: limiter})
+	ctx := context.Background()
+
+	// Each attempt takes a request, so the third one is turned away.
+	gateway.enqueue(errorReply(http.StatusServiceUnavailable, "overloaded"), errorReply(http.StatusServiceUnavailable, "overloaded"))
+	_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, 2, gateway.count(""))
+
+	// Retries wait for pauses the gateway asks for.
+	limiter = NewRateLimiter(&RateLimiterOptions{Limits: []RateLimit{{RequestsPerMinute: 100}}})
+	client = gateway.client(&ClientOptions{RetryConfig: retries, RateLimiter: limiter})
+	gateway.enqueue(func(w http.ResponseWriter) {
+		w.Header().Set("X-Ratelimit-Remaining-Requests", "0")
+		w.Header().Set("X-Ratelimit-Reset-Requests", "100ms")
+		errorReply(http.StatusServiceUnavailable, "overloaded")(w)
+	})
+	start := time.Now()
+	_, err = client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	assert.Equal(t, 4, gateway.count(""))
+}
@@ -0,0 +1,251 @@
+package sdk
+
+import (
+	"context"
+	"crypto/rand"
+	"math"
+	mathrand "math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the header that carries a call's idempotency key.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	defaultRetryBudgetRatio      = 0.1
+	defaultRetryBudgetMinRetries = 10
+	defaultRetryBudgetWindow     = 10 * time.Second
+)
+
+// Jitter randomizes backoff delays so that clients failing together don't
+// retry together.
+type Jitter string
+
+const (
+	// JitterNone waits the exponential backoff as is.
+	JitterNone Jitter = ""
+	// JitterFull waits a random delay up to the backoff.
+	JitterFull Jitter = "full"
+	// JitterEqual waits half the backoff plus a random delay up to the
+	// other half.
+	JitterEqual Jitter = "equal"
+	// JitterDecorrelated waits a random delay between the initial backoff
+	// and three times the previous delay, capped by the maximum backoff.
+	JitterDecorrelated Jitter = "decorrelated"
+)
+
+// RetryDecision is a RetryClassifier's verdict on an attempt.
+type RetryDecision int
+
+const (
+	// RetryDefault leaves the attempt to RetryableStatusCodes and the
+	// default network error checks.
+	RetryDefault RetryDecision = iota
+	// Retry retries the attempt.
+	Retry
+	// NoRetry returns the attempt's outcome as is.
+	NoRetry
+)
+
+// RetryAttempt is the outcome of one attempt of a call.
+type RetryAttempt struct {
+	// Attempt counts the attempts of the call, from 1.
+	Attempt int
+	// StatusCode and Header are zero when no response was received. Body
+	// is empty for streams.
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Err        error
+}
+
+// RetryClassifier decides whether an attempt is retried, e.g. to retry
+// provider-specific overload errors or never retry a status code.
+type RetryClassifier func(attempt RetryAttempt) RetryDecision
+
+// RetryBudgetOptions configures a RetryBudget.
+type RetryBudgetOptions struct {
+	// Ratio is the share of calls that may be retried, e.g. 0.1 for one
+	// retry per ten calls. Defaults to 0.1.
+	Ratio float64
+	// MinRetriesPerSecond are allowed regardless of Ratio, so that clients
+	// with little traffic still retry. Defaults to 10.
+	MinRetriesPerSecond int
+	// Window is how far back calls and retries are counted. Defaults to 10
+	// seconds.
+	Window time.Duration
+}
+
+// RetryBudget caps retries to a share of the calls in a sliding window, so
+// that an outage doesn't multiply the traffic it gets. Set it as
+// RetryConfig.Budget; share one budget between clients to cap a whole
+// service. Once the budget is spent, failed attempts are returned without
+// retrying. It is safe for concurrent use.
+type RetryBudget struct {
+	options RetryBudgetOptions
+
+	mu    sync.Mutex
+	slots []retryBudgetSlot
+}
+
+// retryBudgetSlot counts the calls and retries of one second.
+type retryBudgetSlot struct {
+	second  int64
+	calls   int
+	retries int
+}
+
+// NewRetryBudget creates a RetryBudget.
+//
+// Example:
+//
+//	budget := sdk.NewRetryBudget(&sdk.RetryBudgetOptions{Ratio: 0.2})
+//	client := sdk.NewClient(&sdk.ClientOptions{
+//		BaseURL: "http://localhost:8080/v1",
+//		RetryConfig: &sdk.RetryConfig{
+//			Enabled:        true,
+//			MaxAttempts:    3,
+//			InitialBackoff: 200 * time.Millisecond,
+//			MaxBackoff:     5 * time.Second,
+//			Jitter:         sdk.JitterFull,
+//			Budget:         budget,
+//		},
+//	})
+func NewRetryBudget(options *RetryBudgetOptions) *RetryBudget {
+	b := &RetryBudget{}
+	if options != nil {
+		b.options = *options
+	}
+	if b.options.Ratio <= 0 {
+		b.options.Ratio = defaultRetryBudgetRatio
+	}
+	if b.options.MinRetriesPerSecond <= 0 {
+		b.options.MinRetriesPerSecond = defaultRetryBudgetMinRetries
+	}
+	if b.options.Window < time.Second {
+		b.options.Window = defaultRetryBudgetWindow
+	}
+	b.slots = make([]retryBudgetSlot, int(b.options.Window/time.Second))
+	return b
+}
+
+// slot returns the slot of the current second. It must be called with the
+// lock held.
+func (b *RetryBudget) slot(now time.Time) *retryBudgetSlot {
+	second := now.Unix()
+	slot := &b.slots[second%int64(len(b.slots))]
+	if slot.second != second {
+		*slot = retryBudgetSlot{second: second}
+	}
+	return slot
+}
+
+// call counts a call.
+func (b *RetryBudget) call() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.slot(time.Now()).calls++
+}
+
+// withdraw takes a retry from the budget, or reports that it is spent.
+func (b *RetryBudget) withdraw() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	oldest := now.Unix() - int64(len(b.slots)) + 1
+	calls, retries := 0, 0
+	for _, slot := range b.slots {
+		if slot.second >= oldest {
+			calls += slot.calls
+			retries += slot.retries
+		}
+	}
+	allowed := int(b.options.Ratio*float64(calls)) + b.options.MinRetriesPerSecond*len(b.slots)
+	if retries >= allowed {
+		return false
+	}
+	b.slot(now).retries++
+	return true
+}
+
+// backoff returns the exponential backoff before retry attempt, counted
+// from 1, without jitter. Durations take precedence over the whole-second
+// fields.
+func (config *RetryConfig) backoff(attempt int) time.Duration {
+	if attempt <= 0 {
+		return 0
+	}
+	initial, maxBackoff := config.backoffRange()
+	multiplier := config.Multiplier
+	if multiplier <= 0 {
+		multiplier = float64(config.BackoffMultiplier)
+	}
+	backoff := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	return time.Duration(min(backoff, float64(maxBackoff)))
+}
+
+func (config *RetryConfig) backoffRange() (initial, maxBackoff time.Duration) {
+	initial, maxBackoff = config.InitialBackoff, config.MaxBackoff
+	if initial <= 0 {
+		initial = time.Duration(config.InitialBackoffSec) * time.Second
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = time.Duration(config.MaxBackoffSec) * time.Second
+	}
+	return initial, maxBackoff
+}
+
+// delay returns the delay before retry attempt with the configured jitter.
+// previous is the delay before the previous retry, if any.
+func (config *RetryConfig) delay(attempt int, previous time.Duration) time.Duration {
+	backoff := config.backoff(attempt)
+	switch config.Jitter {
+	case JitterFull:
+		return randomDuration(0, backoff)
+	case JitterEqual:
+		return backoff/2 + randomDuration(0, backoff-backoff/2)
+	case JitterDecorrelated:
+		initial, maxBackoff := config.backoffRange()
+		previous = max(previous, initial)
+		return min(maxBackoff, randomDuration(initial, 3*previous))
+	}
+	return backoff
+}
+
+// randomDuration returns a random duration in [low, high).
+func randomDuration(low, high time.Duration) time.Duration {
+	if high <= low {
+		return low
+	}
+	return low + time.Duration(mathrand.Int64N(int64(high-low)))
+}
+
+type idempotencyKeyContextKey struct{}
+
+// WithIdempotencyKey sets the idempotency key of the calls made with ctx,
+// e.g. to let the gateway deduplicate a job that is resubmitted. The key is
+// sent in the Idempotency-Key header even when RetryConfig.IdempotencyKeys
+// is off.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key)
+}
+
+// IdempotencyKey returns the idempotency key set by WithIdempotencyKey.
+func IdempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyContextKey{}).(string)
+	return key
+}
+
+// newIdempotencyKey returns a random key for a call.
+func newIdempotencyKey() string {
+	return rand.Text()
+}
@@ -0,0 +1,200 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+func TestRetryConfig_Backoff(t *testing.T) {
+	config := &RetryConfig{
+		InitialBackoffSec: 5,
+		MaxBackoffSec:     60,
+		BackoffMultiplier: 3,
+		InitialBackoff:    100 * time.Millisecond,
+		MaxBackoff:        time.Second,
+		Multiplier:        1.5,
+	}
+	assert.Equal(t, time.Duration(0), config.backoff(0))
+	assert.Equal(t, 100*time.Millisecond, config.backoff(1))
+	assert.Equal(t, 150*time.Millisecond, config.backoff(2))
+	assert.Equal(t, 225*time.Millisecond, config.backoff(3))
+	assert.Equal(t, time.Second, config.backoff(10))
+
+	// The whole-second fields still apply on their own.
+	legacy := &RetryConfig{InitialBackoffSec: 1, MaxBackoffSec: 10, BackoffMultiplier: 2}
+	assert.Equal(t, 4*time.Second, legacy.backoff(3))
+	assert.Equal(t, 10*time.Second, legacy.backoff(5))
+}
+
+func TestRetryConfig_Jitter(t *testing.T) {
+	config := &RetryConfig{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+
+	for range 200 {
+		config.Jitter = JitterFull
+		delay := config.delay(2, 0)
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.Less(t, delay, 200*time.Millisecond)
+
+		config.Jitter = JitterEqual
+		delay = config.delay(2, 0)
+		assert.GreaterOrEqual(t, delay, 100*time.Millisecond)
+		assert.Less(t, delay, 200*time.Millisecond)
+
+		config.Jitter = JitterDecorrelated
+		delay = config.delay(2, 250*time.Millisecond)
+		assert.GreaterOrEqual(t, delay, 100*time.Millisecond)
+		assert.Less(t, delay, 750*time.Millisecond)
+		assert.LessOrEqual(t, config.delay(5, 900*time.Millisecond), time.Second)
+	}
+
+	config.Jitter = JitterNone
+	assert.Equal(t, 200*time.Millisecond, config.delay(2, 0))
+}
+
+func TestRetryBudget(t *testing.T) {
+	budget := NewRetryBudget(&RetryBudgetOptions{Ratio: 0.5, MinRetriesPerSecond: 1, Window: 10 * time.Second})
+	for range 20 {
+		budget.call()
+	}
+	// Half of 20 calls plus one retry per second of the window.
+	for range 20 {
+		require.True(t, budget.withdraw())
+	}
+	assert.False(t, budget.withdraw())
+
+	budget.call()
+	budget.call()
+	assert.True(t, budget.withdraw())
+	assert.False(t, budget.withdraw())
+
+	var none *RetryBudget
+	assert.True(t, none.withdraw())
+}
+
+// newRetryGateway answers with errors of the statuses given, one per call,
+// then as usual, through a client retrying with config.
+func newRetryGateway(t *testing.T, config *RetryConfig, statuses ...int) (*testGateway, Client) {
+	t.Helper()
+	gateway := newTestGateway(t)
+	for _, status := range statuses {
+		gateway.enqueue(errorReply(status, fmt.Sprintf("status %d", status)))
+	}
+	return gateway, gateway.client(&ClientOptions{RetryConfig: config})
+}
+
+// idempotencyKeys returns the idempotency key of every request so far.
+func idempotencyKeys(gateway *testGateway) []string {
+	var keys []string
+	for _, r := range gateway.calls() {
+		keys = append(keys, r.header.Get(IdempotencyKeyHeader))
+	}
+	return keys
+}
+
+func TestRetryConfig_Classifier(t *testing.T) {
+	var attempts []RetryAttempt
+	config := &RetryConfig{
+		Enabled:        true,
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		Classifier: func(attempt RetryAttempt) RetryDecision {
+			attempts = append(attempts, attempt)
+			switch {
+			case strings.Contains(string(attempt.Body), "status 400"):
+				return Retry
+			case attempt.StatusCode == http.StatusServiceUnavailable:
+				return NoRetry
+			}
+			return RetryDefault
+		},
+	}
+	ctx := context.Background()
+
+	server, client := newRetryGateway(t, config, http.StatusBadRequest, http.StatusInternalServerError)
+	_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Equal(t, 3, server.count(""))
+	require.Len(t, attempts, 3)
+	assert.Equal(t, 1, attempts[0].Attempt)
+	assert.Equal(t, http.StatusBadRequest, attempts[0].StatusCode)
+	assert.Equal(t, http.StatusInternalServerError, attempts[1].StatusCode)
+	assert.Equal(t, http.StatusOK, attempts[2].StatusCode)
+
+	server, client = newRetryGateway(t, config, http.StatusServiceUnavailable)
+	_, err = client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	assert.ErrorContains(t, err, "status 503")
+	assert.Equal(t, 1, server.count(""))
+}
+
+func TestRetryConfig_Budget(t *testing.T) {
+	budget := NewRetryBudget(&RetryBudgetOptions{Ratio: 0.1, MinRetriesPerSecond: 1, Window: time.Second})
+	var retries int
+	config := &RetryConfig{
+		Enabled:        true,
+		MaxAttempts:    5,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		Budget:         budget,
+		OnRetry:        func(int, error, time.Duration) { retries++ },
+	}
+	server, client := newRetryGateway(t, config, 500, 500, 500, 500, 500)
+
+	// One retry a second is allowed; the call then fails without the rest.
+	_, err := client.GenerateContent(context.Background(), Openai, "gpt-4o", hello())
+	assert.ErrorContains(t, err, "HTTP 500")
+	assert.LessOrEqual(t, server.count(""), 3, "a new second may refill the budget once")
+	assert.Equal(t, server.count("")-1, retries)
+}
+
+func TestRetryConfig_IdempotencyKeys(t *testing.T) {
+	config := &RetryConfig{
+		Enabled:         true,
+		MaxAttempts:     3,
+		InitialBackoff:  time.Millisecond,
+		MaxBackoff:      time.Millisecond,
+		IdempotencyKeys: true,
+	}
+	server, client := newRetryGateway(t, config, http.StatusBadGateway)
+	ctx := context.Background()
+
+	_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	_, err = client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	_, err = client.ListModels(ctx)
+	require.NoError(t, err)
+
+	keys := idempotencyKeys(server)
+	require.Len(t, keys, 4)
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1], "retries reuse the call's key")
+	assert.NotEqual(t, keys[0], keys[2])
+	assert.Empty(t, keys[3], "only POST requests carry keys")
+
+	// Keys set on the context are sent even with IdempotencyKeys off.
+	server, client = newRetryGateway(t, &RetryConfig{Enabled: false})
+	_, err = client.GenerateContent(WithIdempotencyKey(ctx, "job-42"), Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"job-42"}, idempotencyKeys(server))
+}
+
+func TestIsRetryableError(t *testing.T) {
+	assert.True(t, isRetryableError(fmt.Errorf("read body: %w", io.ErrUnexpectedEOF)))
+	assert.True(t, isRetryableError(&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}))
+	assert.True(t, isRetryableError(fmt.Errorf("write: %w", syscall.ECONNRESET)))
+	assert.True(t, isRetryableError(&net.DNSError{Err: "no such host"}))
+	assert.False(t, isRetryableError(fmt.Errorf("invalid timeout value")), "messages aren't matched")
+	assert.False(t, isRetryableError(context.Canceled))
+	assert.False(t, isRetryableError(nil))
+}
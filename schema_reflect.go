@@ -0,0 +1,411 @@
+package sdk
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSONSchemaFor derives a strict JSON Schema from the Go type T, suitable for
+// Structured Outputs (`response_format` json_schema), tool parameters and
+// ValidateJSONSchema.
+//
+// Struct fields follow encoding/json: the `json` tag names fields, `-` skips
+// them, embedded structs are flattened and `,string` turns numbers into
+// strings. Every object is closed (`additionalProperties: false`) and lists
+// all of its properties as required, as strict mode demands; pointer and
+// `omitempty` fields are made nullable instead, so the model sends null
+// where encoding/json would leave the key out. Recursive types are emitted
+// as `$ref`s into `$defs`.
+//
+// Use a `jsonschema` tag to add keywords, separated by commas (escape a
+// literal comma as `\,`):
+//
+//	type Forecast struct {
+//		City  string  `json:"city" jsonschema:"description=City name\, country"`
+//		Unit  string  `json:"unit" jsonschema:"enum=celsius,enum=fahrenheit"`
+//		Days  int     `json:"days" jsonschema:"minimum=1,maximum=14"`
+//		Notes *string `json:"notes,omitempty"`
+//	}
+//
+// Supported keys are description, title, enum, format, pattern, minimum,
+// maximum, exclusiveMinimum, exclusiveMaximum, multipleOf, minLength,
+// maxLength, minItems and maxItems. Enum values of non-string fields are
+// parsed as numbers or booleans where possible.
+func JSONSchemaFor[T any]() (map[string]any, error) {
+	return JSONSchemaForType(reflect.TypeFor[T]())
+}
+
+// JSONSchemaForType is JSONSchemaFor for a reflect.Type.
+func JSONSchemaForType(t reflect.Type) (map[string]any, error) {
+	r := &schemaReflector{
+		defs:  map[string]any{},
+		names: map[reflect.Type]string{},
+		stack: map[reflect.Type]bool{},
+	}
+	schema, err := r.schemaOf(t)
+	if err != nil {
+		return nil, err
+	}
+
+	for len(r.pending) > 0 {
+		def := r.pending[0]
+		r.pending = r.pending[1:]
+		r.stack[def] = true
+		s, err := r.structSchema(def)
+		delete(r.stack, def)
+		if err != nil {
+			return nil, err
+		}
+		r.defs[r.names[def]] = s
+	}
+	if len(r.defs) > 0 {
+		schema["$defs"] = r.defs
+	}
+
+	return schema, nil
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()
+	jsonNumberType    = reflect.TypeFor[json.Number]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+type schemaReflector struct {
+	defs    map[string]any
+	names   map[reflect.Type]string // struct types referenced through $defs
+	stack   map[reflect.Type]bool   // struct types currently being expanded
+	pending []reflect.Type          // $defs entries still to be generated
+}
+
+func (r *schemaReflector) schemaOf(t reflect.Type) (map[string]any, error) {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}, nil
+	case rawMessageType:
+		return map[string]any{}, nil
+	case jsonNumberType:
+		return map[string]any{"type": "number"}, nil
+	}
+
+	if t.Kind() == reflect.Pointer {
+		elem, err := r.schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return nullableSchema(elem), nil
+	}
+
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		// A custom encoding has an unknown shape.
+		return map[string]any{}, nil
+	}
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return map[string]any{"type": "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": "integer", "minimum": 0}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Interface:
+		return map[string]any{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}, nil
+		}
+		items, err := r.schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		schema := map[string]any{"type": "array", "items": items}
+		if t.Kind() == reflect.Array {
+			schema["minItems"] = t.Len()
+			schema["maxItems"] = t.Len()
+		}
+		return schema, nil
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		values, err := r.schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		if r.stack[t] {
+			return map[string]any{"$ref": "#/$defs/" + r.defName(t)}, nil
+		}
+		r.stack[t] = true
+		defer delete(r.stack, t)
+		return r.structSchema(t)
+	}
+
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// defName returns the $defs key for a recursive struct type, scheduling its
+// definition the first time it is seen.
+func (r *schemaReflector) defName(t reflect.Type) string {
+	if name, ok := r.names[t]; ok {
+		return name
+	}
+	base := t.Name()
+	if base == "" {
+		base = "Object"
+	}
+	name := base
+	for i := 2; r.nameTaken(name); i++ {
+		name = base + strconv.Itoa(i)
+	}
+	r.names[t] = name
+	r.pending = append(r.pending, t)
+	return name
+}
+
+func (r *schemaReflector) nameTaken(name string) bool {
+	for _, taken := range r.names {
+		if taken == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *schemaReflector) structSchema(t reflect.Type) (map[string]any, error) {
+	fields := schemaFields(t)
+
+	properties := make(map[string]any, len(fields))
+	required := make([]string, 0, len(fields))
+	for _, f := range fields {
+		// Tags describe the value itself, so they go on the element schema
+		// before a pointer or omitempty field is made nullable.
+		typ := f.typ
+		for typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		schema, err := r.schemaOf(typ)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.name, err)
+		}
+		if f.asString {
+			schema = map[string]any{"type": "string"}
+		}
+		if err := applySchemaTag(schema, f.tag); err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.name, err)
+		}
+		if f.omitEmpty || typ != f.typ {
+			schema = nullableSchema(schema)
+		}
+		properties[f.name] = schema
+		required = append(required, f.name)
+	}
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}, nil
+}
+
+type schemaField struct {
+	name      string
+	typ       reflect.Type
+	tag       string
+	omitEmpty bool
+	asString  bool
+	depth     int
+}
+
+// schemaFields lists the JSON-visible fields of a struct type, flattening
+// embedded structs the way encoding/json does: a field at a shallower depth
+// hides one with the same name further down.
+func schemaFields(t reflect.Type) []schemaField {
+	var fields []schemaField
+	index := map[string]int{}
+
+	var walk func(t reflect.Type, depth int, seen map[reflect.Type]bool)
+	walk = func(t reflect.Type, depth int, seen map[reflect.Type]bool) {
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+
+			if sf.Anonymous && name == "" {
+				ft := sf.Type
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					walk(ft, depth+1, seen)
+					continue
+				}
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = sf.Name
+			}
+
+			f := schemaField{
+				name:      name,
+				typ:       sf.Type,
+				tag:       sf.Tag.Get("jsonschema"),
+				omitEmpty: hasTagOption(opts, "omitempty") || hasTagOption(opts, "omitzero"),
+				asString:  hasTagOption(opts, "string") && isStringableKind(sf.Type),
+				depth:     depth,
+			}
+			if existing, ok := index[name]; ok {
+				if fields[existing].depth > depth {
+					fields[existing] = f
+				}
+				continue
+			}
+			index[name] = len(fields)
+			fields = append(fields, f)
+		}
+	}
+	walk(t, 0, map[reflect.Type]bool{})
+
+	return fields
+}
+
+func hasTagOption(opts, option string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+func isStringableKind(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// nullableSchema additionally allows null: by widening `type` where that is
+// enough, and with anyOf otherwise.
+func nullableSchema(schema map[string]any) map[string]any {
+	if typ, ok := schema["type"].(string); ok {
+		schema["type"] = []any{typ, "null"}
+		if enum, ok := schema["enum"].([]any); ok {
+			schema["enum"] = append(enum, nil)
+		}
+		return schema
+	}
+	if len(schema) == 0 {
+		return schema
+	}
+	return map[string]any{"anyOf": []any{schema, map[string]any{"type": "null"}}}
+}
+
+// applySchemaTag merges the keywords of a `jsonschema` struct tag into
+// schema.
+func applySchemaTag(schema map[string]any, tag string) error {
+	if tag == "" {
+		return nil
+	}
+	for _, part := range splitSchemaTag(tag) {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return fmt.Errorf("invalid jsonschema tag entry %q", part)
+		}
+		switch key {
+		case "description", "title", "format", "pattern":
+			schema[key] = value
+		case "enum":
+			enum, _ := schema["enum"].([]any)
+			if schema["type"] == "string" {
+				schema["enum"] = append(enum, value)
+			} else {
+				schema["enum"] = append(enum, schemaTagValue(value))
+			}
+		case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("jsonschema %s must be a number, got %q", key, value)
+			}
+			schema[key] = n
+		case "minLength", "maxLength", "minItems", "maxItems":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("jsonschema %s must be an integer, got %q", key, value)
+			}
+			schema[key] = n
+		default:
+			return fmt.Errorf("unsupported jsonschema tag key %q", key)
+		}
+	}
+	return nil
+}
+
+// splitSchemaTag splits a tag on commas, honouring `\,` escapes.
+func splitSchemaTag(tag string) []string {
+	var parts []string
+	var current strings.Builder
+	for i := 0; i < len(tag); i++ {
+		switch {
+		case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',':
+			current.WriteByte(',')
+			i++
+		case tag[i] == ',':
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(tag[i])
+		}
+	}
+	return append(parts, current.String())
+}
+
+func schemaTagValue(value string) any {
+	if value == "true" || value == "false" {
+		return value == "true"
+	}
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		return n
+	}
+	return value
+}
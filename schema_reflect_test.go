@@ -0,0 +1,130 @@
+package sdk
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+type schemaTestBase struct {
+	ID      string `json:"id"`
+	Ignored string `json:"name"`
+}
+
+type schemaTestForecast struct {
+	schemaTestBase
+	Name     string            `json:"name" jsonschema:"description=City name\\, country"`
+	Unit     string            `json:"unit" jsonschema:"enum=celsius,enum=fahrenheit"`
+	Days     int               `json:"days" jsonschema:"minimum=1,maximum=14"`
+	Rain     *float64          `json:"rain,omitempty"`
+	Level    *int              `json:"level" jsonschema:"enum=1,enum=2"`
+	Tags     []string          `json:"tags"`
+	Pair     [2]int            `json:"pair"`
+	Extra    map[string]bool   `json:"extra"`
+	Count    int64             `json:"count,string"`
+	At       time.Time         `json:"at"`
+	Raw      json.RawMessage   `json:"raw"`
+	Skipped  string            `json:"-"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	internal string
+}
+
+type schemaTestNode struct {
+	Value    string            `json:"value"`
+	Children []*schemaTestNode `json:"children"`
+}
+
+func TestJSONSchemaFor(t *testing.T) {
+	schema, err := JSONSchemaFor[schemaTestForecast]()
+	require.NoError(t, err)
+
+	expected := `{
+		"type": "object",
+		"additionalProperties": false,
+		"required": ["id", "name", "unit", "days", "rain", "level", "tags", "pair", "extra", "count", "at", "raw", "labels"],
+		"properties": {
+			"id": {"type": "string"},
+			"name": {"type": "string", "description": "City name, country"},
+			"unit": {"type": "string", "enum": ["celsius", "fahrenheit"]},
+			"days": {"type": "integer", "minimum": 1, "maximum": 14},
+			"rain": {"type": ["number", "null"]},
+			"level": {"type": ["integer", "null"], "enum": [1, 2, null]},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"pair": {"type": "array", "items": {"type": "integer"}, "minItems": 2, "maxItems": 2},
+			"extra": {"type": "object", "additionalProperties": {"type": "boolean"}},
+			"count": {"type": "string"},
+			"at": {"type": "string", "format": "date-time"},
+			"raw": {},
+			"labels": {"type": ["object", "null"], "additionalProperties": {"type": "string"}}
+		}
+	}`
+	data, err := json.Marshal(schema)
+	require.NoError(t, err)
+	assert.JSONEq(t, expected, string(data))
+}
+
+func TestJSONSchemaFor_Recursive(t *testing.T) {
+	schema, err := JSONSchemaFor[schemaTestNode]()
+	require.NoError(t, err)
+
+	data, err := json.Marshal(schema)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"additionalProperties": false,
+		"required": ["value", "children"],
+		"properties": {
+			"value": {"type": "string"},
+			"children": {"type": "array", "items": {"anyOf": [{"$ref": "#/$defs/schemaTestNode"}, {"type": "null"}]}}
+		},
+		"$defs": {
+			"schemaTestNode": {
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["value", "children"],
+				"properties": {
+					"value": {"type": "string"},
+					"children": {"type": "array", "items": {"anyOf": [{"$ref": "#/$defs/schemaTestNode"}, {"type": "null"}]}}
+				}
+			}
+		}
+	}`, string(data))
+
+	tree := `{"value":"root","children":[{"value":"leaf","children":[]},null]}`
+	assert.NoError(t, ValidateJSONSchema(schema, decodeJSON(t, tree)))
+	assert.Error(t, ValidateJSONSchema(schema, decodeJSON(t, `{"value":"root","children":[{"value":1,"children":[]}]}`)))
+}
+
+func TestJSONSchemaFor_Validates(t *testing.T) {
+	schema, err := JSONSchemaFor[schemaTestForecast]()
+	require.NoError(t, err)
+
+	value := schemaTestForecast{
+		Name:   "Paris",
+		Unit:   "celsius",
+		Days:   3,
+		Rain:   new(0.5),
+		Tags:   []string{},
+		Extra:  map[string]bool{},
+		Raw:    json.RawMessage(`1`),
+		Labels: map[string]string{"source": "test"},
+	}
+	data, err := json.Marshal(value)
+	require.NoError(t, err)
+	assert.NoError(t, ValidateJSONSchema(schema, decodeJSON(t, string(data))))
+}
+
+func TestJSONSchemaFor_Unsupported(t *testing.T) {
+	_, err := JSONSchemaFor[struct {
+		Callback func() `json:"callback"`
+	}]()
+	assert.EqualError(t, err, "field callback: unsupported type func()")
+
+	_, err = JSONSchemaFor[struct {
+		Days int `json:"days" jsonschema:"minimum=one"`
+	}]()
+	assert.EqualError(t, err, `field days: jsonschema minimum must be a number, got "one"`)
+}
@@ -0,0 +1,505 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// SchemaViolation is a single way a value fails a JSON Schema.
+type SchemaViolation struct {
+	// Path locates the offending value, e.g. `$.items[2].name`.
+	Path string `json:"path"`
+	// Message describes the failure.
+	Message string `json:"message"`
+}
+
+// SchemaValidationError lists every violation found while validating a value
+// against a JSON Schema.
+type SchemaValidationError struct {
+	Violations []SchemaViolation
+}
+
+func (e *SchemaValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = fmt.Sprintf("%s: %s", v.Path, v.Message)
+	}
+	return "schema validation failed: " + strings.Join(parts, "; ")
+}
+
+// ValidateJSONSchema checks value, as decoded by encoding/json, against
+// schema. It supports the subset of JSON Schema draft 2020-12 used for tool
+// parameters and structured outputs: type (including type arrays and
+// "null"), enum, const, properties, required, additionalProperties, items,
+// prefixItems, minItems, maxItems, uniqueItems, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, multipleOf, minLength, maxLength,
+// pattern, minProperties, maxProperties, allOf, anyOf, oneOf, not, and local
+// $ref pointers into $defs. Unknown keywords are ignored.
+//
+// It returns nil or a *SchemaValidationError.
+func ValidateJSONSchema(schema map[string]any, value any) error {
+	v := &schemaValidator{root: schema}
+	v.validate(schema, value, "$")
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &SchemaValidationError{Violations: v.violations}
+}
+
+type schemaValidator struct {
+	root       map[string]any
+	violations []SchemaViolation
+	depth      int
+}
+
+func (v *schemaValidator) fail(path, format string, args ...any) {
+	v.violations = append(v.violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// check validates value against schema without recording violations and
+// reports whether it passed.
+func (v *schemaValidator) check(schema any, value any, path string) bool {
+	sub := &schemaValidator{root: v.root, depth: v.depth}
+	sub.validate(schema, value, path)
+	return len(sub.violations) == 0
+}
+
+func (v *schemaValidator) validate(rawSchema any, value any, path string) {
+	switch s := rawSchema.(type) {
+	case bool:
+		if !s {
+			v.fail(path, "no value is allowed here")
+		}
+		return
+	case FunctionParameters:
+		rawSchema = map[string]any(s)
+	case ResponseFormatJSONSchemaSchema:
+		rawSchema = map[string]any(s)
+	}
+
+	schema, ok := rawSchema.(map[string]any)
+	if !ok {
+		return
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		v.depth++
+		defer func() { v.depth-- }()
+		if v.depth > 64 {
+			v.fail(path, "schema $ref nesting is too deep")
+			return
+		}
+		target, err := resolveSchemaRef(v.root, ref)
+		if err != nil {
+			v.fail(path, "%v", err)
+			return
+		}
+		v.validate(target, value, path)
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if jsonTypeMatches(t, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(path, "expected %s, got %s", strings.Join(types, " or "), jsonTypeName(value))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"]; ok {
+		options := toAnySlice(enum)
+		found := false
+		for _, option := range options {
+			if jsonEqual(option, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "must be one of %s", formatEnum(options))
+		}
+	}
+
+	if constant, ok := schema["const"]; ok && !jsonEqual(constant, value) {
+		v.fail(path, "must equal %s", formatJSON(constant))
+	}
+
+	switch val := value.(type) {
+	case map[string]any:
+		v.validateObject(schema, val, path)
+	case []any:
+		v.validateArray(schema, val, path)
+	case string:
+		v.validateString(schema, val, path)
+	case float64:
+		v.validateNumber(schema, val, path)
+	case json.Number:
+		if f, err := val.Float64(); err == nil {
+			v.validateNumber(schema, f, path)
+		}
+	}
+
+	for _, sub := range toAnySlice(schema["allOf"]) {
+		v.validate(sub, value, path)
+	}
+
+	if anyOf := toAnySlice(schema["anyOf"]); len(anyOf) > 0 {
+		matched := false
+		for _, sub := range anyOf {
+			if v.check(sub, value, path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(path, "does not match any of the allowed schemas")
+		}
+	}
+
+	if oneOf := toAnySlice(schema["oneOf"]); len(oneOf) > 0 {
+		matches := 0
+		for _, sub := range oneOf {
+			if v.check(sub, value, path) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			v.fail(path, "must match exactly one of the allowed schemas, matched %d", matches)
+		}
+	}
+
+	if not, ok := schema["not"]; ok && v.check(not, value, path) {
+		v.fail(path, "must not match the disallowed schema")
+	}
+}
+
+func (v *schemaValidator) validateObject(schema map[string]any, obj map[string]any, path string) {
+	for _, name := range toStringSlice(schema["required"]) {
+		if _, ok := obj[name]; !ok {
+			v.fail(joinSchemaPath(path, name), "is required")
+		}
+	}
+
+	properties, _ := schemaMap(schema["properties"])
+
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if propSchema, ok := properties[key]; ok {
+			v.validate(propSchema, obj[key], joinSchemaPath(path, key))
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.fail(joinSchemaPath(path, key), "is not an allowed property")
+			}
+		case nil:
+		default:
+			v.validate(additional, obj[key], joinSchemaPath(path, key))
+		}
+	}
+
+	if n, ok := schemaNumber(schema["minProperties"]); ok && float64(len(obj)) < n {
+		v.fail(path, "must have at least %s properties", formatNumber(n))
+	}
+	if n, ok := schemaNumber(schema["maxProperties"]); ok && float64(len(obj)) > n {
+		v.fail(path, "must have at most %s properties", formatNumber(n))
+	}
+}
+
+func (v *schemaValidator) validateArray(schema map[string]any, arr []any, path string) {
+	prefix := toAnySlice(schema["prefixItems"])
+	for i, item := range arr {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		if i < len(prefix) {
+			v.validate(prefix[i], item, itemPath)
+			continue
+		}
+		if items, ok := schema["items"]; ok {
+			v.validate(items, item, itemPath)
+		}
+	}
+
+	if n, ok := schemaNumber(schema["minItems"]); ok && float64(len(arr)) < n {
+		v.fail(path, "must have at least %s items", formatNumber(n))
+	}
+	if n, ok := schemaNumber(schema["maxItems"]); ok && float64(len(arr)) > n {
+		v.fail(path, "must have at most %s items", formatNumber(n))
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if jsonEqual(arr[i], arr[j]) {
+					v.fail(path, "items %d and %d are duplicates", i, j)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (v *schemaValidator) validateString(schema map[string]any, s string, path string) {
+	length := float64(utf8.RuneCountInString(s))
+	if n, ok := schemaNumber(schema["minLength"]); ok && length < n {
+		v.fail(path, "must be at least %s characters long", formatNumber(n))
+	}
+	if n, ok := schemaNumber(schema["maxLength"]); ok && length > n {
+		v.fail(path, "must be at most %s characters long", formatNumber(n))
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := compileSchemaPattern(pattern)
+		if err != nil {
+			v.fail(path, "schema pattern %q is invalid: %v", pattern, err)
+		} else if !re.MatchString(s) {
+			v.fail(path, "must match pattern %q", pattern)
+		}
+	}
+}
+
+func (v *schemaValidator) validateNumber(schema map[string]any, n float64, path string) {
+	if min, ok := schemaNumber(schema["minimum"]); ok && n < min {
+		v.fail(path, "must be >= %s", formatNumber(min))
+	}
+	if max, ok := schemaNumber(schema["maximum"]); ok && n > max {
+		v.fail(path, "must be <= %s", formatNumber(max))
+	}
+	if min, ok := schemaNumber(schema["exclusiveMinimum"]); ok && n <= min {
+		v.fail(path, "must be > %s", formatNumber(min))
+	}
+	if max, ok := schemaNumber(schema["exclusiveMaximum"]); ok && n >= max {
+		v.fail(path, "must be < %s", formatNumber(max))
+	}
+	if m, ok := schemaNumber(schema["multipleOf"]); ok && m > 0 {
+		if q := n / m; math.Abs(q-math.Round(q)) > 1e-9 {
+			v.fail(path, "must be a multiple of %s", formatNumber(m))
+		}
+	}
+}
+
+var schemaPatternCache sync.Map
+
+func compileSchemaPattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := schemaPatternCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	schemaPatternCache.Store(pattern, re)
+	return re, nil
+}
+
+// resolveSchemaRef resolves a local JSON pointer such as `#/$defs/Item`.
+func resolveSchemaRef(root map[string]any, ref string) (any, error) {
+	if ref == "#" {
+		return root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported schema $ref %q", ref)
+	}
+
+	var current any = root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		m, ok := schemaMap(current)
+		if !ok {
+			return nil, fmt.Errorf("unresolvable schema $ref %q", ref)
+		}
+		if current, ok = m[token]; !ok {
+			return nil, fmt.Errorf("unresolvable schema $ref %q", ref)
+		}
+	}
+	return current, nil
+}
+
+func schemaMap(v any) (map[string]any, bool) {
+	switch m := v.(type) {
+	case map[string]any:
+		return m, true
+	case FunctionParameters:
+		return m, true
+	case ResponseFormatJSONSchemaSchema:
+		return m, true
+	}
+	return nil, false
+}
+
+func schemaTypes(v any) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	default:
+		return toStringSlice(v)
+	}
+}
+
+func jsonTypeMatches(schemaType string, value any) bool {
+	switch schemaType {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		switch value.(type) {
+		case float64, json.Number:
+			return true
+		}
+		return false
+	case "integer":
+		switch n := value.(type) {
+		case float64:
+			return n == math.Trunc(n) && !math.IsInf(n, 0)
+		case json.Number:
+			_, err := n.Int64()
+			return err == nil
+		}
+		return false
+	}
+	return true
+}
+
+func jsonTypeName(value any) string {
+	switch n := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		if n == math.Trunc(n) {
+			return "integer"
+		}
+		return "number"
+	case json.Number:
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// toAnySlice accepts []any as well as typed slices built in Go code, such as
+// the []string enums used throughout the examples.
+func toAnySlice(v any) []any {
+	switch s := v.(type) {
+	case nil:
+		return nil
+	case []any:
+		return s
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil
+	}
+	out := make([]any, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out
+}
+
+func toStringSlice(v any) []string {
+	items := toAnySlice(v)
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func schemaNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// jsonEqual compares two values by their JSON encoding, so that schema
+// values written in Go (ints, []string) compare equal to decoded JSON.
+func jsonEqual(a, b any) bool {
+	return formatJSON(a) == formatJSON(b)
+}
+
+func formatJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+func formatEnum(options []any) string {
+	parts := make([]string, len(options))
+	for i, option := range options {
+		parts[i] = formatJSON(option)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+func joinSchemaPath(path, key string) string {
+	if isSchemaIdentifier(key) {
+		return path + "." + key
+	}
+	return fmt.Sprintf("%s[%q]", path, key)
+}
+
+func isSchemaIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
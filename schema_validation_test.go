@@ -0,0 +1,168 @@
+package sdk
+
+import (
+	"encoding/json"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+func decodeJSON(t *testing.T, s string) any {
+	t.Helper()
+	var v any
+	require.NoError(t, json.Unmarshal([]byte(s), &v))
+	return v
+}
+
+func TestValidateJSONSchema(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"location": map[string]any{"type": "string", "minLength": 2, "pattern": "^[a-z ]+$"},
+			"unit":     map[string]any{"type": "string", "enum": []string{"celsius", "fahrenheit"}},
+			"days":     map[string]any{"type": "integer", "minimum": 1, "maximum": 14},
+			"ratio":    map[string]any{"type": "number", "exclusiveMinimum": 0, "exclusiveMaximum": 1},
+			"note":     map[string]any{"type": []any{"string", "null"}, "maxLength": 5},
+			"tags": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"minItems":    1,
+				"maxItems":    3,
+				"uniqueItems": true,
+			},
+			"owner": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name": map[string]any{"type": "string"},
+				},
+				"required":             []string{"name"},
+				"additionalProperties": false,
+			},
+		},
+		"required": []string{"location"},
+	}
+
+	tests := []struct {
+		name       string
+		value      string
+		violations []SchemaViolation
+	}{
+		{
+			name:  "valid value",
+			value: `{"location":"san francisco","unit":"celsius","days":3,"ratio":0.5,"note":null,"tags":["a","b"],"owner":{"name":"x"}}`,
+		},
+		{
+			name:       "missing required property",
+			value:      `{"unit":"celsius"}`,
+			violations: []SchemaViolation{{Path: "$.location", Message: "is required"}},
+		},
+		{
+			name:       "wrong type",
+			value:      `{"location":12}`,
+			violations: []SchemaViolation{{Path: "$.location", Message: "expected string, got integer"}},
+		},
+		{
+			name:       "enum",
+			value:      `{"location":"london","unit":"kelvin"}`,
+			violations: []SchemaViolation{{Path: "$.unit", Message: `must be one of ["celsius", "fahrenheit"]`}},
+		},
+		{
+			name:  "numeric bounds",
+			value: `{"location":"london","days":20,"ratio":1}`,
+			violations: []SchemaViolation{
+				{Path: "$.days", Message: "must be <= 14"},
+				{Path: "$.ratio", Message: "must be < 1"},
+			},
+		},
+		{
+			name:       "integer rejects fractions",
+			value:      `{"location":"london","days":1.5}`,
+			violations: []SchemaViolation{{Path: "$.days", Message: "expected integer, got number"}},
+		},
+		{
+			name:  "string length and pattern",
+			value: `{"location":"X","note":"too long"}`,
+			violations: []SchemaViolation{
+				{Path: "$.location", Message: "must be at least 2 characters long"},
+				{Path: "$.location", Message: `must match pattern "^[a-z ]+$"`},
+				{Path: "$.note", Message: "must be at most 5 characters long"},
+			},
+		},
+		{
+			name:  "arrays",
+			value: `{"location":"london","tags":["a","a",3,"d"]}`,
+			violations: []SchemaViolation{
+				{Path: "$.tags[2]", Message: "expected string, got integer"},
+				{Path: "$.tags", Message: "must have at most 3 items"},
+				{Path: "$.tags", Message: "items 0 and 1 are duplicates"},
+			},
+		},
+		{
+			name:  "nested objects",
+			value: `{"location":"london","owner":{"nickname":"x"}}`,
+			violations: []SchemaViolation{
+				{Path: "$.owner.name", Message: "is required"},
+				{Path: "$.owner.nickname", Message: "is not an allowed property"},
+			},
+		},
+		{
+			name:       "root type",
+			value:      `["london"]`,
+			violations: []SchemaViolation{{Path: "$", Message: "expected object, got array"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateJSONSchema(schema, decodeJSON(t, tt.value))
+			if tt.violations == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var validationErr *SchemaValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tt.violations, validationErr.Violations)
+		})
+	}
+}
+
+func TestValidateJSONSchema_Combinators(t *testing.T) {
+	schema := map[string]any{
+		"$defs": map[string]any{
+			"id": map[string]any{"type": "string", "pattern": "^id_"},
+		},
+		"type": "object",
+		"properties": map[string]any{
+			"ref":    map[string]any{"$ref": "#/$defs/id"},
+			"either": map[string]any{"anyOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "integer"}}},
+			"one":    map[string]any{"oneOf": []any{map[string]any{"type": "number"}, map[string]any{"type": "integer"}}},
+			"kind":   map[string]any{"const": "point"},
+			"pair":   map[string]any{"type": "array", "prefixItems": []any{map[string]any{"type": "string"}, map[string]any{"type": "number"}}},
+		},
+	}
+
+	assert.NoError(t, ValidateJSONSchema(schema, decodeJSON(t, `{"ref":"id_1","either":3,"one":1.5,"kind":"point","pair":["x",1]}`)))
+
+	err := ValidateJSONSchema(schema, decodeJSON(t, `{"ref":"x","either":true,"one":2,"kind":"line","pair":[1,"x"]}`))
+	var validationErr *SchemaValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []SchemaViolation{
+		{Path: "$.either", Message: "does not match any of the allowed schemas"},
+		{Path: "$.kind", Message: `must equal "point"`},
+		{Path: "$.one", Message: "must match exactly one of the allowed schemas, matched 2"},
+		{Path: "$.pair[0]", Message: "expected string, got integer"},
+		{Path: "$.pair[1]", Message: "expected number, got string"},
+		{Path: "$.ref", Message: `must match pattern "^id_"`},
+	}, validationErr.Violations)
+}
+
+func TestValidateJSONSchema_FunctionParameters(t *testing.T) {
+	params := FunctionParameters{
+		"type":       "object",
+		"properties": map[string]any{"a": map[string]any{"type": "number"}},
+		"required":   []string{"a"},
+	}
+	assert.NoError(t, ValidateJSONSchema(params, map[string]any{"a": 1.0}))
+	assert.Error(t, ValidateJSONSchema(params, map[string]any{}))
+}
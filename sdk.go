@@ -5,11 +5,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"math"
+	"log/slog"
 	"net"
 	"net/http"
+	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
@@ -34,6 +37,8 @@ type Client interface {
 	GenerateContentStream(ctx context.Context, provider Provider, model string, messages []Message) (<-chan SSEvent, error)
 	CreateMessage(ctx context.Context, provider Provider, request CreateMessagesRequest) (*MessagesResponse, error)
 	CreateMessageStream(ctx context.Context, provider Provider, request CreateMessagesRequest) (<-chan SSEvent, error)
+	CreateResponse(ctx context.Context, provider Provider, request CreateResponseRequest) (*Response, error)
+	CreateResponseStream(ctx context.Context, provider Provider, request CreateResponseRequest) (<-chan SSEvent, error)
 	CreateImage(ctx context.Context, provider Provider, request CreateImageRequest) (*ImagesResponse, error)
 	CreateImageEdit(ctx context.Context, provider Provider, request CreateImageEditMultipartBody) (*ImagesResponse, error)
 	CreateImageVariation(ctx context.Context, provider Provider, request CreateImageVariationMultipartBody) (*ImagesResponse, error)
@@ -46,35 +51,23 @@ func isRetryableError(err error) bool {
 		return false
 	}
 
-	if netErr, ok := err.(net.Error); ok {
-		if netErr.Timeout() {
-			return true
-		}
-	}
-
-	if opErr, ok := err.(*net.OpError); ok {
-		if opErr.Op == "dial" || opErr.Op == "read" {
-			return true
-		}
-		if sysErr, ok := opErr.Err.(*syscall.Errno); ok {
-			return *sysErr == syscall.ECONNREFUSED || *sysErr == syscall.ECONNRESET
-		}
+	if netErr, ok := errors.AsType[net.Error](err); ok && netErr.Timeout() {
+		return true
 	}
 
-	if _, ok := err.(*net.DNSError); ok {
+	if opErr, ok := errors.AsType[*net.OpError](err); ok && (opErr.Op == "dial" || opErr.Op == "read") {
 		return true
 	}
 
-	if err == context.DeadlineExceeded {
+	if _, ok := errors.AsType[*net.DNSError](err); ok {
 		return true
 	}
 
-	errStr := err.Error()
-	return strings.Contains(errStr, "connection refused") ||
-		strings.Contains(errStr, "connection reset") ||
-		strings.Contains(errStr, "timeout") ||
-		strings.Contains(errStr, "EOF") ||
-		strings.Contains(errStr, "timeout awaiting response headers")
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.DeadlineExceeded)
 }
 
 // isRetryableStatusCode determines if an HTTP status code should trigger a retry
@@ -102,19 +95,10 @@ func isRetryableStatusCode(statusCode int, config *RetryConfig) bool {
 	}
 }
 
-// calculateBackoff calculates the backoff delay for exponential backoff
+// calculateBackoff calculates the backoff delay for exponential backoff,
+// before jitter
 func calculateBackoff(attempt int, config *RetryConfig) time.Duration {
-	if attempt == 0 {
-		return 0
-	}
-
-	backoff := float64(config.InitialBackoffSec) * math.Pow(float64(config.BackoffMultiplier), float64(attempt-1))
-
-	if backoff > float64(config.MaxBackoffSec) {
-		backoff = float64(config.MaxBackoffSec)
-	}
-
-	return time.Duration(backoff) * time.Second
+	return config.backoff(attempt)
 }
 
 // getDefaultRetryConfig returns the default retry configuration
@@ -130,12 +114,20 @@ func getDefaultRetryConfig() *RetryConfig {
 
 // clientImpl represents the concrete implementation of the SDK client
 type clientImpl struct {
-	baseURL     string        // Base URL of the Inference Gateway API
-	http        *resty.Client // HTTP client for making requests
-	token       string        // Authentication token
-	tools       *[]ChatCompletionTool
-	options     *CreateChatCompletionRequest // Custom request options
-	retryConfig *RetryConfig                 // Retry configuration
+	baseURL      string        // Base URL of the Inference Gateway API
+	http         *resty.Client // HTTP client for making requests
+	token        string        // Authentication token
+	tools        *[]ChatCompletionTool
+	options      *CreateChatCompletionRequest // Custom request options
+	retryConfig  *RetryConfig                 // Retry configuration
+	usage        *UsageTracker                // Usage and budget tracking
+	validator    *RequestValidator            // Pre-flight request validation
+	breaker      *CircuitBreaker              // Per provider and endpoint circuit breaker
+	limiter      *RateLimiter                 // Per provider and model rate limits
+	hedging      *HedgingPolicy               // Hedged requests against slow responses
+	interceptors []Interceptor                // Interceptors of every call, outermost first
+	logger       *slog.Logger                 // Structured log of every call
+	logging      LoggingOptions               // What the logger logs besides outcomes
 }
 
 // NewClient creates a new SDK client with the specified options.
@@ -171,19 +163,40 @@ func NewClient(options *ClientOptions) Client {
 		client.SetTransport(options.Transport)
 	}
 
+	client.OnBeforeRequest(func(_ *resty.Client, r *resty.Request) error {
+		if key := IdempotencyKey(r.Context()); key != "" && r.Method == http.MethodPost {
+			r.SetHeader(IdempotencyKeyHeader, key)
+		}
+		return nil
+	})
+
 	retryConfig := options.RetryConfig
 	if retryConfig == nil {
 		retryConfig = getDefaultRetryConfig()
 	}
 
-	return &clientImpl{
-		baseURL:     options.BaseURL,
-		http:        client,
-		token:       options.APIKey,
-		tools:       options.Tools,
-		options:     nil,
-		retryConfig: retryConfig,
-	}
+	impl := &clientImpl{
+		baseURL:      options.BaseURL,
+		http:         client,
+		token:        options.APIKey,
+		tools:        options.Tools,
+		options:      nil,
+		retryConfig:  retryConfig,
+		usage:        options.UsageTracker,
+		validator:    options.Validator,
+		breaker:      options.CircuitBreaker,
+		limiter:      options.RateLimiter,
+		hedging:      options.Hedging,
+		interceptors: slices.Clone(options.Interceptors),
+		logger:       options.Logger,
+	}
+	if options.Logging != nil {
+		impl.logging = *options.Logging
+	}
+	if options.Cache != nil {
+		impl.interceptors = append(impl.interceptors, options.Cache.Intercept)
+	}
+	return impl
 }
 
 // parseRetryAfter parses the Retry-After header and returns the delay duration
@@ -209,30 +222,37 @@ func parseRetryAfter(retryAfter string) (time.Duration, bool) {
 
 // executeWithRetry executes an HTTP request with retry logic
 func (c *clientImpl) executeWithRetry(ctx context.Context, request func() (*resty.Response, error)) (*resty.Response, error) {
+	call := loggedCall(ctx)
+	request = call.observe(request)
 	if !c.retryConfig.Enabled {
 		return request()
 	}
 
 	var lastErr error
 	var resp *resty.Response
+	var delay time.Duration
 
+	c.retryConfig.Budget.call()
 	for attempt := 0; attempt < c.retryConfig.MaxAttempts; attempt++ {
 		if attempt > 0 {
-			var delay time.Duration
+			if !c.retryConfig.Budget.withdraw() {
+				break
+			}
 
+			retryAfterDelay, ok := time.Duration(0), false
 			if resp != nil && resp.StatusCode() == 429 {
-				if retryAfterDelay, ok := parseRetryAfter(resp.Header().Get("Retry-After")); ok {
-					delay = retryAfterDelay
-				} else {
-					delay = calculateBackoff(attempt, c.retryConfig)
-				}
+				retryAfterDelay, ok = parseRetryAfter(resp.Header().Get("Retry-After"))
+			}
+			if ok {
+				delay = retryAfterDelay
 			} else {
-				delay = calculateBackoff(attempt, c.retryConfig)
+				delay = c.retryConfig.delay(attempt, delay)
 			}
 
 			if c.retryConfig.OnRetry != nil {
 				c.retryConfig.OnRetry(attempt, lastErr, delay)
 			}
+			call.retry(ctx, attempt, lastErr, delay)
 
 			select {
 			case <-ctx.Done():
@@ -245,25 +265,87 @@ func (c *clientImpl) executeWithRetry(ctx context.Context, request func() (*rest
 		}
 
 		resp, lastErr = request()
+		retry := c.shouldRetry(attempt+1, resp, lastErr)
 
 		if lastErr == nil {
-			if !resp.IsError() || !isRetryableStatusCode(resp.StatusCode(), c.retryConfig) {
+			if !retry {
 				return resp, nil
 			}
-			lastErr = fmt.Errorf("HTTP %d", resp.StatusCode())
+			lastErr = &APIError{StatusCode: resp.StatusCode(), Body: resp.Body(), text: fmt.Sprintf("HTTP %d", resp.StatusCode())}
 			closeRawBody(resp)
 		}
 
-		if !isRetryableError(lastErr) && (resp == nil || !isRetryableStatusCode(resp.StatusCode(), c.retryConfig)) {
+		if !retry || ctx.Err() != nil {
 			break
 		}
+	}
 
-		if ctx.Err() != nil {
-			return resp, lastErr
+	return resp, lastErr
+}
+
+// shouldRetry classifies an attempt: first by the configured classifier,
+// then by the retryable status codes and network errors.
+func (c *clientImpl) shouldRetry(attempt int, resp *resty.Response, err error) bool {
+	if c.retryConfig.Classifier != nil {
+		outcome := RetryAttempt{Attempt: attempt, Err: err}
+		if resp != nil && resp.RawResponse != nil {
+			outcome.StatusCode = resp.StatusCode()
+			outcome.Header = resp.Header()
+			outcome.Body = resp.Body()
+		}
+		switch c.retryConfig.Classifier(outcome) {
+		case Retry:
+			return true
+		case NoRetry:
+			return false
 		}
 	}
 
-	return resp, lastErr
+	if err != nil {
+		return isRetryableError(err) || (resp != nil && isRetryableStatusCode(resp.StatusCode(), c.retryConfig))
+	}
+	return resp.IsError() && isRetryableStatusCode(resp.StatusCode(), c.retryConfig)
+}
+
+// execute runs request through executeWithRetry. When a circuit breaker is
+// configured, every attempt asks the breaker of provider and endpoint first
+// and reports its outcome, so half-open probes send one request and retries
+// stop once the circuit opens. A rejected attempt returns a
+// *CircuitOpenError without a response. The rate limiter, if any, sees the
+// headers of every attempt, and retries wait for its buckets through limit
+// like calls do. send is called with a context carrying the call's
+// idempotency key, if it has one.
+func (c *clientImpl) execute(ctx context.Context, provider Provider, endpoint, model string, limit *rateCall, send func(ctx context.Context) (*resty.Response, error)) (*resty.Response, error) {
+	loggedCall(ctx).setModel(model)
+	if c.retryConfig.IdempotencyKeys && IdempotencyKey(ctx) == "" {
+		ctx = WithIdempotencyKey(ctx, newIdempotencyKey())
+	}
+	attempts := 0
+	request := func() (*resty.Response, error) {
+		if attempts++; attempts > 1 {
+			if err := limit.retry(ctx); err != nil {
+				return nil, err
+			}
+		}
+		var done func(statusCode int, err error)
+		if c.breaker != nil {
+			var err error
+			if done, err = c.breaker.allow(c.breaker.key(provider, endpoint, model)); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := send(ctx)
+		c.limiter.observe(provider, model, resp)
+		if done != nil {
+			statusCode := 0
+			if resp != nil {
+				statusCode = resp.StatusCode()
+			}
+			done(statusCode, err)
+		}
+		return resp, err
+	}
+	return c.executeWithRetry(ctx, request)
 }
 
 // WithAuthToken sets the authentication token for the client.
@@ -352,6 +434,70 @@ func (c *clientImpl) WithOptions(options *CreateChatCompletionRequest) Client {
 	return c
 }
 
+// chatOptionsKey is the context key of per-call chat completion options.
+type chatOptionsKey struct{}
+
+// WithChatOptions returns a context whose chat completions are sent with
+// options on top of the client's own: each field options sets replaces the
+// client's, while Model, Messages and Stream are ignored. Unlike
+// WithOptions it leaves the client untouched, so concurrent calls can use
+// different options. Options added to a context that already has some are
+// applied after them.
+//
+// Client implementations read them with ChatOptions. The SDK's client
+// removes them from the context it passes on, so they apply to one call
+// and not to the calls interceptors make.
+//
+// Example:
+//
+//	ctx = sdk.WithChatOptions(ctx, &sdk.CreateChatCompletionRequest{Seed: new(42)})
+//	response, err := client.GenerateContent(ctx, sdk.Openai, "gpt-4o", messages)
+func WithChatOptions(ctx context.Context, options *CreateChatCompletionRequest) context.Context {
+	var merged CreateChatCompletionRequest
+	if outer := ChatOptions(ctx); outer != nil {
+		merged = *outer
+	}
+	if options != nil {
+		mergeChatOptions(&merged, options)
+	}
+	return context.WithValue(ctx, chatOptionsKey{}, &merged)
+}
+
+// ChatOptions returns a copy of the per-call chat completion options set on
+// ctx with WithChatOptions, or nil when there are none.
+func ChatOptions(ctx context.Context) *CreateChatCompletionRequest {
+	options, ok := ctx.Value(chatOptionsKey{}).(*CreateChatCompletionRequest)
+	if !ok || options == nil {
+		return nil
+	}
+	clone := *options
+	return &clone
+}
+
+// applyChatOptions applies the per-call options in ctx to request, which is
+// sent for model and messages whatever the client's options say, and
+// returns ctx without them.
+func applyChatOptions(ctx context.Context, request *CreateChatCompletionRequest, model string, messages []Message) context.Context {
+	options := ChatOptions(ctx)
+	if options == nil {
+		return ctx
+	}
+	stream := request.Stream
+	mergeChatOptions(request, options)
+	request.Model, request.Messages, request.Stream = model, messages, stream
+	return context.WithValue(ctx, chatOptionsKey{}, (*CreateChatCompletionRequest)(nil))
+}
+
+// mergeChatOptions copies the fields options sets onto request.
+func mergeChatOptions(request, options *CreateChatCompletionRequest) {
+	dst, src := reflect.ValueOf(request).Elem(), reflect.ValueOf(options).Elem()
+	for i := range src.NumField() {
+		if field := src.Field(i); !field.IsZero() {
+			dst.Field(i).Set(field)
+		}
+	}
+}
+
 // WithHeaders sets custom headers for the client.
 //
 // Example:
@@ -439,6 +585,14 @@ func (c *clientImpl) WithMiddlewareOptions(options *MiddlewareOptions) Client {
 //
 //	models, err := client.ListModels(ctx, sdk.ListModelsParamsIncludeContextWindow)
 func (c *clientImpl) ListModels(ctx context.Context, include ...ListModelsParamsInclude) (*ListModelsResponse, error) {
+	params := &ListModelsParams{Include: includeParam(include)}
+	return intercept(ctx, c, OperationListModels, "models", "", params, func(ctx context.Context, _ Provider, params *ListModelsParams) (*ListModelsResponse, error) {
+		return c.listModels(ctx, includeList(params))
+	})
+}
+
+// listModels lists the models of every provider.
+func (c *clientImpl) listModels(ctx context.Context, include []ListModelsParamsInclude) (*ListModelsResponse, error) {
 	resp, err := c.executeWithRetry(ctx, func() (*resty.Response, error) {
 		req := c.http.R().
 			SetContext(ctx).
@@ -454,7 +608,11 @@ func (c *clientImpl) ListModels(ctx context.Context, include ...ListModelsParams
 	}
 
 	if resp.IsError() {
-		return &ListModelsResponse{}, fmt.Errorf("failed to list models, status code: %d", resp.StatusCode())
+		return &ListModelsResponse{}, &APIError{
+			StatusCode: resp.StatusCode(),
+			Body:       resp.Body(),
+			text:       fmt.Sprintf("failed to list models, status code: %d", resp.StatusCode()),
+		}
 	}
 
 	result, ok := resp.Result().(*ListModelsResponse)
@@ -485,6 +643,14 @@ func (c *clientImpl) ListModels(ctx context.Context, include ...ListModelsParams
 //
 //	resp, err := client.ListProviderModels(ctx, sdk.Ollama, sdk.ListModelsParamsIncludeContextWindow)
 func (c *clientImpl) ListProviderModels(ctx context.Context, provider Provider, include ...ListModelsParamsInclude) (*ListModelsResponse, error) {
+	params := &ListModelsParams{Provider: &provider, Include: includeParam(include)}
+	return intercept(ctx, c, OperationListProviderModels, "models", provider, params, func(ctx context.Context, provider Provider, params *ListModelsParams) (*ListModelsResponse, error) {
+		return c.listProviderModels(ctx, provider, includeList(params))
+	})
+}
+
+// listProviderModels lists the models of one provider.
+func (c *clientImpl) listProviderModels(ctx context.Context, provider Provider, include []ListModelsParamsInclude) (*ListModelsResponse, error) {
 	resp, err := c.executeWithRetry(ctx, func() (*resty.Response, error) {
 		req := c.http.R().
 			SetContext(ctx).
@@ -500,18 +666,7 @@ func (c *clientImpl) ListProviderModels(ctx context.Context, provider Provider,
 	}
 
 	if resp.IsError() {
-		var errorResp Error
-		if err := json.Unmarshal(resp.Body(), &errorResp); err == nil && errorResp.Error != nil {
-			return nil, fmt.Errorf("API error: %s (status code: %d)", *errorResp.Error, resp.StatusCode())
-		}
-
-		errMsg := fmt.Sprintf("failed to list provider models, status code: %d", resp.StatusCode())
-
-		if len(resp.Body()) > 0 {
-			errMsg = fmt.Sprintf("%s, response body: %s", errMsg, string(resp.Body()))
-		}
-
-		return nil, fmt.Errorf("%s", errMsg)
+		return nil, gatewayError(resp.StatusCode(), resp.Body(), "API error", fmt.Sprintf("failed to list provider models, status code: %d", resp.StatusCode()))
 	}
 
 	result, ok := resp.Result().(*ListModelsResponse)
@@ -538,6 +693,13 @@ func (c *clientImpl) ListProviderModels(ctx context.Context, provider Provider,
 //	}
 //	fmt.Printf("Available tools: %+v\n", tools.Data)
 func (c *clientImpl) ListTools(ctx context.Context) (*ListToolsResponse, error) {
+	return intercept(ctx, c, OperationListTools, "mcp/tools", "", (*struct{})(nil), func(ctx context.Context, _ Provider, _ *struct{}) (*ListToolsResponse, error) {
+		return c.listTools(ctx)
+	})
+}
+
+// listTools lists the MCP tools.
+func (c *clientImpl) listTools(ctx context.Context) (*ListToolsResponse, error) {
 	resp, err := c.executeWithRetry(ctx, func() (*resty.Response, error) {
 		return c.http.R().
 			SetContext(ctx).
@@ -550,18 +712,7 @@ func (c *clientImpl) ListTools(ctx context.Context) (*ListToolsResponse, error)
 	}
 
 	if resp.IsError() {
-		var errorResp Error
-		if err := json.Unmarshal(resp.Body(), &errorResp); err == nil && errorResp.Error != nil {
-			return nil, fmt.Errorf("API error: %s (status code: %d)", *errorResp.Error, resp.StatusCode())
-		}
-
-		errMsg := fmt.Sprintf("failed to list MCP tools, status code: %d", resp.StatusCode())
-
-		if len(resp.Body()) > 0 {
-			errMsg = fmt.Sprintf("%s, response body: %s", errMsg, string(resp.Body()))
-		}
-
-		return nil, fmt.Errorf("%s", errMsg)
+		return nil, gatewayError(resp.StatusCode(), resp.Body(), "API error", fmt.Sprintf("failed to list MCP tools, status code: %d", resp.StatusCode()))
 	}
 
 	result, ok := resp.Result().(*ListToolsResponse)
@@ -627,13 +778,43 @@ func (c *clientImpl) GenerateContent(ctx context.Context, provider Provider, mod
 
 		request = options
 	}
+	ctx = applyChatOptions(ctx, &request, model, messages)
+
+	return intercept(ctx, c, OperationGenerateContent, "chat/completions", provider, &request, func(ctx context.Context, provider Provider, request *CreateChatCompletionRequest) (*CreateChatCompletionResponse, error) {
+		if c.hedging == nil {
+			return c.generateContent(ctx, provider, *request)
+		}
+		return hedge(ctx, c.hedging, provider, request.Model, func(ctx context.Context, provider Provider, model string) (*CreateChatCompletionResponse, error) {
+			request := *request
+			request.Model = model
+			return c.generateContent(ctx, provider, request)
+		})
+	})
+}
+
+// generateContent sends a chat completion request built by GenerateContent.
+func (c *clientImpl) generateContent(ctx context.Context, provider Provider, request CreateChatCompletionRequest) (*CreateChatCompletionResponse, error) {
+	if err := c.validator.ValidateChat(ctx, provider, request); err != nil {
+		return nil, err
+	}
+
+	usage, err := c.usage.start(ctx, provider, request.Model, request.User)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := c.limiter.acquire(ctx, provider, request.Model, request)
+	if err != nil {
+		return nil, err
+	}
+	defer limit.cancel()
 
 	queryParams := make(map[string]string)
 	if provider != "" {
 		queryParams["provider"] = string(provider)
 	}
 
-	resp, err := c.executeWithRetry(ctx, func() (*resty.Response, error) {
+	resp, err := c.execute(ctx, provider, "chat/completions", request.Model, limit, func(ctx context.Context) (*resty.Response, error) {
 		return c.http.R().
 			SetContext(ctx).
 			SetQueryParams(queryParams).
@@ -647,18 +828,7 @@ func (c *clientImpl) GenerateContent(ctx context.Context, provider Provider, mod
 	}
 
 	if resp.IsError() {
-		var errorResp Error
-		if err := json.Unmarshal(resp.Body(), &errorResp); err == nil && errorResp.Error != nil {
-			return nil, fmt.Errorf("API error: %s (status code: %d)", *errorResp.Error, resp.StatusCode())
-		}
-
-		errMsg := fmt.Sprintf("failed to generate content, status code: %d", resp.StatusCode())
-
-		if len(resp.Body()) > 0 {
-			errMsg = fmt.Sprintf("%s, response body: %s", errMsg, string(resp.Body()))
-		}
-
-		return nil, fmt.Errorf("%s", errMsg)
+		return nil, gatewayError(resp.StatusCode(), resp.Body(), "API error", fmt.Sprintf("failed to generate content, status code: %d", resp.StatusCode()))
 	}
 
 	result, ok := resp.Result().(*CreateChatCompletionResponse)
@@ -666,6 +836,12 @@ func (c *clientImpl) GenerateContent(ctx context.Context, provider Provider, mod
 		return nil, fmt.Errorf("failed to parse response")
 	}
 
+	var reported GenerateUsage
+	if result.Usage != nil {
+		reported = chatUsage(*result.Usage)
+	}
+	usage.done(ctx, reported)
+	limit.done(reported)
 	return result, nil
 }
 
@@ -716,8 +892,6 @@ func (c *clientImpl) GenerateContent(ctx context.Context, provider Provider, mod
 //		}
 //	}
 func (c *clientImpl) GenerateContentStream(ctx context.Context, provider Provider, model string, messages []Message) (<-chan SSEvent, error) {
-	eventChan := make(chan SSEvent, 100)
-
 	request := CreateChatCompletionRequest{
 		Model:    model,
 		Messages: messages,
@@ -742,13 +916,46 @@ func (c *clientImpl) GenerateContentStream(ctx context.Context, provider Provide
 
 		request = options
 	}
+	ctx = applyChatOptions(ctx, &request, model, messages)
+
+	return interceptStream(ctx, c, OperationGenerateContentStream, "chat/completions", provider, &request, func(ctx context.Context, provider Provider, request *CreateChatCompletionRequest) (<-chan SSEvent, error) {
+		return c.generateContentStream(ctx, provider, *request)
+	})
+}
+
+// generateContentStream sends a streaming chat completion request built by
+// GenerateContentStream.
+func (c *clientImpl) generateContentStream(ctx context.Context, provider Provider, request CreateChatCompletionRequest) (<-chan SSEvent, error) {
+	eventChan := make(chan SSEvent, 100)
+
+	if err := c.validator.ValidateChat(ctx, provider, request); err != nil {
+		close(eventChan)
+		return eventChan, err
+	}
+
+	usage, err := c.usage.start(ctx, provider, request.Model, request.User)
+	if err != nil {
+		close(eventChan)
+		return eventChan, err
+	}
+
+	limit, err := c.limiter.acquire(ctx, provider, request.Model, request)
+	if err != nil {
+		close(eventChan)
+		return eventChan, err
+	}
+	defer limit.cancel()
+	if (usage != nil || limit != nil) && request.StreamOptions == nil {
+		// Usage is only reported at the end of a stream when asked for.
+		request.StreamOptions = &ChatCompletionStreamOptions{IncludeUsage: true}
+	}
 
 	queryParams := make(map[string]string)
 	if provider != "" {
 		queryParams["provider"] = string(provider)
 	}
 
-	resp, err := c.executeWithRetry(ctx, func() (*resty.Response, error) {
+	resp, err := c.execute(ctx, provider, "chat/completions", request.Model, limit, func(ctx context.Context) (*resty.Response, error) {
 		return c.http.R().
 			SetContext(ctx).
 			SetQueryParams(queryParams).
@@ -766,18 +973,7 @@ func (c *clientImpl) GenerateContentStream(ctx context.Context, provider Provide
 
 		body, _ := io.ReadAll(resp.RawBody())
 		closeRawBody(resp)
-		var errorResp Error
-		if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error != nil {
-			return eventChan, fmt.Errorf("API stream error: %s (status code: %d)", *errorResp.Error, resp.StatusCode())
-		}
-
-		errMsg := fmt.Sprintf("stream request failed with status: %d", resp.StatusCode())
-
-		if len(body) > 0 {
-			errMsg = fmt.Sprintf("%s, response body: %s", errMsg, string(body))
-		}
-
-		return eventChan, fmt.Errorf("%s", errMsg)
+		return eventChan, gatewayError(resp.StatusCode(), body, "API stream error", fmt.Sprintf("stream request failed with status: %d", resp.StatusCode()))
 	}
 
 	rawBody := resp.RawBody()
@@ -788,7 +984,7 @@ func (c *clientImpl) GenerateContentStream(ctx context.Context, provider Provide
 
 	go readSSEStream(ctx, rawBody, eventChan)
 
-	return eventChan, nil
+	return limit.stream(ctx, usage.stream(ctx, eventChan, APIChat), APIChat), nil
 }
 
 // readSSEStream reads `data: ` lines off an SSE body, emits ContentDelta
@@ -873,12 +1069,41 @@ func readSSEStream(ctx context.Context, rawBody io.ReadCloser, eventChan chan SS
 func (c *clientImpl) CreateMessage(ctx context.Context, provider Provider, request CreateMessagesRequest) (*MessagesResponse, error) {
 	request.Stream = boolPtr(false)
 
+	return intercept(ctx, c, OperationCreateMessage, "messages", provider, &request, func(ctx context.Context, provider Provider, request *CreateMessagesRequest) (*MessagesResponse, error) {
+		if c.hedging == nil {
+			return c.createMessage(ctx, provider, *request)
+		}
+		return hedge(ctx, c.hedging, provider, request.Model, func(ctx context.Context, provider Provider, model string) (*MessagesResponse, error) {
+			request := *request
+			request.Model = model
+			return c.createMessage(ctx, provider, request)
+		})
+	})
+}
+
+// createMessage sends a Messages API request.
+func (c *clientImpl) createMessage(ctx context.Context, provider Provider, request CreateMessagesRequest) (*MessagesResponse, error) {
+	if err := c.validator.ValidateMessages(ctx, provider, request); err != nil {
+		return nil, err
+	}
+
+	usage, err := c.usage.start(ctx, provider, request.Model, messagesUser(request))
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := c.limiter.acquire(ctx, provider, request.Model, request)
+	if err != nil {
+		return nil, err
+	}
+	defer limit.cancel()
+
 	queryParams := make(map[string]string)
 	if provider != "" {
 		queryParams["provider"] = string(provider)
 	}
 
-	resp, err := c.executeWithRetry(ctx, func() (*resty.Response, error) {
+	resp, err := c.execute(ctx, provider, "messages", request.Model, limit, func(ctx context.Context) (*resty.Response, error) {
 		return c.http.R().
 			SetContext(ctx).
 			SetQueryParams(queryParams).
@@ -900,6 +1125,9 @@ func (c *clientImpl) CreateMessage(ctx context.Context, provider Provider, reque
 		return nil, fmt.Errorf("failed to parse response")
 	}
 
+	reported := messagesUsage(result.Usage)
+	usage.done(ctx, reported)
+	limit.done(reported)
 	return result, nil
 }
 
@@ -907,16 +1135,41 @@ func (c *clientImpl) CreateMessage(ctx context.Context, provider Provider, reque
 // API in streaming mode. Each ContentDelta event's Data is a JSON-serialized
 // MessagesStreamEvent; the channel closes when the stream ends.
 func (c *clientImpl) CreateMessageStream(ctx context.Context, provider Provider, request CreateMessagesRequest) (<-chan SSEvent, error) {
+	request.Stream = boolPtr(true)
+
+	return interceptStream(ctx, c, OperationCreateMessageStream, "messages", provider, &request, func(ctx context.Context, provider Provider, request *CreateMessagesRequest) (<-chan SSEvent, error) {
+		return c.createMessageStream(ctx, provider, *request)
+	})
+}
+
+// createMessageStream sends a streaming Messages API request.
+func (c *clientImpl) createMessageStream(ctx context.Context, provider Provider, request CreateMessagesRequest) (<-chan SSEvent, error) {
 	eventChan := make(chan SSEvent, 100)
 
-	request.Stream = boolPtr(true)
+	if err := c.validator.ValidateMessages(ctx, provider, request); err != nil {
+		close(eventChan)
+		return eventChan, err
+	}
+
+	usage, err := c.usage.start(ctx, provider, request.Model, messagesUser(request))
+	if err != nil {
+		close(eventChan)
+		return eventChan, err
+	}
+
+	limit, err := c.limiter.acquire(ctx, provider, request.Model, request)
+	if err != nil {
+		close(eventChan)
+		return eventChan, err
+	}
+	defer limit.cancel()
 
 	queryParams := make(map[string]string)
 	if provider != "" {
 		queryParams["provider"] = string(provider)
 	}
 
-	resp, err := c.executeWithRetry(ctx, func() (*resty.Response, error) {
+	resp, err := c.execute(ctx, provider, "messages", request.Model, limit, func(ctx context.Context) (*resty.Response, error) {
 		return c.http.R().
 			SetContext(ctx).
 			SetQueryParams(queryParams).
@@ -945,7 +1198,160 @@ func (c *clientImpl) CreateMessageStream(ctx context.Context, provider Provider,
 
 	go readSSEStream(ctx, rawBody, eventChan)
 
-	return eventChan, nil
+	return limit.stream(ctx, usage.stream(ctx, eventChan, APIMessages), APIMessages), nil
+}
+
+// CreateResponse creates a model response using the OpenAI-compatible
+// Responses API. Not every provider implements it; unsupported providers
+// return an error — use GenerateContent for those.
+//
+// Example:
+//
+//	client := sdk.NewClient(&sdk.ClientOptions{
+//		BaseURL: "http://localhost:8080/v1",
+//	})
+//	var input sdk.ResponseInput
+//	_ = input.FromResponseInput0("What is Go?")
+//	response, err := client.CreateResponse(ctx, sdk.Openai, sdk.CreateResponseRequest{
+//		Model: "gpt-5",
+//		Input: input,
+//	})
+func (c *clientImpl) CreateResponse(ctx context.Context, provider Provider, request CreateResponseRequest) (*Response, error) {
+	request.Stream = boolPtr(false)
+
+	return intercept(ctx, c, OperationCreateResponse, "responses", provider, &request, func(ctx context.Context, provider Provider, request *CreateResponseRequest) (*Response, error) {
+		if c.hedging == nil {
+			return c.createResponse(ctx, provider, *request)
+		}
+		return hedge(ctx, c.hedging, provider, request.Model, func(ctx context.Context, provider Provider, model string) (*Response, error) {
+			request := *request
+			request.Model = model
+			return c.createResponse(ctx, provider, request)
+		})
+	})
+}
+
+// createResponse sends a Responses API request.
+func (c *clientImpl) createResponse(ctx context.Context, provider Provider, request CreateResponseRequest) (*Response, error) {
+	if err := c.validator.ValidateResponse(ctx, provider, request); err != nil {
+		return nil, err
+	}
+
+	usage, err := c.usage.start(ctx, provider, request.Model, request.User)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := c.limiter.acquire(ctx, provider, request.Model, request)
+	if err != nil {
+		return nil, err
+	}
+	defer limit.cancel()
+
+	queryParams := make(map[string]string)
+	if provider != "" {
+		queryParams["provider"] = string(provider)
+	}
+
+	resp, err := c.execute(ctx, provider, "responses", request.Model, limit, func(ctx context.Context) (*resty.Response, error) {
+		return c.http.R().
+			SetContext(ctx).
+			SetQueryParams(queryParams).
+			SetBody(request).
+			SetResult(&Response{}).
+			Post(fmt.Sprintf("%s/responses", c.baseURL))
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, responsesAPIError(resp.StatusCode(), resp.Body())
+	}
+
+	result, ok := resp.Result().(*Response)
+	if !ok || result == nil {
+		return nil, fmt.Errorf("failed to parse response")
+	}
+
+	var reported GenerateUsage
+	if result.Usage != nil {
+		reported = responsesUsage(*result.Usage)
+	}
+	usage.done(ctx, reported)
+	limit.done(reported)
+	return result, nil
+}
+
+// CreateResponseStream creates a model response using the OpenAI-compatible
+// Responses API in streaming mode. Each ContentDelta event's Data is a
+// JSON-serialized ResponseStreamEvent; the channel closes when the stream ends.
+func (c *clientImpl) CreateResponseStream(ctx context.Context, provider Provider, request CreateResponseRequest) (<-chan SSEvent, error) {
+	request.Stream = boolPtr(true)
+
+	return interceptStream(ctx, c, OperationCreateResponseStream, "responses", provider, &request, func(ctx context.Context, provider Provider, request *CreateResponseRequest) (<-chan SSEvent, error) {
+		return c.createResponseStream(ctx, provider, *request)
+	})
+}
+
+// createResponseStream sends a streaming Responses API request.
+func (c *clientImpl) createResponseStream(ctx context.Context, provider Provider, request CreateResponseRequest) (<-chan SSEvent, error) {
+	eventChan := make(chan SSEvent, 100)
+
+	if err := c.validator.ValidateResponse(ctx, provider, request); err != nil {
+		close(eventChan)
+		return eventChan, err
+	}
+
+	usage, err := c.usage.start(ctx, provider, request.Model, request.User)
+	if err != nil {
+		close(eventChan)
+		return eventChan, err
+	}
+
+	limit, err := c.limiter.acquire(ctx, provider, request.Model, request)
+	if err != nil {
+		close(eventChan)
+		return eventChan, err
+	}
+	defer limit.cancel()
+
+	queryParams := make(map[string]string)
+	if provider != "" {
+		queryParams["provider"] = string(provider)
+	}
+
+	resp, err := c.execute(ctx, provider, "responses", request.Model, limit, func(ctx context.Context) (*resty.Response, error) {
+		return c.http.R().
+			SetContext(ctx).
+			SetQueryParams(queryParams).
+			SetBody(request).
+			SetDoNotParseResponse(true).
+			Post(fmt.Sprintf("%s/responses", c.baseURL))
+	})
+	if err != nil {
+		close(eventChan)
+		return eventChan, err
+	}
+
+	if resp.IsError() {
+		close(eventChan)
+
+		body, _ := io.ReadAll(resp.RawBody())
+		closeRawBody(resp)
+		return eventChan, responsesAPIError(resp.StatusCode(), body)
+	}
+
+	rawBody := resp.RawBody()
+	if rawBody == nil {
+		close(eventChan)
+		return eventChan, fmt.Errorf("empty response body")
+	}
+
+	go readSSEStream(ctx, rawBody, eventChan)
+
+	return limit.stream(ctx, usage.stream(ctx, eventChan, APIResponses), APIResponses), nil
 }
 
 // CreateImage generates an image using the OpenAI-compatible Images API.
@@ -961,12 +1367,34 @@ func (c *clientImpl) CreateMessageStream(ctx context.Context, provider Provider,
 //		Prompt: "A cute cat",
 //	})
 func (c *clientImpl) CreateImage(ctx context.Context, provider Provider, request CreateImageRequest) (*ImagesResponse, error) {
+	return intercept(ctx, c, OperationCreateImage, "images/generations", provider, &request, func(ctx context.Context, provider Provider, request *CreateImageRequest) (*ImagesResponse, error) {
+		return c.createImage(ctx, provider, *request)
+	})
+}
+
+// createImage sends an image generation request.
+func (c *clientImpl) createImage(ctx context.Context, provider Provider, request CreateImageRequest) (*ImagesResponse, error) {
+	model := ""
+	if request.Model != nil {
+		model = *request.Model
+	}
+	usage, err := c.usage.start(ctx, provider, model, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := c.limiter.acquire(ctx, provider, model, request.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	defer limit.cancel()
+
 	queryParams := make(map[string]string)
 	if provider != "" {
 		queryParams["provider"] = string(provider)
 	}
 
-	resp, err := c.executeWithRetry(ctx, func() (*resty.Response, error) {
+	resp, err := c.execute(ctx, provider, "images/generations", model, limit, func(ctx context.Context) (*resty.Response, error) {
 		return c.http.R().
 			SetContext(ctx).
 			SetQueryParams(queryParams).
@@ -975,7 +1403,13 @@ func (c *clientImpl) CreateImage(ctx context.Context, provider Provider, request
 			Post(fmt.Sprintf("%s/images/generations", c.baseURL))
 	})
 
-	return imagesResult(resp, err)
+	result, err := imagesResult(resp, err)
+	if err == nil {
+		reported := imagesUsage(result)
+		usage.done(ctx, reported)
+		limit.done(reported)
+	}
+	return result, err
 }
 
 // CreateImageEdit edits an image using the OpenAI-compatible Images API
@@ -983,6 +1417,13 @@ func (c *clientImpl) CreateImage(ctx context.Context, provider Provider, request
 // openapi_types.File.InitFromBytes. Not every provider implements it;
 // unsupported providers return a 400 error.
 func (c *clientImpl) CreateImageEdit(ctx context.Context, provider Provider, request CreateImageEditMultipartBody) (*ImagesResponse, error) {
+	return intercept(ctx, c, OperationCreateImageEdit, "images/edits", provider, &request, func(ctx context.Context, provider Provider, request *CreateImageEditMultipartBody) (*ImagesResponse, error) {
+		return c.createImageEdit(ctx, provider, *request)
+	})
+}
+
+// createImageEdit sends an image edit request.
+func (c *clientImpl) createImageEdit(ctx context.Context, provider Provider, request CreateImageEditMultipartBody) (*ImagesResponse, error) {
 	files := map[string]openapi_types.File{"image": request.Image}
 	if request.Mask != nil {
 		files["mask"] = *request.Mask
@@ -1013,6 +1454,13 @@ func (c *clientImpl) CreateImageEdit(ctx context.Context, provider Provider, req
 // Build the image field with openapi_types.File.InitFromBytes. Not every
 // provider implements it; unsupported providers return a 400 error.
 func (c *clientImpl) CreateImageVariation(ctx context.Context, provider Provider, request CreateImageVariationMultipartBody) (*ImagesResponse, error) {
+	return intercept(ctx, c, OperationCreateImageVariation, "images/variations", provider, &request, func(ctx context.Context, provider Provider, request *CreateImageVariationMultipartBody) (*ImagesResponse, error) {
+		return c.createImageVariation(ctx, provider, *request)
+	})
+}
+
+// createImageVariation sends an image variation request.
+func (c *clientImpl) createImageVariation(ctx context.Context, provider Provider, request CreateImageVariationMultipartBody) (*ImagesResponse, error) {
 	files := map[string]openapi_types.File{"image": request.Image}
 
 	fields := map[string]string{}
@@ -1035,12 +1483,23 @@ func (c *clientImpl) CreateImageVariation(ctx context.Context, provider Provider
 // postImagesMultipart posts a multipart/form-data request to an Images API
 // endpoint and parses the shared ImagesResponse.
 func (c *clientImpl) postImagesMultipart(ctx context.Context, provider Provider, path string, fields map[string]string, files map[string]openapi_types.File) (*ImagesResponse, error) {
+	usage, err := c.usage.start(ctx, provider, fields["model"], nil)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := c.limiter.acquire(ctx, provider, fields["model"], fields["prompt"])
+	if err != nil {
+		return nil, err
+	}
+	defer limit.cancel()
+
 	queryParams := make(map[string]string)
 	if provider != "" {
 		queryParams["provider"] = string(provider)
 	}
 
-	resp, err := c.executeWithRetry(ctx, func() (*resty.Response, error) {
+	resp, err := c.execute(ctx, provider, strings.TrimPrefix(path, "/"), fields["model"], limit, func(ctx context.Context) (*resty.Response, error) {
 		req := c.http.R().
 			SetContext(ctx).
 			SetQueryParams(queryParams).
@@ -1056,7 +1515,13 @@ func (c *clientImpl) postImagesMultipart(ctx context.Context, provider Provider,
 		return req.Post(c.baseURL + path)
 	})
 
-	return imagesResult(resp, err)
+	result, err := imagesResult(resp, err)
+	if err == nil {
+		reported := imagesUsage(result)
+		usage.done(ctx, reported)
+		limit.done(reported)
+	}
+	return result, err
 }
 
 // imagesResult turns a resty response from an Images API endpoint into an
@@ -1067,18 +1532,7 @@ func imagesResult(resp *resty.Response, err error) (*ImagesResponse, error) {
 	}
 
 	if resp.IsError() {
-		var errorResp Error
-		if err := json.Unmarshal(resp.Body(), &errorResp); err == nil && errorResp.Error != nil {
-			return nil, fmt.Errorf("API error: %s (status code: %d)", *errorResp.Error, resp.StatusCode())
-		}
-
-		errMsg := fmt.Sprintf("image request failed with status: %d", resp.StatusCode())
-
-		if len(resp.Body()) > 0 {
-			errMsg = fmt.Sprintf("%s, response body: %s", errMsg, string(resp.Body()))
-		}
-
-		return nil, fmt.Errorf("%s", errMsg)
+		return nil, gatewayError(resp.StatusCode(), resp.Body(), "API error", fmt.Sprintf("image request failed with status: %d", resp.StatusCode()))
 	}
 
 	result, ok := resp.Result().(*ImagesResponse)
@@ -1089,12 +1543,73 @@ func imagesResult(resp *resty.Response, err error) (*ImagesResponse, error) {
 	return result, nil
 }
 
+// APIError is returned when the gateway answers with an error status. The
+// status code and parsed body let callers branch on the failure without
+// matching the error text.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Type is the error type of an Anthropic-format error body, e.g.
+	// not_supported_error. Empty for other endpoints.
+	Type string
+	// Message is the error message from the response body, empty when the
+	// body didn't parse.
+	Message string
+	// Body is the raw response body.
+	Body []byte
+
+	text string
+}
+
+func (e *APIError) Error() string {
+	return e.text
+}
+
+// IsNotSupported reports whether err is the gateway rejecting an endpoint the
+// selected provider doesn't implement (MessagesNotSupported,
+// ResponsesNotSupported or ImagesNotSupported).
+func IsNotSupported(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	return apiErr.Type == "not_supported_error" ||
+		strings.Contains(strings.ToLower(apiErr.Message), "not supported")
+}
+
+// gatewayError builds an APIError from an Error-format body, reported as
+// "<prefix>: <message>". Bodies that don't parse are reported as fallback
+// followed by the raw body.
+func gatewayError(statusCode int, body []byte, prefix, fallback string) error {
+	apiErr := &APIError{StatusCode: statusCode, Body: body}
+
+	var errorResp Error
+	if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error != nil {
+		apiErr.Message = *errorResp.Error
+		apiErr.text = fmt.Sprintf("%s: %s (status code: %d)", prefix, apiErr.Message, statusCode)
+		return apiErr
+	}
+
+	apiErr.text = fallback
+	if len(body) > 0 {
+		apiErr.text = fmt.Sprintf("%s, response body: %s", fallback, string(body))
+	}
+
+	return apiErr
+}
+
 // messagesAPIError builds an error from an Anthropic-format error body,
 // falling back to the raw body when it doesn't parse.
 func messagesAPIError(statusCode int, body []byte) error {
 	var errorResp MessagesError
 	if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error.Message != "" {
-		return fmt.Errorf("API error: %s (status code: %d)", errorResp.Error.Message, statusCode)
+		return &APIError{
+			StatusCode: statusCode,
+			Type:       errorResp.Error.Type,
+			Message:    errorResp.Error.Message,
+			Body:       body,
+			text:       fmt.Sprintf("API error: %s (status code: %d)", errorResp.Error.Message, statusCode),
+		}
 	}
 
 	errMsg := fmt.Sprintf("messages request failed with status: %d", statusCode)
@@ -1103,7 +1618,11 @@ func messagesAPIError(statusCode int, body []byte) error {
 		errMsg = fmt.Sprintf("%s, response body: %s", errMsg, string(body))
 	}
 
-	return fmt.Errorf("%s", errMsg)
+	return &APIError{StatusCode: statusCode, Body: body, text: errMsg}
+}
+
+func responsesAPIError(statusCode int, body []byte) error {
+	return gatewayError(statusCode, body, "API error", fmt.Sprintf("responses request failed with status: %d", statusCode))
 }
 
 // closeRawBody closes an unparsed (SetDoNotParseResponse) response body so the
@@ -1131,6 +1650,22 @@ func joinInclude(include []ListModelsParamsInclude) string {
 	return strings.Join(parts, ",")
 }
 
+// includeParam returns include as the Include of ListModelsParams.
+func includeParam(include []ListModelsParamsInclude) *[]ListModelsParamsInclude {
+	if len(include) == 0 {
+		return nil
+	}
+	return &include
+}
+
+// includeList returns the Include of params as a list.
+func includeList(params *ListModelsParams) []ListModelsParamsInclude {
+	if params.Include == nil {
+		return nil
+	}
+	return *params.Include
+}
+
 // HealthCheck performs a health check request to verify API availability.
 //
 // Example:
@@ -1144,6 +1679,14 @@ func joinInclude(include []ListModelsParamsInclude) string {
 //	    log.Fatalf("Health check failed: %v", err)
 //	}
 func (c *clientImpl) HealthCheck(ctx context.Context) error {
+	_, err := intercept(ctx, c, OperationHealthCheck, "health", "", (*struct{})(nil), func(ctx context.Context, _ Provider, _ *struct{}) (any, error) {
+		return nil, c.healthCheck(ctx)
+	})
+	return err
+}
+
+// healthCheck checks the gateway's health endpoint.
+func (c *clientImpl) healthCheck(ctx context.Context) error {
 	resp, err := c.executeWithRetry(ctx, func() (*resty.Response, error) {
 		return c.http.R().
 			SetContext(ctx).
@@ -1155,7 +1698,11 @@ func (c *clientImpl) HealthCheck(ctx context.Context) error {
 	}
 
 	if resp.IsError() {
-		return fmt.Errorf("health check failed with status: %d", resp.StatusCode())
+		return &APIError{
+			StatusCode: resp.StatusCode(),
+			Body:       resp.Body(),
+			text:       fmt.Sprintf("health check failed with status: %d", resp.StatusCode()),
+		}
 	}
 
 	return nil
@@ -1047,6 +1047,43 @@ func TestWithOptions(t *testing.T) {
 	}
 }
 
+func TestWithChatOptions(t *testing.T) {
+	gateway := newTestGateway(t)
+	var client Client
+	nested := false
+	client = gateway.client(&ClientOptions{Interceptors: []Interceptor{
+		func(ctx context.Context, call *Call, next Invoker) (any, error) {
+			if !nested {
+				nested = true
+				_, err := client.GenerateContent(ctx, Openai, "gpt-4o-mini", hello())
+				require.NoError(t, err)
+			}
+			return next(ctx, call)
+		},
+	}}).WithOptions(&CreateChatCompletionRequest{Temperature: new(float32(0.2)), Seed: new(1)})
+
+	ctx := WithChatOptions(context.Background(), &CreateChatCompletionRequest{Seed: new(7)})
+	ctx = WithChatOptions(ctx, &CreateChatCompletionRequest{Model: "ignored", TopP: new(float32(0.5))})
+	_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+
+	bodies := gateway.bodies()
+	require.Len(t, bodies, 2)
+	call := bodies[1]
+	assert.Equal(t, "gpt-4o", call["model"])
+	assert.Equal(t, float64(7), call["seed"], "per-call options replace the client's")
+	assert.InDelta(t, 0.5, call["top_p"], 0.001)
+	assert.InDelta(t, 0.2, call["temperature"], 0.001)
+
+	// Calls made from an interceptor with the call's context don't get
+	// its options.
+	assert.Equal(t, float64(1), bodies[0]["seed"])
+	assert.Nil(t, bodies[0]["top_p"])
+
+	assert.Equal(t, 7, *ChatOptions(ctx).Seed)
+	assert.Nil(t, ChatOptions(context.Background()))
+}
+
 func TestWithHeaders(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -2547,6 +2584,174 @@ func TestCreateMessageStream(t *testing.T) {
 	assert.True(t, sawStreamEnd)
 }
 
+func TestCreateResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/responses", r.URL.Path)
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "openai", r.URL.Query().Get("provider"))
+
+		var requestBody CreateResponseRequest
+		err := json.NewDecoder(r.Body).Decode(&requestBody)
+		assert.NoError(t, err)
+		assert.Equal(t, "gpt-5", requestBody.Model)
+		assert.False(t, *requestBody.Stream)
+		input, err := requestBody.Input.AsResponseInput0()
+		assert.NoError(t, err)
+		assert.Equal(t, "What is Go?", input)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, err = fmt.Fprint(w, `{
+			"id": "resp_123",
+			"object": "response",
+			"created_at": 1700000000,
+			"model": "gpt-5",
+			"status": "completed",
+			"output": [{
+				"type": "message",
+				"id": "msg_1",
+				"role": "assistant",
+				"content": [{"type": "output_text", "text": "Go is a programming language."}]
+			}]
+		}`)
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	client := NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+
+	var input ResponseInput
+	require.NoError(t, input.FromResponseInput0("What is Go?"))
+
+	response, err := client.CreateResponse(context.Background(), Openai, CreateResponseRequest{
+		Model: "gpt-5",
+		Input: input,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "resp_123", response.ID)
+	require.Len(t, response.Output, 1)
+	message, err := response.Output[0].AsResponseOutputMessage()
+	require.NoError(t, err)
+	require.Len(t, message.Content, 1)
+	text, err := message.Content[0].AsResponseOutputText()
+	require.NoError(t, err)
+	assert.Equal(t, "Go is a programming language.", text.Text)
+}
+
+func TestCreateResponse_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, err := fmt.Fprint(w, `{"error": "The Responses API is not supported by this provider yet."}`)
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	client := NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+
+	var input ResponseInput
+	require.NoError(t, input.FromResponseInput0("What is Go?"))
+
+	response, err := client.CreateResponse(context.Background(), Groq, CreateResponseRequest{Model: "some-model", Input: input})
+
+	assert.Error(t, err)
+	assert.Nil(t, response)
+	assert.Contains(t, err.Error(), "not supported")
+}
+
+func TestAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		var err error
+		switch r.URL.Path {
+		case "/v1/messages":
+			_, err = fmt.Fprint(w, `{"type": "error", "error": {"type": "not_supported_error", "message": "The Messages API is not supported by this provider yet."}}`)
+		case "/v1/images/generations":
+			_, err = fmt.Fprint(w, `{"error": "The Images API is not supported by this provider yet."}`)
+		default:
+			_, err = fmt.Fprint(w, `{"error": "model is required"}`)
+		}
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	client := NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+	ctx := context.Background()
+
+	_, err := client.CreateMessage(ctx, Groq, CreateMessagesRequest{Model: "some-model", MaxTokens: 16})
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, "not_supported_error", apiErr.Type)
+	assert.EqualError(t, err, "API error: The Messages API is not supported by this provider yet. (status code: 400)")
+	assert.True(t, IsNotSupported(err))
+
+	_, err = client.CreateImage(ctx, Groq, CreateImageRequest{Prompt: "A cat"})
+	assert.True(t, IsNotSupported(err))
+
+	_, err = client.GenerateContent(ctx, Openai, "", nil)
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "model is required", apiErr.Message)
+	assert.False(t, IsNotSupported(err))
+	assert.False(t, IsNotSupported(fmt.Errorf("not supported")))
+}
+
+func TestCreateResponseStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/responses", r.URL.Path)
+
+		var requestBody CreateResponseRequest
+		err := json.NewDecoder(r.Body).Decode(&requestBody)
+		assert.NoError(t, err)
+		assert.True(t, *requestBody.Stream)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher, ok := w.(http.Flusher)
+		require.True(t, ok, "Streaming not supported")
+
+		chunks := []string{
+			`{"type": "response.output_text.delta", "item_id": "msg_1", "output_index": 0, "content_index": 0, "delta": "Go is"}`,
+			`{"type": "response.output_text.delta", "item_id": "msg_1", "output_index": 0, "content_index": 0, "delta": " amazing"}`,
+			`{"type": "response.completed"}`,
+		}
+		for _, chunk := range chunks {
+			_, err := fmt.Fprintf(w, "data: %s\n\n", chunk)
+			require.NoError(t, err)
+			flusher.Flush()
+		}
+		_, err = fmt.Fprint(w, "data: [DONE]\n\n")
+		require.NoError(t, err)
+		flusher.Flush()
+	}))
+	defer server.Close()
+
+	client := NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+
+	var input ResponseInput
+	require.NoError(t, input.FromResponseInput0("What is Go?"))
+
+	events, err := client.CreateResponseStream(context.Background(), Openai, CreateResponseRequest{Model: "gpt-5", Input: input})
+	require.NoError(t, err)
+
+	var text string
+	var sawStreamEnd bool
+	for event := range events {
+		require.NotNil(t, event.Event)
+		switch *event.Event {
+		case ContentDelta:
+			var streamEvent ResponseStreamEvent
+			require.NoError(t, json.Unmarshal(*event.Data, &streamEvent))
+			if streamEvent.Type == "response.output_text.delta" && streamEvent.Delta != nil {
+				text += *streamEvent.Delta
+			}
+		case StreamEnd:
+			sawStreamEnd = true
+		}
+	}
+
+	assert.Equal(t, "Go is amazing", text)
+	assert.True(t, sawStreamEnd)
+}
+
 func TestCreateImage(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "/v1/images/generations", r.URL.Path)
@@ -0,0 +1,663 @@
+// Package sdkmock provides MockClient, an sdk.Client whose calls are
+// answered by expectations set in tests.
+//
+// Each expectation names an operation, optionally narrowed by matchers on
+// the provider, model, messages or request, and what the call returns: a
+// canned response, a stream of events, an error or the result of a
+// function. Calls are recorded for verification, and expectations that
+// were never met fail the test when it ends.
+//
+// Example:
+//
+//	client := sdkmock.NewMockClient(t)
+//	client.On(sdk.OperationGenerateContent, sdkmock.Provider(sdk.Openai), sdkmock.MessageContains("weather")).
+//		Return(sdkmock.ChatCompletion("Sunny.")).
+//		Once()
+//	client.On(sdk.OperationGenerateContentStream).
+//		ReturnStream(sdkmock.ChatStream("Sun", "ny.")...)
+//
+//	agent := NewAgent(client) // the code under test
+package sdkmock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	sdk "github.com/inference-gateway/sdk"
+)
+
+// ErrUnexpectedCall is returned, wrapped, by calls that match no
+// expectation. They also fail the test.
+var ErrUnexpectedCall = errors.New("sdkmock: unexpected call")
+
+// Settings are the options set on a client with its With methods.
+type Settings struct {
+	AuthToken  string
+	Tools      *[]sdk.ChatCompletionTool
+	Options    *sdk.CreateChatCompletionRequest
+	Headers    map[string]string
+	Middleware *sdk.MiddlewareOptions
+}
+
+// Call is a call made to a MockClient.
+type Call struct {
+	Operation sdk.Operation
+	Provider  sdk.Provider
+	// Model is the model of the call or its request, if any.
+	Model string
+	// Messages are the messages of chat, Messages and Responses calls, in
+	// chat completion form. Messages and Responses requests are converted
+	// with sdk.MessagesToChat and sdk.ResponsesToChat.
+	Messages []sdk.Message
+	// Include is the metadata asked for by model listing calls.
+	Include []sdk.ListModelsParamsInclude
+	// Request is a pointer to the request of Messages, Responses and image
+	// calls, e.g. *sdk.CreateMessagesRequest.
+	Request any
+	// Settings are those of the client the call was made on.
+	Settings Settings
+	// ChatOptions are the per-call options of chat completion calls, set on
+	// their context with sdk.WithChatOptions.
+	ChatOptions *sdk.CreateChatCompletionRequest
+}
+
+// Text returns the text of the call's messages, one message a line.
+func (c *Call) Text() string {
+	lines := make([]string, 0, len(c.Messages))
+	for _, message := range c.Messages {
+		lines = append(lines, messageText(message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// String describes the call, as in failure messages.
+func (c *Call) String() string {
+	var args []string
+	if c.Provider != "" {
+		args = append(args, "provider "+string(c.Provider))
+	}
+	if c.Model != "" {
+		args = append(args, "model "+c.Model)
+	}
+	if len(c.Messages) > 0 {
+		args = append(args, fmt.Sprintf("%d messages", len(c.Messages)))
+	}
+	return fmt.Sprintf("%s(%s)", c.Operation, strings.Join(args, ", "))
+}
+
+// messageText returns the text of a message: its string content or the
+// text of its content parts.
+func messageText(message sdk.Message) string {
+	if text, err := message.Content.AsMessageContent0(); err == nil {
+		return text
+	}
+	parts, err := message.Content.AsMessageContent1()
+	if err != nil {
+		return ""
+	}
+	var texts []string
+	for _, part := range parts {
+		if text, err := part.AsTextContentPart(); err == nil && text.Text != "" {
+			texts = append(texts, text.Text)
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+// Matcher narrows the calls an expectation applies to.
+type Matcher struct {
+	// Description is shown in failure messages, e.g. "model gpt-4o".
+	Description string
+	Match       func(call *Call) bool
+}
+
+// Func returns a Matcher of calls for which match returns true.
+func Func(description string, match func(call *Call) bool) Matcher {
+	return Matcher{Description: description, Match: match}
+}
+
+// Provider matches calls to provider.
+func Provider(provider sdk.Provider) Matcher {
+	return Func("provider "+string(provider), func(call *Call) bool {
+		return call.Provider == provider
+	})
+}
+
+// Model matches calls for model.
+func Model(model string) Matcher {
+	return Func("model "+model, func(call *Call) bool {
+		return call.Model == model
+	})
+}
+
+// MessageContains matches calls with a message whose text contains
+// substr.
+func MessageContains(substr string) Matcher {
+	return Func(fmt.Sprintf("message containing %q", substr), func(call *Call) bool {
+		for _, message := range call.Messages {
+			if strings.Contains(messageText(message), substr) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// LastMessage matches calls whose last message satisfies match, given its
+// role and text.
+func LastMessage(description string, match func(role sdk.MessageRole, text string) bool) Matcher {
+	return Func(description, func(call *Call) bool {
+		if len(call.Messages) == 0 {
+			return false
+		}
+		last := call.Messages[len(call.Messages)-1]
+		return match(last.Role, messageText(last))
+	})
+}
+
+// Request matches calls whose request is a *T that satisfies match, e.g.
+// Request("max tokens", func(r *sdk.CreateMessagesRequest) bool { return
+// r.MaxTokens <= 1024 }).
+func Request[T any](description string, match func(request *T) bool) Matcher {
+	return Func(description, func(call *Call) bool {
+		request, ok := call.Request.(*T)
+		return ok && match(request)
+	})
+}
+
+// Expectation is the expected call of an operation and its result.
+type Expectation struct {
+	mock      *mock
+	operation sdk.Operation
+	matchers  []Matcher
+
+	response any
+	events   []sdk.SSEvent
+	err      error
+	do       func(ctx context.Context, call *Call) (any, error)
+	times    int
+	optional bool
+	calls    int
+}
+
+// Return makes matching calls return response, which must be of the
+// operation's response type, e.g. *sdk.CreateChatCompletionResponse for
+// GenerateContent.
+func (e *Expectation) Return(response any) *Expectation {
+	e.mock.mu.Lock()
+	defer e.mock.mu.Unlock()
+	e.response = response
+	return e
+}
+
+// ReturnError makes matching calls fail with err.
+func (e *Expectation) ReturnError(err error) *Expectation {
+	e.mock.mu.Lock()
+	defer e.mock.mu.Unlock()
+	e.err = err
+	return e
+}
+
+// ReturnStream makes matching streaming calls return a channel of events.
+func (e *Expectation) ReturnStream(events ...sdk.SSEvent) *Expectation {
+	e.mock.mu.Lock()
+	defer e.mock.mu.Unlock()
+	e.events = events
+	return e
+}
+
+// Do makes matching calls return the result of fn. Streaming calls may
+// return a <-chan sdk.SSEvent or a []sdk.SSEvent.
+func (e *Expectation) Do(fn func(ctx context.Context, call *Call) (any, error)) *Expectation {
+	e.mock.mu.Lock()
+	defer e.mock.mu.Unlock()
+	e.do = fn
+	return e
+}
+
+// Times limits the expectation to n calls, and requires them all.
+func (e *Expectation) Times(n int) *Expectation {
+	e.mock.mu.Lock()
+	defer e.mock.mu.Unlock()
+	e.times = n
+	return e
+}
+
+// Once is Times(1).
+func (e *Expectation) Once() *Expectation {
+	return e.Times(1)
+}
+
+// Maybe makes the expectation optional: the test doesn't fail if no call
+// meets it.
+func (e *Expectation) Maybe() *Expectation {
+	e.mock.mu.Lock()
+	defer e.mock.mu.Unlock()
+	e.optional = true
+	return e
+}
+
+// String describes the expectation, as in failure messages.
+func (e *Expectation) String() string {
+	descriptions := make([]string, 0, len(e.matchers))
+	for _, matcher := range e.matchers {
+		descriptions = append(descriptions, matcher.Description)
+	}
+	return fmt.Sprintf("%s(%s)", e.operation, strings.Join(descriptions, ", "))
+}
+
+// matches reports whether the expectation applies to call. The caller
+// holds the lock.
+func (e *Expectation) matches(call *Call) bool {
+	if e.operation != call.Operation || (e.times > 0 && e.calls >= e.times) {
+		return false
+	}
+	for _, matcher := range e.matchers {
+		if !matcher.Match(call) {
+			return false
+		}
+	}
+	return true
+}
+
+// mock is the state shared by a MockClient and the clients derived from
+// it with its With methods.
+type mock struct {
+	t testing.TB
+
+	mu           sync.Mutex
+	expectations []*Expectation
+	calls        []Call
+}
+
+// MockClient is an sdk.Client whose calls are answered by expectations.
+// It is safe for concurrent use.
+type MockClient struct {
+	*mock
+	settings Settings
+}
+
+// NewMockClient creates a MockClient that fails t on unexpected calls
+// and, when the test ends, on unmet expectations.
+func NewMockClient(t testing.TB) *MockClient {
+	m := &MockClient{mock: &mock{t: t}}
+	t.Cleanup(func() { m.AssertExpectations() })
+	return m
+}
+
+// On expects calls of operation that satisfy all matchers. Expectations
+// are tried in the order they were set; calls get the first that
+// matches and hasn't been used up. By default it returns zero responses
+// and closed streams.
+func (m *MockClient) On(operation sdk.Operation, matchers ...Matcher) *Expectation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expectation := &Expectation{mock: m.mock, operation: operation, matchers: matchers}
+	m.expectations = append(m.expectations, expectation)
+	return expectation
+}
+
+// Calls returns the calls made, in order.
+func (m *MockClient) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// CallsOf returns the calls of operation made, in order.
+func (m *MockClient) CallsOf(operation sdk.Operation) []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var calls []Call
+	for _, call := range m.calls {
+		if call.Operation == operation {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// AssertCalled fails the test unless a call of operation satisfied all
+// matchers, and reports whether one did.
+func (m *MockClient) AssertCalled(operation sdk.Operation, matchers ...Matcher) bool {
+	m.t.Helper()
+	expected := &Expectation{operation: operation, matchers: matchers}
+	for _, call := range m.CallsOf(operation) {
+		if expected.matches(&call) {
+			return true
+		}
+	}
+	m.t.Errorf("sdkmock: expected a call %s, got %d calls of %s", expected, len(m.CallsOf(operation)), operation)
+	return false
+}
+
+// AssertNotCalled fails the test if a call of operation satisfied all
+// matchers, and reports whether none did.
+func (m *MockClient) AssertNotCalled(operation sdk.Operation, matchers ...Matcher) bool {
+	m.t.Helper()
+	expected := &Expectation{operation: operation, matchers: matchers}
+	for _, call := range m.CallsOf(operation) {
+		if expected.matches(&call) {
+			m.t.Errorf("sdkmock: unexpected call %s", call.String())
+			return false
+		}
+	}
+	return true
+}
+
+// AssertExpectations fails the test for each expectation that isn't
+// optional and wasn't met, and reports whether all were. NewMockClient
+// runs it when the test ends.
+func (m *MockClient) AssertExpectations() bool {
+	m.t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	met := true
+	for _, expectation := range m.expectations {
+		switch {
+		case expectation.optional:
+		case expectation.times > 0 && expectation.calls < expectation.times:
+			m.t.Errorf("sdkmock: expected %d calls %s, got %d", expectation.times, expectation, expectation.calls)
+			met = false
+		case expectation.calls == 0:
+			m.t.Errorf("sdkmock: expected a call %s, got none", expectation)
+			met = false
+		}
+	}
+	return met
+}
+
+// call records call and answers it with the first matching expectation.
+func (m *MockClient) call(ctx context.Context, call Call) (any, error) {
+	call.Settings = m.settings
+	m.mu.Lock()
+	m.calls = append(m.calls, call)
+	var expectation *Expectation
+	for _, e := range m.expectations {
+		if e.matches(&call) {
+			expectation = e
+			break
+		}
+	}
+	if expectation == nil {
+		m.mu.Unlock()
+		m.t.Errorf("sdkmock: unexpected call %s", call.String())
+		return nil, fmt.Errorf("%w %s", ErrUnexpectedCall, call.String())
+	}
+	expectation.calls++
+	response, events, err, do := expectation.response, expectation.events, expectation.err, expectation.do
+	m.mu.Unlock()
+
+	if do != nil {
+		return do(ctx, &call)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if call.Operation.Streaming() && response == nil {
+		return events, nil
+	}
+	return response, nil
+}
+
+// answer makes call and converts its response to T, the operation's
+// response type.
+func answer[T any](ctx context.Context, m *MockClient, call Call, zero func() T) (T, error) {
+	var none T
+	response, err := m.call(ctx, call)
+	if err != nil {
+		return none, err
+	}
+	if response == nil {
+		return zero(), nil
+	}
+	result, ok := response.(T)
+	if !ok {
+		m.t.Errorf("sdkmock: %s returned %T, want %T", call.String(), response, none)
+		return none, fmt.Errorf("sdkmock: %s returned %T, want %T", call.String(), response, none)
+	}
+	return result, nil
+}
+
+// stream makes a streaming call and returns its events as a channel.
+func stream(ctx context.Context, m *MockClient, call Call) (<-chan sdk.SSEvent, error) {
+	response, err := m.call(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	switch response := response.(type) {
+	case <-chan sdk.SSEvent:
+		return response, nil
+	case chan sdk.SSEvent:
+		return response, nil
+	case []sdk.SSEvent:
+		events := make(chan sdk.SSEvent, len(response))
+		for _, event := range response {
+			events <- event
+		}
+		close(events)
+		return events, nil
+	case nil:
+		events := make(chan sdk.SSEvent)
+		close(events)
+		return events, nil
+	}
+	m.t.Errorf("sdkmock: %s returned %T, want <-chan sdk.SSEvent", call.String(), response)
+	return nil, fmt.Errorf("sdkmock: %s returned %T, want <-chan sdk.SSEvent", call.String(), response)
+}
+
+// with returns a client sharing m's expectations and calls, with settings
+// changed by set.
+func (m *MockClient) with(set func(settings *Settings)) sdk.Client {
+	derived := &MockClient{mock: m.mock, settings: m.settings}
+	if m.settings.Headers != nil {
+		derived.settings.Headers = make(map[string]string, len(m.settings.Headers))
+		for name, value := range m.settings.Headers {
+			derived.settings.Headers[name] = value
+		}
+	}
+	set(&derived.settings)
+	return derived
+}
+
+// WithAuthToken implements sdk.Client.
+func (m *MockClient) WithAuthToken(token string) sdk.Client {
+	return m.with(func(settings *Settings) { settings.AuthToken = token })
+}
+
+// WithTools implements sdk.Client.
+func (m *MockClient) WithTools(tools *[]sdk.ChatCompletionTool) sdk.Client {
+	return m.with(func(settings *Settings) { settings.Tools = tools })
+}
+
+// WithOptions implements sdk.Client.
+func (m *MockClient) WithOptions(options *sdk.CreateChatCompletionRequest) sdk.Client {
+	return m.with(func(settings *Settings) { settings.Options = options })
+}
+
+// WithHeaders implements sdk.Client.
+func (m *MockClient) WithHeaders(headers map[string]string) sdk.Client {
+	return m.with(func(settings *Settings) {
+		if settings.Headers == nil {
+			settings.Headers = map[string]string{}
+		}
+		for name, value := range headers {
+			settings.Headers[name] = value
+		}
+	})
+}
+
+// WithHeader implements sdk.Client.
+func (m *MockClient) WithHeader(name, value string) sdk.Client {
+	return m.WithHeaders(map[string]string{name: value})
+}
+
+// WithMiddlewareOptions implements sdk.Client.
+func (m *MockClient) WithMiddlewareOptions(options *sdk.MiddlewareOptions) sdk.Client {
+	return m.with(func(settings *Settings) { settings.Middleware = options })
+}
+
+// ListModels implements sdk.Client.
+func (m *MockClient) ListModels(ctx context.Context, include ...sdk.ListModelsParamsInclude) (*sdk.ListModelsResponse, error) {
+	return answer(ctx, m, Call{Operation: sdk.OperationListModels, Include: include}, newListModelsResponse)
+}
+
+// ListProviderModels implements sdk.Client.
+func (m *MockClient) ListProviderModels(ctx context.Context, provider sdk.Provider, include ...sdk.ListModelsParamsInclude) (*sdk.ListModelsResponse, error) {
+	return answer(ctx, m, Call{Operation: sdk.OperationListProviderModels, Provider: provider, Include: include}, newListModelsResponse)
+}
+
+// ListTools implements sdk.Client.
+func (m *MockClient) ListTools(ctx context.Context) (*sdk.ListToolsResponse, error) {
+	return answer(ctx, m, Call{Operation: sdk.OperationListTools}, func() *sdk.ListToolsResponse {
+		return &sdk.ListToolsResponse{Object: "list", Data: []sdk.MCPTool{}}
+	})
+}
+
+// GenerateContent implements sdk.Client.
+func (m *MockClient) GenerateContent(ctx context.Context, provider sdk.Provider, model string, messages []sdk.Message) (*sdk.CreateChatCompletionResponse, error) {
+	call := Call{Operation: sdk.OperationGenerateContent, Provider: provider, Model: model, Messages: messages, ChatOptions: sdk.ChatOptions(ctx)}
+	return answer(ctx, m, call, func() *sdk.CreateChatCompletionResponse { return ChatCompletion("") })
+}
+
+// GenerateContentStream implements sdk.Client.
+func (m *MockClient) GenerateContentStream(ctx context.Context, provider sdk.Provider, model string, messages []sdk.Message) (<-chan sdk.SSEvent, error) {
+	return stream(ctx, m, Call{Operation: sdk.OperationGenerateContentStream, Provider: provider, Model: model, Messages: messages, ChatOptions: sdk.ChatOptions(ctx)})
+}
+
+// CreateMessage implements sdk.Client.
+func (m *MockClient) CreateMessage(ctx context.Context, provider sdk.Provider, request sdk.CreateMessagesRequest) (*sdk.MessagesResponse, error) {
+	return answer(ctx, m, messagesCall(sdk.OperationCreateMessage, provider, &request), func() *sdk.MessagesResponse { return new(sdk.MessagesResponse) })
+}
+
+// CreateMessageStream implements sdk.Client.
+func (m *MockClient) CreateMessageStream(ctx context.Context, provider sdk.Provider, request sdk.CreateMessagesRequest) (<-chan sdk.SSEvent, error) {
+	return stream(ctx, m, messagesCall(sdk.OperationCreateMessageStream, provider, &request))
+}
+
+// CreateResponse implements sdk.Client.
+func (m *MockClient) CreateResponse(ctx context.Context, provider sdk.Provider, request sdk.CreateResponseRequest) (*sdk.Response, error) {
+	return answer(ctx, m, responsesCall(sdk.OperationCreateResponse, provider, &request), func() *sdk.Response { return new(sdk.Response) })
+}
+
+// CreateResponseStream implements sdk.Client.
+func (m *MockClient) CreateResponseStream(ctx context.Context, provider sdk.Provider, request sdk.CreateResponseRequest) (<-chan sdk.SSEvent, error) {
+	return stream(ctx, m, responsesCall(sdk.OperationCreateResponseStream, provider, &request))
+}
+
+// CreateImage implements sdk.Client.
+func (m *MockClient) CreateImage(ctx context.Context, provider sdk.Provider, request sdk.CreateImageRequest) (*sdk.ImagesResponse, error) {
+	call := Call{Operation: sdk.OperationCreateImage, Provider: provider, Model: deref(request.Model), Request: &request}
+	return answer(ctx, m, call, newImagesResponse)
+}
+
+// CreateImageEdit implements sdk.Client.
+func (m *MockClient) CreateImageEdit(ctx context.Context, provider sdk.Provider, request sdk.CreateImageEditMultipartBody) (*sdk.ImagesResponse, error) {
+	call := Call{Operation: sdk.OperationCreateImageEdit, Provider: provider, Model: deref(request.Model), Request: &request}
+	return answer(ctx, m, call, newImagesResponse)
+}
+
+// CreateImageVariation implements sdk.Client.
+func (m *MockClient) CreateImageVariation(ctx context.Context, provider sdk.Provider, request sdk.CreateImageVariationMultipartBody) (*sdk.ImagesResponse, error) {
+	call := Call{Operation: sdk.OperationCreateImageVariation, Provider: provider, Model: deref(request.Model), Request: &request}
+	return answer(ctx, m, call, newImagesResponse)
+}
+
+// HealthCheck implements sdk.Client.
+func (m *MockClient) HealthCheck(ctx context.Context) error {
+	_, err := m.call(ctx, Call{Operation: sdk.OperationHealthCheck})
+	return err
+}
+
+func messagesCall(operation sdk.Operation, provider sdk.Provider, request *sdk.CreateMessagesRequest) Call {
+	messages, _ := sdk.MessagesToChat(request.System, request.Messages)
+	return Call{Operation: operation, Provider: provider, Model: request.Model, Messages: messages, Request: request}
+}
+
+func responsesCall(operation sdk.Operation, provider sdk.Provider, request *sdk.CreateResponseRequest) Call {
+	messages, _ := sdk.ResponsesToChat(request.Instructions, request.Input)
+	return Call{Operation: operation, Provider: provider, Model: request.Model, Messages: messages, Request: request}
+}
+
+func newListModelsResponse() *sdk.ListModelsResponse {
+	return &sdk.ListModelsResponse{Object: "list", Data: []sdk.Model{}}
+}
+
+func newImagesResponse() *sdk.ImagesResponse {
+	return &sdk.ImagesResponse{Data: []sdk.Image{}}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// ChatCompletion returns a chat completion of text, for Return.
+func ChatCompletion(text string) *sdk.CreateChatCompletionResponse {
+	return &sdk.CreateChatCompletionResponse{
+		ID:     "chatcmpl-mock",
+		Object: "chat.completion",
+		Choices: []sdk.ChatCompletionChoice{{
+			FinishReason: sdk.Stop,
+			Message:      sdk.Message{Role: sdk.Assistant, Content: sdk.NewMessageContent(text)},
+		}},
+	}
+}
+
+// ChatStream returns the events of a chat completion stream of deltas,
+// ending with a stop, for ReturnStream.
+func ChatStream(deltas ...string) []sdk.SSEvent {
+	chunks := make([]any, 0, len(deltas)+1)
+	for i, delta := range deltas {
+		choice := sdk.ChatCompletionStreamChoice{Delta: sdk.ChatCompletionStreamResponseDelta{Content: delta}}
+		if i == 0 {
+			choice.Delta.Role = sdk.Assistant
+		}
+		chunks = append(chunks, chatChunk(choice))
+	}
+	chunks = append(chunks, chatChunk(sdk.ChatCompletionStreamChoice{FinishReason: sdk.Stop}))
+	return append(Events(chunks...), StreamEnd())
+}
+
+func chatChunk(choice sdk.ChatCompletionStreamChoice) sdk.CreateChatCompletionStreamResponse {
+	return sdk.CreateChatCompletionStreamResponse{
+		ID:      "chatcmpl-mock",
+		Object:  "chat.completion.chunk",
+		Choices: []sdk.ChatCompletionStreamChoice{choice},
+	}
+}
+
+// Events returns content-delta events of the JSON of each payload, e.g.
+// sdk.MessagesStreamEvent or sdk.ResponseStreamEvent values, or of
+// payloads that are already JSON strings or bytes.
+func Events(payloads ...any) []sdk.SSEvent {
+	events := make([]sdk.SSEvent, 0, len(payloads))
+	for _, payload := range payloads {
+		var data []byte
+		switch payload := payload.(type) {
+		case string:
+			data = []byte(payload)
+		case []byte:
+			data = payload
+		default:
+			var err error
+			if data, err = json.Marshal(payload); err != nil {
+				panic(fmt.Sprintf("sdkmock: failed to encode event: %v", err))
+			}
+		}
+		events = append(events, sdk.SSEvent{Event: new(sdk.ContentDelta), Data: &data})
+	}
+	return events
+}
+
+// StreamEnd returns the event that ends chat completion streams.
+func StreamEnd() sdk.SSEvent {
+	return sdk.SSEvent{Event: new(sdk.StreamEnd)}
+}
@@ -0,0 +1,193 @@
+package sdkmock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	sdk "github.com/inference-gateway/sdk"
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// MockClient must implement every method of sdk.Client; this fails to
+// compile when the interface grows.
+var _ sdk.Client = (*MockClient)(nil)
+
+// failures is a testing.TB that records failures instead of failing the
+// test, and runs cleanups when asked.
+type failures struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (f *failures) Helper() {}
+
+func (f *failures) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *failures) Cleanup(cleanup func()) {
+	f.cleanups = append(f.cleanups, cleanup)
+}
+
+func (f *failures) end() {
+	for _, cleanup := range f.cleanups {
+		cleanup()
+	}
+}
+
+func messages(texts ...string) []sdk.Message {
+	out := make([]sdk.Message, 0, len(texts))
+	for _, text := range texts {
+		out = append(out, sdk.Message{Role: sdk.User, Content: sdk.NewMessageContent(text)})
+	}
+	return out
+}
+
+func content(t *testing.T, response *sdk.CreateChatCompletionResponse) string {
+	t.Helper()
+	require.NotEmpty(t, response.Choices)
+	text, err := response.Choices[0].Message.Content.AsMessageContent0()
+	require.NoError(t, err)
+	return text
+}
+
+func TestMockClient_Expectations(t *testing.T) {
+	client := NewMockClient(t)
+	overloaded := errors.New("overloaded")
+	client.On(sdk.OperationGenerateContent, Provider(sdk.Openai), MessageContains("weather")).
+		Return(ChatCompletion("Sunny.")).
+		Once()
+	client.On(sdk.OperationGenerateContent, Model("gpt-4o")).ReturnError(overloaded)
+	client.On(sdk.OperationGenerateContent).Return(ChatCompletion("Anything else."))
+	client.On(sdk.OperationHealthCheck).Maybe()
+	ctx := context.Background()
+
+	response, err := client.GenerateContent(ctx, sdk.Openai, "gpt-4o", messages("What's the weather?"))
+	require.NoError(t, err)
+	assert.Equal(t, "Sunny.", content(t, response))
+
+	// The first expectation is used up.
+	_, err = client.GenerateContent(ctx, sdk.Openai, "gpt-4o", messages("What's the weather?"))
+	assert.ErrorIs(t, err, overloaded)
+
+	response, err = client.WithHeader("X-Team", "agents").
+		WithOptions(&sdk.CreateChatCompletionRequest{MaxTokens: new(50)}).
+		GenerateContent(ctx, sdk.Groq, "llama", messages("Hi"))
+	require.NoError(t, err)
+	assert.Equal(t, "Anything else.", content(t, response))
+
+	calls := client.CallsOf(sdk.OperationGenerateContent)
+	require.Len(t, calls, 3)
+	assert.Equal(t, "What's the weather?", calls[0].Text())
+	assert.Empty(t, calls[0].Settings)
+	assert.Equal(t, map[string]string{"X-Team": "agents"}, calls[2].Settings.Headers)
+	assert.Equal(t, 50, *calls[2].Settings.Options.MaxTokens)
+	assert.Len(t, client.Calls(), 3)
+
+	client.AssertCalled(sdk.OperationGenerateContent, Provider(sdk.Groq), LastMessage("greeting", func(role sdk.MessageRole, text string) bool {
+		return role == sdk.User && text == "Hi"
+	}))
+	client.AssertNotCalled(sdk.OperationGenerateContent, Provider(sdk.Anthropic))
+}
+
+func TestMockClient_Streams(t *testing.T) {
+	client := NewMockClient(t)
+	client.On(sdk.OperationGenerateContentStream).ReturnStream(ChatStream("Sun", "ny.")...)
+	client.On(sdk.OperationCreateMessageStream, Request("small budget", func(r *sdk.CreateMessagesRequest) bool {
+		return r.MaxTokens <= 1024
+	})).ReturnStream(Events(
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":3,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Cloudy."}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}`,
+	)...)
+
+	// The mock drives the SDK's own helpers, here the Generator.
+	generator := sdk.NewGenerator(client, nil)
+	for api, want := range map[sdk.API]string{sdk.APIChat: "Sunny.", sdk.APIMessages: "Cloudy."} {
+		events, err := generator.GenerateStream(context.Background(), sdk.GenerateRequest{
+			Provider:  sdk.Anthropic,
+			Model:     "claude",
+			API:       api,
+			Messages:  messages("Weather?"),
+			MaxTokens: 512,
+		})
+		require.NoError(t, err)
+		var text strings.Builder
+		var response *sdk.GenerateResponse
+		for event := range events {
+			require.NoError(t, event.Err)
+			text.WriteString(event.Text)
+			if event.Response != nil {
+				response = event.Response
+			}
+		}
+		assert.Equal(t, want, text.String(), api)
+		require.NotNil(t, response, api)
+		assert.Equal(t, sdk.Stop, response.FinishReason, api)
+	}
+
+	// Chat options are passed per call, through the context.
+	calls := client.CallsOf(sdk.OperationGenerateContentStream)
+	require.Len(t, calls, 1)
+	require.NotNil(t, calls[0].ChatOptions)
+	assert.Equal(t, 512, *calls[0].ChatOptions.MaxTokens)
+	assert.True(t, calls[0].ChatOptions.StreamOptions.IncludeUsage)
+	assert.Nil(t, calls[0].Settings.Options)
+
+	calls = client.CallsOf(sdk.OperationCreateMessageStream)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "claude", calls[0].Model)
+	assert.Equal(t, "Weather?", calls[0].Text(), "Messages requests are seen in chat form")
+	assert.IsType(t, &sdk.CreateMessagesRequest{}, calls[0].Request)
+}
+
+func TestMockClient_Do(t *testing.T) {
+	client := NewMockClient(t)
+	client.On(sdk.OperationCreateResponse).Do(func(_ context.Context, call *Call) (any, error) {
+		return &sdk.Response{ID: "resp_" + call.Model}, nil
+	}).Times(2)
+	client.On(sdk.OperationListProviderModels).Return(&sdk.ListModelsResponse{Data: []sdk.Model{{ID: "openai/gpt-4o"}}})
+
+	for range 2 {
+		response, err := client.CreateResponse(context.Background(), sdk.Openai, sdk.CreateResponseRequest{Model: "gpt-5"})
+		require.NoError(t, err)
+		assert.Equal(t, "resp_gpt-5", response.ID)
+	}
+	models, err := client.ListProviderModels(context.Background(), sdk.Openai, sdk.ListModelsParamsIncludePricing)
+	require.NoError(t, err)
+	assert.Len(t, models.Data, 1)
+	assert.Equal(t, []sdk.ListModelsParamsInclude{sdk.ListModelsParamsIncludePricing}, client.Calls()[2].Include)
+}
+
+func TestMockClient_Failures(t *testing.T) {
+	tb := &failures{TB: t}
+	client := NewMockClient(tb)
+	client.On(sdk.OperationGenerateContent, Model("gpt-4o")).Return(&sdk.MessagesResponse{})
+	client.On(sdk.OperationListTools).Times(2)
+	client.On(sdk.OperationCreateImage).Maybe()
+	client.On(sdk.OperationHealthCheck)
+
+	_, err := client.GenerateContent(context.Background(), sdk.Openai, "gpt-4o", messages("Hi"))
+	require.Error(t, err, "wrong response type")
+	_, err = client.GenerateContent(context.Background(), sdk.Openai, "llama", messages("Hi"))
+	assert.ErrorIs(t, err, ErrUnexpectedCall)
+
+	// Expectations without a response return zero values.
+	tools, err := client.ListTools(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, tools.Data)
+
+	tb.end()
+	assert.Equal(t, []string{
+		"sdkmock: GenerateContent(provider openai, model gpt-4o, 1 messages) returned *sdk.MessagesResponse, want *sdk.CreateChatCompletionResponse",
+		"sdkmock: unexpected call GenerateContent(provider openai, model llama, 1 messages)",
+		"sdkmock: expected 2 calls ListTools(), got 1",
+		"sdkmock: expected a call HealthCheck(), got none",
+	}, tb.errors)
+}
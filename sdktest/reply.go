@@ -0,0 +1,416 @@
+package sdktest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	sdk "github.com/inference-gateway/sdk"
+)
+
+// Reply is the answer to a request. Its zero value answers with the
+// endpoint's default: a completion of DefaultText in the format of the
+// endpoint, streamed if the request asked for it, the models, the tools
+// or images.
+type Reply struct {
+	// Status defaults to 200. Errors without a Body get a gateway error
+	// body.
+	Status int
+	Header http.Header
+	// Text is the text of default completions. Defaults to DefaultText.
+	Text string
+	// Body, when set, is sent as the JSON body instead of the default.
+	// []byte and string bodies are sent as they are.
+	Body any
+	// Events, when set, are sent as the data of server-sent events
+	// instead of the default.
+	Events []string
+
+	// Latency delays the response.
+	Latency time.Duration
+	// ChunkDelay delays each stream event after the first.
+	ChunkDelay time.Duration
+	// DisconnectAfter, when positive, drops the connection after that
+	// many stream events, without ending the stream.
+	DisconnectAfter int
+	// Malformed inserts an event of invalid JSON after the first stream
+	// event, or truncates JSON bodies.
+	Malformed bool
+}
+
+// Text returns a Reply of a completion of text.
+func Text(text string) Reply {
+	return Reply{Text: text}
+}
+
+// JSON returns a Reply of body encoded as JSON.
+func JSON(status int, body any) Reply {
+	return Reply{Status: status, Body: body}
+}
+
+// Error returns a Reply of a gateway error.
+func Error(status int, message string) Reply {
+	return JSON(status, errorBody(message))
+}
+
+// RateLimited returns a 429 Reply with a Retry-After header of
+// retryAfter, rounded up to seconds.
+func RateLimited(retryAfter time.Duration) Reply {
+	reply := Error(http.StatusTooManyRequests, "Too Many Requests")
+	reply.Header = http.Header{"Retry-After": {strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))}}
+	return reply
+}
+
+// Events returns a Reply of a stream of the given event data, sent as
+// they are. End chat completion streams with "[DONE]" as the gateway does.
+func Events(data ...string) Reply {
+	return Reply{Events: data}
+}
+
+// DefaultModels returns the models a Server lists by default, with
+// pricing, context window and modalities metadata.
+func DefaultModels() []sdk.Model {
+	updated := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	model := func(provider sdk.Provider, name string, created int64, contextWindow int, input, output string, modalities [2][]sdk.Modality) sdk.Model {
+		return sdk.Model{
+			ID:            string(provider) + "/" + name,
+			Object:        "model",
+			Created:       created,
+			OwnedBy:       string(provider),
+			ServedBy:      provider,
+			ContextWindow: &sdk.ContextWindow{Tokens: contextWindow, Source: sdk.ContextWindowSourceProvider},
+			Pricing: &sdk.Pricing{
+				Currency:       "USD",
+				InputPerToken:  input,
+				OutputPerToken: output,
+				Source:         sdk.PricingSourceProvider,
+				UpdatedAt:      updated,
+			},
+			Modalities: &sdk.ModelModalities{Input: modalities[0], Output: modalities[1]},
+		}
+	}
+	text := []sdk.Modality{sdk.ModalityText}
+	vision := []sdk.Modality{sdk.ModalityText, sdk.ModalityImage}
+	image := []sdk.Modality{sdk.ModalityImage}
+	return []sdk.Model{
+		model(sdk.Openai, "gpt-4o", 1686935002, 128000, "0.0000025", "0.00001", [2][]sdk.Modality{vision, text}),
+		model(sdk.Openai, "gpt-image-1", 1745280000, 32000, "0.000005", "0.00004", [2][]sdk.Modality{vision, image}),
+		model(sdk.Anthropic, "claude-sonnet-4-5", 1759104000, 200000, "0.000003", "0.000015", [2][]sdk.Modality{vision, text}),
+		model(sdk.Groq, "llama-3.3-70b-versatile", 1733443200, 131072, "0.00000059", "0.00000079", [2][]sdk.Modality{text, text}),
+	}
+}
+
+// render sends the default reply of the endpoint of request.
+func (s *Server) render(w http.ResponseWriter, request *Request, reply Reply, status int) {
+	text := reply.Text
+	if text == "" {
+		text = DefaultText
+	}
+
+	switch request.Endpoint {
+	case EndpointModels:
+		s.writeModels(w, request, reply, status)
+	case EndpointTools:
+		tools := s.options.Tools
+		if tools == nil {
+			tools = []sdk.MCPTool{}
+		}
+		writeJSON(w, status, sdk.ListToolsResponse{Object: "list", Data: tools}, reply.Malformed)
+	case EndpointChatCompletions, EndpointMessages, EndpointResponses:
+		if request.Model == "" {
+			writeError(w, http.StatusBadRequest, "model is required")
+			return
+		}
+		completion := completion{
+			server:       s,
+			request:      request,
+			text:         text,
+			inputTokens:  estimateTokens(request.Body),
+			outputTokens: int64(len(strings.Fields(text))),
+		}
+		if request.Stream {
+			s.stream(w, status, reply, completion.events())
+			return
+		}
+		writeJSON(w, status, completion.body(), reply.Malformed)
+	case EndpointImagesGenerations, EndpointImagesEdits, EndpointImagesVariations:
+		s.writeImages(w, request, reply, status)
+	case EndpointProxy:
+		body := request.Body
+		if len(body) == 0 {
+			body = []byte("{}")
+		}
+		writeJSON(w, status, body, reply.Malformed)
+	case EndpointMetrics:
+		mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+		switch mediaType {
+		case "application/json":
+			writeJSON(w, status, []byte("{}"), reply.Malformed)
+		case "application/x-protobuf":
+			w.Header().Set("Content-Type", "application/x-protobuf")
+			w.WriteHeader(status)
+		default:
+			writeError(w, http.StatusUnsupportedMediaType, "unsupported content type")
+		}
+	case EndpointHealth:
+		w.WriteHeader(status)
+	}
+}
+
+// writeModels lists the models of the requested provider, with the
+// requested metadata.
+func (s *Server) writeModels(w http.ResponseWriter, request *Request, reply Reply, status int) {
+	include := map[sdk.ListModelsParamsInclude]bool{}
+	if value := request.Query.Get("include"); value != "" {
+		for key := range strings.SplitSeq(value, ",") {
+			key := sdk.ListModelsParamsInclude(strings.TrimSpace(key))
+			if !key.Valid() {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("Unsupported include value: '%s'. Supported values: pricing, context_window, modalities", key))
+				return
+			}
+			include[key] = true
+		}
+	}
+
+	response := sdk.ListModelsResponse{Object: "list", Data: []sdk.Model{}}
+	if request.Provider != "" {
+		response.Provider = &request.Provider
+	}
+	for _, model := range s.options.Models {
+		if request.Provider != "" && model.ServedBy != request.Provider {
+			continue
+		}
+		if !include[sdk.ListModelsParamsIncludePricing] {
+			model.Pricing = nil
+		}
+		if !include[sdk.ListModelsParamsIncludeContextWindow] {
+			model.ContextWindow = nil
+		}
+		if !include[sdk.ListModelsParamsIncludeModalities] {
+			model.Modalities = nil
+		}
+		response.Data = append(response.Data, model)
+	}
+	writeJSON(w, status, response, reply.Malformed)
+}
+
+// placeholderPNG is a 1x1 transparent PNG, the image of default image
+// replies.
+var placeholderPNG = []byte{
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d,
+	0x49, 0x48, 0x44, 0x52, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01,
+	0x08, 0x06, 0x00, 0x00, 0x00, 0x1f, 0x15, 0xc4, 0x89, 0x00, 0x00, 0x00,
+	0x0d, 0x49, 0x44, 0x41, 0x54, 0x78, 0x9c, 0x63, 0x00, 0x01, 0x00, 0x00,
+	0x05, 0x00, 0x01, 0x0d, 0x0a, 0x2d, 0xb4, 0x00, 0x00, 0x00, 0x00, 0x49,
+	0x45, 0x4e, 0x44, 0xae, 0x42, 0x60, 0x82,
+}
+
+// writeImages answers image requests with n placeholder images, as URLs
+// or base64 as the request asks.
+func (s *Server) writeImages(w http.ResponseWriter, request *Request, reply Reply, status int) {
+	fields := struct {
+		Prompt         string `json:"prompt"`
+		N              int    `json:"n"`
+		ResponseFormat string `json:"response_format"`
+	}{N: 1}
+	if request.Endpoint == EndpointImagesGenerations {
+		if err := request.Decode(&fields); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	} else {
+		if _, ok := request.Files["image"]; !ok {
+			writeError(w, http.StatusBadRequest, "image is required")
+			return
+		}
+		fields.Prompt = request.Fields["prompt"]
+		fields.ResponseFormat = request.Fields["response_format"]
+		if n, err := strconv.Atoi(request.Fields["n"]); err == nil {
+			fields.N = n
+		}
+	}
+	if request.Endpoint != EndpointImagesVariations && fields.Prompt == "" {
+		writeError(w, http.StatusBadRequest, "prompt is required")
+		return
+	}
+
+	response := sdk.ImagesResponse{Created: time.Now().Unix(), Data: make([]sdk.Image, max(fields.N, 1))}
+	for i := range response.Data {
+		if fields.ResponseFormat == "b64_json" {
+			response.Data[i].B64Json = new(base64.StdEncoding.EncodeToString(placeholderPNG))
+		} else {
+			response.Data[i].URL = new(fmt.Sprintf("%s/images/%s.png", s.URL, s.nextID("img_")))
+		}
+	}
+	writeJSON(w, status, response, reply.Malformed)
+}
+
+// completion renders a completion of text in the format of the endpoint
+// of request.
+type completion struct {
+	server                    *Server
+	request                   *Request
+	text                      string
+	inputTokens, outputTokens int64
+}
+
+// body returns the completion as a JSON body.
+func (c completion) body() any {
+	switch c.request.Endpoint {
+	case EndpointMessages:
+		return c.message([]any{map[string]any{"type": "text", "text": c.text}}, "end_turn", c.outputTokens)
+	case EndpointResponses:
+		return c.response(c.server.nextID("resp_fake_"), "completed", c.outputItems())
+	}
+	return map[string]any{
+		"id":      c.server.nextID("chatcmpl-fake-"),
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   c.request.Model,
+		"choices": []any{map[string]any{
+			"index":         0,
+			"finish_reason": "stop",
+			"message":       map[string]any{"role": "assistant", "content": c.text},
+		}},
+		"usage": c.chatUsage(),
+	}
+}
+
+// events returns the completion as the data of stream events.
+func (c completion) events() []string {
+	var events []any
+	switch c.request.Endpoint {
+	case EndpointMessages:
+		events = append(events,
+			map[string]any{"type": "message_start", "message": c.message([]any{}, nil, 1)},
+			map[string]any{"type": "content_block_start", "index": 0, "content_block": map[string]any{"type": "text", "text": ""}},
+		)
+		for _, word := range words(c.text) {
+			events = append(events, map[string]any{"type": "content_block_delta", "index": 0, "delta": map[string]any{"type": "text_delta", "text": word}})
+		}
+		events = append(events,
+			map[string]any{"type": "content_block_stop", "index": 0},
+			map[string]any{"type": "message_delta", "delta": map[string]any{"stop_reason": "end_turn"}, "usage": map[string]any{"output_tokens": c.outputTokens}},
+			map[string]any{"type": "message_stop"},
+		)
+	case EndpointResponses:
+		id := c.server.nextID("resp_fake_")
+		item := c.server.nextID("msg_fake_")
+		events = append(events, map[string]any{"type": "response.created", "response": c.response(id, "in_progress", []any{})})
+		for _, word := range words(c.text) {
+			events = append(events, map[string]any{"type": "response.output_text.delta", "item_id": item, "output_index": 0, "content_index": 0, "delta": word})
+		}
+		events = append(events,
+			map[string]any{"type": "response.output_text.done", "item_id": item, "output_index": 0, "content_index": 0, "text": c.text},
+			map[string]any{"type": "response.completed", "response": c.response(id, "completed", c.outputItems())},
+		)
+		for i, event := range events {
+			event.(map[string]any)["sequence_number"] = i
+		}
+	default:
+		id := c.server.nextID("chatcmpl-fake-")
+		chunk := func(delta map[string]any, finish any) map[string]any {
+			return map[string]any{
+				"id":      id,
+				"object":  "chat.completion.chunk",
+				"created": time.Now().Unix(),
+				"model":   c.request.Model,
+				"choices": []any{map[string]any{"index": 0, "delta": delta, "finish_reason": finish}},
+			}
+		}
+		for i, word := range words(c.text) {
+			delta := map[string]any{"content": word}
+			if i == 0 {
+				delta["role"] = "assistant"
+			}
+			events = append(events, chunk(delta, nil))
+		}
+		events = append(events, chunk(map[string]any{}, "stop"))
+		var options struct {
+			StreamOptions struct {
+				IncludeUsage bool `json:"include_usage"`
+			} `json:"stream_options"`
+		}
+		if c.request.Decode(&options) == nil && options.StreamOptions.IncludeUsage {
+			usage := chunk(nil, nil)
+			usage["choices"] = []any{}
+			usage["usage"] = c.chatUsage()
+			events = append(events, usage)
+		}
+	}
+
+	data := make([]string, 0, len(events)+1)
+	for _, event := range events {
+		data = append(data, string(mustJSON(event)))
+	}
+	if c.request.Endpoint == EndpointChatCompletions {
+		data = append(data, "[DONE]")
+	}
+	return data
+}
+
+func (c completion) chatUsage() map[string]any {
+	return map[string]any{
+		"prompt_tokens":     c.inputTokens,
+		"completion_tokens": c.outputTokens,
+		"total_tokens":      c.inputTokens + c.outputTokens,
+	}
+}
+
+// message returns a Messages API message.
+func (c completion) message(content []any, stopReason any, outputTokens int64) map[string]any {
+	return map[string]any{
+		"id":            c.server.nextID("msg_fake_"),
+		"type":          "message",
+		"role":          "assistant",
+		"model":         c.request.Model,
+		"content":       content,
+		"stop_reason":   stopReason,
+		"stop_sequence": nil,
+		"usage":         map[string]any{"input_tokens": c.inputTokens, "output_tokens": outputTokens},
+	}
+}
+
+// response returns a Responses API response.
+func (c completion) response(id, status string, output []any) map[string]any {
+	response := map[string]any{
+		"id":         id,
+		"object":     "response",
+		"created_at": time.Now().Unix(),
+		"model":      c.request.Model,
+		"status":     status,
+		"output":     output,
+	}
+	if status == "completed" {
+		response["usage"] = map[string]any{
+			"input_tokens":  c.inputTokens,
+			"output_tokens": c.outputTokens,
+			"total_tokens":  c.inputTokens + c.outputTokens,
+		}
+	}
+	return response
+}
+
+func (c completion) outputItems() []any {
+	return []any{map[string]any{
+		"type":    "message",
+		"id":      c.server.nextID("msg_fake_"),
+		"role":    "assistant",
+		"status":  "completed",
+		"content": []any{map[string]any{"type": "output_text", "text": c.text, "annotations": []any{}}},
+	}}
+}
+
+func mustJSON(v any) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("sdktest: failed to encode event: %v", err))
+	}
+	return data
+}
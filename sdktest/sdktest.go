@@ -0,0 +1,456 @@
+// Package sdktest provides a fake Inference Gateway for tests.
+//
+// A Server implements every endpoint of the gateway in process: model
+// listing with include metadata, chat completions, Messages and Responses
+// calls, streaming or not, image generations, edits and variations, MCP
+// tools, the provider proxy, metrics and health. It answers with
+// plausible defaults, or with replies scripted per endpoint, and can
+// inject faults: latency, rate limiting, dropped streams and malformed
+// chunks. It records the requests it gets for assertions.
+//
+// Example:
+//
+//	gateway := sdktest.NewServer(t, nil)
+//	gateway.Enqueue(sdktest.EndpointChatCompletions,
+//		sdktest.RateLimited(time.Second),
+//		sdktest.Text("Paris."),
+//	)
+//	client := gateway.Client(nil)
+//	response, err := client.GenerateContent(ctx, sdk.Openai, "gpt-4o", messages)
+package sdktest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/inference-gateway/sdk"
+)
+
+// DefaultText is the text of completions whose Reply sets none.
+const DefaultText = "Hello from the fake gateway."
+
+// maxMultipartMemory is the memory used to parse image uploads before
+// they spill to disk.
+const maxMultipartMemory = 32 << 20
+
+// Endpoint is an endpoint of the gateway, named as in sdk.Call.Endpoint.
+type Endpoint string
+
+const (
+	EndpointModels            Endpoint = "models"
+	EndpointChatCompletions   Endpoint = "chat/completions"
+	EndpointMessages          Endpoint = "messages"
+	EndpointResponses         Endpoint = "responses"
+	EndpointImagesGenerations Endpoint = "images/generations"
+	EndpointImagesEdits       Endpoint = "images/edits"
+	EndpointImagesVariations  Endpoint = "images/variations"
+	EndpointTools             Endpoint = "mcp/tools"
+	EndpointProxy             Endpoint = "proxy"
+	EndpointMetrics           Endpoint = "metrics"
+	EndpointHealth            Endpoint = "health"
+)
+
+// Request is a request the Server got.
+type Request struct {
+	Endpoint Endpoint
+	Method   string
+	// Path is the URL path, e.g. /v1/chat/completions.
+	Path   string
+	Query  url.Values
+	Header http.Header
+	// Provider is the provider query parameter, or the provider of proxy
+	// requests.
+	Provider sdk.Provider
+	// Model is the model of JSON and multipart bodies.
+	Model string
+	// Stream reports whether a JSON body asked for a stream.
+	Stream bool
+	Body   []byte
+	// Fields and Files are the parts of multipart bodies.
+	Fields map[string]string
+	Files  map[string][]byte
+}
+
+// Decode decodes the JSON body into v, e.g. an
+// *sdk.CreateChatCompletionRequest.
+func (r *Request) Decode(v any) error {
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		return fmt.Errorf("failed to decode request body: %w", err)
+	}
+	return nil
+}
+
+// Handler returns the reply to a request.
+type Handler func(request *Request) Reply
+
+// Options configures a Server.
+type Options struct {
+	// APIKey, when set, must be sent as a bearer token. Other requests,
+	// but health checks, get 401.
+	APIKey string
+	// Models are the models listed. Defaults to DefaultModels.
+	Models []sdk.Model
+	// Tools are the MCP tools listed.
+	Tools []sdk.MCPTool
+}
+
+// Server is a fake Inference Gateway. It is safe for concurrent use.
+type Server struct {
+	// URL is the base URL of the gateway, with the /v1 prefix, as set in
+	// sdk.ClientOptions.BaseURL.
+	URL string
+
+	t       testing.TB
+	server  *httptest.Server
+	options Options
+
+	mu       sync.Mutex
+	scripts  map[Endpoint][]Reply
+	handlers map[Endpoint]Handler
+	checks   map[Endpoint][]func(*Request) error
+	requests []*Request
+	ids      int
+}
+
+// NewServer starts a Server, which is closed when the test ends.
+func NewServer(t testing.TB, options *Options) *Server {
+	t.Helper()
+	s := &Server{
+		t:        t,
+		scripts:  map[Endpoint][]Reply{},
+		handlers: map[Endpoint]Handler{},
+		checks:   map[Endpoint][]func(*Request) error{},
+	}
+	if options != nil {
+		s.options = *options
+	}
+	if s.options.Models == nil {
+		s.options.Models = DefaultModels()
+	}
+
+	mux := http.NewServeMux()
+	routes := map[string]Endpoint{
+		"GET /v1/models":                 EndpointModels,
+		"POST /v1/chat/completions":      EndpointChatCompletions,
+		"POST /v1/messages":              EndpointMessages,
+		"POST /v1/responses":             EndpointResponses,
+		"POST /v1/images/generations":    EndpointImagesGenerations,
+		"POST /v1/images/edits":          EndpointImagesEdits,
+		"POST /v1/images/variations":     EndpointImagesVariations,
+		"GET /v1/mcp/tools":              EndpointTools,
+		"/v1/proxy/{provider}/{path...}": EndpointProxy,
+		"POST /v1/metrics":               EndpointMetrics,
+		"GET /health":                    EndpointHealth,
+		"GET /v1/health":                 EndpointHealth,
+	}
+	for pattern, endpoint := range routes {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			s.serve(endpoint, w, r)
+		})
+	}
+	s.server = httptest.NewServer(mux)
+	s.URL = s.server.URL + "/v1"
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// Close shuts the server down. Tests needn't call it.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Client returns a client of the server. options, which may be nil, are
+// copied with BaseURL set to the server and, if empty, APIKey to the
+// server's.
+func (s *Server) Client(options *sdk.ClientOptions) sdk.Client {
+	var copied sdk.ClientOptions
+	if options != nil {
+		copied = *options
+	}
+	copied.BaseURL = s.URL
+	if copied.APIKey == "" {
+		copied.APIKey = s.options.APIKey
+	}
+	return sdk.NewClient(&copied)
+}
+
+// Enqueue scripts the next replies of an endpoint, one per request, in
+// order. Requests beyond the script get the handler's or the default
+// reply.
+func (s *Server) Enqueue(endpoint Endpoint, replies ...Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[endpoint] = append(s.scripts[endpoint], replies...)
+}
+
+// Handle sets the handler of the requests to an endpoint that no scripted
+// reply answers.
+func (s *Server) Handle(endpoint Endpoint, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[endpoint] = handler
+}
+
+// Expect checks every later request to an endpoint. Failed checks fail
+// the test.
+func (s *Server) Expect(endpoint Endpoint, check func(request *Request) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks[endpoint] = append(s.checks[endpoint], check)
+}
+
+// Requests returns the requests the server got, in order.
+func (s *Server) Requests() []*Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Request(nil), s.requests...)
+}
+
+// RequestsTo returns the requests the server got for an endpoint, in
+// order.
+func (s *Server) RequestsTo(endpoint Endpoint) []*Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*Request
+	for _, request := range s.requests {
+		if request.Endpoint == endpoint {
+			out = append(out, request)
+		}
+	}
+	return out
+}
+
+// LastRequest returns the last request to an endpoint, or nil.
+func (s *Server) LastRequest(endpoint Endpoint) *Request {
+	requests := s.RequestsTo(endpoint)
+	if len(requests) == 0 {
+		return nil
+	}
+	return requests[len(requests)-1]
+}
+
+// serve records, checks and answers a request.
+func (s *Server) serve(endpoint Endpoint, w http.ResponseWriter, r *http.Request) {
+	request, err := readRequest(endpoint, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, request)
+	checks := s.checks[endpoint]
+	var reply Reply
+	var scripted bool
+	if script := s.scripts[endpoint]; len(script) > 0 {
+		reply, s.scripts[endpoint], scripted = script[0], script[1:], true
+	}
+	handler := s.handlers[endpoint]
+	s.mu.Unlock()
+
+	for _, check := range checks {
+		if err := check(request); err != nil {
+			s.t.Errorf("sdktest: %s %s: %v", request.Method, request.Path, err)
+		}
+	}
+
+	if s.options.APIKey != "" && endpoint != EndpointHealth &&
+		r.Header.Get("Authorization") != "Bearer "+s.options.APIKey {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if !scripted && handler != nil {
+		reply = handler(request)
+	}
+	s.write(w, r, request, reply)
+}
+
+// readRequest reads the body of r and the fields of interest in it.
+func readRequest(endpoint Endpoint, r *http.Request) (*Request, error) {
+	request := &Request{
+		Endpoint: endpoint,
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		Query:    r.URL.Query(),
+		Header:   r.Header.Clone(),
+		Provider: sdk.Provider(r.URL.Query().Get("provider")),
+	}
+	if endpoint == EndpointProxy {
+		request.Provider = sdk.Provider(r.PathValue("provider"))
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	request.Body = body
+
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "multipart/form-data":
+		form, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(maxMultipartMemory)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse multipart body: %w", err)
+		}
+		defer func() { _ = form.RemoveAll() }()
+		request.Fields = map[string]string{}
+		for name, values := range form.Value {
+			request.Fields[name] = values[0]
+		}
+		request.Files = map[string][]byte{}
+		for name, headers := range form.File {
+			file, err := headers[0].Open()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file %s: %w", name, err)
+			}
+			data, err := io.ReadAll(file)
+			_ = file.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file %s: %w", name, err)
+			}
+			request.Files[name] = data
+		}
+		request.Model = request.Fields["model"]
+	case mediaType == "application/json" && len(body) > 0:
+		var fields struct {
+			Model  string `json:"model"`
+			Stream bool   `json:"stream"`
+		}
+		if err := json.Unmarshal(body, &fields); err != nil && endpoint != EndpointProxy && endpoint != EndpointMetrics {
+			return nil, fmt.Errorf("failed to decode request body: %w", err)
+		}
+		request.Model, request.Stream = fields.Model, fields.Stream
+	}
+	return request, nil
+}
+
+// nextID returns a new ID with prefix.
+func (s *Server) nextID(prefix string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids++
+	return fmt.Sprintf("%s%d", prefix, s.ids)
+}
+
+// write sends reply, or the default reply of the endpoint when it sets no
+// body or events.
+func (s *Server) write(w http.ResponseWriter, r *http.Request, request *Request, reply Reply) {
+	if reply.Latency > 0 {
+		select {
+		case <-time.After(reply.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	for name, values := range reply.Header {
+		w.Header()[name] = values
+	}
+	status := reply.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	switch {
+	case reply.Events != nil:
+		s.stream(w, status, reply, reply.Events)
+	case reply.Body != nil:
+		writeJSON(w, status, reply.Body, reply.Malformed)
+	case status >= http.StatusBadRequest:
+		writeJSON(w, status, errorBody(http.StatusText(status)), reply.Malformed)
+	default:
+		s.render(w, request, reply, status)
+	}
+}
+
+// stream sends events as server-sent events, each data an event, with the
+// faults of reply.
+func (s *Server) stream(w http.ResponseWriter, status int, reply Reply, events []string) {
+	if reply.Malformed && len(events) > 0 {
+		events = append([]string{events[0], `{"id": "malformed`}, events[1:]...)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	flusher, _ := w.(http.Flusher)
+	for i, event := range events {
+		if reply.DisconnectAfter > 0 && i == reply.DisconnectAfter {
+			// Aborting drops the connection without ending the body.
+			panic(http.ErrAbortHandler)
+		}
+		if i > 0 && reply.ChunkDelay > 0 {
+			time.Sleep(reply.ChunkDelay)
+		}
+		if name := eventName(event); name != "" {
+			_, _ = fmt.Fprintf(w, "event: %s\n", name)
+		}
+		_, _ = fmt.Fprintf(w, "data: %s\n\n", event)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// eventName returns the type of Messages and Responses stream events, sent
+// as the SSE event name as their APIs do.
+func eventName(data string) string {
+	var event struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal([]byte(data), &event) != nil {
+		return ""
+	}
+	return event.Type
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any, malformed bool) {
+	data, ok := body.([]byte)
+	if !ok {
+		if text, isText := body.(string); isText {
+			data = []byte(text)
+		} else {
+			var err error
+			if data, err = json.Marshal(body); err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+	}
+	if malformed {
+		data = data[:len(data)/2]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorBody(message), false)
+}
+
+func errorBody(message string) sdk.Error {
+	return sdk.Error{Error: &message}
+}
+
+// estimateTokens estimates the input tokens of a request at four bytes a
+// token.
+func estimateTokens(body []byte) int64 {
+	return max(1, int64(math.Ceil(float64(len(body))/4)))
+}
+
+// words splits text into words that keep their trailing spaces, as
+// stream deltas.
+func words(text string) []string {
+	return strings.SplitAfter(text, " ")
+}
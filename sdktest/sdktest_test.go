@@ -0,0 +1,234 @@
+package sdktest
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/inference-gateway/sdk"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+func content(t *testing.T, response *sdk.CreateChatCompletionResponse) string {
+	t.Helper()
+	require.NotEmpty(t, response.Choices)
+	text, err := response.Choices[0].Message.Content.AsMessageContent0()
+	require.NoError(t, err)
+	return text
+}
+
+func hello() []sdk.Message {
+	return []sdk.Message{{Role: sdk.User, Content: sdk.NewMessageContent("Hello")}}
+}
+
+// generate runs a request through a Generator, streaming or not, and
+// returns the text and the error of the stream.
+func generate(t *testing.T, client sdk.Client, api sdk.API, stream bool) (string, *sdk.GenerateResponse, error) {
+	t.Helper()
+	generator := sdk.NewGenerator(client, nil)
+	request := sdk.GenerateRequest{Provider: sdk.Openai, Model: "gpt-4o", API: api, Messages: hello(), MaxTokens: 100}
+	if !stream {
+		response, err := generator.Generate(context.Background(), request)
+		if err != nil {
+			return "", nil, err
+		}
+		return response.Text, response, nil
+	}
+	events, err := generator.GenerateStream(context.Background(), request)
+	require.NoError(t, err)
+	var text strings.Builder
+	var response *sdk.GenerateResponse
+	for event := range events {
+		if event.Err != nil {
+			return text.String(), nil, event.Err
+		}
+		text.WriteString(event.Text)
+		if event.Response != nil {
+			response = event.Response
+		}
+	}
+	return text.String(), response, nil
+}
+
+func TestServer_Completions(t *testing.T) {
+	gateway := NewServer(t, nil)
+	client := gateway.Client(nil)
+
+	for _, api := range []sdk.API{sdk.APIChat, sdk.APIMessages, sdk.APIResponses} {
+		for _, stream := range []bool{false, true} {
+			text, response, err := generate(t, client, api, stream)
+			require.NoError(t, err, "%s stream=%v", api, stream)
+			assert.Equal(t, DefaultText, text, "%s stream=%v", api, stream)
+			require.NotNil(t, response)
+			assert.Equal(t, sdk.Stop, response.FinishReason, "%s stream=%v", api, stream)
+			assert.Equal(t, int64(5), response.Usage.OutputTokens, "%s stream=%v", api, stream)
+			assert.Positive(t, response.Usage.InputTokens, "%s stream=%v", api, stream)
+		}
+	}
+
+	requests := gateway.Requests()
+	require.Len(t, requests, 6)
+	assert.Equal(t, EndpointChatCompletions, requests[1].Endpoint)
+	assert.True(t, requests[1].Stream)
+	assert.Equal(t, sdk.Openai, requests[1].Provider)
+	assert.Equal(t, "gpt-4o", requests[1].Model)
+	assert.Len(t, gateway.RequestsTo(EndpointMessages), 2)
+
+	gateway.Enqueue(EndpointChatCompletions, Text("Paris is the capital."))
+	response, err := client.GenerateContent(context.Background(), sdk.Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Equal(t, "Paris is the capital.", content(t, response))
+	response, err = client.GenerateContent(context.Background(), sdk.Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Equal(t, DefaultText, content(t, response), "scripts are used once")
+
+	_, err = client.GenerateContent(context.Background(), sdk.Openai, "", hello())
+	assert.ErrorContains(t, err, "model is required")
+}
+
+func TestServer_Models(t *testing.T) {
+	gateway := NewServer(t, nil)
+	client := gateway.Client(nil)
+	ctx := context.Background()
+
+	all, err := client.ListModels(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all.Data, len(DefaultModels()))
+	assert.Nil(t, all.Data[0].Pricing)
+
+	openai, err := client.ListProviderModels(ctx, sdk.Openai, sdk.ListModelsParamsIncludePricing, sdk.ListModelsParamsIncludeModalities)
+	require.NoError(t, err)
+	require.Len(t, openai.Data, 2)
+	assert.Equal(t, sdk.Openai, *openai.Provider)
+	assert.Equal(t, "0.0000025", openai.Data[0].Pricing.InputPerToken)
+	assert.Equal(t, []sdk.Modality{sdk.ModalityImage}, openai.Data[1].Modalities.Output)
+	assert.Nil(t, openai.Data[0].ContextWindow)
+
+	_, err = client.ListModels(ctx, sdk.ListModelsParamsInclude("size"))
+	assert.ErrorContains(t, err, "status code: 400")
+
+	tools, err := client.ListTools(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tools.Data)
+	require.NoError(t, client.HealthCheck(ctx))
+}
+
+func TestServer_Images(t *testing.T) {
+	gateway := NewServer(t, nil)
+	client := gateway.Client(nil)
+	ctx := context.Background()
+
+	generated, err := client.CreateImage(ctx, sdk.Openai, sdk.CreateImageRequest{
+		Prompt:         "A lighthouse",
+		N:              new(2),
+		ResponseFormat: new(sdk.CreateImageRequestResponseFormat("b64_json")),
+	})
+	require.NoError(t, err)
+	require.Len(t, generated.Data, 2)
+	png, err := base64.StdEncoding.DecodeString(*generated.Data[0].B64Json)
+	require.NoError(t, err)
+	assert.Equal(t, placeholderPNG, png)
+
+	var image openapi_types.File
+	image.InitFromBytes([]byte("image bytes"), "in.png")
+	edited, err := client.CreateImageEdit(ctx, sdk.Openai, sdk.CreateImageEditMultipartBody{Image: image, Prompt: "Add a moon", Model: new("gpt-image-1")})
+	require.NoError(t, err)
+	require.Len(t, edited.Data, 1)
+	assert.NotNil(t, edited.Data[0].URL)
+
+	request := gateway.LastRequest(EndpointImagesEdits)
+	require.NotNil(t, request)
+	assert.Equal(t, "Add a moon", request.Fields["prompt"])
+	assert.Equal(t, "gpt-image-1", request.Model)
+	assert.Equal(t, []byte("image bytes"), request.Files["image"])
+
+	_, err = client.CreateImageVariation(ctx, sdk.Openai, sdk.CreateImageVariationMultipartBody{Image: image})
+	require.NoError(t, err)
+}
+
+func TestServer_Faults(t *testing.T) {
+	gateway := NewServer(t, nil)
+	ctx := context.Background()
+	client := gateway.Client(&sdk.ClientOptions{
+		RetryConfig: &sdk.RetryConfig{Enabled: true, MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	})
+
+	gateway.Enqueue(EndpointChatCompletions, RateLimited(0), Text("Retried."))
+	response, err := client.GenerateContent(ctx, sdk.Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	assert.Equal(t, "Retried.", content(t, response))
+	assert.Len(t, gateway.RequestsTo(EndpointChatCompletions), 2)
+
+	client = gateway.Client(&sdk.ClientOptions{RetryConfig: &sdk.RetryConfig{Enabled: false}})
+	gateway.Enqueue(EndpointChatCompletions, Error(http.StatusServiceUnavailable, "overloaded"))
+	_, err = client.GenerateContent(ctx, sdk.Openai, "gpt-4o", hello())
+	assert.ErrorContains(t, err, "overloaded")
+
+	gateway.Enqueue(EndpointChatCompletions, Reply{Text: "one two three four", DisconnectAfter: 2})
+	text, _, err := generate(t, client, sdk.APIChat, true)
+	require.Error(t, err)
+	assert.Equal(t, "one two ", text, "the stream breaks off")
+
+	gateway.Enqueue(EndpointMessages, Reply{Malformed: true})
+	_, _, err = generate(t, client, sdk.APIMessages, true)
+	assert.ErrorContains(t, err, "failed to parse stream event")
+
+	gateway.Enqueue(EndpointResponses, Reply{Malformed: true})
+	_, _, err = generate(t, client, sdk.APIResponses, false)
+	require.Error(t, err)
+
+	gateway.Enqueue(EndpointChatCompletions, Reply{Latency: time.Second})
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = client.GenerateContent(timeout, sdk.Openai, "gpt-4o", hello())
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+
+	gateway.Enqueue(EndpointChatCompletions, Events(`{"id":"1","choices":[{"index":0,"delta":{"content":"Raw"},"finish_reason":"stop"}]}`, "[DONE]"))
+	text, _, err = generate(t, client, sdk.APIChat, true)
+	require.NoError(t, err)
+	assert.Equal(t, "Raw", text)
+}
+
+func TestServer_Assertions(t *testing.T) {
+	gateway := NewServer(t, &Options{APIKey: "sk-test"})
+	var checked int
+	gateway.Expect(EndpointChatCompletions, func(request *Request) error {
+		checked++
+		var body sdk.CreateChatCompletionRequest
+		if err := request.Decode(&body); err != nil {
+			return err
+		}
+		if len(body.Messages) != 1 {
+			return errors.New("want one message")
+		}
+		return nil
+	})
+	gateway.Handle(EndpointChatCompletions, func(request *Request) Reply {
+		return Text("Echo: " + request.Model)
+	})
+
+	response, err := gateway.Client(nil).GenerateContent(context.Background(), sdk.Groq, "llama", hello())
+	require.NoError(t, err)
+	assert.Equal(t, "Echo: llama", content(t, response))
+	assert.Equal(t, 1, checked)
+	assert.Equal(t, "Bearer sk-test", gateway.LastRequest(EndpointChatCompletions).Header.Get("Authorization"))
+
+	_, err = gateway.Client(&sdk.ClientOptions{APIKey: "sk-wrong"}).GenerateContent(context.Background(), sdk.Groq, "llama", hello())
+	assert.ErrorContains(t, err, "Unauthorized")
+	require.NoError(t, gateway.Client(&sdk.ClientOptions{APIKey: "sk-wrong"}).HealthCheck(context.Background()))
+
+	proxied, err := http.Post(gateway.URL+"/proxy/openai/v1/embeddings", "application/json", strings.NewReader(`{"input":"hi"}`))
+	require.NoError(t, err)
+	_ = proxied.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, proxied.StatusCode)
+	request := gateway.LastRequest(EndpointProxy)
+	require.NotNil(t, request)
+	assert.Equal(t, sdk.Openai, request.Provider)
+	assert.Equal(t, "/v1/proxy/openai/v1/embeddings", request.Path)
+}
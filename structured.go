@@ -0,0 +1,598 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// StructuredMode selects how GenerateStructured and its Messages and
+// Responses equivalents ask the model for JSON.
+type StructuredMode string
+
+const (
+	// StructuredModeAuto uses native schema enforcement and falls back to
+	// StructuredModeJSONObject when the provider rejects it.
+	StructuredModeAuto StructuredMode = ""
+	// StructuredModeSchema always uses native schema enforcement: a
+	// json_schema format for chat completions and the Responses API, and a
+	// forced tool call for the Messages API.
+	StructuredModeSchema StructuredMode = "json_schema"
+	// StructuredModeJSONObject describes the schema in the prompt and only
+	// asks for JSON mode. The Messages API has no JSON mode, so there the
+	// prompt alone carries the schema.
+	StructuredModeJSONObject StructuredMode = "json_object"
+)
+
+// StructuredOptions configures GenerateStructured, CreateMessageStructured
+// and CreateResponseStructured.
+type StructuredOptions struct {
+	// Name identifies the schema (or, for the Messages API, the tool the
+	// model is made to call). It defaults to the name of T.
+	Name string
+	// Description tells the model what the output is for.
+	Description string
+	// Strict requests strict schema adherence where supported. Defaults to
+	// true.
+	Strict *bool
+	// MaxRetries is how many more times to ask after a reply fails to parse
+	// or validate. The validation errors are sent back to the model each
+	// time. Zero means no retries.
+	MaxRetries int
+	// Mode selects how the output is requested; see StructuredMode.
+	Mode StructuredMode
+}
+
+// StructuredOutputError reports a reply that still did not match the schema
+// once all retries were used up.
+type StructuredOutputError struct {
+	// Content is the model's last reply.
+	Content string
+	// Attempts is the number of requests made.
+	Attempts int
+	// Err is the last JSON syntax, *SchemaValidationError or decoding error.
+	Err error
+}
+
+func (e *StructuredOutputError) Error() string {
+	return fmt.Sprintf("structured output invalid after %d attempt(s): %v", e.Attempts, e.Err)
+}
+
+func (e *StructuredOutputError) Unwrap() error {
+	return e.Err
+}
+
+// GenerateStructured asks a chat completion for a value of type T. It
+// derives a strict JSON Schema from T (see JSONSchemaFor), requests it as a
+// json_schema response format, validates the reply and decodes it into T.
+// Replies that fail are sent back with the validation errors up to
+// MaxRetries times before a *StructuredOutputError is returned.
+//
+// The client's options set with WithOptions are kept; only the response
+// format is replaced, and only for these requests.
+//
+// Example:
+//
+//	type Weather struct {
+//		City    string  `json:"city"`
+//		Celsius float64 `json:"celsius"`
+//	}
+//
+//	weather, _, err := sdk.GenerateStructured[Weather](ctx, client, sdk.Openai, "gpt-4o", messages,
+//		sdk.StructuredOptions{MaxRetries: 2})
+func GenerateStructured[T any](ctx context.Context, client Client, provider Provider, model string, messages []Message, opts ...StructuredOptions) (T, *CreateChatCompletionResponse, error) {
+	options := structuredOptions(opts)
+	s, err := newStructuredSchema[T](options)
+	if err != nil {
+		var zero T
+		return zero, nil, err
+	}
+
+	conversation := append([]Message(nil), messages...)
+
+	return runStructured[T](s, options, structuredTurn[CreateChatCompletionResponse]{
+		send: func(mode StructuredMode) (*CreateChatCompletionResponse, error) {
+			request := conversation
+			format := s.chatResponseFormat()
+			if mode == StructuredModeJSONObject {
+				request = withSystemInstructions(conversation, s.instructions())
+				format = NewResponseFormatJSONObject()
+			}
+			return client.GenerateContent(WithChatOptions(ctx, &CreateChatCompletionRequest{ResponseFormat: format}), provider, model, request)
+		},
+		content: func(response *CreateChatCompletionResponse) (string, error) {
+			if len(response.Choices) == 0 {
+				return "", fmt.Errorf("response has no choices")
+			}
+			return messageText(response.Choices[0].Message.Content)
+		},
+		feedback: func(_ *CreateChatCompletionResponse, content string, err error) error {
+			conversation = append(conversation,
+				Message{Role: Assistant, Content: NewMessageContent(content)},
+				Message{Role: User, Content: NewMessageContent(structuredFeedback(err))},
+			)
+			return nil
+		},
+	})
+}
+
+// CreateMessageStructured is GenerateStructured for the Messages API. In
+// schema mode it adds a tool whose input schema is derived from T and forces
+// the model to call it; the tool input is the result.
+func CreateMessageStructured[T any](ctx context.Context, client Client, provider Provider, request CreateMessagesRequest, opts ...StructuredOptions) (T, *MessagesResponse, error) {
+	options := structuredOptions(opts)
+	s, err := newStructuredSchema[T](options)
+	if err != nil {
+		var zero T
+		return zero, nil, err
+	}
+
+	conversation := append([]MessagesMessage(nil), request.Messages...)
+
+	return runStructured[T](s, options, structuredTurn[MessagesResponse]{
+		send: func(mode StructuredMode) (*MessagesResponse, error) {
+			req := request
+			req.Messages = conversation
+			if mode == StructuredModeJSONObject {
+				req.System = withSystemPrompt(request.System, s.instructions())
+			} else {
+				tools := []MessagesTool{}
+				if request.Tools != nil {
+					tools = append(tools, *request.Tools...)
+				}
+				tool := MessagesTool{Name: s.name, InputSchema: FunctionParameters(s.schema)}
+				if s.description != "" {
+					tool.Description = &s.description
+				}
+				tools = append(tools, tool)
+				req.Tools = &tools
+				req.ToolChoice = MessagesToolChoiceTool(s.name)
+			}
+			return client.CreateMessage(ctx, provider, req)
+		},
+		content: func(response *MessagesResponse) (string, error) {
+			if toolUse, ok := structuredToolUse(response, s.name); ok {
+				data, err := json.Marshal(toolUse.Input)
+				return string(data), err
+			}
+			var text strings.Builder
+			for _, block := range response.Content {
+				if b, err := block.AsMessagesTextBlock(); err == nil && b.Type == MessagesTextBlockTypeText {
+					text.WriteString(b.Text)
+				}
+			}
+			return text.String(), nil
+		},
+		feedback: func(response *MessagesResponse, _ string, err error) error {
+			blocks := make([]MessagesRequestContentBlock, 0, len(response.Content))
+			for _, block := range response.Content {
+				data, _ := block.MarshalJSON()
+				var requestBlock MessagesRequestContentBlock
+				if requestBlock.UnmarshalJSON(data) == nil {
+					blocks = append(blocks, requestBlock)
+				}
+			}
+			var assistant MessagesMessage_Content
+			mustBuildUnion(assistant.FromMessagesMessageContent1(blocks))
+
+			var reply MessagesMessage_Content
+			if toolUse, ok := structuredToolUse(response, s.name); ok {
+				var content MessagesToolResultBlock_Content
+				mustBuildUnion(content.FromMessagesToolResultBlockContent0(structuredFeedback(err)))
+				var block MessagesRequestContentBlock
+				mustBuildUnion(block.FromMessagesToolResultBlock(MessagesToolResultBlock{
+					Type:      ToolResult,
+					ToolUseID: toolUse.ID,
+					IsError:   boolPtr(true),
+					Content:   &content,
+				}))
+				mustBuildUnion(reply.FromMessagesMessageContent1([]MessagesRequestContentBlock{block}))
+			} else {
+				mustBuildUnion(reply.FromMessagesMessageContent0(structuredFeedback(err)))
+			}
+
+			conversation = append(conversation,
+				MessagesMessage{Role: MessagesMessageRoleAssistant, Content: assistant},
+				MessagesMessage{Role: MessagesMessageRoleUser, Content: reply},
+			)
+			return nil
+		},
+	})
+}
+
+// CreateResponseStructured is GenerateStructured for the Responses API. It
+// sets `text.format` to a json_schema derived from T.
+func CreateResponseStructured[T any](ctx context.Context, client Client, provider Provider, request CreateResponseRequest, opts ...StructuredOptions) (T, *Response, error) {
+	options := structuredOptions(opts)
+	s, err := newStructuredSchema[T](options)
+	if err != nil {
+		var zero T
+		return zero, nil, err
+	}
+
+	// The original input is only replaced once there is feedback to add.
+	var conversation []ResponseInputItem
+
+	return runStructured[T](s, options, structuredTurn[Response]{
+		send: func(mode StructuredMode) (*Response, error) {
+			req := request
+			if conversation != nil {
+				mustBuildUnion(req.Input.FromResponseInput1(conversation))
+			}
+			if mode == StructuredModeJSONObject {
+				instructions := s.instructions()
+				if request.Instructions != nil && *request.Instructions != "" {
+					instructions = *request.Instructions + "\n\n" + instructions
+				}
+				req.Instructions = &instructions
+				req.Text = responseTextFormat(ResponseTextConfigFormatTypeJSONObject, nil)
+			} else {
+				req.Text = responseTextFormat(ResponseTextConfigFormatTypeJSONSchema, s)
+			}
+			return client.CreateResponse(ctx, provider, req)
+		},
+		content: func(response *Response) (string, error) {
+			if response.Error != nil {
+				return "", fmt.Errorf("response failed: %s", response.Error.Message)
+			}
+			var text strings.Builder
+			for _, item := range response.Output {
+				message, err := item.AsResponseOutputMessage()
+				if err != nil || message.Type != ResponseOutputMessageTypeMessage {
+					continue
+				}
+				for _, part := range message.Content {
+					v, err := part.Value()
+					if err != nil {
+						return "", err
+					}
+					switch part := v.(type) {
+					case ResponseOutputText:
+						text.WriteString(part.Text)
+					case ResponseOutputRefusal:
+						return "", fmt.Errorf("model refused: %s", part.Refusal)
+					}
+				}
+			}
+			return text.String(), nil
+		},
+		feedback: func(_ *Response, content string, err error) error {
+			if conversation == nil {
+				items, inputErr := responseInputItems(request.Input)
+				if inputErr != nil {
+					return fmt.Errorf("failed to read request input: %w", inputErr)
+				}
+				conversation = items
+			}
+			conversation = append(conversation,
+				responseInputMessage(ResponseRoleAssistant, content),
+				responseInputMessage(ResponseRoleUser, structuredFeedback(err)),
+			)
+			return nil
+		},
+	})
+}
+
+// structuredTurn adapts one API to runStructured.
+type structuredTurn[R any] struct {
+	// send makes one request in the given mode.
+	send func(mode StructuredMode) (*R, error)
+	// content extracts the JSON text of a reply. An error ends the loop
+	// without retrying, e.g. for a refusal.
+	content func(response *R) (string, error)
+	// feedback records the failed reply and the validation error for the
+	// next request. An error ends the loop.
+	feedback func(response *R, content string, err error) error
+}
+
+func runStructured[T, R any](s *structuredSchema, options StructuredOptions, turn structuredTurn[R]) (T, *R, error) {
+	var zero T
+
+	mode := options.Mode
+	fellBack := false
+	attempts := 0
+	for {
+		sendMode := mode
+		if sendMode == StructuredModeAuto {
+			sendMode = StructuredModeSchema
+		}
+
+		response, err := turn.send(sendMode)
+		if err != nil {
+			if mode == StructuredModeAuto && !fellBack && isResponseFormatError(err) {
+				mode = StructuredModeJSONObject
+				fellBack = true
+				continue
+			}
+			return zero, response, err
+		}
+		attempts++
+
+		content, err := turn.content(response)
+		if err != nil {
+			return zero, response, err
+		}
+
+		value, err := decodeStructured[T](s, content)
+		if err == nil {
+			return value, response, nil
+		}
+		if attempts > options.MaxRetries {
+			return zero, response, &StructuredOutputError{Content: content, Attempts: attempts, Err: err}
+		}
+		if err := turn.feedback(response, content, err); err != nil {
+			return zero, response, err
+		}
+	}
+}
+
+// structuredSchema is the schema derived for a structured output request.
+type structuredSchema struct {
+	name        string
+	description string
+	schema      map[string]any
+	strict      bool
+	// wrapped is set when T is not an object: providers require an object
+	// at the root, so the value travels as {"value": ...}.
+	wrapped bool
+}
+
+func newStructuredSchema[T any](options StructuredOptions) (*structuredSchema, error) {
+	t := reflect.TypeFor[T]()
+	schema, err := JSONSchemaForType(t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive JSON schema for %s: %w", t, err)
+	}
+
+	s := &structuredSchema{
+		name:        options.Name,
+		description: options.Description,
+		schema:      schema,
+		strict:      options.Strict == nil || *options.Strict,
+	}
+	if s.name == "" {
+		s.name = schemaName(t)
+	}
+	if schema["type"] != "object" {
+		defs := schema["$defs"]
+		delete(schema, "$defs")
+		s.schema = map[string]any{
+			"type":                 "object",
+			"properties":           map[string]any{"value": schema},
+			"required":             []string{"value"},
+			"additionalProperties": false,
+		}
+		if defs != nil {
+			s.schema["$defs"] = defs
+		}
+		s.wrapped = true
+	}
+	return s, nil
+}
+
+func (s *structuredSchema) chatResponseFormat() *CreateChatCompletionRequest_ResponseFormat {
+	format := ResponseFormatJSONSchema{Type: JSONSchema}
+	format.JSONSchema.Name = s.name
+	format.JSONSchema.Strict = &s.strict
+	schema := ResponseFormatJSONSchemaSchema(s.schema)
+	format.JSONSchema.Schema = &schema
+	if s.description != "" {
+		format.JSONSchema.Description = &s.description
+	}
+
+	var union CreateChatCompletionRequest_ResponseFormat
+	mustBuildUnion(union.FromResponseFormatJSONSchema(format))
+	return &union
+}
+
+// instructions describes the schema in the prompt, for JSON mode.
+func (s *structuredSchema) instructions() string {
+	data, _ := json.Marshal(s.schema)
+	var b strings.Builder
+	if s.description != "" {
+		b.WriteString(s.description)
+		b.WriteString("\n\n")
+	}
+	b.WriteString("Respond with a single JSON object that conforms to this JSON Schema, without any other text or Markdown:\n")
+	b.Write(data)
+	return b.String()
+}
+
+func decodeStructured[T any](s *structuredSchema, content string) (T, error) {
+	var zero T
+	data := []byte(trimCodeFence(content))
+
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return zero, err
+	}
+	if err := ValidateJSONSchema(s.schema, value); err != nil {
+		return zero, err
+	}
+
+	if s.wrapped {
+		var wrapper struct {
+			Value T `json:"value"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return zero, err
+		}
+		return wrapper.Value, nil
+	}
+
+	var result T
+	if err := json.Unmarshal(data, &result); err != nil {
+		return zero, err
+	}
+	return result, nil
+}
+
+// structuredFeedback tells the model what was wrong with its last reply.
+func structuredFeedback(err error) string {
+	var validationErr *SchemaValidationError
+	if !errors.As(err, &validationErr) {
+		return fmt.Sprintf("Your previous reply was not valid JSON for the requested format (%v). Reply again with only the corrected JSON.", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("Your previous reply did not match the required JSON Schema:\n")
+	for _, v := range validationErr.Violations {
+		fmt.Fprintf(&b, "- %s: %s\n", v.Path, v.Message)
+	}
+	b.WriteString("Reply again with only the corrected JSON.")
+	return b.String()
+}
+
+// isResponseFormatError reports whether a request error is the provider
+// rejecting the structured output format, so Auto mode can fall back to
+// JSON mode. Only client errors count; a server error that happens to
+// mention the format is not a rejection of it.
+func isResponseFormatError(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode < http.StatusBadRequest || apiErr.StatusCode >= http.StatusInternalServerError {
+		return false
+	}
+	msg := strings.ToLower(apiErr.Message + " " + apiErr.Type)
+	return strings.Contains(msg, "response_format") ||
+		strings.Contains(msg, "json_schema") ||
+		strings.Contains(msg, "text.format")
+}
+
+func structuredOptions(opts []StructuredOptions) StructuredOptions {
+	if len(opts) == 0 {
+		return StructuredOptions{}
+	}
+	return opts[0]
+}
+
+// schemaName derives a schema name from a Go type, restricted to the
+// characters providers accept.
+func schemaName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var b strings.Builder
+	for _, r := range t.Name() {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	name := b.String()
+	if name == "" {
+		return "response"
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// withSystemInstructions adds instructions to the leading system message,
+// or prepends one.
+func withSystemInstructions(messages []Message, instructions string) []Message {
+	if len(messages) > 0 && messages[0].Role == System {
+		if text, err := messages[0].Content.AsMessageContent0(); err == nil {
+			result := append([]Message(nil), messages...)
+			result[0].Content = NewMessageContent(text + "\n\n" + instructions)
+			return result
+		}
+	}
+	return append([]Message{{Role: System, Content: NewMessageContent(instructions)}}, messages...)
+}
+
+// withSystemPrompt appends instructions to a Messages API system prompt,
+// keeping its blocks (and their cache_control) intact.
+func withSystemPrompt(system *CreateMessagesRequest_System, instructions string) *CreateMessagesRequest_System {
+	if system == nil {
+		return SystemText(instructions)
+	}
+	v, err := system.Value()
+	if err != nil {
+		return SystemText(instructions)
+	}
+	switch s := v.(type) {
+	case string:
+		return SystemText(s + "\n\n" + instructions)
+	case []MessagesTextBlock:
+		blocks := append([]MessagesTextBlock(nil), s...)
+		return SystemBlocks(append(blocks, MessagesTextBlock{Text: instructions})...)
+	}
+	return SystemText(instructions)
+}
+
+// messageText returns the text of a chat message's content.
+func messageText(content MessageContent) (string, error) {
+	v, err := content.Value()
+	if err != nil {
+		return "", err
+	}
+	switch c := v.(type) {
+	case string:
+		return c, nil
+	case []ContentPart:
+		var text strings.Builder
+		for _, part := range c {
+			if p, err := part.AsTextContentPart(); err == nil && p.Type == TextContentPartTypeText {
+				text.WriteString(p.Text)
+			}
+		}
+		return text.String(), nil
+	}
+	return "", nil
+}
+
+func structuredToolUse(response *MessagesResponse, name string) (MessagesToolUseBlock, bool) {
+	for _, block := range response.Content {
+		toolUse, err := block.AsMessagesToolUseBlock()
+		if err == nil && toolUse.Type == MessagesToolUseBlockTypeToolUse && toolUse.Name == name {
+			return toolUse, true
+		}
+	}
+	return MessagesToolUseBlock{}, false
+}
+
+func responseTextFormat(formatType ResponseTextConfigFormatType, s *structuredSchema) *ResponseTextConfig {
+	text := &ResponseTextConfig{}
+	text.Format = &struct {
+		Name   *string                      `json:"name,omitempty"`
+		Schema *FunctionParameters          `json:"schema,omitempty"`
+		Strict *bool                        `json:"strict,omitempty"`
+		Type   ResponseTextConfigFormatType `json:"type"`
+	}{Type: formatType}
+	if s != nil {
+		schema := FunctionParameters(s.schema)
+		text.Format.Name = &s.name
+		text.Format.Schema = &schema
+		text.Format.Strict = &s.strict
+	}
+	return text
+}
+
+// responseInputItems returns the input of a Responses API request as a list
+// of items.
+func responseInputItems(input ResponseInput) ([]ResponseInputItem, error) {
+	v, err := input.Value()
+	if err != nil {
+		return nil, err
+	}
+	switch in := v.(type) {
+	case string:
+		return []ResponseInputItem{responseInputMessage(ResponseRoleUser, in)}, nil
+	case []ResponseInputItem:
+		return append([]ResponseInputItem(nil), in...), nil
+	}
+	return []ResponseInputItem{}, nil
+}
+
+func responseInputMessage(role ResponseRole, text string) ResponseInputItem {
+	var content ResponseInputMessageContent
+	mustBuildUnion(content.FromResponseInputMessageContent0(text))
+	return ResponseInputItem{Role: role, Content: content}
+}
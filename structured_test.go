@@ -0,0 +1,386 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+type structuredWeather struct {
+	City    string  `json:"city"`
+	Celsius float64 `json:"celsius"`
+}
+
+// structuredServer answers successive requests to path with replies,
+// recording each decoded request body.
+func structuredServer(t *testing.T, path string, replies ...func(w http.ResponseWriter)) (*httptest.Server, *[]map[string]any) {
+	t.Helper()
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, path, r.URL.Path)
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, body)
+
+		if !assert.LessOrEqual(t, len(requests), len(replies), "unexpected extra request") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		replies[len(requests)-1](w)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func chatReply(content string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		data, _ := json.Marshal(content)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{
+			"id": "chatcmpl-1",
+			"object": "chat.completion",
+			"created": 1700000000,
+			"model": "gpt-4o",
+			"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": %s}}]
+		}`, data)
+	}
+}
+
+func errorReply(status int, message string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+	}
+}
+
+func TestGenerateStructured(t *testing.T) {
+	server, requests := structuredServer(t, "/v1/chat/completions",
+		chatReply("```json\n{\"city\": \"Paris\", \"celsius\": 21.5}\n```"),
+	)
+	client := NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+
+	weather, response, err := GenerateStructured[structuredWeather](context.Background(), client, Openai, "gpt-4o",
+		[]Message{{Role: User, Content: NewMessageContent("Weather in Paris?")}})
+	require.NoError(t, err)
+	assert.Equal(t, structuredWeather{City: "Paris", Celsius: 21.5}, weather)
+	assert.Equal(t, "chatcmpl-1", response.ID)
+
+	require.Len(t, *requests, 1)
+	format, err := json.Marshal((*requests)[0]["response_format"])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "json_schema",
+		"json_schema": {
+			"name": "structuredWeather",
+			"strict": true,
+			"schema": {
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["city", "celsius"],
+				"properties": {"city": {"type": "string"}, "celsius": {"type": "number"}}
+			}
+		}
+	}`, string(format))
+}
+
+func TestGenerateStructured_RetriesWithFeedback(t *testing.T) {
+	server, requests := structuredServer(t, "/v1/chat/completions",
+		chatReply(`{"city": "Paris"}`),
+		chatReply(`{"city": "Paris", "celsius": 21}`),
+	)
+	client := NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+
+	weather, _, err := GenerateStructured[structuredWeather](context.Background(), client, Openai, "gpt-4o",
+		[]Message{{Role: User, Content: NewMessageContent("Weather in Paris?")}},
+		StructuredOptions{MaxRetries: 1})
+	require.NoError(t, err)
+	assert.Equal(t, structuredWeather{City: "Paris", Celsius: 21}, weather)
+
+	require.Len(t, *requests, 2)
+	messages := (*requests)[1]["messages"].([]any)
+	require.Len(t, messages, 3)
+	assert.Equal(t, map[string]any{"role": "assistant", "content": `{"city": "Paris"}`}, messages[1])
+	feedback := messages[2].(map[string]any)
+	assert.Equal(t, "user", feedback["role"])
+	assert.Contains(t, feedback["content"], "- $.celsius: is required")
+}
+
+func TestGenerateStructured_RetriesExhausted(t *testing.T) {
+	server, requests := structuredServer(t, "/v1/chat/completions",
+		chatReply(`not json`),
+		chatReply(`{"city": 1, "celsius": 2}`),
+	)
+	client := NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+
+	_, response, err := GenerateStructured[structuredWeather](context.Background(), client, Openai, "gpt-4o",
+		[]Message{{Role: User, Content: NewMessageContent("Weather?")}},
+		StructuredOptions{MaxRetries: 1})
+
+	var structuredErr *StructuredOutputError
+	require.ErrorAs(t, err, &structuredErr)
+	assert.Equal(t, 2, structuredErr.Attempts)
+	assert.Equal(t, `{"city": 1, "celsius": 2}`, structuredErr.Content)
+	var validationErr *SchemaValidationError
+	require.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, []SchemaViolation{{Path: "$.city", Message: "expected string, got integer"}}, validationErr.Violations)
+	assert.NotNil(t, response)
+
+	messages := (*requests)[1]["messages"].([]any)
+	assert.Contains(t, messages[2].(map[string]any)["content"], "not valid JSON")
+}
+
+func TestGenerateStructured_FallsBackToJSONObject(t *testing.T) {
+	server, requests := structuredServer(t, "/v1/chat/completions",
+		errorReply(http.StatusBadRequest, "This response_format type is unavailable now"),
+		chatReply(`{"city": "Paris", "celsius": 21}`),
+	)
+	client := NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+
+	weather, _, err := GenerateStructured[structuredWeather](context.Background(), client, Deepseek, "deepseek-chat",
+		[]Message{
+			{Role: System, Content: NewMessageContent("You are a weather bot.")},
+			{Role: User, Content: NewMessageContent("Weather in Paris?")},
+		})
+	require.NoError(t, err)
+	assert.Equal(t, "Paris", weather.City)
+
+	require.Len(t, *requests, 2)
+	assert.Equal(t, map[string]any{"type": "json_object"}, (*requests)[1]["response_format"])
+	messages := (*requests)[1]["messages"].([]any)
+	require.Len(t, messages, 2)
+	system := messages[0].(map[string]any)["content"].(string)
+	assert.Contains(t, system, "You are a weather bot.\n\nRespond with a single JSON object")
+	assert.Contains(t, system, `"required":["city","celsius"]`)
+}
+
+func TestGenerateStructured_NoFallbackForOtherErrors(t *testing.T) {
+	server, requests := structuredServer(t, "/v1/chat/completions",
+		errorReply(http.StatusBadRequest, "model not found"),
+	)
+	client := NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+
+	_, _, err := GenerateStructured[structuredWeather](context.Background(), client, Openai, "nope", nil)
+	assert.ErrorContains(t, err, "model not found")
+	assert.Len(t, *requests, 1)
+}
+
+func TestGenerateStructured_NoFallbackForServerErrors(t *testing.T) {
+	server, requests := structuredServer(t, "/v1/chat/completions",
+		errorReply(http.StatusInternalServerError, "upstream failed while applying response_format"),
+	)
+	client := NewClient(&ClientOptions{BaseURL: server.URL + "/v1", RetryConfig: &RetryConfig{Enabled: false}})
+
+	_, _, err := GenerateStructured[structuredWeather](context.Background(), client, Openai, "gpt-4o", nil)
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	assert.Len(t, *requests, 1)
+}
+
+func TestGenerateStructured_NonObject(t *testing.T) {
+	server, requests := structuredServer(t, "/v1/chat/completions",
+		chatReply(`{"value": ["a", "b"]}`),
+	)
+	client := NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+
+	tags, _, err := GenerateStructured[[]string](context.Background(), client, Openai, "gpt-4o", nil,
+		StructuredOptions{Name: "tags"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, tags)
+
+	format := (*requests)[0]["response_format"].(map[string]any)["json_schema"].(map[string]any)
+	assert.Equal(t, "tags", format["name"])
+	assert.Equal(t, []any{"value"}, format["schema"].(map[string]any)["required"])
+}
+
+func TestGenerateStructured_KeepsClientOptions(t *testing.T) {
+	wrappers := map[string]func(client Client) Client{
+		"client": func(client Client) Client { return client },
+		// Wrappers only pass the call on, so options must not be set on them.
+		"wrapped": func(client Client) Client {
+			return NewFallbackClient(client, FallbackOptions{Targets: []FallbackTarget{{Provider: Openai}}})
+		},
+	}
+	for name, wrap := range wrappers {
+		t.Run(name, func(t *testing.T) {
+			server, requests := structuredServer(t, "/v1/chat/completions",
+				chatReply(`{"city": "Paris", "celsius": 21}`),
+				chatReply(`plain text`),
+			)
+			temperature := float32(0.2)
+			client := wrap(NewClient(&ClientOptions{BaseURL: server.URL + "/v1"}).
+				WithOptions(&CreateChatCompletionRequest{Temperature: &temperature}))
+
+			_, _, err := GenerateStructured[structuredWeather](context.Background(), client, Openai, "gpt-4o", nil)
+			require.NoError(t, err)
+			assert.InDelta(t, 0.2, (*requests)[0]["temperature"], 0.001)
+			assert.NotNil(t, (*requests)[0]["response_format"])
+
+			_, err = client.GenerateContent(context.Background(), Openai, "gpt-4o", nil)
+			require.NoError(t, err)
+			assert.InDelta(t, 0.2, (*requests)[1]["temperature"], 0.001)
+			assert.Nil(t, (*requests)[1]["response_format"], "the response format must not leak into later requests")
+		})
+	}
+}
+
+func messagesReply(content string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{
+			"id": "msg_1",
+			"type": "message",
+			"role": "assistant",
+			"model": "claude-sonnet-5",
+			"content": %s,
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 10, "output_tokens": 7}
+		}`, content)
+	}
+}
+
+func TestCreateMessageStructured(t *testing.T) {
+	server, requests := structuredServer(t, "/v1/messages",
+		messagesReply(`[{"type": "tool_use", "id": "toolu_1", "name": "weather", "input": {"city": "Paris"}}]`),
+		messagesReply(`[{"type": "tool_use", "id": "toolu_2", "name": "weather", "input": {"city": "Paris", "celsius": 21}}]`),
+	)
+	client := NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+
+	var content MessagesMessage_Content
+	require.NoError(t, content.FromMessagesMessageContent0("Weather in Paris?"))
+
+	weather, response, err := CreateMessageStructured[structuredWeather](context.Background(), client, Anthropic, CreateMessagesRequest{
+		Model:     "claude-sonnet-5",
+		MaxTokens: 1024,
+		Messages:  []MessagesMessage{{Role: MessagesMessageRoleUser, Content: content}},
+	}, StructuredOptions{Name: "weather", Description: "Report the weather", MaxRetries: 1})
+	require.NoError(t, err)
+	assert.Equal(t, structuredWeather{City: "Paris", Celsius: 21}, weather)
+	assert.Equal(t, "msg_1", response.ID)
+
+	first := (*requests)[0]
+	assert.Equal(t, map[string]any{"type": "tool", "name": "weather"}, first["tool_choice"])
+	tools := first["tools"].([]any)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "weather", tools[0].(map[string]any)["name"])
+	assert.Equal(t, "Report the weather", tools[0].(map[string]any)["description"])
+
+	messages := (*requests)[1]["messages"].([]any)
+	require.Len(t, messages, 3)
+	assert.Equal(t, "assistant", messages[1].(map[string]any)["role"])
+	result := messages[2].(map[string]any)["content"].([]any)[0].(map[string]any)
+	assert.Equal(t, "tool_result", result["type"])
+	assert.Equal(t, "toolu_1", result["tool_use_id"])
+	assert.Equal(t, true, result["is_error"])
+	assert.Contains(t, result["content"], "$.celsius: is required")
+}
+
+func TestCreateMessageStructured_JSONObjectMode(t *testing.T) {
+	server, requests := structuredServer(t, "/v1/messages",
+		messagesReply(`[{"type": "text", "text": "{\"city\": \"Paris\", \"celsius\": 21}"}]`),
+	)
+	client := NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+
+	weather, _, err := CreateMessageStructured[structuredWeather](context.Background(), client, Anthropic, CreateMessagesRequest{
+		Model:     "claude-sonnet-5",
+		MaxTokens: 1024,
+		System:    SystemBlocks(MessagesTextBlock{Text: "You are a weather bot."}),
+	}, StructuredOptions{Mode: StructuredModeJSONObject})
+	require.NoError(t, err)
+	assert.Equal(t, "Paris", weather.City)
+
+	request := (*requests)[0]
+	assert.Nil(t, request["tools"])
+	system := request["system"].([]any)
+	require.Len(t, system, 2)
+	assert.Equal(t, "You are a weather bot.", system[0].(map[string]any)["text"])
+	assert.Contains(t, system[1].(map[string]any)["text"], "Respond with a single JSON object")
+}
+
+func responsesReply(content string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{
+			"id": "resp_1",
+			"object": "response",
+			"created_at": 1700000000,
+			"model": "gpt-5",
+			"status": "completed",
+			"output": [{"type": "message", "id": "msg_1", "role": "assistant", "content": [%s]}]
+		}`, content)
+	}
+}
+
+func TestCreateResponseStructured(t *testing.T) {
+	server, requests := structuredServer(t, "/v1/responses",
+		responsesReply(`{"type": "output_text", "text": "{\"city\": \"Paris\"}"}`),
+		responsesReply(`{"type": "output_text", "text": "{\"city\": \"Paris\", \"celsius\": 21}"}`),
+	)
+	client := NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+
+	var input ResponseInput
+	require.NoError(t, input.FromResponseInput0("Weather in Paris?"))
+
+	weather, response, err := CreateResponseStructured[structuredWeather](context.Background(), client, Openai,
+		CreateResponseRequest{Model: "gpt-5", Input: input}, StructuredOptions{MaxRetries: 1})
+	require.NoError(t, err)
+	assert.Equal(t, structuredWeather{City: "Paris", Celsius: 21}, weather)
+	assert.Equal(t, "resp_1", response.ID)
+
+	format := (*requests)[0]["text"].(map[string]any)["format"].(map[string]any)
+	assert.Equal(t, "json_schema", format["type"])
+	assert.Equal(t, "structuredWeather", format["name"])
+	assert.Equal(t, true, format["strict"])
+	assert.Equal(t, "Weather in Paris?", (*requests)[0]["input"])
+
+	items := (*requests)[1]["input"].([]any)
+	require.Len(t, items, 3)
+	assert.Equal(t, map[string]any{"role": "user", "content": "Weather in Paris?"}, items[0])
+	assert.Equal(t, map[string]any{"role": "assistant", "content": `{"city": "Paris"}`}, items[1])
+	assert.Contains(t, items[2].(map[string]any)["content"], "$.celsius: is required")
+}
+
+func TestCreateResponseStructured_InvalidInput(t *testing.T) {
+	server, requests := structuredServer(t, "/v1/responses",
+		responsesReply(`{"type": "output_text", "text": "{\"city\": \"Paris\"}"}`),
+	)
+	client := NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+
+	// The input can't be resent with the feedback, so the call fails
+	// instead of retrying without it.
+	var input ResponseInput
+	require.NoError(t, input.UnmarshalJSON([]byte(`{"role": "user"}`)))
+	_, _, err := CreateResponseStructured[structuredWeather](context.Background(), client, Openai,
+		CreateResponseRequest{Model: "gpt-5", Input: input}, StructuredOptions{MaxRetries: 1})
+	assert.ErrorContains(t, err, "failed to read request input")
+	assert.Len(t, *requests, 1)
+}
+
+func TestCreateResponseStructured_FallbackAndRefusal(t *testing.T) {
+	server, requests := structuredServer(t, "/v1/responses",
+		errorReply(http.StatusBadRequest, "text.format json_schema is not supported"),
+		responsesReply(`{"type": "refusal", "refusal": "I can't help with that."}`),
+	)
+	client := NewClient(&ClientOptions{BaseURL: server.URL + "/v1"})
+
+	var input ResponseInput
+	require.NoError(t, input.FromResponseInput0("Weather?"))
+	instructions := "Be terse."
+
+	_, _, err := CreateResponseStructured[structuredWeather](context.Background(), client, Openai,
+		CreateResponseRequest{Model: "gpt-5", Input: input, Instructions: &instructions}, StructuredOptions{MaxRetries: 3})
+	assert.EqualError(t, err, "model refused: I can't help with that.")
+
+	require.Len(t, *requests, 2, "refusals are not retried")
+	second := (*requests)[1]
+	assert.Equal(t, map[string]any{"type": "json_object"}, second["text"].(map[string]any)["format"])
+	assert.Contains(t, second["instructions"], "Be terse.\n\nRespond with a single JSON object")
+}
@@ -0,0 +1,474 @@
+package sdk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"  // register decoders for image size heuristics
+	_ "image/jpeg" // register decoders for image size heuristics
+	_ "image/png"  // register decoders for image size heuristics
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Tokenizer counts the tokens of a piece of text.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// HeuristicTokenizer estimates tokens from the text length. It needs no
+// vocabulary and is accurate to within a few percent for English prose.
+type HeuristicTokenizer struct {
+	// CharsPerToken defaults to 4.
+	CharsPerToken float64
+}
+
+// CountTokens implements Tokenizer.
+func (t HeuristicTokenizer) CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	perToken := t.CharsPerToken
+	if perToken <= 0 {
+		perToken = 4
+	}
+	return int(math.Ceil(float64(len([]rune(text))) / perToken))
+}
+
+// DefaultBPEPattern splits text into pieces before byte pair merging. It
+// follows the cl100k_base pattern, minus the lookahead Go's regexp doesn't
+// support.
+const DefaultBPEPattern = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`
+
+// BPEOptions configures a BPETokenizer.
+type BPEOptions struct {
+	// Pattern splits text before merging. Defaults to DefaultBPEPattern.
+	Pattern string
+}
+
+// BPETokenizer is a byte pair encoding tokenizer using a tiktoken-style rank
+// file, e.g. cl100k_base.tiktoken or o200k_base.tiktoken.
+type BPETokenizer struct {
+	ranks   map[string]int
+	pattern *regexp.Regexp
+}
+
+// LoadBPEFile reads a tiktoken-style rank file from disk.
+//
+// Example:
+//
+//	tokenizer, err := sdk.LoadBPEFile("o200k_base.tiktoken", nil)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	counter := sdk.NewTokenCounter(tokenizer)
+func LoadBPEFile(path string, options *BPEOptions) (*BPETokenizer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	return NewBPETokenizer(file, options)
+}
+
+// NewBPETokenizer reads a tiktoken-style rank file: one base64-encoded token
+// and its rank per line.
+func NewBPETokenizer(r io.Reader, options *BPEOptions) (*BPETokenizer, error) {
+	pattern := DefaultBPEPattern
+	if options != nil && options.Pattern != "" {
+		pattern = options.Pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BPE pattern: %w", err)
+	}
+
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		token, rank, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("invalid BPE rank file: line %d: expected token and rank", line)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BPE rank file: line %d: %w", line, err)
+		}
+		n, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BPE rank file: line %d: %w", line, err)
+		}
+		ranks[string(decoded)] = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read BPE rank file: %w", err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("invalid BPE rank file: no tokens")
+	}
+	return &BPETokenizer{ranks: ranks, pattern: re}, nil
+}
+
+// Encode returns the token ranks of text. Bytes missing from the vocabulary
+// are encoded as -1.
+func (t *BPETokenizer) Encode(text string) []int {
+	var tokens []int
+	for _, piece := range t.pattern.FindAllString(text, -1) {
+		if rank, ok := t.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+			continue
+		}
+		for _, part := range t.merge(piece) {
+			rank, ok := t.ranks[part]
+			if !ok {
+				rank = -1
+			}
+			tokens = append(tokens, rank)
+		}
+	}
+	return tokens
+}
+
+// CountTokens implements Tokenizer.
+func (t *BPETokenizer) CountTokens(text string) int {
+	return len(t.Encode(text))
+}
+
+// merge splits piece into bytes and repeatedly joins the adjacent pair with
+// the lowest rank.
+func (t *BPETokenizer) merge(piece string) []string {
+	parts := make([]string, len(piece))
+	for i := range piece {
+		parts[i] = piece[i : i+1]
+	}
+	for len(parts) > 1 {
+		best, at := -1, -1
+		for i := 0; i < len(parts)-1; i++ {
+			if rank, ok := t.ranks[parts[i]+parts[i+1]]; ok && (at < 0 || rank < best) {
+				best, at = rank, i
+			}
+		}
+		if at < 0 {
+			break
+		}
+		parts[at] += parts[at+1]
+		parts = slices.Delete(parts, at+1, at+2)
+	}
+	return parts
+}
+
+// Per-message overheads, following OpenAI's published counting recipe.
+const (
+	tokensPerMessage = 4
+	tokensPerReply   = 3
+	tokensPerTool    = 8
+)
+
+// TokenCounter estimates the prompt tokens of requests offline, for
+// budgeting and truncation. Counts can be calibrated against the usage
+// reported by the gateway. It is safe for concurrent use.
+type TokenCounter struct {
+	tokenizer Tokenizer
+
+	mu        sync.Mutex
+	estimated int64
+	actual    int64
+}
+
+// NewTokenCounter creates a TokenCounter. A nil tokenizer uses
+// HeuristicTokenizer.
+func NewTokenCounter(tokenizer Tokenizer) *TokenCounter {
+	if tokenizer == nil {
+		tokenizer = HeuristicTokenizer{}
+	}
+	return &TokenCounter{tokenizer: tokenizer}
+}
+
+// CountText counts the tokens of text.
+func (c *TokenCounter) CountText(text string) int {
+	return c.scale(c.tokenizer.CountTokens(text))
+}
+
+// CountMessages counts the prompt tokens of chat messages, including images,
+// tool calls and reasoning.
+func (c *TokenCounter) CountMessages(messages []Message) int {
+	return c.scale(c.countMessages(messages))
+}
+
+// CountTools counts the tokens of chat tool definitions.
+func (c *TokenCounter) CountTools(tools []ChatCompletionTool) int {
+	return c.scale(c.countTools(tools))
+}
+
+// CountRequest counts the prompt tokens of a chat completion request: its
+// messages and tools.
+func (c *TokenCounter) CountRequest(request CreateChatCompletionRequest) int {
+	return c.scale(c.countRequest(request))
+}
+
+// CountSystem counts the tokens of a Messages API system prompt.
+func (c *TokenCounter) CountSystem(system CreateMessagesRequest_System) int {
+	text, _ := system.Text()
+	return c.scale(c.tokenizer.CountTokens(text))
+}
+
+// CountMessagesTools counts the tokens of Messages API tool definitions.
+func (c *TokenCounter) CountMessagesTools(tools []MessagesTool) int {
+	return c.scale(c.countMessagesTools(tools))
+}
+
+// CountMessagesRequest counts the input tokens of a Messages API request: its
+// system prompt, messages and tools.
+func (c *TokenCounter) CountMessagesRequest(request CreateMessagesRequest) int {
+	return c.scale(c.countMessagesRequest(request))
+}
+
+// Calibrate records the prompt tokens the gateway reported for request.
+// Later counts are scaled by the ratio of reported to estimated tokens seen
+// so far, which corrects for the tokenizer and provider formatting.
+func (c *TokenCounter) Calibrate(request CreateChatCompletionRequest, usage CompletionUsage) {
+	c.record(c.countRequest(request), usage.PromptTokens)
+}
+
+// CalibrateMessages is Calibrate for the Messages API. Cached input counts
+// towards the prompt.
+func (c *TokenCounter) CalibrateMessages(request CreateMessagesRequest, usage MessagesUsage) {
+	actual := usage.InputTokens
+	if usage.CacheReadInputTokens != nil {
+		actual += *usage.CacheReadInputTokens
+	}
+	if usage.CacheCreationInputTokens != nil {
+		actual += *usage.CacheCreationInputTokens
+	}
+	c.record(c.countMessagesRequest(request), actual)
+}
+
+// Factor returns the calibration factor applied to counts, 1 before any
+// calibration.
+func (c *TokenCounter) Factor() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.estimated == 0 || c.actual == 0 {
+		return 1
+	}
+	return float64(c.actual) / float64(c.estimated)
+}
+
+func (c *TokenCounter) record(estimated int, actual int64) {
+	if estimated <= 0 || actual <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.estimated += int64(estimated)
+	c.actual += actual
+}
+
+func (c *TokenCounter) scale(tokens int) int {
+	return int(math.Round(float64(tokens) * c.Factor()))
+}
+
+func (c *TokenCounter) countRequest(request CreateChatCompletionRequest) int {
+	tokens := c.countMessages(request.Messages)
+	if request.Tools != nil {
+		tokens += c.countTools(*request.Tools)
+	}
+	return tokens
+}
+
+func (c *TokenCounter) countMessages(messages []Message) int {
+	tokens := tokensPerReply
+	for _, message := range messages {
+		tokens += tokensPerMessage
+		if v, err := message.Content.Value(); err == nil {
+			switch content := v.(type) {
+			case string:
+				tokens += c.tokenizer.CountTokens(content)
+			case []ContentPart:
+				for _, part := range content {
+					pv, err := part.Value()
+					if err != nil {
+						continue
+					}
+					switch p := pv.(type) {
+					case TextContentPart:
+						tokens += c.tokenizer.CountTokens(p.Text)
+					case ImageContentPart:
+						tokens += chatImageTokens(p.ImageURL)
+					}
+				}
+			}
+		}
+		if message.ToolCalls != nil {
+			for _, call := range *message.ToolCalls {
+				tokens += tokensPerMessage + c.tokenizer.CountTokens(call.Function.Name) + c.tokenizer.CountTokens(call.Function.Arguments)
+			}
+		}
+		tokens += c.tokenizer.CountTokens(firstNonEmpty(message.ReasoningContent, message.Reasoning))
+	}
+	return tokens
+}
+
+func (c *TokenCounter) countTools(tools []ChatCompletionTool) int {
+	tokens := 0
+	for _, tool := range tools {
+		tokens += c.countTool(tool.Function.Name, tool.Function.Description, tool.Function.Parameters)
+	}
+	return tokens
+}
+
+func (c *TokenCounter) countMessagesTools(tools []MessagesTool) int {
+	tokens := 0
+	for _, tool := range tools {
+		tokens += c.countTool(tool.Name, tool.Description, &tool.InputSchema)
+	}
+	return tokens
+}
+
+func (c *TokenCounter) countTool(name string, description *string, parameters *FunctionParameters) int {
+	tokens := tokensPerTool + c.tokenizer.CountTokens(name)
+	if description != nil {
+		tokens += c.tokenizer.CountTokens(*description)
+	}
+	if parameters != nil {
+		if schema, err := json.Marshal(parameters); err == nil {
+			tokens += c.tokenizer.CountTokens(string(schema))
+		}
+	}
+	return tokens
+}
+
+func (c *TokenCounter) countMessagesRequest(request CreateMessagesRequest) int {
+	tokens := tokensPerReply
+	if request.System != nil {
+		text, _ := request.System.Text()
+		tokens += c.tokenizer.CountTokens(text)
+	}
+	for _, message := range request.Messages {
+		tokens += tokensPerMessage
+		v, err := message.Content.Value()
+		if err != nil {
+			continue
+		}
+		switch content := v.(type) {
+		case string:
+			tokens += c.tokenizer.CountTokens(content)
+		case []MessagesRequestContentBlock:
+			for _, block := range content {
+				tokens += c.countMessagesBlock(block)
+			}
+		}
+	}
+	if request.Tools != nil {
+		tokens += c.countMessagesTools(*request.Tools)
+	}
+	return tokens
+}
+
+func (c *TokenCounter) countMessagesBlock(block MessagesRequestContentBlock) int {
+	v, err := block.Value()
+	if err != nil {
+		return 0
+	}
+	switch b := v.(type) {
+	case MessagesTextBlock:
+		return c.tokenizer.CountTokens(b.Text)
+	case MessagesImageBlock:
+		return messagesImageTokens(b.Source)
+	case MessagesToolUseBlock:
+		input, _ := json.Marshal(b.Input)
+		return tokensPerMessage + c.tokenizer.CountTokens(b.Name) + c.tokenizer.CountTokens(string(input))
+	case MessagesToolResultBlock:
+		tokens := tokensPerMessage
+		if b.Content == nil {
+			return tokens
+		}
+		v, _ := b.Content.Value()
+		switch content := v.(type) {
+		case string:
+			tokens += c.tokenizer.CountTokens(content)
+		case []MessagesTextBlock:
+			for _, text := range content {
+				tokens += c.tokenizer.CountTokens(text.Text)
+			}
+		}
+		return tokens
+	case MessagesThinkingBlock:
+		return c.tokenizer.CountTokens(b.Thinking)
+	}
+	return 0
+}
+
+// chatImageTokens follows OpenAI's vision pricing: 85 tokens at low detail,
+// otherwise 85 plus 170 per 512px tile once the image is scaled to fit
+// 2048x2048 with its short side at most 768px. The size is read from data
+// URLs; remote images are assumed to take four tiles.
+func chatImageTokens(url ImageURL) int {
+	if url.Detail != nil && *url.Detail == ImageURLDetailLow {
+		return 85
+	}
+	width, height, ok := dataURLImageSize(url.URL)
+	if !ok {
+		return 85 + 4*170
+	}
+	w, h := float64(width), float64(height)
+	if scale := 2048 / math.Max(w, h); scale < 1 {
+		w, h = w*scale, h*scale
+	}
+	if scale := 768 / math.Min(w, h); scale < 1 {
+		w, h = w*scale, h*scale
+	}
+	tiles := int(math.Ceil(w/512) * math.Ceil(h/512))
+	return 85 + 170*tiles
+}
+
+// messagesImageTokens follows Anthropic's estimate of width*height/750 once
+// the long edge is scaled to at most 1568px. Images of unknown size are
+// assumed to be at that limit.
+func messagesImageTokens(source MessagesImageSource) int {
+	if source.Data == nil {
+		return 1600
+	}
+	width, height, ok := base64ImageSize(*source.Data)
+	if !ok {
+		return 1600
+	}
+	w, h := float64(width), float64(height)
+	if scale := 1568 / math.Max(w, h); scale < 1 {
+		w, h = w*scale, h*scale
+	}
+	return int(math.Ceil(w * h / 750))
+}
+
+func dataURLImageSize(url string) (int, int, bool) {
+	header, data, ok := strings.Cut(url, ",")
+	if !ok || !strings.HasPrefix(header, "data:") || !strings.HasSuffix(header, ";base64") {
+		return 0, 0, false
+	}
+	return base64ImageSize(data)
+}
+
+func base64ImageSize(data string) (int, int, bool) {
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return 0, 0, false
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(decoded))
+	if err != nil || config.Width == 0 || config.Height == 0 {
+		return 0, 0, false
+	}
+	return config.Width, config.Height, true
+}
@@ -0,0 +1,181 @@
+package sdk
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// testRanks is a tiny tiktoken-style vocabulary.
+func testRanks() string {
+	var lines []string
+	for rank, token := range []string{"a", "b", "c", " ", "ab", "bc", "abc"} {
+		lines = append(lines, fmt.Sprintf("%s %d", base64.StdEncoding.EncodeToString([]byte(token)), rank))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func pngDataURL(t *testing.T, width, height int) string {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestHeuristicTokenizer(t *testing.T) {
+	assert.Equal(t, 0, HeuristicTokenizer{}.CountTokens(""))
+	assert.Equal(t, 3, HeuristicTokenizer{}.CountTokens("Hello, world"))
+	assert.Equal(t, 1, HeuristicTokenizer{}.CountTokens("héé"))
+	assert.Equal(t, 6, HeuristicTokenizer{CharsPerToken: 2}.CountTokens("Hello, world"))
+}
+
+func TestBPETokenizer(t *testing.T) {
+	tokenizer, err := NewBPETokenizer(strings.NewReader(testRanks()), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{6}, tokenizer.Encode("abc"))
+	// abcb merges ab first, then abc, leaving b.
+	assert.Equal(t, []int{6, 1}, tokenizer.Encode("abcb"))
+	assert.Equal(t, []int{4, 3, 6}, tokenizer.Encode("ab abc"))
+	assert.Equal(t, []int{0, -1}, tokenizer.Encode("az"))
+	assert.Equal(t, 3, tokenizer.CountTokens("ab abc"))
+}
+
+func TestLoadBPEFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.tiktoken")
+	require.NoError(t, os.WriteFile(path, []byte(testRanks()), 0o600))
+
+	tokenizer, err := LoadBPEFile(path, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, tokenizer.CountTokens("abcb"))
+
+	_, err = LoadBPEFile(filepath.Join(dir, "missing.tiktoken"), nil)
+	assert.Error(t, err)
+
+	_, err = NewBPETokenizer(strings.NewReader("YQ== zero\n"), nil)
+	assert.EqualError(t, err, `invalid BPE rank file: line 1: strconv.Atoi: parsing "zero": invalid syntax`)
+
+	_, err = NewBPETokenizer(strings.NewReader(testRanks()), &BPEOptions{Pattern: "("})
+	assert.ErrorContains(t, err, "invalid BPE pattern")
+}
+
+func TestTokenCounter_CountMessages(t *testing.T) {
+	counter := NewTokenCounter(nil)
+
+	assert.Equal(t, 3, counter.CountMessages(nil))
+	assert.Equal(t, 3+4+3, counter.CountMessages([]Message{{Role: User, Content: NewMessageContent("Hello, world")}}))
+
+	text, err := NewTextContentPart("What is this?")
+	require.NoError(t, err)
+	low, err := NewImageContentPart("https://example.com/cat.png", new(ImageURLDetailLow))
+	require.NoError(t, err)
+	remote, err := NewImageContentPart("https://example.com/cat.png", nil)
+	require.NoError(t, err)
+	small, err := NewImageContentPart(pngDataURL(t, 512, 512), nil)
+	require.NoError(t, err)
+	large, err := NewImageContentPart(pngDataURL(t, 4096, 1024), new(ImageURLDetailHigh))
+	require.NoError(t, err)
+
+	count := func(part ContentPart) int {
+		return counter.CountMessages([]Message{{Role: User, Content: NewMessageContent([]ContentPart{part})}}) - 3 - 4
+	}
+	assert.Equal(t, 4, count(text))
+	assert.Equal(t, 85, count(low))
+	assert.Equal(t, 85+4*170, count(remote))
+	assert.Equal(t, 85+170, count(small))
+	// 4096x1024 fits 2048x512: four tiles wide, one tall.
+	assert.Equal(t, 85+4*170, count(large))
+
+	toolCalls := []ChatCompletionMessageToolCall{{
+		ID:       "call_1",
+		Type:     Function,
+		Function: ChatCompletionMessageToolCallFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+	}}
+	assert.Equal(t, 3+4+4+3+4, counter.CountMessages([]Message{{Role: Assistant, Content: NewMessageContent(""), ToolCalls: &toolCalls}}))
+}
+
+func TestTokenCounter_CountRequest(t *testing.T) {
+	counter := NewTokenCounter(nil)
+	tools := []ChatCompletionTool{{Type: Function, Function: weatherTool()}}
+	request := CreateChatCompletionRequest{
+		Model:    "openai/gpt-4o",
+		Messages: []Message{{Role: User, Content: NewMessageContent("Weather in Paris?")}},
+		Tools:    &tools,
+	}
+
+	// get_weather, plus the compact JSON schema.
+	schema, err := json.Marshal(weatherTool().Parameters)
+	require.NoError(t, err)
+	toolTokens := 8 + 3 + (len(schema)+3)/4
+	assert.Equal(t, toolTokens, counter.CountTools(tools))
+	assert.Equal(t, counter.CountMessages(request.Messages)+toolTokens, counter.CountRequest(request))
+}
+
+func TestTokenCounter_CountMessagesRequest(t *testing.T) {
+	counter := NewTokenCounter(nil)
+	image := strings.TrimPrefix(pngDataURL(t, 750, 100), "data:image/png;base64,")
+
+	var request CreateMessagesRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"model": "claude-sonnet-4",
+		"max_tokens": 1024,
+		"system": [{"type": "text", "text": "Be brief."}],
+		"messages": [
+			{"role": "user", "content": [
+				{"type": "text", "text": "What is this?"},
+				{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "`+image+`"}}
+			]},
+			{"role": "assistant", "content": [{"type": "tool_use", "id": "toolu_1", "name": "lookup", "input": {"q": "cat"}}]},
+			{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "toolu_1", "content": "A cat."}]}
+		],
+		"tools": [{"name": "lookup", "input_schema": {"type": "object"}}]
+	}`), &request))
+
+	system := 3 // Be brief.
+	user := 4 + 4 + 100
+	assistant := 4 + 4 + 2 + 3 // {"q":"cat"}
+	result := 4 + 4 + 2
+	tools := 8 + 2 + 5 // {"type":"object"}
+	assert.Equal(t, system, counter.CountSystem(*request.System))
+	assert.Equal(t, tools, counter.CountMessagesTools(*request.Tools))
+	assert.Equal(t, 3+system+user+assistant+result+tools, counter.CountMessagesRequest(request))
+}
+
+func TestTokenCounter_Calibrate(t *testing.T) {
+	counter := NewTokenCounter(nil)
+	request := CreateChatCompletionRequest{
+		Model:    "openai/gpt-4o",
+		Messages: []Message{{Role: User, Content: NewMessageContent("Hello, world")}},
+	}
+	estimate := counter.CountRequest(request)
+	assert.Equal(t, 1.0, counter.Factor())
+
+	counter.Calibrate(request, CompletionUsage{PromptTokens: int64(estimate * 2)})
+	assert.Equal(t, 2.0, counter.Factor())
+	assert.Equal(t, estimate*2, counter.CountRequest(request))
+
+	// Reports are pooled, so a single outlier doesn't dominate.
+	counter.Calibrate(request, CompletionUsage{PromptTokens: int64(estimate)})
+	assert.Equal(t, 1.5, counter.Factor())
+
+	// Reports without usage are ignored.
+	counter.Calibrate(request, CompletionUsage{})
+	assert.Equal(t, 1.5, counter.Factor())
+
+	counter = NewTokenCounter(nil)
+	messages := CreateMessagesRequest{Model: "claude-sonnet-4", Messages: []MessagesMessage{}}
+	estimate = counter.CountMessagesRequest(messages)
+	counter.CalibrateMessages(messages, MessagesUsage{InputTokens: 1, CacheReadInputTokens: new(int64(estimate - 1))})
+	assert.Equal(t, 1.0, counter.Factor())
+}
@@ -0,0 +1,247 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ToolArgumentsOptions controls how tool call arguments are checked.
+type ToolArgumentsOptions struct {
+	// Repair attempts to fix common model mistakes - trailing commas,
+	// single-quoted strings, unclosed strings, braces and brackets, and
+	// Markdown code fences - before giving up on arguments that are not
+	// valid JSON.
+	Repair bool
+}
+
+// ToolArgumentsError reports tool call arguments that are not valid JSON,
+// that do not match the tool's declared parameters, or that name a tool
+// that was never declared.
+type ToolArgumentsError struct {
+	// ToolCallID is the ID of the offending tool call.
+	ToolCallID string
+	// Name is the name of the function the model tried to call.
+	Name string
+	// Err is the JSON syntax error or unknown-tool error, if any.
+	Err error
+	// Violations lists every schema violation, if the arguments parsed.
+	Violations []SchemaViolation
+}
+
+func (e *ToolArgumentsError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("invalid arguments for tool %q: %v", e.Name, e.Err)
+	}
+	return fmt.Sprintf("invalid arguments for tool %q: %v", e.Name, &SchemaValidationError{Violations: e.Violations})
+}
+
+func (e *ToolArgumentsError) Unwrap() error {
+	return e.Err
+}
+
+// ToolMessage renders the error as a tool-role message answering the tool
+// call. The content is a JSON object describing what was wrong, so the model
+// can correct its arguments on the next turn.
+func (e *ToolArgumentsError) ToolMessage() Message {
+	body := struct {
+		Error      string            `json:"error"`
+		Tool       string            `json:"tool"`
+		Message    string            `json:"message"`
+		Violations []SchemaViolation `json:"violations,omitempty"`
+	}{
+		Error:      "invalid_tool_arguments",
+		Tool:       e.Name,
+		Message:    e.Error(),
+		Violations: e.Violations,
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return toolMessage(e.ToolCallID, e.Error())
+	}
+	return toolMessage(e.ToolCallID, string(data))
+}
+
+// ValidateToolCall parses a tool call's arguments and checks them against
+// the parameters of the matching function in tools. It returns the decoded
+// arguments, or a *ToolArgumentsError whose ToolMessage can be sent back to
+// the model.
+//
+// Example:
+//
+//	for _, call := range *resp.Choices[0].Message.ToolCalls {
+//		args, err := sdk.ValidateToolCall(call, tools, &sdk.ToolArgumentsOptions{Repair: true})
+//		var argErr *sdk.ToolArgumentsError
+//		if errors.As(err, &argErr) {
+//			messages = append(messages, argErr.ToolMessage())
+//			continue
+//		}
+//		// dispatch call.Function.Name with args
+//	}
+func ValidateToolCall(call ChatCompletionMessageToolCall, tools []ChatCompletionTool, options *ToolArgumentsOptions) (map[string]any, error) {
+	for _, tool := range tools {
+		if tool.Function.Name != call.Function.Name {
+			continue
+		}
+		args, err := ValidateToolArguments(call.Function.Arguments, tool.Function.Parameters, options)
+		if argErr, ok := err.(*ToolArgumentsError); ok {
+			argErr.ToolCallID = call.ID
+			argErr.Name = call.Function.Name
+		}
+		return args, err
+	}
+
+	return nil, &ToolArgumentsError{
+		ToolCallID: call.ID,
+		Name:       call.Function.Name,
+		Err:        fmt.Errorf("unknown tool %q", call.Function.Name),
+	}
+}
+
+// ValidateToolArguments parses raw JSON arguments and checks them against a
+// function's parameters schema. A nil schema accepts any object. Empty
+// arguments are treated as an empty object.
+func ValidateToolArguments(arguments string, parameters *FunctionParameters, options *ToolArgumentsOptions) (map[string]any, error) {
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(arguments), &value); err != nil {
+		if options == nil || !options.Repair {
+			return nil, &ToolArgumentsError{Err: err}
+		}
+		if repairErr := json.Unmarshal([]byte(RepairJSON(arguments)), &value); repairErr != nil {
+			return nil, &ToolArgumentsError{Err: err}
+		}
+	}
+
+	args, ok := value.(map[string]any)
+	if !ok {
+		return nil, &ToolArgumentsError{Violations: []SchemaViolation{{Path: "$", Message: "expected object, got " + jsonTypeName(value)}}}
+	}
+
+	if parameters != nil {
+		if err := ValidateJSONSchema(*parameters, args); err != nil {
+			return nil, &ToolArgumentsError{Violations: err.(*SchemaValidationError).Violations}
+		}
+	}
+
+	return args, nil
+}
+
+// RepairJSON makes a best-effort attempt at turning almost-JSON produced by
+// a model into valid JSON. It strips Markdown code fences, converts
+// single-quoted strings to double-quoted ones, drops trailing commas and
+// closes unterminated strings, objects and arrays. Input that is already
+// valid JSON is returned unchanged.
+func RepairJSON(input string) string {
+	if json.Valid([]byte(input)) {
+		return input
+	}
+
+	s := trimCodeFence(input)
+
+	var out strings.Builder
+	var stack []byte
+	var quote byte // the quote that opened the current string, 0 outside strings
+	escaped := false
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+				if quote == '\'' && ch == '\'' {
+					// \' is not a valid JSON escape; emit a bare quote.
+					out.WriteByte('\'')
+					continue
+				}
+				out.WriteByte('\\')
+				out.WriteByte(ch)
+			case ch == '\\':
+				escaped = true
+			case ch == quote:
+				quote = 0
+				out.WriteByte('"')
+			case ch == '"':
+				out.WriteString(`\"`)
+			case ch == '\n':
+				out.WriteString(`\n`)
+			default:
+				out.WriteByte(ch)
+			}
+			continue
+		}
+
+		switch ch {
+		case '"', '\'':
+			quote = ch
+			out.WriteByte('"')
+		case '{':
+			stack = append(stack, '}')
+			out.WriteByte(ch)
+		case '[':
+			stack = append(stack, ']')
+			out.WriteByte(ch)
+		case '}', ']':
+			if len(stack) > 0 && stack[len(stack)-1] == ch {
+				stack = stack[:len(stack)-1]
+			}
+			out.WriteByte(ch)
+		case ',':
+			if next := nextNonSpace(s, i+1); next == 0 || next == '}' || next == ']' {
+				continue
+			}
+			out.WriteByte(ch)
+		default:
+			out.WriteByte(ch)
+		}
+	}
+
+	if quote != 0 {
+		if escaped {
+			out.WriteString(`\\`)
+		}
+		out.WriteByte('"')
+	}
+
+	repaired := strings.TrimRight(out.String(), " \t\r\n")
+	repaired = strings.TrimSuffix(repaired, ",")
+	if strings.HasSuffix(repaired, ":") {
+		repaired += "null"
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		repaired += string(stack[i])
+	}
+	return repaired
+}
+
+// trimCodeFence strips surrounding whitespace and a Markdown code fence
+// (```json ... ```) that models like to wrap JSON in.
+func trimCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	s = strings.TrimSuffix(strings.TrimSpace(s), "```")
+	return strings.TrimSpace(s)
+}
+
+// nextNonSpace returns the first non-whitespace byte at or after i, or 0.
+func nextNonSpace(s string, i int) byte {
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return s[i]
+	}
+	return 0
+}
@@ -0,0 +1,127 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+func weatherTools() []ChatCompletionTool {
+	return []ChatCompletionTool{
+		{
+			Type: Function,
+			Function: FunctionObject{
+				Name: "get_current_weather",
+				Parameters: &FunctionParameters{
+					"type": "object",
+					"properties": map[string]any{
+						"location": map[string]any{"type": "string"},
+						"unit":     map[string]any{"type": "string", "enum": []string{"celsius", "fahrenheit"}},
+					},
+					"required": []string{"location"},
+				},
+			},
+		},
+	}
+}
+
+func weatherCall(arguments string) ChatCompletionMessageToolCall {
+	return ChatCompletionMessageToolCall{
+		ID:       "call_1",
+		Type:     Function,
+		Function: ChatCompletionMessageToolCallFunction{Name: "get_current_weather", Arguments: arguments},
+	}
+}
+
+func TestValidateToolCall(t *testing.T) {
+	args, err := ValidateToolCall(weatherCall(`{"location":"london","unit":"celsius"}`), weatherTools(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"location": "london", "unit": "celsius"}, args)
+
+	_, err = ValidateToolCall(weatherCall(`{"unit":"kelvin"}`), weatherTools(), nil)
+	var argErr *ToolArgumentsError
+	require.ErrorAs(t, err, &argErr)
+	assert.Equal(t, "call_1", argErr.ToolCallID)
+	assert.Equal(t, "get_current_weather", argErr.Name)
+	assert.Equal(t, []SchemaViolation{
+		{Path: "$.location", Message: "is required"},
+		{Path: "$.unit", Message: `must be one of ["celsius", "fahrenheit"]`},
+	}, argErr.Violations)
+
+	msg := argErr.ToolMessage()
+	assert.Equal(t, Tool, msg.Role)
+	require.NotNil(t, msg.ToolCallID)
+	assert.Equal(t, "call_1", *msg.ToolCallID)
+	content, err := msg.Content.AsMessageContent0()
+	require.NoError(t, err)
+	var body struct {
+		Error      string            `json:"error"`
+		Tool       string            `json:"tool"`
+		Violations []SchemaViolation `json:"violations"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(content), &body))
+	assert.Equal(t, "invalid_tool_arguments", body.Error)
+	assert.Equal(t, "get_current_weather", body.Tool)
+	assert.Len(t, body.Violations, 2)
+}
+
+func TestValidateToolCall_UnknownTool(t *testing.T) {
+	call := weatherCall(`{}`)
+	call.Function.Name = "get_time"
+
+	_, err := ValidateToolCall(call, weatherTools(), nil)
+	var argErr *ToolArgumentsError
+	require.ErrorAs(t, err, &argErr)
+	assert.EqualError(t, argErr.Err, `unknown tool "get_time"`)
+}
+
+func TestValidateToolCall_InvalidJSON(t *testing.T) {
+	_, err := ValidateToolCall(weatherCall(`{'location': 'london',}`), weatherTools(), nil)
+	var argErr *ToolArgumentsError
+	require.ErrorAs(t, err, &argErr)
+	var syntaxErr *json.SyntaxError
+	assert.True(t, errors.As(err, &syntaxErr), "the JSON syntax error should be unwrappable")
+
+	args, err := ValidateToolCall(weatherCall(`{'location': 'london',}`), weatherTools(), &ToolArgumentsOptions{Repair: true})
+	require.NoError(t, err)
+	assert.Equal(t, "london", args["location"])
+}
+
+func TestValidateToolArguments_Empty(t *testing.T) {
+	args, err := ValidateToolArguments("", nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, args)
+
+	_, err = ValidateToolArguments(`"london"`, nil, nil)
+	var argErr *ToolArgumentsError
+	require.ErrorAs(t, err, &argErr)
+	assert.Equal(t, []SchemaViolation{{Path: "$", Message: "expected object, got string"}}, argErr.Violations)
+}
+
+func TestRepairJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "already valid", input: `{"a": [1, 2]}`, expected: `{"a": [1, 2]}`},
+		{name: "trailing commas", input: `{"a": [1, 2,], "b": 3,}`, expected: `{"a": [1, 2], "b": 3}`},
+		{name: "single quotes", input: `{'a': 'it\'s "fine"'}`, expected: `{"a": "it's \"fine\""}`},
+		{name: "unclosed braces", input: `{"a": {"b": [1, 2`, expected: `{"a": {"b": [1, 2]}}`},
+		{name: "unclosed string", input: `{"a": "hel`, expected: `{"a": "hel"}`},
+		{name: "dangling key", input: `{"a": 1, "b":`, expected: `{"a": 1, "b":null}`},
+		{name: "code fence", input: "```json\n{\"a\": 1,}\n```", expected: `{"a": 1}`},
+		{name: "commas inside strings are kept", input: `{"a": "x,}",}`, expected: `{"a": "x,}"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RepairJSON(tt.input)
+			assert.Equal(t, tt.expected, got)
+			assert.True(t, json.Valid([]byte(got)), "repaired output should be valid JSON: %s", got)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package sdk
 
 import (
 	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 )
@@ -24,6 +25,36 @@ type ClientOptions struct {
 	// inject an http.RoundTripper - e.g. one that propagates W3C trace-context
 	// headers so gateway calls join the caller's distributed trace.
 	Transport http.RoundTripper
+	// UsageTracker, when set, records the tokens and cost of every call and
+	// rejects calls once a hard budget is used up.
+	UsageTracker *UsageTracker
+	// Validator, when set, checks chat, Messages and Responses requests
+	// before they are sent and fails fast with a *RequestValidationError.
+	Validator *RequestValidator
+	// CircuitBreaker, when set, fails calls fast with ErrCircuitOpen while
+	// their provider and endpoint keep failing.
+	CircuitBreaker *CircuitBreaker
+	// RateLimiter, when set, holds calls back to the requests and tokens
+	// per minute configured for their provider and model.
+	RateLimiter *RateLimiter
+	// Hedging, when set, sends a second request when GenerateContent,
+	// CreateMessage or CreateResponse is slower than usual for its provider
+	// and model, and returns whichever answers first.
+	Hedging *HedgingPolicy
+	// Interceptors see every call of the client, in order: the first sees
+	// the call first and its response last.
+	Interceptors []Interceptor
+	// Logger, when set, logs one record per call with its endpoint,
+	// provider, model, status, latency, attempts, usage and gateway request
+	// ID, and a warning per retry. Failed calls are logged at error level.
+	Logger *slog.Logger
+	// Logging configures what Logger logs besides the outcome of calls,
+	// e.g. redacted prompts and completions.
+	Logging *LoggingOptions
+	// Cache, when set, serves repeated chat, Messages and Responses
+	// requests from a ResponseCache. It runs after Interceptors, so that
+	// requests are keyed as they rewrite them.
+	Cache *ResponseCache
 }
 
 // RetryConfig represents the retry configuration for HTTP requests
@@ -44,6 +75,23 @@ type RetryConfig struct {
 	// OnRetry is called before each retry attempt with attempt number, error, and delay.
 	// The attempt number starts from 1 for the first retry (after initial request fails)
 	OnRetry func(attempt int, err error, delay time.Duration)
+	// InitialBackoff and MaxBackoff take precedence over InitialBackoffSec
+	// and MaxBackoffSec when set, allowing sub-second backoffs.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Multiplier takes precedence over BackoffMultiplier when set, e.g. 1.5.
+	Multiplier float64
+	// Jitter randomizes the backoff delays. Defaults to JitterNone.
+	Jitter Jitter
+	// Budget, when set, caps retries to a share of the calls.
+	Budget *RetryBudget
+	// Classifier, when set, decides whether an attempt is retried before
+	// RetryableStatusCodes and the default network error checks do.
+	Classifier RetryClassifier
+	// IdempotencyKeys sends an Idempotency-Key header with every call, the
+	// same for all its attempts, so that the gateway can tell retries of a
+	// non-idempotent request from new ones.
+	IdempotencyKeys bool
 }
 
 // MiddlewareOptions represents options for controlling middleware behavior
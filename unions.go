@@ -0,0 +1,513 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Builders and accessors for the oneOf unions in generated_types.go.
+//
+// Every union gets a Value method that inspects the raw JSON and decodes it
+// into the matching variant type, so callers can use a type switch instead
+// of guessing which AsX method to call:
+//
+//	switch v, _ := req.ToolChoice.Value(); choice := v.(type) {
+//	case sdk.ChatCompletionToolChoiceOption0:
+//		fmt.Println("mode:", choice)
+//	case sdk.ChatCompletionNamedToolChoice:
+//		fmt.Println("forced:", choice.Function.Name)
+//	}
+//
+// Value returns (nil, nil) for an empty union. String variants are returned
+// as their named type where the schema defines one (e.g.
+// ChatCompletionToolChoiceOption0) and as plain strings otherwise.
+
+// ToolChoiceAuto lets the model decide whether to call tools.
+func ToolChoiceAuto() *ChatCompletionToolChoiceOption {
+	return toolChoiceMode(ChatCompletionToolChoiceOption0Auto)
+}
+
+// ToolChoiceNone prevents the model from calling tools.
+func ToolChoiceNone() *ChatCompletionToolChoiceOption {
+	return toolChoiceMode(ChatCompletionToolChoiceOption0None)
+}
+
+// ToolChoiceRequired makes the model call one or more tools.
+func ToolChoiceRequired() *ChatCompletionToolChoiceOption {
+	return toolChoiceMode(ChatCompletionToolChoiceOption0Required)
+}
+
+// ToolChoiceFunction forces the model to call the named function.
+func ToolChoiceFunction(name string) *ChatCompletionToolChoiceOption {
+	named := ChatCompletionNamedToolChoice{Type: Function}
+	named.Function.Name = name
+
+	var choice ChatCompletionToolChoiceOption
+	mustBuildUnion(choice.FromChatCompletionNamedToolChoice(named))
+	return &choice
+}
+
+func toolChoiceMode(mode ChatCompletionToolChoiceOption0) *ChatCompletionToolChoiceOption {
+	var choice ChatCompletionToolChoiceOption
+	mustBuildUnion(choice.FromChatCompletionToolChoiceOption0(mode))
+	return &choice
+}
+
+// NewResponseFormatText requests plain text output.
+func NewResponseFormatText() *CreateChatCompletionRequest_ResponseFormat {
+	var format CreateChatCompletionRequest_ResponseFormat
+	mustBuildUnion(format.FromResponseFormatText(ResponseFormatText{Type: ResponseFormatTextTypeText}))
+	return &format
+}
+
+// NewResponseFormatJSONObject requests JSON mode: the reply is valid JSON,
+// but no schema is enforced.
+func NewResponseFormatJSONObject() *CreateChatCompletionRequest_ResponseFormat {
+	var format CreateChatCompletionRequest_ResponseFormat
+	mustBuildUnion(format.FromResponseFormatJSONObject(ResponseFormatJSONObject{Type: JSONObject}))
+	return &format
+}
+
+// NewResponseFormatJSONSchema requests Structured Outputs matching schema.
+//
+// Example:
+//
+//	request.ResponseFormat = sdk.NewResponseFormatJSONSchema("weather", map[string]any{
+//		"type":                 "object",
+//		"properties":           map[string]any{"celsius": map[string]any{"type": "number"}},
+//		"required":             []string{"celsius"},
+//		"additionalProperties": false,
+//	}, true)
+func NewResponseFormatJSONSchema(name string, schema map[string]any, strict bool) *CreateChatCompletionRequest_ResponseFormat {
+	format := ResponseFormatJSONSchema{Type: JSONSchema}
+	format.JSONSchema.Name = name
+	format.JSONSchema.Strict = &strict
+	if schema != nil {
+		s := ResponseFormatJSONSchemaSchema(schema)
+		format.JSONSchema.Schema = &s
+	}
+
+	var union CreateChatCompletionRequest_ResponseFormat
+	mustBuildUnion(union.FromResponseFormatJSONSchema(format))
+	return &union
+}
+
+// StopSequences builds the `stop` parameter in the array form, which every
+// provider accepts. The spec allows up to four sequences; RequestValidator
+// reports longer lists before they are sent.
+func StopSequences(sequences ...string) *CreateChatCompletionRequest_Stop {
+	var stop CreateChatCompletionRequest_Stop
+	mustBuildUnion(stop.FromCreateChatCompletionRequestStop1(sequences))
+	return &stop
+}
+
+// SystemText builds a Messages API system prompt from a plain string.
+func SystemText(text string) *CreateMessagesRequest_System {
+	var system CreateMessagesRequest_System
+	mustBuildUnion(system.FromCreateMessagesRequestSystem0(text))
+	return &system
+}
+
+// SystemBlocks builds a Messages API system prompt from text blocks, which
+// can carry cache_control for prompt caching.
+func SystemBlocks(blocks ...MessagesTextBlock) *CreateMessagesRequest_System {
+	for i := range blocks {
+		if blocks[i].Type == "" {
+			blocks[i].Type = MessagesTextBlockTypeText
+		}
+	}
+
+	var system CreateMessagesRequest_System
+	mustBuildUnion(system.FromCreateMessagesRequestSystem1(blocks))
+	return &system
+}
+
+// MessagesToolChoiceAuto lets the model decide whether to use tools.
+func MessagesToolChoiceAuto() *MessagesToolChoice {
+	var choice MessagesToolChoice
+	mustBuildUnion(choice.FromMessagesToolChoice0(MessagesToolChoice0Auto))
+	return &choice
+}
+
+// MessagesToolChoiceAny makes the model use one of the tools.
+func MessagesToolChoiceAny() *MessagesToolChoice {
+	var choice MessagesToolChoice
+	mustBuildUnion(choice.FromMessagesToolChoice0(MessagesToolChoice0Any))
+	return &choice
+}
+
+// MessagesToolChoiceTool forces the model to use the named tool.
+func MessagesToolChoiceTool(name string) *MessagesToolChoice {
+	var choice MessagesToolChoice
+	mustBuildUnion(choice.FromMessagesToolChoice1(MessagesToolChoice1{Name: name, Type: MessagesToolChoiceTypeTool}))
+	return &choice
+}
+
+// ResponseToolChoiceAuto lets the model decide whether to call tools.
+func ResponseToolChoiceAuto() *ResponseToolChoice {
+	return responseToolChoiceMode(ResponseToolChoice0Auto)
+}
+
+// ResponseToolChoiceNone prevents the model from calling tools.
+func ResponseToolChoiceNone() *ResponseToolChoice {
+	return responseToolChoiceMode(ResponseToolChoice0None)
+}
+
+// ResponseToolChoiceRequired makes the model call one or more tools.
+func ResponseToolChoiceRequired() *ResponseToolChoice {
+	return responseToolChoiceMode(ResponseToolChoice0Required)
+}
+
+// ResponseToolChoiceFunction forces the model to call the named function.
+func ResponseToolChoiceFunction(name string) *ResponseToolChoice {
+	var choice ResponseToolChoice
+	mustBuildUnion(choice.FromResponseToolChoice1(ResponseToolChoice1{Name: name, Type: ResponseToolChoiceTypeFunction}))
+	return &choice
+}
+
+func responseToolChoiceMode(mode ResponseToolChoice0) *ResponseToolChoice {
+	var choice ResponseToolChoice
+	mustBuildUnion(choice.FromResponseToolChoice0(mode))
+	return &choice
+}
+
+// mustBuildUnion panics on a From* error. The builders above only pass
+// values that always marshal, so an error here is a programming bug.
+func mustBuildUnion(err error) {
+	if err != nil {
+		panic(fmt.Sprintf("failed to build union: %v", err))
+	}
+}
+
+// Value returns a ChatCompletionToolChoiceOption0 or a
+// ChatCompletionNamedToolChoice.
+func (t ChatCompletionToolChoiceOption) Value() (any, error) {
+	switch unionShape(t.union) {
+	case 0:
+		return nil, nil
+	case '"':
+		return variant(t.AsChatCompletionToolChoiceOption0())
+	case '{':
+		return variant(t.AsChatCompletionNamedToolChoice())
+	}
+	return nil, unionShapeError("tool_choice", t.union)
+}
+
+// Value returns a TextContentPart or an ImageContentPart.
+func (t ContentPart) Value() (any, error) {
+	kind, err := unionType(t.union)
+	if err != nil || kind == "" {
+		return nil, err
+	}
+	switch kind {
+	case string(TextContentPartTypeText):
+		return variant(t.AsTextContentPart())
+	case string(ImageContentPartTypeImageURL):
+		return variant(t.AsImageContentPart())
+	}
+	return nil, unknownUnionType("content part", kind)
+}
+
+// Value returns a ResponseFormatText, ResponseFormatJSONSchema or
+// ResponseFormatJSONObject.
+func (t CreateChatCompletionRequest_ResponseFormat) Value() (any, error) {
+	kind, err := unionType(t.union)
+	if err != nil || kind == "" {
+		return nil, err
+	}
+	switch kind {
+	case string(ResponseFormatTextTypeText):
+		return variant(t.AsResponseFormatText())
+	case string(JSONSchema):
+		return variant(t.AsResponseFormatJSONSchema())
+	case string(JSONObject):
+		return variant(t.AsResponseFormatJSONObject())
+	}
+	return nil, unknownUnionType("response format", kind)
+}
+
+// Value returns a string or a []string.
+func (t CreateChatCompletionRequest_Stop) Value() (any, error) {
+	switch unionShape(t.union) {
+	case 0:
+		return nil, nil
+	case '"':
+		return variant(t.AsCreateChatCompletionRequestStop0())
+	case '[':
+		return variant(t.AsCreateChatCompletionRequestStop1())
+	}
+	return nil, unionShapeError("stop", t.union)
+}
+
+// Sequences returns the stop sequences regardless of which form was used.
+func (t CreateChatCompletionRequest_Stop) Sequences() ([]string, error) {
+	v, err := t.Value()
+	switch s := v.(type) {
+	case string:
+		return []string{s}, err
+	case []string:
+		return s, err
+	}
+	return nil, err
+}
+
+// Value returns a string or a []MessagesTextBlock.
+func (t CreateMessagesRequest_System) Value() (any, error) {
+	switch unionShape(t.union) {
+	case 0:
+		return nil, nil
+	case '"':
+		return variant(t.AsCreateMessagesRequestSystem0())
+	case '[':
+		return variant(t.AsCreateMessagesRequestSystem1())
+	}
+	return nil, unionShapeError("system", t.union)
+}
+
+// Text returns the system prompt as a single string, joining blocks with
+// blank lines.
+func (t CreateMessagesRequest_System) Text() (string, error) {
+	v, err := t.Value()
+	switch s := v.(type) {
+	case string:
+		return s, err
+	case []MessagesTextBlock:
+		parts := make([]string, len(s))
+		for i, block := range s {
+			parts[i] = block.Text
+		}
+		return strings.Join(parts, "\n\n"), err
+	}
+	return "", err
+}
+
+// Value returns a string or a []ContentPart.
+func (t MessageContent) Value() (any, error) {
+	switch unionShape(t.union) {
+	case 0:
+		return nil, nil
+	case '"':
+		return variant(t.AsMessageContent0())
+	case '[':
+		return variant(t.AsMessageContent1())
+	}
+	return nil, unionShapeError("message content", t.union)
+}
+
+// Value returns a string or a []MessagesRequestContentBlock.
+func (t MessagesMessage_Content) Value() (any, error) {
+	switch unionShape(t.union) {
+	case 0:
+		return nil, nil
+	case '"':
+		return variant(t.AsMessagesMessageContent0())
+	case '[':
+		return variant(t.AsMessagesMessageContent1())
+	}
+	return nil, unionShapeError("message content", t.union)
+}
+
+// Value returns a MessagesTextBlock, MessagesImageBlock,
+// MessagesToolUseBlock, MessagesToolResultBlock, MessagesDocumentBlock,
+// MessagesThinkingBlock or MessagesRedactedThinkingBlock.
+func (t MessagesRequestContentBlock) Value() (any, error) {
+	kind, err := unionType(t.union)
+	if err != nil || kind == "" {
+		return nil, err
+	}
+	switch kind {
+	case string(MessagesTextBlockTypeText):
+		return variant(t.AsMessagesTextBlock())
+	case string(MessagesImageBlockTypeImage):
+		return variant(t.AsMessagesImageBlock())
+	case string(MessagesToolUseBlockTypeToolUse):
+		return variant(t.AsMessagesToolUseBlock())
+	case string(ToolResult):
+		return variant(t.AsMessagesToolResultBlock())
+	case string(Document):
+		return variant(t.AsMessagesDocumentBlock())
+	case string(Thinking):
+		return variant(t.AsMessagesThinkingBlock())
+	case string(RedactedThinking):
+		return variant(t.AsMessagesRedactedThinkingBlock())
+	}
+	return nil, unknownUnionType("content block", kind)
+}
+
+// Value returns a MessagesTextBlock, MessagesToolUseBlock,
+// MessagesThinkingBlock or MessagesRedactedThinkingBlock.
+func (t MessagesResponseContentBlock) Value() (any, error) {
+	kind, err := unionType(t.union)
+	if err != nil || kind == "" {
+		return nil, err
+	}
+	switch kind {
+	case string(MessagesTextBlockTypeText):
+		return variant(t.AsMessagesTextBlock())
+	case string(MessagesToolUseBlockTypeToolUse):
+		return variant(t.AsMessagesToolUseBlock())
+	case string(Thinking):
+		return variant(t.AsMessagesThinkingBlock())
+	case string(RedactedThinking):
+		return variant(t.AsMessagesRedactedThinkingBlock())
+	}
+	return nil, unknownUnionType("content block", kind)
+}
+
+// Value returns a MessagesToolChoice0 or a MessagesToolChoice1.
+func (t MessagesToolChoice) Value() (any, error) {
+	switch unionShape(t.union) {
+	case 0:
+		return nil, nil
+	case '"':
+		return variant(t.AsMessagesToolChoice0())
+	case '{':
+		return variant(t.AsMessagesToolChoice1())
+	}
+	return nil, unionShapeError("tool_choice", t.union)
+}
+
+// Value returns a string or a []MessagesTextBlock.
+func (t MessagesToolResultBlock_Content) Value() (any, error) {
+	switch unionShape(t.union) {
+	case 0:
+		return nil, nil
+	case '"':
+		return variant(t.AsMessagesToolResultBlockContent0())
+	case '[':
+		return variant(t.AsMessagesToolResultBlockContent1())
+	}
+	return nil, unionShapeError("tool result content", t.union)
+}
+
+// Value returns a string or a []ResponseInputItem.
+func (t ResponseInput) Value() (any, error) {
+	switch unionShape(t.union) {
+	case 0:
+		return nil, nil
+	case '"':
+		return variant(t.AsResponseInput0())
+	case '[':
+		return variant(t.AsResponseInput1())
+	}
+	return nil, unionShapeError("input", t.union)
+}
+
+// Value returns a ResponseInputText or a ResponseInputImage.
+func (t ResponseInputContentPart) Value() (any, error) {
+	kind, err := unionType(t.union)
+	if err != nil || kind == "" {
+		return nil, err
+	}
+	switch kind {
+	case string(InputText):
+		return variant(t.AsResponseInputText())
+	case string(InputImage):
+		return variant(t.AsResponseInputImage())
+	}
+	return nil, unknownUnionType("input content part", kind)
+}
+
+// Value returns a string or a []ResponseInputContentPart.
+func (t ResponseInputMessageContent) Value() (any, error) {
+	switch unionShape(t.union) {
+	case 0:
+		return nil, nil
+	case '"':
+		return variant(t.AsResponseInputMessageContent0())
+	case '[':
+		return variant(t.AsResponseInputMessageContent1())
+	}
+	return nil, unionShapeError("input message content", t.union)
+}
+
+// Value returns a ResponseOutputText or a ResponseOutputRefusal.
+func (t ResponseOutputContent) Value() (any, error) {
+	kind, err := unionType(t.union)
+	if err != nil || kind == "" {
+		return nil, err
+	}
+	switch kind {
+	case string(OutputText):
+		return variant(t.AsResponseOutputText())
+	case string(Refusal):
+		return variant(t.AsResponseOutputRefusal())
+	}
+	return nil, unknownUnionType("output content", kind)
+}
+
+// Value returns a ResponseOutputMessage, ResponseFunctionToolCall or
+// ResponseReasoningItem.
+func (t ResponseOutputItem) Value() (any, error) {
+	kind, err := unionType(t.union)
+	if err != nil || kind == "" {
+		return nil, err
+	}
+	switch kind {
+	case string(ResponseOutputMessageTypeMessage):
+		return variant(t.AsResponseOutputMessage())
+	case string(ResponseFunctionToolCallTypeFunctionCall):
+		return variant(t.AsResponseFunctionToolCall())
+	case string(Reasoning):
+		return variant(t.AsResponseReasoningItem())
+	}
+	return nil, unknownUnionType("output item", kind)
+}
+
+// Value returns a ResponseToolChoice0 or a ResponseToolChoice1.
+func (t ResponseToolChoice) Value() (any, error) {
+	switch unionShape(t.union) {
+	case 0:
+		return nil, nil
+	case '"':
+		return variant(t.AsResponseToolChoice0())
+	case '{':
+		return variant(t.AsResponseToolChoice1())
+	}
+	return nil, unionShapeError("tool_choice", t.union)
+}
+
+// variant adapts a generated AsX result to Value's return type.
+func variant[T any](v T, err error) (any, error) {
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// unionShape returns the first significant byte of raw - '"', '[' or '{'
+// for the shapes the unions use - or 0 when the union is empty or null.
+func unionShape(raw json.RawMessage) byte {
+	s := strings.TrimSpace(string(raw))
+	if s == "" || s == "null" {
+		return 0
+	}
+	return s[0]
+}
+
+// unionType reads the `type` discriminator of an object union. It returns
+// "" for an empty union.
+func unionType(raw json.RawMessage) (string, error) {
+	if unionShape(raw) == 0 {
+		return "", nil
+	}
+	var discriminator struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &discriminator); err != nil {
+		return "", err
+	}
+	if discriminator.Type == "" {
+		return "", fmt.Errorf("union value has no type discriminator: %s", string(raw))
+	}
+	return discriminator.Type, nil
+}
+
+func unionShapeError(name string, raw json.RawMessage) error {
+	return fmt.Errorf("unexpected %s value: %s", name, string(raw))
+}
+
+func unknownUnionType(name, kind string) error {
+	return fmt.Errorf("unknown %s type %q", name, kind)
+}
@@ -0,0 +1,175 @@
+package sdk
+
+import (
+	"encoding/json"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+func TestUnionBuilders(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    any
+		expected string
+	}{
+		{name: "tool choice auto", value: ToolChoiceAuto(), expected: `"auto"`},
+		{name: "tool choice none", value: ToolChoiceNone(), expected: `"none"`},
+		{name: "tool choice required", value: ToolChoiceRequired(), expected: `"required"`},
+		{name: "tool choice function", value: ToolChoiceFunction("get_weather"), expected: `{"type":"function","function":{"name":"get_weather"}}`},
+		{name: "response format text", value: NewResponseFormatText(), expected: `{"type":"text"}`},
+		{name: "response format json object", value: NewResponseFormatJSONObject(), expected: `{"type":"json_object"}`},
+		{
+			name:     "response format json schema",
+			value:    NewResponseFormatJSONSchema("weather", map[string]any{"type": "object"}, true),
+			expected: `{"type":"json_schema","json_schema":{"name":"weather","schema":{"type":"object"},"strict":true}}`,
+		},
+		{name: "stop sequences", value: StopSequences("STOP", "END"), expected: `["STOP","END"]`},
+		{name: "system text", value: SystemText("Be brief."), expected: `"Be brief."`},
+		{name: "system blocks", value: SystemBlocks(MessagesTextBlock{Text: "Be brief."}), expected: `[{"type":"text","text":"Be brief."}]`},
+		{name: "messages tool choice auto", value: MessagesToolChoiceAuto(), expected: `"auto"`},
+		{name: "messages tool choice any", value: MessagesToolChoiceAny(), expected: `"any"`},
+		{name: "messages tool choice tool", value: MessagesToolChoiceTool("get_weather"), expected: `{"type":"tool","name":"get_weather"}`},
+		{name: "responses tool choice auto", value: ResponseToolChoiceAuto(), expected: `"auto"`},
+		{name: "responses tool choice function", value: ResponseToolChoiceFunction("get_weather"), expected: `{"type":"function","name":"get_weather"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.value)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(data))
+		})
+	}
+}
+
+// valuer is implemented by every union in generated_types.go via unions.go.
+type valuer interface {
+	Value() (any, error)
+}
+
+func TestUnionValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		union    valuer
+		json     string
+		expected any
+	}{
+		{name: "tool choice mode", union: &ChatCompletionToolChoiceOption{}, json: `"required"`, expected: ChatCompletionToolChoiceOption0Required},
+		{name: "tool choice named", union: &ChatCompletionToolChoiceOption{}, json: `{"type":"function","function":{"name":"f"}}`, expected: namedToolChoice("f")},
+		{name: "content part text", union: &ContentPart{}, json: `{"type":"text","text":"hi"}`, expected: TextContentPart{Type: TextContentPartTypeText, Text: "hi"}},
+		{
+			name:     "content part image",
+			union:    &ContentPart{},
+			json:     `{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}`,
+			expected: ImageContentPart{Type: ImageContentPartTypeImageURL, ImageURL: ImageURL{URL: "https://example.com/a.png"}},
+		},
+		{name: "response format json object", union: &CreateChatCompletionRequest_ResponseFormat{}, json: `{"type":"json_object"}`, expected: ResponseFormatJSONObject{Type: JSONObject}},
+		{name: "stop string", union: &CreateChatCompletionRequest_Stop{}, json: `"\n"`, expected: "\n"},
+		{name: "stop array", union: &CreateChatCompletionRequest_Stop{}, json: `["a","b"]`, expected: []string{"a", "b"}},
+		{name: "system string", union: &CreateMessagesRequest_System{}, json: `"Be brief."`, expected: "Be brief."},
+		{name: "message content string", union: &MessageContent{}, json: `"hello"`, expected: "hello"},
+		{name: "messages content string", union: &MessagesMessage_Content{}, json: `"hello"`, expected: "hello"},
+		{
+			name:     "messages request tool use block",
+			union:    &MessagesRequestContentBlock{},
+			json:     `{"type":"tool_use","id":"toolu_1","name":"f","input":{"a":1}}`,
+			expected: MessagesToolUseBlock{Type: MessagesToolUseBlockTypeToolUse, ID: "toolu_1", Name: "f", Input: map[string]any{"a": 1.0}},
+		},
+		{
+			name:     "messages response text block",
+			union:    &MessagesResponseContentBlock{},
+			json:     `{"type":"text","text":"hi"}`,
+			expected: MessagesTextBlock{Type: MessagesTextBlockTypeText, Text: "hi"},
+		},
+		{name: "messages tool choice named", union: &MessagesToolChoice{}, json: `{"type":"tool","name":"f"}`, expected: MessagesToolChoice1{Type: MessagesToolChoiceTypeTool, Name: "f"}},
+		{name: "tool result content string", union: &MessagesToolResultBlock_Content{}, json: `"42"`, expected: "42"},
+		{name: "responses input string", union: &ResponseInput{}, json: `"hello"`, expected: "hello"},
+		{name: "responses input text part", union: &ResponseInputContentPart{}, json: `{"type":"input_text","text":"hi"}`, expected: ResponseInputText{Type: InputText, Text: "hi"}},
+		{name: "responses input message content", union: &ResponseInputMessageContent{}, json: `"hi"`, expected: "hi"},
+		{name: "responses output refusal", union: &ResponseOutputContent{}, json: `{"type":"refusal","refusal":"no"}`, expected: ResponseOutputRefusal{Type: Refusal, Refusal: "no"}},
+		{
+			name:     "responses output function call",
+			union:    &ResponseOutputItem{},
+			json:     `{"type":"function_call","call_id":"call_1","name":"f","arguments":"{}"}`,
+			expected: ResponseFunctionToolCall{Type: ResponseFunctionToolCallTypeFunctionCall, CallID: "call_1", Name: "f", Arguments: "{}"},
+		},
+		{name: "responses tool choice mode", union: &ResponseToolChoice{}, json: `"none"`, expected: ResponseToolChoice0None},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, json.Unmarshal([]byte(tt.json), tt.union))
+			got, err := tt.union.Value()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+// namedToolChoice builds the expected decoded named tool choice.
+func namedToolChoice(name string) ChatCompletionNamedToolChoice {
+	named := ChatCompletionNamedToolChoice{Type: Function}
+	named.Function.Name = name
+	return named
+}
+
+func TestUnionValue_Empty(t *testing.T) {
+	var stop CreateChatCompletionRequest_Stop
+	got, err := stop.Value()
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	var block MessagesRequestContentBlock
+	got, err = block.Value()
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestUnionValue_UnknownType(t *testing.T) {
+	var block MessagesResponseContentBlock
+	require.NoError(t, json.Unmarshal([]byte(`{"type":"server_tool_use"}`), &block))
+	_, err := block.Value()
+	assert.EqualError(t, err, `unknown content block type "server_tool_use"`)
+
+	var part ContentPart
+	require.NoError(t, json.Unmarshal([]byte(`{"text":"no type"}`), &part))
+	_, err = part.Value()
+	assert.Error(t, err)
+}
+
+func TestUnionAccessors(t *testing.T) {
+	sequences, err := StopSequences("a", "b").Sequences()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, sequences)
+
+	var stop CreateChatCompletionRequest_Stop
+	require.NoError(t, stop.FromCreateChatCompletionRequestStop0("END"))
+	sequences, err = stop.Sequences()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"END"}, sequences)
+
+	text, err := SystemBlocks(MessagesTextBlock{Text: "one"}, MessagesTextBlock{Text: "two"}).Text()
+	require.NoError(t, err)
+	assert.Equal(t, "one\n\ntwo", text)
+
+	text, err = SystemText("solo").Text()
+	require.NoError(t, err)
+	assert.Equal(t, "solo", text)
+}
+
+func TestUnionBuilders_RoundTrip(t *testing.T) {
+	data, err := json.Marshal(NewResponseFormatJSONSchema("weather", map[string]any{"type": "object"}, true))
+	require.NoError(t, err)
+
+	var decoded CreateChatCompletionRequest_ResponseFormat
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	got, err := decoded.Value()
+	require.NoError(t, err)
+	format, ok := got.(ResponseFormatJSONSchema)
+	require.True(t, ok, "expected ResponseFormatJSONSchema, got %T", got)
+	assert.Equal(t, "weather", format.JSONSchema.Name)
+	require.NotNil(t, format.JSONSchema.Strict)
+	assert.True(t, *format.JSONSchema.Strict)
+}
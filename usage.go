@@ -0,0 +1,554 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// UsageSnapshotVersion is the version of the JSON form written by
+// UsageTracker.MarshalJSON.
+const UsageSnapshotVersion = 1
+
+// ErrBudgetExceeded is returned, wrapped in a *BudgetExceededError, when a
+// call would run over a hard budget.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetExceededError reports the hard budget that rejected a call.
+type BudgetExceededError struct {
+	Budget Budget
+	// Tokens and Cost are what the budget's scope has used so far.
+	Tokens int64
+	Cost   Decimal
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("budget %q exceeded: used %d tokens and %s", e.Budget.Name, e.Tokens, e.Cost)
+}
+
+// Unwrap lets errors.Is match ErrBudgetExceeded.
+func (e *BudgetExceededError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// UsageScope selects usage by provider, model and labels. Empty fields
+// match everything; Labels must all be present on the usage.
+type UsageScope struct {
+	Provider Provider          `json:"provider,omitempty"`
+	Model    string            `json:"model,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+func (s UsageScope) matches(provider Provider, model string, labels map[string]string) bool {
+	if s.Provider != "" && s.Provider != provider {
+		return false
+	}
+	if s.Model != "" && s.Model != model {
+		return false
+	}
+	for key, value := range s.Labels {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Budget caps the tokens or cost used within a scope, e.g. a tenant label.
+type Budget struct {
+	// Name identifies the budget in errors and events.
+	Name  string
+	Scope UsageScope
+	// MaxTokens and MaxCost are the limits; zero means no limit.
+	MaxTokens int64
+	MaxCost   Decimal
+	// Soft budgets never reject calls; they only report thresholds.
+	Soft bool
+	// Thresholds are fractions of the limit, e.g. 0.5, 0.8 and 1, at which
+	// UsageTrackerOptions.OnThreshold is called once each. Defaults to 1.
+	Thresholds []float64
+}
+
+// exceeded reports whether used has reached the limits.
+func (b Budget) exceeded(used UsageTotals) bool {
+	return (b.MaxTokens > 0 && used.TotalTokens >= b.MaxTokens) ||
+		(!b.MaxCost.IsZero() && used.Cost.Cmp(b.MaxCost) >= 0)
+}
+
+// fraction returns how much of the budget used has spent, by the tighter of
+// the two limits.
+func (b Budget) fraction(used UsageTotals) float64 {
+	var fraction float64
+	if b.MaxTokens > 0 {
+		fraction = float64(used.TotalTokens) / float64(b.MaxTokens)
+	}
+	if !b.MaxCost.IsZero() {
+		fraction = max(fraction, used.Cost.Float64()/b.MaxCost.Float64())
+	}
+	return fraction
+}
+
+// BudgetEvent reports that usage crossed a budget threshold.
+type BudgetEvent struct {
+	Budget    Budget
+	Threshold float64
+	Tokens    int64
+	Cost      Decimal
+}
+
+// UsageTotals sums the usage of one or more calls.
+type UsageTotals struct {
+	Requests          int64   `json:"requests"`
+	InputTokens       int64   `json:"input_tokens"`
+	OutputTokens      int64   `json:"output_tokens"`
+	TotalTokens       int64   `json:"total_tokens"`
+	CachedInputTokens int64   `json:"cached_input_tokens,omitempty"`
+	CacheWriteTokens  int64   `json:"cache_write_tokens,omitempty"`
+	ReasoningTokens   int64   `json:"reasoning_tokens,omitempty"`
+	Cost              Decimal `json:"cost"`
+	Currency          string  `json:"currency,omitempty"`
+}
+
+func (t *UsageTotals) add(other UsageTotals) {
+	t.Requests += other.Requests
+	t.InputTokens += other.InputTokens
+	t.OutputTokens += other.OutputTokens
+	t.TotalTokens += other.TotalTokens
+	t.CachedInputTokens += other.CachedInputTokens
+	t.CacheWriteTokens += other.CacheWriteTokens
+	t.ReasoningTokens += other.ReasoningTokens
+	t.Cost = t.Cost.Add(other.Cost)
+	if t.Currency == "" {
+		t.Currency = other.Currency
+	}
+}
+
+// UsageRecord is the usage of one provider, model and label set.
+type UsageRecord struct {
+	Provider Provider          `json:"provider"`
+	Model    string            `json:"model"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	UsageTotals
+}
+
+func (r UsageRecord) key() string {
+	var key strings.Builder
+	key.WriteString(string(r.Provider) + "\x00" + r.Model)
+	for _, name := range slices.Sorted(maps.Keys(r.Labels)) {
+		key.WriteString("\x00" + name + "=" + r.Labels[name])
+	}
+	return key.String()
+}
+
+// usageSnapshot is the persisted form of a UsageTracker.
+type usageSnapshot struct {
+	Version int           `json:"version"`
+	Records []UsageRecord `json:"records"`
+}
+
+// UsageTrackerOptions configures a UsageTracker.
+type UsageTrackerOptions struct {
+	Budgets []Budget
+	// OnThreshold is called when usage crosses a budget threshold. It runs
+	// on the goroutine that made the call.
+	OnThreshold func(event BudgetEvent)
+	// Costs prices recorded usage. Without it usage is tracked by tokens
+	// only, and cost budgets never run out.
+	Costs *CostCalculator
+}
+
+// UsageTracker aggregates the tokens and cost of every call made through
+// the clients it is attached to with ClientOptions.UsageTracker, and
+// enforces budgets. Usage is grouped by provider, model and labels: those
+// set with WithUsageLabels, and "user" from the request's user field. It is
+// safe for concurrent use.
+type UsageTracker struct {
+	mu          sync.Mutex
+	budgets     []Budget
+	onThreshold func(event BudgetEvent)
+	costs       *CostCalculator
+	records     map[string]*UsageRecord
+	// fired is the highest threshold reported per budget.
+	fired map[string]float64
+}
+
+// NewUsageTracker creates a UsageTracker.
+//
+// Example:
+//
+//	pricing := sdk.NewClient(&sdk.ClientOptions{BaseURL: "http://localhost:8080/v1"})
+//	tracker := sdk.NewUsageTracker(&sdk.UsageTrackerOptions{
+//		Budgets: []sdk.Budget{{
+//			Name:    "tenant-a",
+//			Scope:   sdk.UsageScope{Labels: map[string]string{"tenant": "a"}},
+//			MaxCost: sdk.MustParseDecimal("25"),
+//		}},
+//		Costs: sdk.NewCostCalculator(pricing, nil),
+//	})
+//	client := sdk.NewClient(&sdk.ClientOptions{
+//		BaseURL:      "http://localhost:8080/v1",
+//		UsageTracker: tracker,
+//	})
+//	ctx = sdk.WithUsageLabels(ctx, map[string]string{"tenant": "a"})
+//	response, err := client.GenerateContent(ctx, sdk.Openai, "gpt-4o", messages)
+//	if errors.Is(err, sdk.ErrBudgetExceeded) {
+//		// tenant a is out of budget
+//	}
+func NewUsageTracker(options *UsageTrackerOptions) *UsageTracker {
+	t := &UsageTracker{
+		records: make(map[string]*UsageRecord),
+		fired:   make(map[string]float64),
+	}
+	if options != nil {
+		t.budgets = slices.Clone(options.Budgets)
+		t.onThreshold = options.OnThreshold
+		t.costs = options.Costs
+	}
+	return t
+}
+
+// SetBudget adds a budget, or replaces the one with the same name.
+func (t *UsageTracker) SetBudget(budget Budget) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.budgets = slices.DeleteFunc(t.budgets, func(b Budget) bool { return b.Name == budget.Name })
+	t.budgets = append(t.budgets, budget)
+	delete(t.fired, budget.Name)
+	t.markFired()
+}
+
+// RemoveBudget removes the budget with the given name.
+func (t *UsageTracker) RemoveBudget(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.budgets = slices.DeleteFunc(t.budgets, func(b Budget) bool { return b.Name == name })
+	delete(t.fired, name)
+}
+
+// Records returns the usage per provider, model and label set.
+func (t *UsageTracker) Records() []UsageRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	records := make([]UsageRecord, 0, len(t.records))
+	for _, key := range slices.Sorted(maps.Keys(t.records)) {
+		record := *t.records[key]
+		record.Labels = maps.Clone(record.Labels)
+		records = append(records, record)
+	}
+	return records
+}
+
+// Totals sums the usage within scope.
+func (t *UsageTracker) Totals(scope UsageScope) UsageTotals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.totals(scope)
+}
+
+func (t *UsageTracker) totals(scope UsageScope) UsageTotals {
+	var totals UsageTotals
+	for _, record := range t.records {
+		if scope.matches(record.Provider, record.Model, record.Labels) {
+			totals.add(record.UsageTotals)
+		}
+	}
+	return totals
+}
+
+// Reset clears all usage.
+func (t *UsageTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.records = make(map[string]*UsageRecord)
+	t.fired = make(map[string]float64)
+}
+
+// Check returns a *BudgetExceededError when a hard budget covering the
+// provider, model and labels has been used up.
+func (t *UsageTracker) Check(provider Provider, model string, labels map[string]string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, budget := range t.budgets {
+		if budget.Soft || !budget.Scope.matches(provider, model, labels) {
+			continue
+		}
+		if used := t.totals(budget.Scope); budget.exceeded(used) {
+			return &BudgetExceededError{Budget: budget, Tokens: used.TotalTokens, Cost: used.Cost}
+		}
+	}
+	return nil
+}
+
+// Record adds the usage of one call, prices it, and reports the budget
+// thresholds it crosses. Clients with a tracker call it for you; use it
+// for usage from elsewhere.
+func (t *UsageTracker) Record(ctx context.Context, provider Provider, model string, labels map[string]string, usage GenerateUsage) {
+	totals := UsageTotals{
+		Requests:          1,
+		InputTokens:       usage.InputTokens,
+		OutputTokens:      usage.OutputTokens,
+		TotalTokens:       usage.TotalTokens,
+		CachedInputTokens: usage.CachedInputTokens,
+		CacheWriteTokens:  usage.CacheWriteTokens,
+		ReasoningTokens:   usage.ReasoningTokens,
+	}
+	if totals.TotalTokens == 0 {
+		totals.TotalTokens = usage.InputTokens + usage.OutputTokens
+	}
+	if t.costs != nil && totals.TotalTokens > 0 {
+		// Unpriced models are tracked by tokens only.
+		if cost, err := t.costs.Cost(ctx, provider, model, usage); err == nil && cost != nil {
+			totals.Cost, totals.Currency = cost.Total, cost.Currency
+		}
+	}
+
+	t.mu.Lock()
+	record := UsageRecord{Provider: provider, Model: model, Labels: maps.Clone(labels)}
+	key := record.key()
+	if existing, ok := t.records[key]; ok {
+		existing.add(totals)
+	} else {
+		record.UsageTotals = totals
+		t.records[key] = &record
+	}
+	events := t.crossed(provider, model, labels)
+	onThreshold := t.onThreshold
+	t.mu.Unlock()
+
+	if onThreshold != nil {
+		for _, event := range events {
+			onThreshold(event)
+		}
+	}
+}
+
+// crossed returns the thresholds newly crossed by budgets covering the
+// provider, model and labels, and marks them reported.
+func (t *UsageTracker) crossed(provider Provider, model string, labels map[string]string) []BudgetEvent {
+	var events []BudgetEvent
+	for _, budget := range t.budgets {
+		if !budget.Scope.matches(provider, model, labels) {
+			continue
+		}
+		used := t.totals(budget.Scope)
+		fraction := budget.fraction(used)
+		for _, threshold := range budgetThresholds(budget) {
+			if threshold > t.fired[budget.Name] && threshold <= fraction {
+				t.fired[budget.Name] = threshold
+				events = append(events, BudgetEvent{Budget: budget, Threshold: threshold, Tokens: used.TotalTokens, Cost: used.Cost})
+			}
+		}
+	}
+	return events
+}
+
+// markFired marks thresholds already crossed as reported without reporting
+// them, e.g. after restoring a snapshot.
+func (t *UsageTracker) markFired() {
+	for _, budget := range t.budgets {
+		fraction := budget.fraction(t.totals(budget.Scope))
+		for _, threshold := range budgetThresholds(budget) {
+			if threshold <= fraction {
+				t.fired[budget.Name] = max(t.fired[budget.Name], threshold)
+			}
+		}
+	}
+}
+
+func budgetThresholds(budget Budget) []float64 {
+	if len(budget.Thresholds) == 0 {
+		return []float64{1}
+	}
+	return slices.Sorted(slices.Values(budget.Thresholds))
+}
+
+// MarshalJSON writes the usage totals with a version number, so they can
+// be persisted and restored after a restart. Budgets are configuration and
+// are not included.
+func (t *UsageTracker) MarshalJSON() ([]byte, error) {
+	return json.Marshal(usageSnapshot{Version: UsageSnapshotVersion, Records: t.Records()})
+}
+
+// UnmarshalJSON replaces the usage totals with a snapshot written by
+// MarshalJSON. Thresholds the restored usage already crossed are not
+// reported again.
+func (t *UsageTracker) UnmarshalJSON(data []byte) error {
+	var snapshot usageSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to parse usage snapshot: %w", err)
+	}
+	if snapshot.Version < 1 || snapshot.Version > UsageSnapshotVersion {
+		return fmt.Errorf("unsupported usage snapshot version %d", snapshot.Version)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.records = make(map[string]*UsageRecord, len(snapshot.Records))
+	for _, record := range snapshot.Records {
+		key := record.key()
+		if existing, ok := t.records[key]; ok {
+			existing.add(record.UsageTotals)
+			continue
+		}
+		t.records[key] = &record
+	}
+	if t.fired == nil {
+		t.fired = make(map[string]float64)
+	}
+	clear(t.fired)
+	t.markFired()
+	return nil
+}
+
+type usageLabelsKey struct{}
+
+// WithUsageLabels returns a context whose calls are tracked under labels,
+// e.g. a tenant or job ID, in addition to any labels already set.
+func WithUsageLabels(ctx context.Context, labels map[string]string) context.Context {
+	merged := maps.Clone(UsageLabels(ctx))
+	if merged == nil {
+		merged = make(map[string]string, len(labels))
+	}
+	maps.Copy(merged, labels)
+	return context.WithValue(ctx, usageLabelsKey{}, merged)
+}
+
+// UsageLabels returns the labels set with WithUsageLabels.
+func UsageLabels(ctx context.Context) map[string]string {
+	labels, _ := ctx.Value(usageLabelsKey{}).(map[string]string)
+	return labels
+}
+
+// messagesUser returns the user ID of a Messages API request.
+func messagesUser(request CreateMessagesRequest) *string {
+	if request.Metadata == nil {
+		return nil
+	}
+	return request.Metadata.UserID
+}
+
+// imagesUsage maps the usage of an images response, which only the GPT
+// image models report.
+func imagesUsage(response *ImagesResponse) GenerateUsage {
+	var usage GenerateUsage
+	if response.Usage == nil {
+		return usage
+	}
+	if response.Usage.InputTokens != nil {
+		usage.InputTokens = *response.Usage.InputTokens
+	}
+	if response.Usage.InputTokensDetails != nil && response.Usage.InputTokensDetails.CachedTokens != nil {
+		usage.CachedInputTokens = *response.Usage.InputTokensDetails.CachedTokens
+	}
+	if response.Usage.OutputTokens != nil {
+		usage.OutputTokens = *response.Usage.OutputTokens
+	}
+	if response.Usage.TotalTokens != nil {
+		usage.TotalTokens = *response.Usage.TotalTokens
+	}
+	return usage
+}
+
+// usageCall is one call made through a client with a UsageTracker. Its
+// methods are no-ops on a nil call, so clients without a tracker skip them.
+type usageCall struct {
+	tracker  *UsageTracker
+	provider Provider
+	model    string
+	labels   map[string]string
+}
+
+// start checks the budgets before a call. user, when set, is tracked as
+// the "user" label.
+func (t *UsageTracker) start(ctx context.Context, provider Provider, model string, user *string) (*usageCall, error) {
+	if t == nil {
+		return nil, nil
+	}
+
+	labels := maps.Clone(UsageLabels(ctx))
+	if user != nil && *user != "" {
+		if labels == nil {
+			labels = make(map[string]string, 1)
+		}
+		if _, ok := labels["user"]; !ok {
+			labels["user"] = *user
+		}
+	}
+
+	if err := t.Check(provider, model, labels); err != nil {
+		return nil, err
+	}
+	return &usageCall{tracker: t, provider: provider, model: model, labels: labels}, nil
+}
+
+// done records the usage of a finished call.
+func (c *usageCall) done(ctx context.Context, usage GenerateUsage) {
+	if c == nil {
+		return
+	}
+	c.tracker.Record(ctx, c.provider, c.model, c.labels, usage)
+}
+
+// stream passes events through and records the usage reported in them once
+// the stream ends.
+func (c *usageCall) stream(ctx context.Context, events <-chan SSEvent, api API) <-chan SSEvent {
+	if c == nil {
+		return events
+	}
+	return watchUsage(ctx, events, api, func(usage GenerateUsage) {
+		c.done(context.WithoutCancel(ctx), usage)
+	})
+}
+
+// watchUsage passes events through and calls done with the usage reported
+// in them once the stream ends, or with no usage if it can't be read.
+func watchUsage(ctx context.Context, events <-chan SSEvent, api API, done func(GenerateUsage)) <-chan SSEvent {
+	return WatchStream(ctx, events, api, func(response *GenerateResponse) {
+		var usage GenerateUsage
+		if response != nil {
+			usage = response.Usage
+		}
+		done(usage)
+	})
+}
+
+// WatchStream passes the events of a stream from api through and calls done
+// with the response they add up to once the stream ends, or with nil if the
+// events can't be read. Use it to observe streams, e.g. in an Interceptor.
+func WatchStream(ctx context.Context, events <-chan SSEvent, api API, done func(*GenerateResponse)) <-chan SSEvent {
+	out := make(chan SSEvent, 100)
+	go func() {
+		defer close(out)
+
+		acc := newStreamAccumulator(api)
+		failed := false
+		defer func() {
+			response, err := acc.result()
+			if err != nil {
+				response = nil
+			}
+			done(response)
+		}()
+
+		for event := range events {
+			if !failed && event.Event != nil && *event.Event == ContentDelta && event.Data != nil {
+				// Keep passing events through even if they don't parse.
+				_, err := acc.add(*event.Data)
+				failed = err != nil
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
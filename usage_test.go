@@ -0,0 +1,217 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// newUsageGateway prices gpt-4o with testPricing and answers every chat
+// completion with 1000 prompt and 100 completion tokens, costing 0.0035. The
+// tracker prices usage through the gateway unless options set Costs.
+func newUsageGateway(t *testing.T, options *UsageTrackerOptions) (*testGateway, *UsageTracker, Client) {
+	t.Helper()
+	pricing, err := json.Marshal(testPricing())
+	require.NoError(t, err)
+
+	gateway := newTestGateway(t)
+	gateway.handle("GET /v1/models", listModelsRoute(fmt.Sprintf(`{
+		"id": "openai/gpt-4o", "object": "model", "created": 1, "owned_by": "openai", "served_by": "openai",
+		"pricing": %s
+	}`, pricing)))
+	gateway.handle("POST /v1/chat/completions", chatRoute("Hi", &CompletionUsage{PromptTokens: 1000, CompletionTokens: 100, TotalTokens: 1100}))
+	gateway.handle("POST /v1/messages", func(w http.ResponseWriter, _ *gatewayRequest) {
+		sseReply(
+			`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4","content":[],"usage":{"input_tokens":20,"output_tokens":1,"cache_read_input_tokens":100}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}`,
+			`{"type":"message_stop"}`,
+		)(w)
+	})
+
+	var o UsageTrackerOptions
+	if options != nil {
+		o = *options
+	}
+	if o.Costs == nil {
+		o.Costs = NewCostCalculator(gateway.client(nil), nil)
+	}
+	tracker := NewUsageTracker(&o)
+	return gateway, tracker, gateway.client(&ClientOptions{UsageTracker: tracker})
+}
+
+func hello() []Message {
+	return []Message{{Role: User, Content: NewMessageContent("Hi")}}
+}
+
+func TestUsageTracker_Aggregates(t *testing.T) {
+	_, tracker, client := newUsageGateway(t, nil)
+
+	ctx := WithUsageLabels(context.Background(), map[string]string{"tenant": "a"})
+	_, err := client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	_, err = client.GenerateContent(ctx, Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+
+	user := "alice"
+	_, err = client.WithOptions(&CreateChatCompletionRequest{User: &user}).GenerateContent(context.Background(), Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+
+	records := tracker.Records()
+	require.Len(t, records, 2)
+	assert.Equal(t, map[string]string{"tenant": "a"}, records[0].Labels)
+	assert.Equal(t, int64(2), records[0].Requests)
+	assert.Equal(t, int64(2200), records[0].TotalTokens)
+	assert.Equal(t, "0.007", records[0].Cost.String())
+	assert.Equal(t, "USD", records[0].Currency)
+	assert.Equal(t, map[string]string{"user": "alice"}, records[1].Labels)
+
+	totals := tracker.Totals(UsageScope{Provider: Openai})
+	assert.Equal(t, int64(3), totals.Requests)
+	assert.Equal(t, "0.0105", totals.Cost.String())
+	assert.Equal(t, int64(1), tracker.Totals(UsageScope{Labels: map[string]string{"user": "alice"}}).Requests)
+	assert.Zero(t, tracker.Totals(UsageScope{Model: "gpt-4o-mini"}).Requests)
+}
+
+func TestUsageTracker_WithoutCosts(t *testing.T) {
+	gateway, _, _ := newUsageGateway(t, nil)
+	tracker := NewUsageTracker(nil)
+	client := gateway.client(&ClientOptions{UsageTracker: tracker})
+
+	_, err := client.GenerateContent(context.Background(), Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+
+	totals := tracker.Totals(UsageScope{})
+	assert.Equal(t, int64(1100), totals.TotalTokens)
+	assert.True(t, totals.Cost.IsZero())
+	assert.Empty(t, gateway.requestsTo("/v1/models"), "usage is only priced with Costs")
+}
+
+func TestUsageTracker_HardBudget(t *testing.T) {
+	server, _, client := newUsageGateway(t, &UsageTrackerOptions{Budgets: []Budget{{
+		Name:    "tenant-a",
+		Scope:   UsageScope{Labels: map[string]string{"tenant": "a"}},
+		MaxCost: MustParseDecimal("0.005"),
+	}}})
+
+	tenantA := WithUsageLabels(context.Background(), map[string]string{"tenant": "a"})
+	for range 2 {
+		_, err := client.GenerateContent(tenantA, Openai, "gpt-4o", hello())
+		require.NoError(t, err)
+	}
+
+	_, err := client.GenerateContent(tenantA, Openai, "gpt-4o", hello())
+	require.ErrorIs(t, err, ErrBudgetExceeded)
+	var budgetErr *BudgetExceededError
+	require.True(t, errors.As(err, &budgetErr))
+	assert.Equal(t, "tenant-a", budgetErr.Budget.Name)
+	assert.Equal(t, "0.007", budgetErr.Cost.String())
+	assert.EqualError(t, err, `budget "tenant-a" exceeded: used 2200 tokens and 0.007`)
+
+	_, err = client.GenerateContentStream(tenantA, Openai, "gpt-4o", hello())
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.Len(t, server.requestsTo("/v1/chat/completions"), 2, "rejected calls are not sent")
+
+	// Other tenants are unaffected.
+	_, err = client.GenerateContent(context.Background(), Openai, "gpt-4o", hello())
+	assert.NoError(t, err)
+}
+
+func TestUsageTracker_SoftBudgetThresholds(t *testing.T) {
+	var events []BudgetEvent
+	_, _, client := newUsageGateway(t, &UsageTrackerOptions{
+		Budgets: []Budget{{
+			Name:       "daily",
+			MaxTokens:  4000,
+			Soft:       true,
+			Thresholds: []float64{1, 0.5},
+		}},
+		OnThreshold: func(event BudgetEvent) { events = append(events, event) },
+	})
+
+	for range 5 {
+		_, err := client.GenerateContent(context.Background(), Openai, "gpt-4o", hello())
+		require.NoError(t, err, "soft budgets never reject")
+	}
+
+	require.Len(t, events, 2)
+	assert.Equal(t, 0.5, events[0].Threshold)
+	assert.Equal(t, int64(2200), events[0].Tokens)
+	assert.Equal(t, 1.0, events[1].Threshold)
+	assert.Equal(t, int64(4400), events[1].Tokens)
+}
+
+func TestUsageTracker_Streams(t *testing.T) {
+	server, tracker, client := newUsageGateway(t, nil)
+
+	events, err := client.GenerateContentStream(context.Background(), Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	for range events {
+	}
+	assert.Equal(t, map[string]any{"include_usage": true}, server.requestsTo("/v1/chat/completions")[0].body["stream_options"])
+
+	var content MessagesMessage_Content
+	require.NoError(t, content.FromMessagesMessageContent0("Hi"))
+	events, err = client.CreateMessageStream(context.Background(), Anthropic, CreateMessagesRequest{
+		Model:     "claude-sonnet-4",
+		MaxTokens: 1024,
+		Messages:  []MessagesMessage{{Role: MessagesMessageRoleUser, Content: content}},
+		Metadata:  &MessagesMetadata{UserID: new("bob")},
+	})
+	require.NoError(t, err)
+	count := 0
+	for range events {
+		count++
+	}
+	assert.Equal(t, 6, count, "events are passed through")
+
+	records := tracker.Records()
+	require.Len(t, records, 2)
+	assert.Equal(t, Anthropic, records[0].Provider)
+	assert.Equal(t, map[string]string{"user": "bob"}, records[0].Labels)
+	assert.Equal(t, int64(120), records[0].InputTokens)
+	assert.Equal(t, int64(100), records[0].CachedInputTokens)
+	assert.Equal(t, int64(5), records[0].OutputTokens)
+	assert.True(t, records[0].Cost.IsZero(), "claude-sonnet-4 has no pricing")
+	assert.Equal(t, int64(1100), records[1].TotalTokens)
+	assert.Equal(t, "0.0035", records[1].Cost.String())
+}
+
+func TestUsageTracker_Snapshot(t *testing.T) {
+	var events []BudgetEvent
+	budgets := []Budget{{Name: "all", MaxTokens: 2000, Thresholds: []float64{0.5}}}
+	_, tracker, client := newUsageGateway(t, &UsageTrackerOptions{Budgets: budgets, OnThreshold: func(event BudgetEvent) { events = append(events, event) }})
+
+	_, err := client.GenerateContent(WithUsageLabels(context.Background(), map[string]string{"job": "nightly"}), Openai, "gpt-4o", hello())
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	data, err := json.Marshal(tracker)
+	require.NoError(t, err)
+
+	events = nil
+	restored := NewUsageTracker(&UsageTrackerOptions{Budgets: budgets, OnThreshold: func(event BudgetEvent) { events = append(events, event) }})
+	require.NoError(t, json.Unmarshal(data, restored))
+	again, err := json.Marshal(restored)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(data), string(again))
+	assert.Empty(t, events, "restored thresholds are not reported again")
+
+	restored.Record(context.Background(), Openai, "gpt-4o", nil, GenerateUsage{InputTokens: 900, OutputTokens: 100})
+	assert.Empty(t, events)
+	assert.ErrorIs(t, restored.Check(Openai, "gpt-4o", nil), ErrBudgetExceeded)
+
+	restored.Reset()
+	assert.Empty(t, restored.Records())
+	assert.NoError(t, restored.Check(Openai, "gpt-4o", nil))
+
+	assert.EqualError(t, json.Unmarshal([]byte(`{"version": 2, "records": []}`), restored), "unsupported usage snapshot version 2")
+}
@@ -0,0 +1,408 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Limits from the OpenAI-compatible specification.
+const (
+	maxRequestTools  = 128
+	maxStopSequences = 4
+	maxTopLogprobs   = 20
+	maxChoices       = 128
+)
+
+// RequestProblem is a single reason a request would be rejected.
+type RequestProblem struct {
+	// Field locates the offending value using JSON names, e.g.
+	// `messages[2].content[0]`.
+	Field string `json:"field"`
+	// Message describes the problem.
+	Message string `json:"message"`
+}
+
+// RequestValidationError lists every problem a RequestValidator found in a
+// request.
+type RequestValidationError struct {
+	Problems []RequestProblem
+}
+
+func (e *RequestValidationError) Error() string {
+	parts := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		parts[i] = fmt.Sprintf("%s: %s", p.Field, p.Message)
+	}
+	return "invalid request: " + strings.Join(parts, "; ")
+}
+
+// RequestValidator checks requests before they are sent, so mistakes
+// surface without a round trip to the gateway. Set it as
+// ClientOptions.Validator to check every chat, Messages and Responses call.
+//
+// Without a catalog it checks enums, ranges and spec limits. With one it
+// also checks that the model is listed, accepts image input when images are
+// sent, and has room for the requested output tokens. Capabilities the
+// gateway doesn't report are not checked.
+//
+// A nil *RequestValidator accepts every request.
+type RequestValidator struct {
+	catalog *ModelCatalog
+}
+
+// NewRequestValidator creates a RequestValidator that looks up models in
+// catalog, which may be nil.
+//
+// Example:
+//
+//	catalog := sdk.NewModelCatalog(sdk.NewClient(&sdk.ClientOptions{BaseURL: baseURL}), nil)
+//	client := sdk.NewClient(&sdk.ClientOptions{
+//		BaseURL:   baseURL,
+//		Validator: sdk.NewRequestValidator(catalog),
+//	})
+func NewRequestValidator(catalog *ModelCatalog) *RequestValidator {
+	return &RequestValidator{catalog: catalog}
+}
+
+// requestCheck collects the problems found in one request.
+type requestCheck struct {
+	model    *Model
+	problems []RequestProblem
+}
+
+func (c *requestCheck) fail(field, format string, args ...any) {
+	c.problems = append(c.problems, RequestProblem{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *requestCheck) err() error {
+	if len(c.problems) == 0 {
+		return nil
+	}
+	return &RequestValidationError{Problems: c.problems}
+}
+
+// begin checks provider and model, looking the model up in the catalog.
+func (v *RequestValidator) begin(ctx context.Context, provider Provider, model string) (*requestCheck, error) {
+	c := &requestCheck{}
+	if provider != "" && !provider.Valid() {
+		c.fail("provider", "unknown provider %q", provider)
+	}
+	if model == "" {
+		c.fail("model", "is required")
+		return c, nil
+	}
+	if v.catalog != nil {
+		found, err := v.catalog.Lookup(ctx, provider, model)
+		if err != nil {
+			return nil, err
+		}
+		if found == nil {
+			c.fail("model", "%q is not listed by the gateway", model)
+		}
+		c.model = found
+	}
+	return c, nil
+}
+
+func (c *requestCheck) enum(field, value string, valid bool) {
+	if !valid {
+		c.fail(field, "unknown value %q", value)
+	}
+}
+
+func (c *requestCheck) between(field string, value *float32, low, high float64) {
+	if value != nil && (float64(*value) < low || float64(*value) > high) {
+		c.fail(field, "must be between %g and %g, got %s", low, high, strconv.FormatFloat(float64(*value), 'g', -1, 32))
+	}
+}
+
+func (c *requestCheck) atMost(field string, count, limit int, noun string) {
+	if count > limit {
+		c.fail(field, "at most %d %s are allowed, got %d", limit, noun, count)
+	}
+}
+
+// image reports image input to a model that doesn't accept it.
+func (c *requestCheck) image(field string) {
+	if c.model != nil && c.model.Modalities != nil && !c.model.AcceptsInput(ModalityImage) {
+		c.fail(field, "%s does not accept image input", c.model.ID)
+	}
+}
+
+// outputTokens checks a max tokens setting against the context window.
+func (c *requestCheck) outputTokens(field string, value int) {
+	switch {
+	case value < 1:
+		c.fail(field, "must be at least 1, got %d", value)
+	case c.model != nil && c.model.ContextWindow != nil && value > c.model.ContextWindow.Tokens:
+		c.fail(field, "%d exceeds the context window of %s (%d tokens)", value, c.model.ID, c.model.ContextWindow.Tokens)
+	}
+}
+
+// namedTool reports a forced tool that isn't among the request's tools.
+func (c *requestCheck) namedTool(field, name string, tools []string) {
+	if !slices.Contains(tools, name) {
+		c.fail(field, "names %q, which is not one of the request's tools", name)
+	}
+}
+
+// ValidateChat checks a chat completion request.
+func (v *RequestValidator) ValidateChat(ctx context.Context, provider Provider, request CreateChatCompletionRequest) error {
+	if v == nil {
+		return nil
+	}
+	c, err := v.begin(ctx, provider, request.Model)
+	if err != nil {
+		return err
+	}
+
+	if len(request.Messages) == 0 {
+		c.fail("messages", "at least one message is required")
+	}
+	for i, message := range request.Messages {
+		field := fmt.Sprintf("messages[%d]", i)
+		c.enum(field+".role", string(message.Role), message.Role.Valid())
+		content, err := message.Content.Value()
+		if err != nil {
+			c.fail(field+".content", "%v", err)
+			continue
+		}
+		parts, _ := content.([]ContentPart)
+		for j, part := range parts {
+			partField := fmt.Sprintf("%s.content[%d]", field, j)
+			value, err := part.Value()
+			if err != nil {
+				c.fail(partField, "%v", err)
+				continue
+			}
+			if image, ok := value.(ImageContentPart); ok {
+				c.image(partField)
+				if detail := image.ImageURL.Detail; detail != nil {
+					c.enum(partField+".image_url.detail", string(*detail), detail.Valid())
+				}
+			}
+		}
+	}
+
+	var tools []string
+	if request.Tools != nil {
+		c.atMost("tools", len(*request.Tools), maxRequestTools, "tools")
+		for i, tool := range *request.Tools {
+			c.enum(fmt.Sprintf("tools[%d].type", i), string(tool.Type), tool.Type.Valid())
+			tools = append(tools, tool.Function.Name)
+		}
+	}
+	if request.ToolChoice != nil {
+		switch choice, err := request.ToolChoice.Value(); choice := choice.(type) {
+		case nil:
+			if err != nil {
+				c.fail("tool_choice", "%v", err)
+			}
+		case ChatCompletionToolChoiceOption0:
+			c.enum("tool_choice", string(choice), choice.Valid())
+		case ChatCompletionNamedToolChoice:
+			c.namedTool("tool_choice.function.name", choice.Function.Name, tools)
+		}
+	}
+	if request.Stop != nil {
+		sequences, err := request.Stop.Sequences()
+		if err != nil {
+			c.fail("stop", "%v", err)
+		}
+		c.atMost("stop", len(sequences), maxStopSequences, "sequences")
+	}
+	if request.ResponseFormat != nil {
+		if _, err := request.ResponseFormat.Value(); err != nil {
+			c.fail("response_format", "%v", err)
+		}
+	}
+
+	c.between("temperature", request.Temperature, 0, 2)
+	c.between("top_p", request.TopP, 0, 1)
+	c.between("frequency_penalty", request.FrequencyPenalty, -2, 2)
+	c.between("presence_penalty", request.PresencePenalty, -2, 2)
+	if request.LogitBias != nil {
+		for _, token := range slices.Sorted(maps.Keys(*request.LogitBias)) {
+			if bias := (*request.LogitBias)[token]; bias < -100 || bias > 100 {
+				c.fail(fmt.Sprintf("logit_bias[%s]", token), "must be between -100 and 100, got %d", bias)
+			}
+		}
+	}
+	if request.N != nil && (*request.N < 1 || *request.N > maxChoices) {
+		c.fail("n", "must be between 1 and %d, got %d", maxChoices, *request.N)
+	}
+	if request.TopLogprobs != nil {
+		if *request.TopLogprobs < 0 || *request.TopLogprobs > maxTopLogprobs {
+			c.fail("top_logprobs", "must be between 0 and %d, got %d", maxTopLogprobs, *request.TopLogprobs)
+		}
+		if request.Logprobs == nil || !*request.Logprobs {
+			c.fail("top_logprobs", "requires logprobs to be true")
+		}
+	}
+	if request.MaxTokens != nil {
+		c.outputTokens("max_tokens", *request.MaxTokens)
+	}
+	if request.MaxCompletionTokens != nil {
+		c.outputTokens("max_completion_tokens", *request.MaxCompletionTokens)
+	}
+	if effort := request.ReasoningEffort; effort != nil {
+		c.enum("reasoning_effort", string(*effort), effort.Valid())
+	}
+	return c.err()
+}
+
+// ValidateMessages checks a Messages API request.
+func (v *RequestValidator) ValidateMessages(ctx context.Context, provider Provider, request CreateMessagesRequest) error {
+	if v == nil {
+		return nil
+	}
+	c, err := v.begin(ctx, provider, request.Model)
+	if err != nil {
+		return err
+	}
+
+	if len(request.Messages) == 0 {
+		c.fail("messages", "at least one message is required")
+	}
+	for i, message := range request.Messages {
+		field := fmt.Sprintf("messages[%d]", i)
+		c.enum(field+".role", string(message.Role), message.Role.Valid())
+		content, err := message.Content.Value()
+		if err != nil {
+			c.fail(field+".content", "%v", err)
+			continue
+		}
+		blocks, _ := content.([]MessagesRequestContentBlock)
+		for j, block := range blocks {
+			blockField := fmt.Sprintf("%s.content[%d]", field, j)
+			value, err := block.Value()
+			if err != nil {
+				c.fail(blockField, "%v", err)
+				continue
+			}
+			if image, ok := value.(MessagesImageBlock); ok {
+				c.image(blockField)
+				c.enum(blockField+".source.type", string(image.Source.Type), image.Source.Type.Valid())
+			}
+		}
+	}
+
+	var tools []string
+	if request.Tools != nil {
+		for _, tool := range *request.Tools {
+			tools = append(tools, tool.Name)
+		}
+	}
+	if request.ToolChoice != nil {
+		switch choice, err := request.ToolChoice.Value(); choice := choice.(type) {
+		case nil:
+			if err != nil {
+				c.fail("tool_choice", "%v", err)
+			}
+		case MessagesToolChoice0:
+			c.enum("tool_choice", string(choice), choice.Valid())
+		case MessagesToolChoice1:
+			c.namedTool("tool_choice.name", choice.Name, tools)
+		}
+	}
+	if request.System != nil {
+		if _, err := request.System.Value(); err != nil {
+			c.fail("system", "%v", err)
+		}
+	}
+
+	c.between("temperature", request.Temperature, 0, 1)
+	c.between("top_p", request.TopP, 0, 1)
+	if request.TopK != nil && *request.TopK < 0 {
+		c.fail("top_k", "must not be negative, got %d", *request.TopK)
+	}
+	c.outputTokens("max_tokens", request.MaxTokens)
+	if thinking := request.Thinking; thinking != nil {
+		c.enum("thinking.type", string(thinking.Type), thinking.Type.Valid())
+		if thinking.BudgetTokens >= request.MaxTokens {
+			c.fail("thinking.budget_tokens", "must be less than max_tokens (%d), got %d", request.MaxTokens, thinking.BudgetTokens)
+		}
+	}
+	return c.err()
+}
+
+// ValidateResponse checks a Responses API request.
+func (v *RequestValidator) ValidateResponse(ctx context.Context, provider Provider, request CreateResponseRequest) error {
+	if v == nil {
+		return nil
+	}
+	c, err := v.begin(ctx, provider, request.Model)
+	if err != nil {
+		return err
+	}
+
+	input, err := request.Input.Value()
+	if err != nil {
+		c.fail("input", "%v", err)
+	}
+	items, _ := input.([]ResponseInputItem)
+	for i, item := range items {
+		field := fmt.Sprintf("input[%d]", i)
+		c.enum(field+".role", string(item.Role), item.Role.Valid())
+		content, err := item.Content.Value()
+		if err != nil {
+			c.fail(field+".content", "%v", err)
+			continue
+		}
+		parts, _ := content.([]ResponseInputContentPart)
+		for j, part := range parts {
+			partField := fmt.Sprintf("%s.content[%d]", field, j)
+			value, err := part.Value()
+			if err != nil {
+				c.fail(partField, "%v", err)
+				continue
+			}
+			if image, ok := value.(ResponseInputImage); ok {
+				c.image(partField)
+				if detail := image.Detail; detail != nil {
+					c.enum(partField+".detail", string(*detail), detail.Valid())
+				}
+			}
+		}
+	}
+
+	var tools []string
+	if request.Tools != nil {
+		c.atMost("tools", len(*request.Tools), maxRequestTools, "tools")
+		for i, tool := range *request.Tools {
+			c.enum(fmt.Sprintf("tools[%d].type", i), string(tool.Type), tool.Type.Valid())
+			tools = append(tools, tool.Name)
+		}
+	}
+	if request.ToolChoice != nil {
+		switch choice, err := request.ToolChoice.Value(); choice := choice.(type) {
+		case nil:
+			if err != nil {
+				c.fail("tool_choice", "%v", err)
+			}
+		case ResponseToolChoice0:
+			c.enum("tool_choice", string(choice), choice.Valid())
+		case ResponseToolChoice1:
+			c.namedTool("tool_choice.name", choice.Name, tools)
+		}
+	}
+
+	c.between("temperature", request.Temperature, 0, 2)
+	c.between("top_p", request.TopP, 0, 1)
+	if request.MaxOutputTokens != nil {
+		c.outputTokens("max_output_tokens", *request.MaxOutputTokens)
+	}
+	if reasoning := request.Reasoning; reasoning != nil {
+		if reasoning.Effort != nil {
+			c.enum("reasoning.effort", string(*reasoning.Effort), reasoning.Effort.Valid())
+		}
+		if reasoning.Summary != nil {
+			c.enum("reasoning.summary", string(*reasoning.Summary), reasoning.Summary.Valid())
+		}
+	}
+	return c.err()
+}
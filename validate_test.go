@@ -0,0 +1,179 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+func requestProblems(t *testing.T, err error) []RequestProblem {
+	t.Helper()
+	var validationErr *RequestValidationError
+	require.True(t, errors.As(err, &validationErr), "got %v", err)
+	return validationErr.Problems
+}
+
+func TestRequestValidator_Chat(t *testing.T) {
+	validator := NewRequestValidator(nil)
+	ctx := context.Background()
+
+	valid := CreateChatCompletionRequest{Model: "gpt-4o", Messages: hello()}
+	assert.NoError(t, validator.ValidateChat(ctx, Openai, valid))
+
+	var request CreateChatCompletionRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"model": "gpt-4o",
+		"messages": [
+			{"role": "wizard", "content": "Hi"},
+			{"role": "user", "content": [{"type": "image_url", "image_url": {"url": "https://example.com/cat.png", "detail": "ultra"}}]}
+		],
+		"stop": ["a", "b", "c", "d", "e"],
+		"temperature": 2.5,
+		"top_p": 1,
+		"presence_penalty": -3,
+		"logit_bias": {"50256": -101},
+		"n": 0,
+		"top_logprobs": 21,
+		"max_completion_tokens": 0,
+		"reasoning_effort": "extreme",
+		"tools": [{"type": "function", "function": {"name": "get_weather"}}],
+		"tool_choice": {"type": "function", "function": {"name": "get_time"}}
+	}`), &request))
+
+	err := validator.ValidateChat(ctx, "bogus", request)
+	assert.Equal(t, []RequestProblem{
+		{Field: "provider", Message: `unknown provider "bogus"`},
+		{Field: "messages[0].role", Message: `unknown value "wizard"`},
+		{Field: "messages[1].content[0].image_url.detail", Message: `unknown value "ultra"`},
+		{Field: "tool_choice.function.name", Message: `names "get_time", which is not one of the request's tools`},
+		{Field: "stop", Message: "at most 4 sequences are allowed, got 5"},
+		{Field: "temperature", Message: "must be between 0 and 2, got 2.5"},
+		{Field: "presence_penalty", Message: "must be between -2 and 2, got -3"},
+		{Field: "logit_bias[50256]", Message: "must be between -100 and 100, got -101"},
+		{Field: "n", Message: "must be between 1 and 128, got 0"},
+		{Field: "top_logprobs", Message: "must be between 0 and 20, got 21"},
+		{Field: "top_logprobs", Message: "requires logprobs to be true"},
+		{Field: "max_completion_tokens", Message: "must be at least 1, got 0"},
+		{Field: "reasoning_effort", Message: `unknown value "extreme"`},
+	}, requestProblems(t, err))
+	assert.ErrorContains(t, err, `invalid request: provider: unknown provider "bogus"; messages[0].role: `)
+
+	tools := make([]ChatCompletionTool, 129)
+	for i := range tools {
+		tools[i] = ChatCompletionTool{Type: Function, Function: weatherTool()}
+	}
+	err = validator.ValidateChat(ctx, Openai, CreateChatCompletionRequest{Messages: hello(), Tools: &tools})
+	assert.Equal(t, []RequestProblem{
+		{Field: "model", Message: "is required"},
+		{Field: "tools", Message: "at most 128 tools are allowed, got 129"},
+	}, requestProblems(t, err))
+
+	var nilValidator *RequestValidator
+	assert.NoError(t, nilValidator.ValidateChat(ctx, "bogus", CreateChatCompletionRequest{}))
+}
+
+func TestRequestValidator_Catalog(t *testing.T) {
+	_, client := newCatalogServer(t)
+	validator := NewRequestValidator(NewModelCatalog(client, nil))
+	ctx := context.Background()
+
+	image, err := NewImageContentPart("https://example.com/cat.png", nil)
+	require.NoError(t, err)
+	messages := []Message{{Role: User, Content: NewMessageContent([]ContentPart{image})}}
+
+	assert.NoError(t, validator.ValidateChat(ctx, Openai, CreateChatCompletionRequest{Model: "gpt-4o", Messages: messages}))
+
+	err = validator.ValidateChat(ctx, Anthropic, CreateChatCompletionRequest{
+		Model:     "claude-3-haiku",
+		Messages:  messages,
+		MaxTokens: new(300000),
+	})
+	assert.Equal(t, []RequestProblem{
+		{Field: "messages[0].content[0]", Message: "anthropic/claude-3-haiku does not accept image input"},
+		{Field: "max_tokens", Message: "300000 exceeds the context window of anthropic/claude-3-haiku (200000 tokens)"},
+	}, requestProblems(t, err))
+
+	err = validator.ValidateChat(ctx, Openai, CreateChatCompletionRequest{Model: "gpt-5", Messages: hello()})
+	assert.Equal(t, []RequestProblem{{Field: "model", Message: `"gpt-5" is not listed by the gateway`}}, requestProblems(t, err))
+}
+
+func TestRequestValidator_Messages(t *testing.T) {
+	_, client := newCatalogServer(t)
+	validator := NewRequestValidator(NewModelCatalog(client, nil))
+
+	var request CreateMessagesRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"model": "claude-3-haiku",
+		"max_tokens": 2048,
+		"temperature": 1.5,
+		"top_k": -1,
+		"thinking": {"type": "enabled", "budget_tokens": 4096},
+		"tool_choice": {"type": "tool", "name": "lookup"},
+		"messages": [{"role": "user", "content": [
+			{"type": "text", "text": "What is this?"},
+			{"type": "image", "source": {"type": "url", "url": "https://example.com/cat.png"}}
+		]}]
+	}`), &request))
+
+	err := validator.ValidateMessages(context.Background(), Anthropic, request)
+	assert.Equal(t, []RequestProblem{
+		{Field: "messages[0].content[1]", Message: "anthropic/claude-3-haiku does not accept image input"},
+		{Field: "tool_choice.name", Message: `names "lookup", which is not one of the request's tools`},
+		{Field: "temperature", Message: "must be between 0 and 1, got 1.5"},
+		{Field: "top_k", Message: "must not be negative, got -1"},
+		{Field: "thinking.budget_tokens", Message: "must be less than max_tokens (2048), got 4096"},
+	}, requestProblems(t, err))
+}
+
+func TestRequestValidator_Response(t *testing.T) {
+	_, client := newCatalogServer(t)
+	validator := NewRequestValidator(NewModelCatalog(client, nil))
+
+	var request CreateResponseRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"model": "gpt-4o",
+		"max_output_tokens": 200000,
+		"top_p": 1.5,
+		"reasoning": {"effort": "max"},
+		"input": [{"role": "user", "content": [
+			{"type": "input_text", "text": "What is this?"},
+			{"type": "input_image", "image_url": "https://example.com/cat.png", "detail": "auto"}
+		]}]
+	}`), &request))
+
+	err := validator.ValidateResponse(context.Background(), Openai, request)
+	assert.Equal(t, []RequestProblem{
+		{Field: "top_p", Message: "must be between 0 and 1, got 1.5"},
+		{Field: "max_output_tokens", Message: "200000 exceeds the context window of openai/gpt-4o (128000 tokens)"},
+		{Field: "reasoning.effort", Message: `unknown value "max"`},
+	}, requestProblems(t, err))
+}
+
+func TestClient_Validator(t *testing.T) {
+	// Nothing listens on the client's address, so a request that got sent
+	// would fail with a connection error instead.
+	_, catalogClient := newCatalogServer(t)
+	validator := NewRequestValidator(NewModelCatalog(catalogClient, nil))
+	client := NewClient(&ClientOptions{BaseURL: "http://127.0.0.1:1/v1", Validator: validator})
+	ctx := context.Background()
+
+	events, err := client.GenerateContentStream(ctx, Openai, "gpt-5", hello())
+	assert.EqualError(t, err, `invalid request: model: "gpt-5" is not listed by the gateway`)
+	_, open := <-events
+	assert.False(t, open)
+
+	_, err = client.CreateMessage(ctx, Anthropic, CreateMessagesRequest{Model: "claude-sonnet-4", Messages: []MessagesMessage{}})
+	assert.EqualError(t, err, "invalid request: messages: at least one message is required; max_tokens: must be at least 1, got 0")
+
+	var input ResponseInput
+	require.NoError(t, input.FromResponseInput0("Hi"))
+	_, err = client.CreateResponseStream(ctx, "bogus", CreateResponseRequest{Model: "gpt-4o", Input: input})
+	assert.EqualError(t, err, `invalid request: provider: unknown provider "bogus"; model: "gpt-4o" is not listed by the gateway`)
+
+	_, err = client.WithOptions(&CreateChatCompletionRequest{Temperature: new(float32(3))}).GenerateContent(ctx, Openai, "gpt-4o", hello())
+	assert.EqualError(t, err, "invalid request: temperature: must be between 0 and 2, got 3")
+}